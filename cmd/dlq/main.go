@@ -0,0 +1,220 @@
+// Command dlq inspecciona y re-publica los mensajes de las colas muertas del worker
+// (una por riel: review, prep, material). Reemplaza el replay a mano desde la UI de
+// RabbitMQ:
+//
+//   - list: lista los mensajes de la DLQ con event_type, ids de correlación, el
+//     header x-death (muertes y motivo) y el destino original. No modifica la cola.
+//   - replay: re-publica los mensajes que pasan el filtro a su exchange/routing key
+//     originales y los ACKea en la DLQ; el resto queda en la cola. Con -dry-run solo
+//     reporta la decisión por mensaje.
+//
+// Filtros (se combinan con AND): -event-type, -school-id, -job-id, -attempt-id y la
+// ventana -since/-until sobre la hora de la última muerte (RFC3339 o una duración
+// hacia atrás: -since 2h).
+//
+// Uso:
+//
+//	go run ./cmd/dlq list   -lane material
+//	go run ./cmd/dlq replay -lane review -school-id 9f1c... -since 6h --dry-run
+//	go run ./cmd/dlq replay -lane all -event-type question.prep_requested -json
+//
+// Toma la URL de RabbitMQ, los nombres de las DLQ y los exchanges de la misma
+// configuración que el worker (config/ + RABBITMQ_URL); -url la sobrescribe.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/config"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/dlq"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/publisher"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "list" && os.Args[1] != "replay") {
+		fmt.Fprintln(os.Stderr, "uso: dlq <list|replay> [flags]  (dlq <cmd> -h para ver los flags)")
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	lane := fs.String("lane", "all", "DLQ a leer: nombre de un carril de `lanes:` (review, prep, material...) o all")
+	queue := fs.String("queue", "", "nombre explícito de la DLQ (sobrescribe -lane)")
+	url := fs.String("url", "", "URL de RabbitMQ (vacío = la de la configuración del worker)")
+	eventTypes := fs.String("event-type", "", "event_types a seleccionar, coma-separados (vacío = todos)")
+	schoolID := fs.String("school-id", "", "selecciona por payload.school_id")
+	jobID := fs.String("job-id", "", "selecciona por payload.job_id (carril de materiales)")
+	attemptID := fs.String("attempt-id", "", "selecciona por payload.attempt_id (carril de revisión)")
+	since := fs.String("since", "", "inicio de la ventana: RFC3339 o duración hacia atrás (p.ej. 2h)")
+	until := fs.String("until", "", "fin de la ventana (exclusivo): RFC3339 o duración hacia atrás")
+	limit := fs.Int("limit", 1000, "máximo de mensajes a leer por DLQ (<= 0 = todos)")
+	dryRun := fs.Bool("dry-run", false, "replay: reporta la decisión por mensaje sin re-publicar")
+	asJSON := fs.Bool("json", false, "salida en JSON lines en vez de tabla")
+	_ = fs.Parse(os.Args[2:])
+
+	now := time.Now()
+	filter := dlq.Filter{
+		SchoolID:  *schoolID,
+		JobID:     *jobID,
+		AttemptID: *attemptID,
+	}
+	if *eventTypes != "" {
+		filter.EventTypes = splitCSV(*eventTypes)
+	}
+	var err error
+	if filter.Since, err = parseWindow(*since, now); err != nil {
+		fatalf("-since: %v", err)
+	}
+	if filter.Until, err = parseWindow(*until, now); err != nil {
+		fatalf("-until: %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatalf("cargando configuración: %v", err)
+	}
+	if *url == "" {
+		*url = cfg.Messaging.RabbitMQ.URL
+	}
+	if *url == "" {
+		fatalf("falta la URL de RabbitMQ (-url o RABBITMQ_URL)")
+	}
+
+	queues, err := resolveQueues(cfg, *lane, *queue)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	conn, err := amqp.Dial(*url)
+	if err != nil {
+		fatalf("conectando a RabbitMQ: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	ch, err := conn.Channel()
+	if err != nil {
+		fatalf("abriendo canal: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	exch := cfg.GetExchangesConfigWithDefaults()
+	replayer := dlq.NewReplayer(ch, publisher.Exchanges{
+		Assessments: exch.Assessments,
+		Materials:   exch.Materials,
+	})
+
+	ctx := context.Background()
+	failed := false
+	for _, q := range queues {
+		entries, err := replayer.Scan(ctx, q, *limit)
+		if err != nil {
+			fatalf("%v", err)
+		}
+
+		if cmd == "list" {
+			var selected []dlq.Entry
+			for _, e := range entries {
+				if ok, _ := filter.Match(e); ok {
+					selected = append(selected, e)
+				}
+			}
+			replayer.Release(entries)
+			if *asJSON {
+				err = writeJSON(len(selected), func(i int) any { return selected[i] })
+			} else {
+				err = dlq.WriteEntries(os.Stdout, selected)
+			}
+			if err != nil {
+				fatalf("escribiendo listado: %v", err)
+			}
+			continue
+		}
+
+		decisions := replayer.Replay(ctx, entries, filter, *dryRun)
+		for _, d := range decisions {
+			if d.Action == dlq.ActionFailed {
+				failed = true
+			}
+		}
+		if *asJSON {
+			err = writeJSON(len(decisions), func(i int) any { return decisions[i] })
+		} else {
+			err = dlq.WriteDecisions(os.Stdout, decisions)
+		}
+		if err != nil {
+			fatalf("escribiendo reporte: %v", err)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// resolveQueues traduce -lane/-queue a los nombres de DLQ de la configuración.
+func resolveQueues(cfg *config.Config, lane, explicit string) ([]string, error) {
+	if explicit != "" {
+		return []string{explicit}, nil
+	}
+	lanes := cfg.GetLanesConfigWithDefaults()
+	names := make([]string, 0, len(lanes))
+	all := make([]string, 0, len(lanes))
+	for _, l := range lanes {
+		if l.Name == lane {
+			return []string{l.DLQ}, nil
+		}
+		names = append(names, l.Name)
+		all = append(all, l.DLQ)
+	}
+	if lane == "all" {
+		return all, nil
+	}
+	return nil, fmt.Errorf("lane %q desconocido (%s|all)", lane, strings.Join(names, "|"))
+}
+
+// parseWindow acepta RFC3339 o una duración hacia atrás desde now. Vacío = sin cota.
+func parseWindow(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q no es RFC3339 ni una duración", v)
+	}
+	return now.Add(-d), nil
+}
+
+// writeJSON emite n elementos como JSON lines (un objeto por mensaje, para jq).
+func writeJSON(n int, item func(i int) any) error {
+	enc := json.NewEncoder(os.Stdout)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(item(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "dlq: "+format+"\n", args...)
+	os.Exit(1)
+}
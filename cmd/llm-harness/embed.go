@@ -27,6 +27,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -124,7 +125,7 @@ type modelResult struct {
 
 // runEmbed carga la batería, corre cada modelo secuencial y reporta tabla + JSON, además de
 // escribir results-<modelo>.json. Sale con código != 0 si un modelo no responde.
-func runEmbed(ollamaURL, modelsCSV, pairsPath, outDir string, timeout time.Duration) {
+func runEmbed(ollamaURL, modelsCSV, pairsPath, outDir string, timeout time.Duration, transport http.RoundTripper) {
 	pairs, err := loadEmbedPairs(pairsPath)
 	if err != nil {
 		fatalf("cargando batería %q: %v", pairsPath, err)
@@ -144,7 +145,7 @@ func runEmbed(ollamaURL, modelsCSV, pairsPath, outDir string, timeout time.Durat
 
 	results := make([]modelResult, 0, len(models))
 	for _, model := range models {
-		res, err := runEmbedModel(ollamaURL, model, pairs, timeout)
+		res, err := runEmbedModel(ollamaURL, model, pairs, timeout, transport)
 		if err != nil {
 			fatalf("modelo %q: %v", model, err)
 		}
@@ -162,8 +163,8 @@ func runEmbed(ollamaURL, modelsCSV, pairsPath, outDir string, timeout time.Durat
 
 // runEmbedModel vectoriza la batería con un modelo y computa todo. Deduplica textos
 // idénticos para no re-embeder, batchea las llamadas y valida la dimensión.
-func runEmbedModel(ollamaURL, model string, pairs []embedPair, timeout time.Duration) (modelResult, error) {
-	embedder := ollama.NewEmbedder(ollama.EmbedConfig{BaseURL: ollamaURL, Model: model, Timeout: timeout})
+func runEmbedModel(ollamaURL, model string, pairs []embedPair, timeout time.Duration, transport http.RoundTripper) (modelResult, error) {
+	embedder := ollama.NewEmbedder(ollama.EmbedConfig{BaseURL: ollamaURL, Model: model, Timeout: timeout, Transport: transport})
 
 	// Junta todos los textos únicos (crudos y normalizados) en un solo diccionario.
 	need := map[string]struct{}{}
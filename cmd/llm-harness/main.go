@@ -22,6 +22,35 @@
 //	go run ./cmd/llm-harness -mode generate -provider api -api-provider anthropic \
 //	    -api-key "$LLM_API_KEY" -api-model claude-sonnet-5 -material ./material.txt
 //
+// Con -cache-dir las respuestas se guardan en disco y una corrida repetida sirve del
+// cache los prompts que no cambiaron (los tiempos reportados dejan de medir al modelo).
+//
+// Con -cassette -cassette-mode record las llamadas HTTP al backend quedan grabadas en
+// el archivo; con -cassette-mode replay la corrida las reproduce sin red (ni Ollama ni
+// API key): las salidas reales del modelo quedan como fixture de regresión para CI.
+//
+//	go run ./cmd/llm-harness -mode review -model qwen3:1.7b -cassette testdata/review.json -cassette-mode record
+//
+// Con -prompt-versions la corrida usa otra versión de los prompts (embebida o de
+// -prompt-dir) en vez del builder: compara una versión candidata contra la batería antes
+// de ponerla en rollout.
+//
+//	go run ./cmd/llm-harness -mode review -model qwen3:1.7b -prompt-dir ./prompts -prompt-versions review_open_ended=v2
+//
+// Con -lang pt|en el modo review corre la misma batería traducida y el modo material
+// toma los documentos de testdata/material/<lang>: mide los prompts en el idioma de las
+// escuelas de Portugal/Brasil y de habla inglesa.
+//
+//	go run ./cmd/llm-harness -mode review -model qwen3:1.7b -lang pt
+//
+// Con -samples N el modo review corrige cada caso por votación (N muestras, veredicto
+// de la mayoría) y reporta el agreement: mide cuánto estabiliza la votación a un modelo
+// chico en los casos limítrofes.
+//
+// Con -logprobs los backends local y openai piden los logprobs de los juicios y el modo
+// review reporta la confianza del veredicto: sirve para calibrar
+// llm.review.min_confidence antes de fijarlo en una escuela.
+//
 // NO instala nada ni asume que hay un Ollama corriendo: si el provider local no
 // responde, reporta el error de conexión y termina con código != 0.
 package main
@@ -31,14 +60,21 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/EduGoGroup/edugo-worker/internal/assessmentimport"
 	"github.com/EduGoGroup/edugo-worker/internal/chunking"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/cassette"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	llmapi "github.com/EduGoGroup/edugo-worker/internal/llm/api"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/cache"
 	"github.com/EduGoGroup/edugo-worker/internal/llm/ollama"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/openaicompat"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
 )
 
 // sampleMaterial es el material por defecto si no se pasa -material.
@@ -51,7 +87,7 @@ glucosa. La ecuación general es: 6 CO2 + 6 H2O + luz -> C6H12O6 + 6 O2.`
 
 func main() {
 	mode := flag.String("mode", "generate", "modo del harness: generate (contrato 038) | review (corrección, 040 T2c) | prep (preparación, 042 F2d) | review-prep (carril triturado short_answer, 042 F3d) | material (pipeline A/B material→evaluación, 043 F3b) | embed (calibración dedupe por embeddings, 044 F1b) | relevance (calibración umbral relevancia, 044 F2a)")
-	provider := flag.String("provider", "local", "provider LLM: local (alias de ollama) | ollama | api. 'local'/'api' espejan el vocabulario de la política por escuela (D-039.2; 'off' no aplica al harness)")
+	provider := flag.String("provider", "local", "provider LLM: local (alias de ollama) | ollama | openai (servidor OpenAI-compatible: vLLM, llama.cpp) | api. 'local'/'api' espejan el vocabulario de la política por escuela (D-039.2; 'off' no aplica al harness)")
 	materialPath := flag.String("material", "", "ruta a un archivo de texto con el material (vacío = muestra interna)")
 	title := flag.String("title", "Fotosíntesis — capítulo 3", "título del material")
 	subjectHint := flag.String("subject", "Biología", "pista de materia")
@@ -60,7 +96,8 @@ func main() {
 	timeout := flag.Duration("timeout", 120*time.Second, "timeout de la generación")
 
 	ollamaURL := flag.String("ollama-url", "http://localhost:11434", "base URL de Ollama")
-	ollamaModel := flag.String("model", "llama3.1", "modelo de Ollama")
+	ollamaModel := flag.String("model", "llama3.1", "modelo de Ollama (o del servidor OpenAI-compatible con -provider openai)")
+	openaiURL := flag.String("openai-url", "http://localhost:8000", "base URL del servidor OpenAI-compatible (-provider openai)")
 
 	apiProvider := flag.String("api-provider", "anthropic", "backend del provider api: anthropic|gemini")
 	apiKey := flag.String("api-key", os.Getenv("LLM_API_KEY"), "API key (default env LLM_API_KEY)")
@@ -82,12 +119,49 @@ func main() {
 	relevanceCasesPath := flag.String("relevance-cases", defaultRelevanceCases, "modo relevance: ruta a la batería de casos central/peripheral/unanswerable")
 	relevanceOutPath := flag.String("relevance-out", "", "modo relevance: ruta del results-<modelo>.json (vacío = junto a -relevance-cases)")
 
+	cacheDir := flag.String("cache-dir", "", "directorio del cache de respuestas en disco: los prompts repetidos entre corridas salen del cache (sin tiempo de modelo). Vacío = sin cache")
+	cacheTTL := flag.Duration("cache-ttl", 7*24*time.Hour, "vencimiento de las entradas del cache en disco")
+
+	cassettePath := flag.String("cassette", "", "archivo de cassette HTTP: graba las llamadas al backend (-cassette-mode record) o las reproduce sin red (replay). Vacío = sin cassette")
+	cassetteMode := flag.String("cassette-mode", string(cassette.ModeReplay), "modo del cassette: record | replay")
+
+	promptDir := flag.String("prompt-dir", "", "directorio con plantillas de prompts <id>/<version>.tmpl (se suman a las embebidas)")
+	promptVersionsCSV := flag.String("prompt-versions", "", "versiones de prompts a usar, coma-separadas id=version (ej. review_open_ended=v2). Vacío = builtin")
+
+	lang := flag.String("lang", language.Default, "idioma de la corrida: es|pt|en. generate: idioma pedido; review: batería en ese idioma; material: carpeta de testdata por defecto y fallback de la detección por entrada")
+
+	reviewSamples := flag.Int("samples", 1, "modo review: muestras por caso con votación por mayoría (como llm.review.samples); 1 = una llamada greedy")
+	logprobs := flag.Bool("logprobs", false, "backends local/openai: pide logprobs en los juicios y reporta la confianza del veredicto (como llm.local.logprobs)")
+
 	flag.Parse()
 
+	runLang, ok := language.Resolve(*lang)
+	if !ok {
+		fatalf("-lang: idioma no soportado %q (usa %s)", *lang, strings.Join(language.Supported, "|"))
+	}
+
+	var transport http.RoundTripper
+	if *cassettePath != "" {
+		rec, err := cassette.New(*cassettePath, cassette.Mode(*cassetteMode), nil)
+		if err != nil {
+			fatalf("abriendo cassette: %v", err)
+		}
+		transport = rec
+	}
+
+	promptVersions, err := parsePromptVersions(*promptVersionsCSV)
+	if err != nil {
+		fatalf("-prompt-versions: %v", err)
+	}
+	registry, err := prompts.New(prompts.Config{Dir: *promptDir, Defaults: promptVersions})
+	if err != nil {
+		fatalf("cargando prompts: %v", err)
+	}
+
 	// El modo embed no genera texto: usa el puerto Embedder (no LLMProvider), así que
 	// no construye el provider LLM ni necesita material. Se resuelve y retorna aquí.
 	if *mode == "embed" {
-		runEmbed(*ollamaURL, *embedModelsCSV, *embedPairsPath, *embedOutDir, *timeout)
+		runEmbed(*ollamaURL, *embedModelsCSV, *embedPairsPath, *embedOutDir, *timeout, transport)
 		return
 	}
 
@@ -103,23 +177,34 @@ func main() {
 	p, err := buildProvider(*provider, providerFlags{
 		ollamaURL:   *ollamaURL,
 		ollamaModel: *ollamaModel,
+		openaiURL:   *openaiURL,
 		timeout:     *timeout,
 		apiProvider: *apiProvider,
 		apiKey:      *apiKey,
 		apiModel:    *apiModel,
 		apiBaseURL:  *apiBaseURL,
+		transport:   transport,
+		prompts:     registry,
+		logprobs:    *logprobs,
 	})
 	if err != nil {
 		fatalf("construyendo provider: %v", err)
 	}
+	if *cacheDir != "" {
+		store, err := cache.NewDiskStore(*cacheDir, *cacheTTL)
+		if err != nil {
+			fatalf("abriendo cache: %v", err)
+		}
+		p = cache.NewProvider(p, store, cache.Config{Prompts: registry})
+	}
 
 	switch *mode {
 	case "generate":
 		material := llm.MaterialInput{Title: *title, Content: content, SubjectHint: *subjectHint}
-		params := llm.GenerationParams{NumQuestions: *numQuestions, Language: "es", Difficulty: *difficulty}
+		params := llm.GenerationParams{NumQuestions: *numQuestions, Language: runLang, Difficulty: *difficulty}
 		runGenerate(p, material, params, len(content), *timeout)
 	case "review":
-		runReview(p, *timeout)
+		runReview(p, *timeout, runLang, *reviewSamples)
 	case "prep":
 		runPrep(p, *timeout)
 	case "review-prep":
@@ -128,6 +213,7 @@ func main() {
 		runMaterial(p, materialOptions{
 			timeout:   *timeout,
 			inputsCSV: *materialInputsCSV,
+			lang:      runLang,
 			chunkCfg: chunking.Config{
 				TargetWords:         *chunkTarget,
 				MaxWords:            *chunkMax,
@@ -139,6 +225,7 @@ func main() {
 			provider:      *provider,
 			ollamaURL:     *ollamaURL,
 			ollamaModel:   *ollamaModel,
+			transport:     transport,
 		})
 	case "relevance":
 		runRelevance(p, *ollamaModel, *relevanceCasesPath, *relevanceOutPath, *timeout)
@@ -192,11 +279,15 @@ func runGenerate(p llm.LLMProvider, material llm.MaterialInput, params llm.Gener
 type providerFlags struct {
 	ollamaURL   string
 	ollamaModel string
+	openaiURL   string
 	timeout     time.Duration
 	apiProvider string
 	apiKey      string
 	apiModel    string
 	apiBaseURL  string
+	transport   http.RoundTripper
+	prompts     *prompts.Registry
+	logprobs    bool
 }
 
 func buildProvider(kind string, f providerFlags) (llm.LLMProvider, error) {
@@ -206,21 +297,48 @@ func buildProvider(kind string, f providerFlags) (llm.LLMProvider, error) {
 	// (no hay generación que medir sin provider).
 	case "local", "ollama":
 		return ollama.New(ollama.Config{
-			BaseURL: f.ollamaURL,
-			Model:   f.ollamaModel,
-			Timeout: f.timeout,
+			BaseURL:   f.ollamaURL,
+			Model:     f.ollamaModel,
+			Timeout:   f.timeout,
+			Transport: f.transport,
+			Prompts:   f.prompts,
+			Logprobs:  f.logprobs,
 		}), nil
+	case "openai":
+		return openaicompat.New(openaicompat.Config{
+			BaseURL:   f.openaiURL,
+			Model:     f.ollamaModel,
+			Timeout:   f.timeout,
+			Transport: f.transport,
+			Prompts:   f.prompts,
+			Logprobs:  f.logprobs,
+		})
 	case "api":
 		return llmapi.New(llmapi.Config{
-			Provider: f.apiProvider,
-			APIKey:   f.apiKey,
-			Model:    f.apiModel,
-			BaseURL:  f.apiBaseURL,
-			Timeout:  f.timeout,
+			Provider:  f.apiProvider,
+			APIKey:    f.apiKey,
+			Model:     f.apiModel,
+			BaseURL:   f.apiBaseURL,
+			Timeout:   f.timeout,
+			Transport: f.transport,
+			Prompts:   f.prompts,
 		})
 	default:
-		return nil, fmt.Errorf("provider desconocido %q (usa local|ollama|api)", kind)
+		return nil, fmt.Errorf("provider desconocido %q (usa local|ollama|openai|api)", kind)
+	}
+}
+
+// parsePromptVersions lee la lista id=version de -prompt-versions.
+func parsePromptVersions(csv string) (map[prompts.ID]string, error) {
+	versions := make(map[prompts.ID]string)
+	for _, entry := range splitCSV(csv) {
+		id, version, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || version == "" {
+			return nil, fmt.Errorf("entrada %q: se espera id=version", entry)
+		}
+		versions[prompts.ID(id)] = version
 	}
+	return versions, nil
 }
 
 func prettyOrRaw(raw json.RawMessage) string {
@@ -24,6 +24,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -33,6 +34,7 @@ import (
 	"github.com/EduGoGroup/edugo-shared/logger"
 	"github.com/EduGoGroup/edugo-worker/internal/chunking"
 	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/pdf"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
@@ -48,6 +50,7 @@ const defaultMaterialDir = "cmd/llm-harness/testdata/material"
 // ni siquiera pudo cargarse: ChunkSeq = -1 y LoadErr poblado).
 type materialChunkMetric struct {
 	Input      string `json:"input"`
+	Language   string `json:"language,omitempty"`
 	ChunkSeq   int    `json:"chunk_seq"`
 	ChunkWords int    `json:"chunk_words"`
 	LoadErr    string `json:"load_err,omitempty"`
@@ -89,8 +92,8 @@ type candidateIssue struct {
 }
 
 // materialInputs resuelve la lista de entradas: la lista explícita (coma-separada) o,
-// si viene vacía, todos los .txt de la carpeta de testdata por defecto.
-func materialInputs(explicit string) ([]string, error) {
+// si viene vacía, todos los .txt de la carpeta de testdata del idioma lang.
+func materialInputs(explicit, lang string) ([]string, error) {
 	if strings.TrimSpace(explicit) != "" {
 		var out []string
 		for p := range strings.SplitSeq(explicit, ",") {
@@ -100,14 +103,18 @@ func materialInputs(explicit string) ([]string, error) {
 		}
 		return out, nil
 	}
-	entries, err := os.ReadDir(defaultMaterialDir)
+	dir := defaultMaterialDir
+	if lang != language.Spanish {
+		dir = filepath.Join(defaultMaterialDir, lang)
+	}
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("leyendo carpeta de testdata %q: %w", defaultMaterialDir, err)
+		return nil, fmt.Errorf("leyendo carpeta de testdata %q: %w", dir, err)
 	}
 	var out []string
 	for _, e := range entries {
 		if !e.IsDir() && strings.HasSuffix(e.Name(), ".txt") {
-			out = append(out, filepath.Join(defaultMaterialDir, e.Name()))
+			out = append(out, filepath.Join(dir, e.Name()))
 		}
 	}
 	sort.Strings(out)
@@ -138,7 +145,10 @@ func loadMaterialText(path string, log logger.Logger) (string, error) {
 type materialOptions struct {
 	timeout   time.Duration
 	inputsCSV string
-	chunkCfg  chunking.Config
+	// lang elige la carpeta de testdata por defecto (la raíz es español; pt y en viven
+	// en subcarpetas) y es el fallback de la detección de idioma por entrada.
+	lang     string
+	chunkCfg chunking.Config
 	// skipPropose omite la Batería B: para experimentos que solo miden el digest A
 	// ahorra la mitad de la corrida.
 	skipPropose bool
@@ -149,11 +159,13 @@ type materialOptions struct {
 	provider      string
 	ollamaURL     string
 	ollamaModel   string
+	// transport es el del cassette (-cassette) para la llamada v1 directa a Ollama.
+	transport http.RoundTripper
 }
 
 // runMaterial corre las baterías A y B sobre las entradas y reporta tabla + JSON.
 func runMaterial(p llm.LLMProvider, opts materialOptions) {
-	inputs, err := materialInputs(opts.inputsCSV)
+	inputs, err := materialInputs(opts.inputsCSV, opts.lang)
 	if err != nil {
 		fatalf("resolviendo entradas: %v", err)
 	}
@@ -190,12 +202,15 @@ func runMaterial(p llm.LLMProvider, opts materialOptions) {
 			metrics = append(metrics, materialChunkMetric{Input: name, ChunkSeq: -1, LoadErr: err.Error()})
 			continue
 		}
+		// Como el processor, el idioma sale del texto con -lang como fallback; aquí por
+		// entrada (el processor decide por trozo, y una entrada de testdata es monolingüe).
+		lang := language.Detect(text, opts.lang)
 		chunks := chunking.Split(text, opts.chunkCfg)
-		fmt.Printf("  %-24s %d bytes → %d trozos\n", name, len(text), len(chunks))
+		fmt.Printf("  %-24s %d bytes → %d trozos (%s)\n", name, len(text), len(chunks), lang)
 
 		var prevSummary *string
 		for _, ch := range chunks {
-			m := runMaterialChunk(p, opts, name, ch, prevSummary)
+			m := runMaterialChunk(p, opts, name, lang, ch, prevSummary)
 			metrics = append(metrics, m)
 			if strings.TrimSpace(m.summaryText) != "" {
 				s := m.summaryText
@@ -212,10 +227,11 @@ func runMaterial(p llm.LLMProvider, opts materialOptions) {
 // runMaterialChunk corre A y —si A dio ideas— B sobre un trozo. La medición NO reintenta
 // (a diferencia de los modos prep/review): F3b quiere ver la tasa cruda del modelo por
 // llamada, no la tasa tras reintentos.
-func runMaterialChunk(p llm.LLMProvider, opts materialOptions, input string, ch chunking.Chunk, prevSummary *string) materialChunkMetric {
+func runMaterialChunk(p llm.LLMProvider, opts materialOptions, input, lang string, ch chunking.Chunk, prevSummary *string) materialChunkMetric {
 	timeout := opts.timeout
 	m := materialChunkMetric{
 		Input:      input,
+		Language:   lang,
 		ChunkSeq:   ch.Seq,
 		ChunkWords: len(strings.Fields(ch.Text)),
 		TypeCounts: map[string]int{},
@@ -227,13 +243,13 @@ func runMaterialChunk(p llm.LLMProvider, opts materialOptions, input string, ch
 	var errA error
 	startA := time.Now()
 	if opts.digestVariant == "v1" {
-		digest, errA = digestChunkV1(opts, ch.Text, prevSummary)
+		digest, errA = digestChunkV1(opts, ch.Text, lang, prevSummary)
 	} else {
 		ctxA, cancelA := context.WithTimeout(context.Background(), timeout)
 		digest, errA = p.DigestChunk(ctxA, llm.DigestChunkInput{
 			ChunkText:   ch.Text,
 			PrevSummary: prevSummary,
-			Language:    "es",
+			Language:    lang,
 		})
 		cancelA()
 	}
@@ -271,7 +287,7 @@ func runMaterialChunk(p llm.LLMProvider, opts materialOptions, input string, ch
 	startB := time.Now()
 	candidates, errB := p.ProposeCandidates(ctxB, llm.ProposeCandidatesInput{
 		Artifacts: digest.Artifacts,
-		Language:  "es",
+		Language:  lang,
 	})
 	m.ProposeMS = time.Since(startB).Milliseconds()
 	cancelB()
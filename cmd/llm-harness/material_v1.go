@@ -22,11 +22,11 @@ import (
 // digestChunkV1 corre la llamada A única legacy contra Ollama: el mismo prompt
 // (BuildDigestChunkPrompt) y parseo (ParseDigestResult) que usaba la ruta productiva
 // antes de la partición, con el request espejado del provider.
-func digestChunkV1(opts materialOptions, chunkText string, prevSummary *string) (*llm.DigestChunkResult, error) {
+func digestChunkV1(opts materialOptions, chunkText, lang string, prevSummary *string) (*llm.DigestChunkResult, error) {
 	prompt := llm.BuildDigestChunkPrompt(llm.DigestChunkInput{
 		ChunkText:   chunkText,
 		PrevSummary: prevSummary,
-		Language:    "es",
+		Language:    lang,
 	})
 	rawJSON, err := ollamaGenerateJSON(opts, prompt)
 	if err != nil {
@@ -55,7 +55,7 @@ func ollamaGenerateJSON(opts materialOptions, prompt string) (json.RawMessage, e
 	if err != nil {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
-	client := &http.Client{Timeout: opts.timeout}
+	client := &http.Client{Timeout: opts.timeout, Transport: opts.transport}
 	resp, err := client.Post(strings.TrimRight(opts.ollamaURL, "/")+"/api/generate", "application/json", bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("ollama request failed: %w", err)
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/vote"
 )
 
 // maxAttempts es el tope de reintentos por caso ante un resultado DEGENERADO
@@ -19,6 +20,9 @@ import (
 // una entrada sin sentido eleva la tasa de `{}` (el modelo "no tiene qué evaluar").
 const maxAttempts = 6
 
+// reviewVoteTemperature espeja la temperatura de las muestras 2..N del processor.
+const reviewVoteTemperature = 0.7
+
 // validVerdict reporta si un resultado no es degenerado.
 func validVerdict(v llm.Verdict) bool {
 	return v == llm.VerdictCorrect || v == llm.VerdictPartial || v == llm.VerdictIncorrect
@@ -116,26 +120,38 @@ var reviewCases = []reviewCase{
 	},
 }
 
-// runReview corre la batería del modo review contra el provider y reporta N/M.
-// Los casos known-flaky que fallan no cuentan contra el total efectivo, pero se
-// listan. Sale con código != 0 si algún caso NO-flaky falla.
-func runReview(p llm.LLMProvider, timeout time.Duration) {
+// runReview corre la batería del modo review en el idioma lang contra el provider y
+// reporta N/M. Los casos known-flaky que fallan no cuentan contra el total efectivo,
+// pero se listan. Sale con código != 0 si algún caso NO-flaky falla.
+func runReview(p llm.LLMProvider, timeout time.Duration, lang string, samples int) {
+	cases := reviewBatteries[lang]
 	fmt.Printf("== llm-harness (review) ==\n")
 	fmt.Printf("provider : %s\n", p.Name())
-	fmt.Printf("casos    : %d\n\n", len(reviewCases))
+	fmt.Printf("idioma   : %s\n", lang)
+	if samples > 1 {
+		fmt.Printf("votación : %d muestras (temp %.1f)\n", samples, reviewVoteTemperature)
+	}
+	fmt.Printf("casos    : %d\n\n", len(cases))
 
 	pass, effectiveTotal := 0, 0
 	hardFail := false
 
-	for _, tc := range reviewCases {
+	for _, tc := range cases {
+		tc.req.Language = lang
 		start := time.Now()
 		var res llm.ReviewResult
 		var err error
 		attempts := 0
+		var voter *vote.Provider
 		for attempts < maxAttempts {
 			attempts++
+			reviewer := p
+			if samples > 1 {
+				voter = vote.NewProvider(p, vote.Config{Samples: samples, Temperature: reviewVoteTemperature})
+				reviewer = voter
+			}
 			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			res, err = p.ReviewAnswer(ctx, tc.req)
+			res, err = reviewer.ReviewAnswer(ctx, tc.req)
 			cancel()
 			// Reintenta solo ante error o resultado degenerado (verdict vacío/inválido),
 			// nunca ante un veredicto válido que discrepe: eso es señal real del prompt.
@@ -174,6 +190,14 @@ func runReview(p llm.LLMProvider, timeout time.Duration) {
 		fmt.Printf("%-5s %-20s (%s)%s%s\n", status, tc.name, elapsed.Round(time.Millisecond), flakyTag, retryTag)
 		fmt.Printf("        verdict=%s score=%.2f  esperado: verdict=%s score∈[%.2f,%.2f]\n",
 			res.Verdict, res.Score, orAny(tc.wantVerdict), tc.scoreMin, tc.scoreMax)
+		if voter != nil {
+			if agreement, ok := voter.Agreement(); ok {
+				fmt.Printf("        agreement: %.2f\n", agreement)
+			}
+		}
+		if res.Confidence != nil {
+			fmt.Printf("        confianza: %.2f\n", *res.Confidence)
+		}
 		fmt.Printf("        feedback: %s\n", truncate(res.Feedback, 120))
 		if !ok {
 			fmt.Printf("        motivo FAIL: %s\n", reason)
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	rabbit "github.com/EduGoGroup/edugo-shared/messaging/rabbit"
+	"github.com/EduGoGroup/edugo-worker/internal/application/processor"
+	"github.com/EduGoGroup/edugo-worker/internal/bootstrap"
+	"github.com/EduGoGroup/edugo-worker/internal/config"
+	laneconsumer "github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/consumer"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/memory"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/publisher"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/retryqueue"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// localAppID marca los eventos que inyecta el modo local, para distinguirlos en el
+// resumen de los que publica el worker.
+const localAppID = "edugo-worker-local"
+
+// localOptions son los flags del modo --local.
+type localOptions struct {
+	// events es el archivo JSONL de eventos ("-" = stdin).
+	events string
+	// retryDelay reemplaza el retraso de cada escalón de retry (0 = los de la
+	// configuración): con 10m en el último escalón una prueba local no termina.
+	retryDelay time.Duration
+}
+
+// localLane es un carril en modo local: su configuración y sus escalones de retry.
+type localLane struct {
+	config.LaneConfig
+	tiers []time.Duration
+}
+
+// runLocal corre el worker de punta a punta sin RabbitMQ: declara la topología de
+// setupRabbitMQ en un broker en memoria, consume cada carril con el consumer de
+// reintentos diferidos (misma semántica de DLQ y retry que en producción) y el
+// ProcessorRegistry real, publica los eventos de opts.events (un envelope JSON por
+// línea; se ignoran las vacías y las que empiezan con #), espera a que se procesen
+// y resume el resultado por carril. Las dependencias HTTP (M2M, LLM) son las de la
+// configuración, igual que en el modo normal.
+func runLocal(ctx context.Context, cfg *config.Config, opts localOptions) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	in := io.Reader(os.Stdin)
+	if opts.events != "-" {
+		f, err := os.Open(opts.events)
+		if err != nil {
+			return fmt.Errorf("abriendo eventos: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+
+	broker := memory.NewBroker()
+	resources, cleanup, err := bootstrap.NewResourceBuilder(ctx, cfg).
+		WithLogger().
+		WithMemoryBroker(broker).
+		WithEventPublisher().
+		WithAuthClient().
+		WithM2MClients().
+		WithLLMProvider().
+		WithInfrastructure().
+		WithProcessors().
+		Build()
+	if err != nil {
+		return fmt.Errorf("inicializando recursos: %w", err)
+	}
+	defer func() { _ = cleanup() }()
+
+	if err := setupRabbitMQ(broker.Channel(), cfg); err != nil {
+		return err
+	}
+
+	lanes, err := startLocalLanes(ctx, resources, cfg, broker, opts.retryDelay)
+	if err != nil {
+		return err
+	}
+	if resources.LLMMonitor != nil {
+		resources.LLMMonitor.Start(ctx)
+	}
+
+	exchanges := cfg.GetExchangesConfigWithDefaults()
+	published, skipped, err := publishLocalEvents(ctx, in, broker.Channel(), publisher.Exchanges{
+		Assessments: exchanges.Assessments,
+		Materials:   exchanges.Materials,
+	}, resources)
+	if err != nil {
+		return err
+	}
+	resources.Logger.Info("eventos locales publicados, esperando a los carriles",
+		"published", published, "skipped", skipped)
+
+	idleErr := broker.WaitIdle(ctx)
+	resources.LaneController.Close(true)
+
+	printLocalSummary(os.Stdout, broker, lanes)
+	if idleErr != nil {
+		return fmt.Errorf("interrumpido antes de procesar todos los eventos: %w", idleErr)
+	}
+	return nil
+}
+
+// startLocalLanes registra y arranca en el LaneController un consumer por carril de
+// `lanes:` sobre el broker, con el mismo handler que main (una pausa del monitor del
+// LLM lo cancela y lo reabre igual que en producción). Los carriles sin reintentos diferidos usan
+// escalones derivados del DLQ compartido (MaxRetries reintentos de RetryDelay,
+// exponenciales si se configuró).
+func startLocalLanes(ctx context.Context, resources *bootstrap.Resources, cfg *config.Config, broker *memory.Broker, retryDelay time.Duration) ([]*localLane, error) {
+	dlqCfg := cfg.GetDLQConfigWithDefaults().ToShared()
+
+	var lanes []*localLane
+	for _, laneCfg := range cfg.GetLanesConfigWithDefaults() {
+		l := &localLane{LaneConfig: laneCfg, tiers: localTiers(laneCfg.Retry, dlqCfg, retryDelay)}
+		consumerCfg := retryqueue.Config{
+			Name:          l.Consumer,
+			PrefetchCount: l.Prefetch,
+			Concurrency:   laneConsumerConcurrency(resources, laneCfg),
+			DLXExchange:   dlqCfg.DLXExchange,
+			DLQName:       l.DLQ,
+			Tiers:         l.tiers,
+			IsPermanent:   processor.IsPermanentError,
+		}
+		handler := newLaneHandler(resources, laneCfg)
+		resources.LaneController.Register(l.Name, l.Consumer, l.Queue, func() (laneconsumer.LaneConsumer, error) {
+			c := retryqueue.NewChannelConsumer(func() (retryqueue.Channel, error) {
+				return broker.Channel(), nil
+			}, consumerCfg, resources.Logger)
+			if err := c.ConsumeWithDLQ(ctx, l.Queue, handler); err != nil {
+				return nil, err
+			}
+			return c, nil
+		})
+		if err := resources.LaneController.Start(l.Name); err != nil {
+			return nil, fmt.Errorf("iniciando consumer local del carril %s: %w", l.Name, err)
+		}
+		lanes = append(lanes, l)
+	}
+	return lanes, nil
+}
+
+// localTiers resuelve los escalones de retry de un carril en modo local.
+func localTiers(retryCfg config.RetryTiersConfig, dlqCfg rabbit.DLQConfig, override time.Duration) []time.Duration {
+	var tiers []time.Duration
+	if retryCfg.Enabled {
+		tiers = append(tiers, retryCfg.Tiers...)
+	} else {
+		delay := dlqCfg.RetryDelay
+		for i := 0; i < dlqCfg.MaxRetries; i++ {
+			tiers = append(tiers, delay)
+			if dlqCfg.UseExponentialBackoff {
+				delay *= 2
+			}
+		}
+	}
+	if override > 0 {
+		for i := range tiers {
+			tiers[i] = override
+		}
+	}
+	return tiers
+}
+
+// publishLocalEvents publica cada envelope de in en el exchange de su event_type
+// (routing key = event_type), como lo haría learning. Las líneas que no son un
+// envelope válido se descartan con un warning.
+func publishLocalEvents(ctx context.Context, in io.Reader, ch *memory.Channel, exchanges publisher.Exchanges, resources *bootstrap.Resources) (published, skipped int, err error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var envelope struct {
+			EventType     string `json:"event_type"`
+			EventID       string `json:"event_id"`
+			CorrelationID string `json:"correlation_id"`
+		}
+		if err := json.Unmarshal([]byte(line), &envelope); err != nil || envelope.EventType == "" {
+			resources.Logger.Warn("línea de eventos inválida, se descarta", "line", lineNo)
+			skipped++
+			continue
+		}
+		exchange, err := exchanges.For(envelope.EventType)
+		if err != nil {
+			resources.Logger.Warn("evento sin exchange, se descarta", "line", lineNo, "event_type", envelope.EventType)
+			skipped++
+			continue
+		}
+
+		if err := ch.PublishWithContext(ctx, exchange, envelope.EventType, false, false, amqp.Publishing{
+			ContentType:   "application/json",
+			DeliveryMode:  amqp.Persistent,
+			MessageId:     envelope.EventID,
+			CorrelationId: envelope.CorrelationID,
+			Type:          envelope.EventType,
+			Timestamp:     time.Now().UTC(),
+			AppId:         localAppID,
+			Body:          []byte(line),
+		}); err != nil {
+			return published, skipped, fmt.Errorf("publicando evento de la línea %d: %w", lineNo, err)
+		}
+		published++
+	}
+	if err := scanner.Err(); err != nil {
+		return published, skipped, fmt.Errorf("leyendo eventos: %w", err)
+	}
+	return published, skipped, nil
+}
+
+// printLocalSummary resume por carril lo recibido, confirmado, reintentado y
+// enviado a la DLQ, los eventos que publicó el worker y los de entrada sin carril.
+func printLocalSummary(w io.Writer, broker *memory.Broker, lanes []*localLane) {
+	_, _ = fmt.Fprintln(w, "\n== Resumen del modo local ==")
+	for _, l := range lanes {
+		stats := broker.Stats(l.Queue)
+		retries := 0
+		for _, ttl := range l.tiers {
+			retries += broker.Stats(retryqueue.QueueName(l.Queue, ttl)).Enqueued
+		}
+		dead := broker.Messages(l.DLQ)
+		_, _ = fmt.Fprintf(w, "%-9s recibidos=%d reintentos=%d dlq=%d pendientes=%d\n",
+			l.Name, stats.Enqueued-retries, retries, len(dead), stats.Ready+stats.Unacked)
+		for _, m := range dead {
+			_, _ = fmt.Fprintf(w, "          dlq: %s event_id=%s retries=%d\n",
+				m.Type, m.MessageId, retryqueue.RetryCount(m.Headers))
+		}
+	}
+
+	var outputs, unrouted []memory.Message
+	for _, m := range broker.Unroutable() {
+		if m.AppId == localAppID {
+			unrouted = append(unrouted, m)
+		} else {
+			outputs = append(outputs, m)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "eventos publicados por el worker: %d\n", len(outputs))
+	for _, m := range outputs {
+		_, _ = fmt.Fprintf(w, "          %s → %s correlation_id=%s\n", m.RoutingKey, m.Exchange, m.CorrelationId)
+	}
+	if len(unrouted) > 0 {
+		_, _ = fmt.Fprintf(w, "eventos de entrada sin carril: %d\n", len(unrouted))
+		for _, m := range unrouted {
+			_, _ = fmt.Fprintf(w, "          %s event_id=%s\n", m.RoutingKey, m.MessageId)
+		}
+	}
+}
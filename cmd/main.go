@@ -2,37 +2,58 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"time"
 
-	"github.com/EduGoGroup/edugo-shared/messaging/events"
 	rabbit "github.com/EduGoGroup/edugo-shared/messaging/rabbit"
+	"github.com/EduGoGroup/edugo-worker/internal/application/processor"
 	"github.com/EduGoGroup/edugo-worker/internal/bootstrap"
 	"github.com/EduGoGroup/edugo-worker/internal/config"
-	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
-	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/ratelimiter"
+	laneconsumer "github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/consumer"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/retryqueue"
 	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/shutdown"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 func main() {
+	local := flag.Bool("local", false, "modo local: broker en memoria y eventos desde -events, sin RabbitMQ")
+	eventsPath := flag.String("events", "-", "modo local: archivo JSONL con un evento por línea (\"-\" = stdin)")
+	retryDelay := flag.Duration("retry-delay", time.Second, "modo local: retraso de cada reintento diferido (0 = escalones de la configuración)")
+	flag.Parse()
+
 	log.Println("🔄 EduGo Worker iniciando...")
 
 	ctx := context.Background()
 
-	// 1. Cargar configuración
-	cfg, err := config.Load()
+	// 1. Cargar configuración. En modo local no hay RabbitMQ: LoadLocal completa la
+	// URL para pasar la validación.
+	load := config.Load
+	if *local {
+		load = config.LoadLocal
+	}
+	cfg, err := load()
 	if err != nil {
 		log.Fatal("❌ Error cargando configuración:", err)
 	}
 
+	// Modo local: mismo registry y consumers de carril sobre un broker en memoria;
+	// procesa los eventos del archivo y termina.
+	if *local {
+		if err := runLocal(ctx, cfg, localOptions{events: *eventsPath, retryDelay: *retryDelay}); err != nil {
+			log.Fatal("❌ Error en modo local: ", err)
+		}
+		return
+	}
+
 	// 2. Inicializar infraestructura usando ResourceBuilder
 	resources, cleanup, err := bootstrap.NewResourceBuilder(ctx, cfg).
 		WithLogger().
 		WithSharedMetrics().
 		WithRabbitMQ().
+		WithEventPublisher().
 		WithAuthClient().
 		WithM2MClients().
 		WithLLMProvider().
@@ -57,180 +78,92 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// 4. Configurar rate limiter
-	var rateLimiter *ratelimiter.MultiRateLimiter
-	rateLimiterCfg := cfg.GetRateLimiterConfigWithDefaults()
-
-	if rateLimiterCfg.Enabled {
-		// Convertir configuración a formato esperado por MultiRateLimiter
-		configs := make(map[string]ratelimiter.Config)
-		for eventType, eventCfg := range rateLimiterCfg.ByEventType {
-			configs[eventType] = ratelimiter.Config{
-				RequestsPerSecond: eventCfg.RequestsPerSecond,
-				BurstSize:         eventCfg.BurstSize,
-			}
-		}
-
-		// Configuración por defecto
-		defaultCfg := &ratelimiter.Config{
-			RequestsPerSecond: rateLimiterCfg.Default.RequestsPerSecond,
-			BurstSize:         rateLimiterCfg.Default.BurstSize,
-		}
-
-		rateLimiter = ratelimiter.NewMulti(configs, defaultCfg)
-		resources.Logger.Info("✅ Rate limiter habilitado",
-			"configured_events", len(configs),
-			"default_rps", defaultCfg.RequestsPerSecond,
-			"default_burst", defaultCfg.BurstSize)
-	} else {
-		resources.Logger.Info("⚠️  Rate limiter deshabilitado")
-	}
-
-	// 5. Crear consumer compartido con soporte DLQ
-	prefetchCount := cfg.Messaging.RabbitMQ.PrefetchCount
-	if prefetchCount == 0 {
-		prefetchCount = 5
-	}
-
+	// 4. Un consumer por carril de `lanes:` (consumer compartido o con reintentos
+	// diferidos). Cada carril tiene instancia PROPIA porque el consumer compartido
+	// tiene guard `running` (una sola ConsumeWithDLQ por instancia) y porque su DLQ es
+	// propia (DLXRoutingKey = DLQ del carril). Todos comparten el ProcessorRegistry
+	// (enruta por event_type).
 	dlqCfg := cfg.GetDLQConfigWithDefaults().ToShared()
+	laneConfigs := cfg.GetLanesConfigWithDefaults()
 
-	consumerCfg := rabbit.ConsumerConfig{
-		Name:          "edugo-worker",
-		AutoAck:       false,
-		PrefetchCount: prefetchCount,
-		DLQ:           dlqCfg,
-	}
-
-	consumer, ok := rabbit.NewConsumer(resources.RabbitMQConn, consumerCfg).(*rabbit.RabbitMQConsumer)
-	if !ok {
-		log.Fatal("failed to create RabbitMQ consumer: unexpected type")
-	}
-
-	// Consumer del carril de PREPARACIÓN (plan 042 F2a): instancia PROPIA porque el
-	// consumer compartido tiene guard `running` (una sola ConsumeWithDLQ por
-	// instancia) y porque su DLQ es propia (DLXRoutingKey = cola de prep + ".dlq").
-	// Comparte el mismo ProcessorRegistry (enruta por event_type).
-	prepDLQCfg := dlqCfg
-	prepDLQCfg.DLXRoutingKey = cfg.GetQueuesConfigWithDefaults().PrepDLQName()
-	prepConsumerCfg := rabbit.ConsumerConfig{
-		Name:          "edugo-worker-prep",
-		AutoAck:       false,
-		PrefetchCount: prefetchCount,
-		DLQ:           prepDLQCfg,
-	}
-	prepConsumer, ok := rabbit.NewConsumer(resources.RabbitMQConn, prepConsumerCfg).(*rabbit.RabbitMQConsumer)
-	if !ok {
-		log.Fatal("failed to create RabbitMQ prep consumer: unexpected type")
-	}
-
-	// Consumer del carril MATERIAL→EVALUACIÓN (plan 043 F3c): instancia PROPIA por la
-	// misma razón que el de prep (guard `running` + DLQ propia). Comparte el mismo
-	// ProcessorRegistry (enruta por event_type).
-	materialDLQCfg := dlqCfg
-	materialDLQCfg.DLXRoutingKey = cfg.GetQueuesConfigWithDefaults().MaterialAssessmentDLQName()
-	materialConsumerCfg := rabbit.ConsumerConfig{
-		Name:          "edugo-worker-material",
-		AutoAck:       false,
-		PrefetchCount: prefetchCount,
-		DLQ:           materialDLQCfg,
-	}
-	materialConsumer, ok := rabbit.NewConsumer(resources.RabbitMQConn, materialConsumerCfg).(*rabbit.RabbitMQConsumer)
-	if !ok {
-		log.Fatal("failed to create RabbitMQ material consumer: unexpected type")
-	}
-
-	// 6. Crear handler con rate limiting integrado
-	handler := func(ctx context.Context, body []byte) error {
-		if rateLimiter != nil {
-			eventType := "unknown"
-			var base struct {
-				EventType string `json:"event_type"`
-			}
-			if err := json.Unmarshal(body, &base); err == nil && base.EventType != "" {
-				eventType = base.EventType
-			}
-
-			start := time.Now()
-			if err := rateLimiter.Wait(ctx, eventType); err != nil {
-				return fmt.Errorf("rate limiter interrupted: %w", err)
+	// 5. Iniciar consumo con soporte DLQ. El LaneController abre el consumer de cada
+	// carril y lo cancela mientras está pausado (la API de administración y el monitor
+	// del LLM pausan sin reiniciar el pod): al reanudar crea una instancia nueva con
+	// startLane. Los carriles con reparto pasan por su FairScheduler antes del registry.
+	lanes := resources.LaneController
+	consumerCtx, cancelConsumer := context.WithCancel(ctx)
+	for _, lane := range laneConfigs {
+		laneDLQCfg := dlqCfg
+		laneDLQCfg.DLXRoutingKey = lane.DLQ
+		consumerCfg := rabbit.ConsumerConfig{
+			Name:          lane.Consumer,
+			AutoAck:       false,
+			PrefetchCount: lane.Prefetch,
+			DLQ:           laneDLQCfg,
+		}
+		concurrency := laneConsumerConcurrency(resources, lane)
+		handler := newLaneHandler(resources, lane)
+		startLane := func() (laneconsumer.LaneConsumer, error) {
+			consumer, err := newLaneConsumer(resources, consumerCfg, lane.Retry, concurrency)
+			if err != nil {
+				return nil, err
 			}
-			waitDuration := time.Since(start).Seconds()
-			metrics.RecordRateLimiterWait(eventType, waitDuration)
-			metrics.RecordRateLimiterAllowed(eventType)
-			if tokens := rateLimiter.Tokens(eventType); tokens >= 0 {
-				metrics.UpdateRateLimiterTokens(eventType, tokens)
+			if err := consumer.ConsumeWithDLQ(consumerCtx, lane.Queue, handler); err != nil {
+				return nil, err
 			}
+			return consumer, nil
 		}
-
-		resources.Logger.Info("Mensaje recibido", "size", len(body))
-		if err := resources.ProcessorRegistry.Process(ctx, body); err != nil {
-			resources.Logger.Error("Error procesando evento", "error", err.Error())
-			return err
+		lanes.Register(lane.Name, lane.Consumer, lane.Queue, startLane)
+		if err := lanes.Start(lane.Name); err != nil {
+			resources.Logger.Error("Error iniciando consumer", "lane", lane.Name, "error", err.Error())
+			log.Fatal(err)
 		}
-		resources.Logger.Info("Evento procesado exitosamente")
-		return nil
-	}
 
-	// 7. Iniciar consumo con soporte DLQ (un consumer por riel, mismo handler/registry)
-	queuesCfg := cfg.GetQueuesConfigWithDefaults()
-	reviewQueue := queuesCfg.AttemptReviewRequested
-	prepQueue := queuesCfg.QuestionPrepRequested
-	materialQueue := queuesCfg.MaterialAssessmentRequested
-	consumerCtx, cancelConsumer := context.WithCancel(ctx)
-	if err := consumer.ConsumeWithDLQ(consumerCtx, reviewQueue, handler); err != nil {
-		resources.Logger.Error("Error iniciando consumer de revisión", "error", err.Error())
-		log.Fatal(err)
-	}
-	if err := prepConsumer.ConsumeWithDLQ(consumerCtx, prepQueue, handler); err != nil {
-		resources.Logger.Error("Error iniciando consumer de preparación", "error", err.Error())
-		log.Fatal(err)
+		resources.Logger.Info("Carril escuchando eventos",
+			"lane", lane.Name,
+			"queue", lane.Queue,
+			"prefetch_count", lane.Prefetch,
+			"concurrency", lane.Concurrency,
+			"event_types", lane.EventTypes,
+			"retry_tiers", retryTiersField(lane.Retry))
 	}
-	if err := materialConsumer.ConsumeWithDLQ(consumerCtx, materialQueue, handler); err != nil {
-		resources.Logger.Error("Error iniciando consumer de materiales", "error", err.Error())
-		log.Fatal(err)
+
+	// Monitor de disponibilidad del LLM local: se arranca con los carriles ya
+	// registrados para que su primera sonda pueda pausarlos.
+	if resources.LLMMonitor != nil {
+		resources.LLMMonitor.Start(consumerCtx)
 	}
 
+	// 6. Resumen del arranque.
 	resources.Logger.Info("Worker escuchando eventos",
-		"review_queue", reviewQueue,
-		"prep_queue", prepQueue,
-		"material_queue", materialQueue,
-		"prefetch_count", prefetchCount,
+		"lanes", len(laneConfigs),
+		"fair_scheduling_lanes", len(resources.FairSchedulers),
 		"dlq_enabled", dlqCfg.Enabled,
 		"max_retries", dlqCfg.MaxRetries)
 
-	// 8. Configurar graceful shutdown
+	// 7. Configurar graceful shutdown
 	shutdownCfg := cfg.GetShutdownConfigWithDefaults()
 	gracefulShutdown := shutdown.NewGracefulShutdown(shutdownCfg.Timeout, resources.Logger)
 
-	// 9. Registrar tareas de shutdown en orden inverso de inicialización
+	// 8. Registrar tareas de shutdown en orden inverso de inicialización
 	// Ultimo en inicializarse, primero en cerrarse (LIFO)
 
-	// 9.1 Detener consumers (dejar de aceptar nuevos mensajes en ambos rieles)
+	// 8.1 Detener consumers (dejar de aceptar nuevos mensajes en todos los carriles)
 	gracefulShutdown.Register("consumer", func(shutdownCtx context.Context) error {
 		resources.Logger.Info("Deteniendo consumers de mensajes...")
 		cancelConsumer()
-		consumer.Stop()
-		prepConsumer.Stop()
-		materialConsumer.Stop()
-
 		if shutdownCfg.WaitForMessages {
 			resources.Logger.Info("Esperando que terminen los mensajes en proceso...")
-			if err := consumer.Wait(); err != nil {
-				resources.Logger.Warn("Consumer de revisión detenido con error", "error", err.Error())
-			}
-			if err := prepConsumer.Wait(); err != nil {
-				resources.Logger.Warn("Consumer de preparación detenido con error", "error", err.Error())
-			}
-			if err := materialConsumer.Wait(); err != nil {
-				resources.Logger.Warn("Consumer de materiales detenido con error", "error", err.Error())
-			}
+		}
+		lanes.Close(shutdownCfg.WaitForMessages)
+		if shutdownCfg.WaitForMessages {
 			resources.Logger.Info("Todos los mensajes fueron procesados")
 		}
 
 		return nil
 	})
 
-	// 9.2 Cerrar servidor de metricas
+	// 8.2 Cerrar servidor de metricas
 	gracefulShutdown.Register("metrics_server", func(shutdownCtx context.Context) error {
 		resources.Logger.Info("Cerrando servidor de métricas...")
 		if resources.MetricsServer != nil {
@@ -239,13 +172,13 @@ func main() {
 		return nil
 	})
 
-	// 9.3 Ejecutar cleanup de recursos (RabbitMQ, logger, etc.)
+	// 8.3 Ejecutar cleanup de recursos (RabbitMQ, logger, etc.)
 	gracefulShutdown.Register("infrastructure_cleanup", func(shutdownCtx context.Context) error {
 		resources.Logger.Info("Ejecutando cleanup de infraestructura...")
 		return cleanup()
 	})
 
-	// 10. Esperar senal de shutdown y ejecutar
+	// 9. Esperar senal de shutdown y ejecutar
 	resources.Logger.Info("Worker listo - esperando mensajes...")
 
 	if err := gracefulShutdown.WaitForSignal(); err != nil {
@@ -256,136 +189,165 @@ func main() {
 	resources.Logger.Info("✅ Worker cerrado correctamente")
 }
 
-// setupRabbitMQ configura exchanges, queue y bindings.
-//
-// Topología post-dieta (plan 040 §6.2):
-//   - Declara el exchange `edugo.assessments` (topic, durable) — carril de revisión.
-//   - Declara la cola `edugo.attempt.review_requested` (durable, prioridad + DLX) y la
-//     bindea a `edugo.assessments` con routing key `attempt.review_requested`. Es la cola
-//     que consume el worker hoy (processor AttemptReviewProcessor, registry ya no vacío).
-//   - Sigue declarando el exchange `edugo.materials` aunque NO consuma su cola: learning
-//     publica ahí y el publisher no declara exchanges; un Rabbit fresco rompería el publish
-//     de material si el worker dejara de declararlo. El plan 041 revive la cola/consumer.
-//   - DLX coherente: el arg `x-dead-letter-exchange` de la cola toma el nombre desde config
-//     (antes hardcodeaba `edugo_dlq`, que nadie declaraba, mientras el consumer declara
-//     `edugo_dlx`). Se añade `x-dead-letter-routing-key` para que el dead-letter nativo caiga
-//     en la misma cola DLQ que declara el consumer compartido.
-func setupRabbitMQ(ch *amqp.Channel, cfg *config.Config) error {
-	exchanges := cfg.GetExchangesConfigWithDefaults()
-	queues := cfg.GetQueuesConfigWithDefaults()
-	dlq := cfg.GetDLQConfigWithDefaults()
+// laneConsumer es lo que main usa de un consumer de carril. Lo satisfacen el
+// consumer compartido (*rabbit.RabbitMQConsumer) y el de reintentos diferidos
+// (*retryqueue.Consumer).
+type laneConsumer interface {
+	ConsumeWithDLQ(ctx context.Context, queue string, handler rabbit.MessageHandler) error
+	Stop()
+	Wait() error
+}
 
-	// Declarar exchange de materiales (solo declaración; sin cola/consumer — plan 041).
-	if err := ch.ExchangeDeclare(
-		exchanges.Materials,
-		"topic",
-		true,  // durable
-		false, // auto-deleted
-		false, // internal
-		false, // no-wait
-		nil,
-	); err != nil {
-		return fmt.Errorf("error declarando materials exchange: %w", err)
+// newLaneConsumer crea el consumer de un carril. Con reintentos diferidos habilitados
+// para su cola usa retryqueue (escalones con TTL en el broker; el MaxRetries/RetryDelay
+// del DLQ compartido no aplica); si no, el consumer compartido.
+func newLaneConsumer(resources *bootstrap.Resources, consumerCfg rabbit.ConsumerConfig, retryCfg config.RetryTiersConfig, concurrency int) (laneConsumer, error) {
+	if retryCfg.Enabled {
+		return retryqueue.NewConsumer(resources.RabbitMQConn, retryqueue.Config{
+			Name:          consumerCfg.Name,
+			PrefetchCount: consumerCfg.PrefetchCount,
+			Concurrency:   concurrency,
+			DLXExchange:   consumerCfg.DLQ.DLXExchange,
+			DLQName:       consumerCfg.DLQ.DLXRoutingKey,
+			Tiers:         retryCfg.Tiers,
+			IsPermanent:   processor.IsPermanentError,
+		}, resources.Logger), nil
 	}
-
-	// Declarar exchange de evaluaciones (carril de revisión).
-	if err := ch.ExchangeDeclare(
-		exchanges.Assessments,
-		"topic",
-		true,  // durable
-		false, // auto-deleted
-		false, // internal
-		false, // no-wait
-		nil,
-	); err != nil {
-		return fmt.Errorf("error declarando assessments exchange: %w", err)
+	c, ok := rabbit.NewConsumer(resources.RabbitMQConn, consumerCfg).(*rabbit.RabbitMQConsumer)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type")
 	}
+	return c, nil
+}
 
-	// Declarar cola de revisión con prioridad + DLX coherente con config.
-	_, err := ch.QueueDeclare(
-		queues.AttemptReviewRequested,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		amqp.Table{
-			"x-max-priority":            10,
-			"x-dead-letter-exchange":    dlq.DLXExchange,
-			"x-dead-letter-routing-key": dlq.DLXRoutingKey,
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("error declarando cola de revisión: %w", err)
+// laneConsumerConcurrency son los mensajes que el consumer de un carril entrega a la
+// vez a su handler: lanes[].concurrency (0 = prefetch). Un carril con reparto por
+// escuela recibe todo el prefetch: su FairScheduler necesita el buffer completo para
+// elegir la próxima escuela y acota él mismo lo que se procesa.
+func laneConsumerConcurrency(resources *bootstrap.Resources, lane config.LaneConfig) int {
+	if _, ok := resources.FairSchedulers[lane.Name]; ok {
+		return lane.Prefetch
+	}
+	if lane.Concurrency > 0 && lane.Concurrency < lane.Prefetch {
+		return lane.Concurrency
 	}
+	return lane.Prefetch
+}
 
-	// Bind cola de revisión al routing key attempt.review_requested.
-	if err := ch.QueueBind(
-		queues.AttemptReviewRequested,
-		"attempt.review_requested",
-		exchanges.Assessments,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("error binding cola de revisión: %w", err)
+// newLaneHandler arma el handler de un carril: el registry (enruta por event_type,
+// rechaza como permanente —a la DLQ del carril— los event_type que el carril no
+// acepta y aplica su cadena de middlewares) con el contexto marcado con el carril
+// (clase de prioridad del scheduler LLM), acotado por el FairScheduler del carril si tiene
+// reparto por escuela o, si no, por lanes[].concurrency cuando es menor que el
+// prefetch; todo detrás del LaneController.
+func newLaneHandler(resources *bootstrap.Resources, lane config.LaneConfig) rabbit.MessageHandler {
+	var h rabbit.MessageHandler = func(ctx context.Context, body []byte) error {
+		return resources.ProcessorRegistry.ProcessLane(llm.WithLane(ctx, lane.Name), body, lane.EventTypes)
 	}
+	if scheduler, ok := resources.FairSchedulers[lane.Name]; ok {
+		h = scheduler.Wrap(h)
+	} else if lane.Concurrency > 0 && lane.Concurrency < lane.Prefetch {
+		h = laneconsumer.LimitConcurrency(lane.Concurrency, h)
+	}
+	return resources.LaneController.Wrap(lane.Name, h)
+}
 
-	// Cola del carril de PREPARACIÓN (plan 042 F2a): canal propio por riel (D-042.3),
-	// sobre el mismo exchange edugo.assessments pero con routing key y DLQ propias
-	// (no comparte cola con revisión). Su dead-letter cae en la DLQ del riel de prep.
-	if _, err := ch.QueueDeclare(
-		queues.QuestionPrepRequested,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		amqp.Table{
-			"x-max-priority":            10,
-			"x-dead-letter-exchange":    dlq.DLXExchange,
-			"x-dead-letter-routing-key": queues.PrepDLQName(),
-		},
-	); err != nil {
-		return fmt.Errorf("error declarando cola de preparación: %w", err)
+// retryTiersField resume los escalones de retry de un carril para el log de arranque.
+func retryTiersField(c config.RetryTiersConfig) string {
+	if !c.Enabled {
+		return "disabled"
 	}
+	return fmt.Sprint(c.Tiers)
+}
+
+// topologyChannel es la porción de *amqp.Channel que usa setupRabbitMQ; el canal del
+// broker en memoria (modo --local) también la satisface.
+type topologyChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+// setupRabbitMQ declara la topología de los carriles de `lanes:`: exchanges, colas
+// y bindings.
+//
+// Topología (plan 040 §6.2, ahora por config):
+//   - Declara siempre los exchanges `edugo.materials` y `edugo.assessments` (topic,
+//     durable): learning publica ahí y el publisher no declara exchanges; un Rabbit
+//     fresco rompería el publish si el worker dejara de declararlos, aunque ningún
+//     carril consuma de alguno.
+//   - Por carril declara los exchanges de sus bindings (topic, durable), su cola
+//     (durable, prioridad + DLX) y los bindings.
+//   - DLX coherente: el arg `x-dead-letter-exchange` de la cola toma el nombre desde config
+//     (antes hardcodeaba `edugo_dlq`, que nadie declaraba, mientras el consumer declara
+//     `edugo_dlx`). `x-dead-letter-routing-key` es la DLQ del carril, para que el
+//     dead-letter nativo caiga en la misma cola DLQ que declara su consumer.
+func setupRabbitMQ(ch topologyChannel, cfg *config.Config) error {
+	exchanges := cfg.GetExchangesConfigWithDefaults()
+	dlq := cfg.GetDLQConfigWithDefaults()
 
-	// Bind cola de preparación al routing key question.prep_requested.
-	if err := ch.QueueBind(
-		queues.QuestionPrepRequested,
-		events.EventTypeQuestionPrepRequested,
-		exchanges.Assessments,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("error binding cola de preparación: %w", err)
+	declared := make(map[string]bool)
+	declareExchange := func(name string) error {
+		if declared[name] {
+			return nil
+		}
+		if err := ch.ExchangeDeclare(
+			name,
+			"topic",
+			true,  // durable
+			false, // auto-deleted
+			false, // internal
+			false, // no-wait
+			nil,
+		); err != nil {
+			return fmt.Errorf("error declarando exchange %s: %w", name, err)
+		}
+		declared[name] = true
+		return nil
 	}
 
-	// Cola del carril MATERIAL→EVALUACIÓN (plan 043 F3c): canal propio por riel. A
-	// diferencia de revisión/preparación, se bindea al exchange edugo.materials (donde
-	// learning publica material.assessment_requested), con routing key y DLQ propias.
-	if _, err := ch.QueueDeclare(
-		queues.MaterialAssessmentRequested,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		amqp.Table{
-			"x-max-priority":            10,
-			"x-dead-letter-exchange":    dlq.DLXExchange,
-			"x-dead-letter-routing-key": queues.MaterialAssessmentDLQName(),
-		},
-	); err != nil {
-		return fmt.Errorf("error declarando cola de materiales: %w", err)
+	if err := declareExchange(exchanges.Materials); err != nil {
+		return err
+	}
+	if err := declareExchange(exchanges.Assessments); err != nil {
+		return err
 	}
 
-	// Bind cola de materiales al routing key material.assessment_requested sobre el
-	// exchange edugo.materials.
-	if err := ch.QueueBind(
-		queues.MaterialAssessmentRequested,
-		events.EventTypeMaterialAssessmentRequested,
-		exchanges.Materials,
-		false,
-		nil,
-	); err != nil {
-		return fmt.Errorf("error binding cola de materiales: %w", err)
+	for _, lane := range cfg.GetLanesConfigWithDefaults() {
+		for _, binding := range lane.Bindings {
+			if err := declareExchange(binding.Exchange); err != nil {
+				return err
+			}
+		}
+
+		// Cola del carril con prioridad + DLX coherente con config.
+		if _, err := ch.QueueDeclare(
+			lane.Queue,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-max-priority":            10,
+				"x-dead-letter-exchange":    dlq.DLXExchange,
+				"x-dead-letter-routing-key": lane.DLQ,
+			},
+		); err != nil {
+			return fmt.Errorf("error declarando cola del carril %s: %w", lane.Name, err)
+		}
+
+		for _, binding := range lane.Bindings {
+			if err := ch.QueueBind(
+				lane.Queue,
+				binding.RoutingKey,
+				binding.Exchange,
+				false,
+				nil,
+			); err != nil {
+				return fmt.Errorf("error binding cola del carril %s a %s/%s: %w",
+					lane.Name, binding.Exchange, binding.RoutingKey, err)
+			}
+		}
 	}
 
 	return nil
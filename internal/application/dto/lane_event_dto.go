@@ -0,0 +1,91 @@
+package dto
+
+import "time"
+
+// Event types que el worker PUBLICA al cerrar el trabajo de cada carril. Hasta hoy
+// los processors terminaban en silencio (M2M + ACK); estos eventos permiten a
+// notificaciones/analítica reaccionar sin hacer polling a learning.
+// NOTA: mapeo local hasta que los contratos vivan en edugo-shared/messaging/events.
+const (
+	// EventTypeAttemptAIReviewed: un intento terminó su revisión asistida por LLM.
+	EventTypeAttemptAIReviewed = "attempt.ai_reviewed"
+	// EventTypeQuestionPrepSaved: una pregunta quedó preparada (prep v1 persistido).
+	EventTypeQuestionPrepSaved = "question.prep_saved"
+	// EventTypeMaterialAssessmentDelivered: el carril material→evaluación entregó el draft.
+	EventTypeMaterialAssessmentDelivered = "material.assessment_delivered"
+	// EventTypeMaterialAssessmentFailed: el job del carril quedó en failed (permanente).
+	EventTypeMaterialAssessmentFailed = "material.assessment_failed"
+)
+
+// LaneEventVersion es la versión del envelope/payload de los eventos de salida.
+const LaneEventVersion = "1.0"
+
+// LaneEvent es el envelope de los eventos que publica el worker. Sigue la forma de
+// los eventos que consume (event_id/event_type/event_version/timestamp/payload) y
+// añade correlation_id: el event_id del evento de entrada que originó el trabajo.
+type LaneEvent struct {
+	EventID       string    `json:"event_id"`
+	EventType     string    `json:"event_type"`
+	EventVersion  string    `json:"event_version"`
+	Timestamp     time.Time `json:"timestamp"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Payload       any       `json:"payload"`
+}
+
+// AttemptAIReviewedPayload resume la revisión de un intento. AnswersReviewed cuenta
+// las respuestas revisadas en ESTA entrega (0 en un redelivery sin pendientes); el
+// desglose por veredicto permite métricas sin leer learning. LowConfidence cuenta las
+// marcadas para el profesor por baja confianza (llm.review.min_confidence) en todo el
+// intento, incluidas las de entregas anteriores.
+type AttemptAIReviewedPayload struct {
+	AttemptID       string `json:"attempt_id"`
+	AssessmentID    string `json:"assessment_id"`
+	SchoolID        string `json:"school_id"`
+	Provider        string `json:"provider"`
+	Mode            string `json:"mode"`
+	Flow            string `json:"flow"`
+	AnswersReviewed int    `json:"answers_reviewed"`
+	Correct         int    `json:"correct"`
+	Partial         int    `json:"partial"`
+	Incorrect       int    `json:"incorrect"`
+	LowConfidence   int    `json:"low_confidence"`
+	Finalized       bool   `json:"finalized"`
+}
+
+// QuestionPrepSavedPayload resume el prep persistido de una pregunta.
+type QuestionPrepSavedPayload struct {
+	QuestionID       string `json:"question_id"`
+	AssessmentID     string `json:"assessment_id"`
+	SchoolID         string `json:"school_id"`
+	QuestionType     string `json:"question_type"`
+	Reason           string `json:"reason,omitempty"`
+	Provider         string `json:"provider"`
+	Mode             string `json:"mode"`
+	ConsumedFeedback bool   `json:"consumed_feedback"`
+	Items            int    `json:"items"`
+	Criteria         int    `json:"criteria"`
+}
+
+// MaterialAssessmentDeliveredPayload resume la entrega de la fase 2 del carril de
+// materiales: el assessment creado y los conteos de la destilación.
+type MaterialAssessmentDeliveredPayload struct {
+	JobID        string `json:"job_id"`
+	MaterialID   string `json:"material_id"`
+	SchoolID     string `json:"school_id"`
+	AssessmentID string `json:"assessment_id"`
+	Provider     string `json:"provider"`
+	Candidates   int    `json:"candidates"`
+	Selected     int    `json:"selected"`
+	Questions    int    `json:"questions"`
+}
+
+// MaterialAssessmentFailedPayload describe un job marcado failed por un error
+// permanente (el mensaje va al DLQ). Phase es la fase en la que falló (0/1/2).
+type MaterialAssessmentFailedPayload struct {
+	JobID      string `json:"job_id"`
+	MaterialID string `json:"material_id"`
+	SchoolID   string `json:"school_id"`
+	Provider   string `json:"provider"`
+	Phase      int16  `json:"phase"`
+	Error      string `json:"error"`
+}
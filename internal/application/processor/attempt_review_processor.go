@@ -6,12 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/EduGoGroup/edugo-shared/logger"
 	"github.com/EduGoGroup/edugo-shared/messaging/events"
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/vote"
 	"github.com/EduGoGroup/edugo-worker/internal/openended"
 	"github.com/EduGoGroup/edugo-worker/internal/questionprep"
 	"github.com/EduGoGroup/edugo-worker/internal/shortanswer"
@@ -24,8 +30,10 @@ const EventTypeAttemptReviewRequested = "attempt.review_requested"
 
 // Claves de política por escuela leídas vía SettingsClient (design 039/040 §rieles).
 const (
-	settingKeyReviewMode = "llm.review.mode" // local | api | off
-	settingKeyReviewFlow = "llm.review.flow" // direct | teacher
+	settingKeyReviewMode          = "llm.review.mode"           // local | api | off
+	settingKeyReviewFlow          = "llm.review.flow"           // direct | teacher
+	settingKeyReviewSamples       = "llm.review.samples"        // 1 (default) | 2..maxReviewSamples
+	settingKeyReviewMinConfidence = "llm.review.min_confidence" // 0..1; ausente = sin umbral
 )
 
 // Valores del carril de revisión (design 040 §rieles).
@@ -39,9 +47,24 @@ const (
 	reviewFlowDirect  = "direct"  // finaliza el intento tras revisar (sin docente)
 )
 
-// reviewLanguage es el idioma que se pide al LLM para el feedback. El carril es
-// español (regla global del ecosistema).
-const reviewLanguage = "es"
+// Revisión por votación (llm.review.samples > 1): cada juicio se muestrea N veces y
+// gana el veredicto de la mayoría (internal/llm/vote).
+const (
+	// maxReviewSamples acota el costo: cada muestra es una llamada completa al LLM.
+	maxReviewSamples = 7
+	// reviewVoteTemperature es la temperatura de las muestras 2..N. La primera usa la
+	// del provider (greedy): sin algo de temperatura las N muestras serían idénticas.
+	reviewVoteTemperature = 0.7
+)
+
+// claimRenewInterval es cada cuánto se renueva el candado mientras se corrige el lote:
+// un tercio de su vencimiento (10 min del lado de learning), para que un intento largo
+// no lo pierda a mitad.
+const claimRenewInterval = 3 * time.Minute
+
+// errClaimLost es la causa con la que se cancela el lote cuando una renovación del
+// candado recibe 409: el intento ya no es de la IA.
+var errClaimLost = errors.New("candado del intento perdido")
 
 // ErrMalformedEvent marca un evento que no se puede decodificar/validar. El
 // clasificador de retry lo trata como permanente: reintentar no lo arregla, va al
@@ -86,12 +109,17 @@ type LearningReviewClient interface {
 // revisión está activa (mode=local|api), lee las respuestas pendientes de learning
 // (M2M), las corrige con el LLMProvider del mode y escribe cada review de vuelta.
 // Con flow=direct finaliza el intento; con flow=teacher lo deja ai_reviewed para el
-// profesor (F3).
+// profesor (F3). Al cerrar el lote publica attempt.ai_reviewed (si hay publisher).
 type AttemptReviewProcessor struct {
 	settings  SchoolSettingsReader
 	learning  LearningReviewClient
 	providers map[string]llm.LLMProvider
+	publisher EventPublisher
 	logger    logger.Logger
+	// concurrency son las respuestas del attempt corregidas a la vez (default 1).
+	concurrency int
+	// claimRenewEvery es el período de renovación del candado (claimRenewInterval).
+	claimRenewEvery time.Duration
 }
 
 // NewAttemptReviewProcessor construye el processor. providers mapea el mode
@@ -104,11 +132,31 @@ func NewAttemptReviewProcessor(
 	log logger.Logger,
 ) *AttemptReviewProcessor {
 	return &AttemptReviewProcessor{
-		settings:  settings,
-		learning:  learning,
-		providers: providers,
-		logger:    log,
+		settings:        settings,
+		learning:        learning,
+		providers:       providers,
+		publisher:       noopEventPublisher{},
+		logger:          log,
+		concurrency:     1,
+		claimRenewEvery: claimRenewInterval,
+	}
+}
+
+// WithEventPublisher cablea el publisher de eventos de salida. nil deja el no-op.
+func (p *AttemptReviewProcessor) WithEventPublisher(pub EventPublisher) *AttemptReviewProcessor {
+	if pub != nil {
+		p.publisher = pub
+	}
+	return p
+}
+
+// WithReviewConcurrency corrige hasta n respuestas de un attempt a la vez. n < 1 se
+// ignora (queda secuencial).
+func (p *AttemptReviewProcessor) WithReviewConcurrency(n int) *AttemptReviewProcessor {
+	if n >= 1 {
+		p.concurrency = n
 	}
+	return p
 }
 
 // EventType satisface processor.Processor.
@@ -137,6 +185,9 @@ func (p *AttemptReviewProcessor) Process(ctx context.Context, payload []byte) er
 
 	mode := settingValueOr(settings, settingKeyReviewMode, reviewModeOff)
 	flow := settingValueOr(settings, settingKeyReviewFlow, reviewFlowTeacher)
+	lang := schoolLanguage(settings)
+	samples := p.reviewSamples(settings, evt.Payload.SchoolID)
+	minConfidence := p.reviewMinConfidence(settings, evt.Payload.SchoolID)
 
 	// Corto-circuito: revisión apagada para esta escuela.
 	if mode == reviewModeOff {
@@ -148,10 +199,14 @@ func (p *AttemptReviewProcessor) Process(ctx context.Context, payload []byte) er
 		return nil
 	}
 
-	return p.orchestrate(ctx, evt.Payload.AttemptID, mode, flow, evt.Payload.Answers)
+	// La escuela elige la versión de los prompts de revisión (rollout).
+	return p.orchestrate(llm.WithSchool(ctx, evt.Payload.SchoolID), evt, mode, flow, lang, samples, minConfidence)
 }
 
-// orchestrate ejecuta la revisión asistida de un intento con la política resuelta.
+// orchestrate ejecuta la revisión asistida de un intento con la política resuelta;
+// lang es el idioma del feedback (setting llm.language de la escuela), samples el
+// número de muestras por juicio (1 = sin votación) y minConfidence el umbral de
+// confianza bajo el cual una respuesta queda para el profesor (0 = sin umbral).
 //
 // Idempotencia y retry (gate de tasks.md): todo el flujo es seguro de reprocesar.
 // El GET re-lee solo las respuestas AÚN pendientes y el POST review es upsert del
@@ -162,7 +217,29 @@ func (p *AttemptReviewProcessor) Process(ctx context.Context, payload []byte) er
 // clasificador marca permanente; aun así el consumer con DLQ reintenta MaxRetries
 // antes de mandar el mensaje al DLQ (ConsumeWithDLQ no consulta el clasificador),
 // lo cual es inofensivo porque el reproceso es idempotente.
-func (p *AttemptReviewProcessor) orchestrate(ctx context.Context, attemptID, mode, flow string, answers []events.AttemptReviewAnswerRef) error {
+//
+// Las respuestas se corrigen en paralelo (hasta p.concurrency a la vez). Un fallo
+// cancela las que no arrancaron y espera a las en curso: lo ya escrito queda escrito
+// y el redelivery retoma solo lo pendiente, igual que en el recorrido secuencial.
+// Mientras corren, el candado se renueva cada p.claimRenewEvery; si una renovación
+// recibe 409 (venció y lo tomó el profesor) se corta todo, también lo en curso, sin
+// escribir más reviews ni cerrar el lote.
+//
+// Al cerrar el lote (finalize o release-claim) publica attempt.ai_reviewed de mejor
+// esfuerzo, también en un redelivery sin pendientes (answers_reviewed=0): así un
+// fallo entre la última review y el cierre no pierde el evento. Los consumidores
+// deduplican por attempt_id.
+//
+// Con flow=direct, una respuesta de baja confianza deja el intento sin finalizar
+// (release-claim, como flow=teacher). Cuentan también las marcadas por una entrega
+// anterior (pending.FlaggedAnswers): un redelivery tras un fallo a mitad ya no las ve
+// pendientes, pero learning las informa. Si learning no las informa (contrato
+// anterior) y la escuela tiene umbral, no se finaliza: no se puede descartar que una
+// entrega anterior marcara alguna.
+func (p *AttemptReviewProcessor) orchestrate(ctx context.Context, evt events.AttemptReviewRequestedEvent, mode, flow, lang string, samples int, minConfidence float64) error {
+	attemptID := evt.Payload.AttemptID
+	answers := evt.Payload.Answers
+
 	provider, ok := p.providers[mode]
 	if !ok || provider == nil {
 		// mode desconocido o provider no disponible: es config errónea, no un fallo
@@ -195,78 +272,210 @@ func (p *AttemptReviewProcessor) orchestrate(ctx context.Context, attemptID, mod
 	}
 	finalizeAtEnd := flow == reviewFlowDirect && !hasShortAnswer
 
+	summary := dto.AttemptAIReviewedPayload{
+		AttemptID:    attemptID,
+		AssessmentID: evt.Payload.AssessmentID,
+		SchoolID:     evt.Payload.SchoolID,
+		Provider:     provider.Name(),
+		Mode:         mode,
+		Flow:         flow,
+	}
+
 	pending, err := p.learning.GetPendingAnswers(ctx, attemptID)
 	if err != nil {
 		return fmt.Errorf("leyendo answers pendientes de attempt %s: %w", attemptID, err)
 	}
 
+	// Las marcadas por una entrega anterior bloquean el finalize igual que las de ésta.
+	switch {
+	case pending.FlaggedAnswers != nil:
+		summary.LowConfidence = *pending.FlaggedAnswers
+	case finalizeAtEnd && minConfidence > 0:
+		p.logger.Warn("learning no informa flagged_answers con llm.review.min_confidence activo: el intento queda para el profesor (sin finalize)",
+			"attempt_id", attemptID, "min_confidence", minConfidence)
+		finalizeAtEnd = false
+	}
+
 	// Sin pendientes: nada que corregir. Puede ser un redelivery de un intento ya
-	// revisado. Si toca finalizar (direct + solo open_ended) intentamos finalize
-	// idempotente; si no, liberamos el candado para el profesor.
+	// revisado. Si toca finalizar (direct + solo open_ended, sin respuestas marcadas)
+	// intentamos finalize idempotente; si no, liberamos el candado para el profesor.
 	if len(pending.Answers) == 0 {
+		if finalizeAtEnd && summary.LowConfidence == 0 {
+			return p.finalizeAndPublish(ctx, evt.EventID, summary, "sin pendientes (posible redelivery)")
+		}
 		if finalizeAtEnd {
-			return p.finalize(ctx, attemptID, "sin pendientes (posible redelivery)")
+			p.logger.Info("review sin pendientes con respuestas de baja confianza: release-claim (sin finalize)",
+				"attempt_id", attemptID, "low_confidence", summary.LowConfidence)
+			p.releaseClaim(ctx, attemptID, "respuestas de baja confianza, intento queda para el profesor")
+			publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeAttemptAIReviewed, evt.EventID, summary)
+			return nil
 		}
 		p.logger.Info("review sin pendientes, flujo teacher/short_answer: release-claim",
 			"attempt_id", attemptID, "mode", mode, "flow", flow, "has_short_answer", hasShortAnswer)
 		p.releaseClaim(ctx, attemptID, "sin pendientes (posible redelivery)")
+		publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeAttemptAIReviewed, evt.EventID, summary)
 		return nil
 	}
 
+	// Cada review se escribe apenas termina. Se espera a todas antes de devolver el
+	// error o cerrar el lote: ninguna escritura queda suelta cuando el redelivery
+	// vuelva a leer las pendientes, y el claim sigue tomado (y renovado) mientras
+	// corren. claimCtx solo se cancela si se pierde el candado; reviewCtx, además, con
+	// el primer fallo, y solo frena las que aún no arrancaron.
+	claimCtx, loseClaim := context.WithCancelCause(ctx)
+	defer loseClaim(nil)
+	stopRenew := p.renewClaim(claimCtx, attemptID, loseClaim)
+	reviewCtx, cancel := context.WithCancel(claimCtx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, p.concurrency)
 	for _, ans := range pending.Answers {
-		result, err := p.reviewOne(ctx, provider, ans)
-		if err != nil {
-			// Fallo del LLM: transitorio. Reintentar es seguro (aún no escribimos esta
-			// review; las ya escritas no vuelven a aparecer en el GET pending).
-			return fmt.Errorf("LLM revisando answer %s (attempt %s): %w", ans.AnswerID, attemptID, err)
+		select {
+		case slots <- struct{}{}:
+		case <-reviewCtx.Done():
 		}
-
-		// Guardia anti-basura: si el verdict no es válido para el tipo de pregunta
-		// (p.ej. "" por un `{}` de qwen3), el LLM no emitió un juicio usable. Se trata
-		// igual que un fallo del provider (mismo carril de retry) y NO se postea una
-		// review IA de 0 puntos «propuesta» — la answer queda para el profesor.
-		if err := validateVerdict(ans.QuestionType, result.Verdict); err != nil {
-			p.logger.Warn("veredicto del LLM inválido, se descarta la propuesta (no se postea review IA)",
-				"attempt_id", attemptID,
-				"answer_id", ans.AnswerID,
-				"question_type", ans.QuestionType,
-				"verdict", string(result.Verdict),
-				"score", result.Score,
-				"provider", provider.Name(),
-			)
-			return fmt.Errorf("revisando answer %s (attempt %s): %w", ans.AnswerID, attemptID, err)
+		if reviewCtx.Err() != nil {
+			break
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			reviewed, err := p.reviewAndPost(claimCtx, attemptID, provider, ans, lang, samples, minConfidence)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			tallyVerdict(&summary, reviewed.verdict)
+			if reviewed.lowConfidence {
+				summary.LowConfidence++
+			}
+		}()
+	}
+	wg.Wait()
+	stopRenew()
+	if errors.Is(context.Cause(claimCtx), errClaimLost) {
+		p.logger.Info("candado perdido durante la revisión, se abstiene (ACK)",
+			"attempt_id", attemptID, "answers", len(pending.Answers))
+		return nil
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("revisando answers de attempt %s: %w", attemptID, err)
+	}
 
-		points := scaledPoints(result.Score, ans.Points)
-		if _, err := p.learning.PostAnswerReview(ctx, attemptID, ans.AnswerID, m2m.AnswerReviewRequest{
-			PointsAwarded: points,
-			Feedback:      result.Feedback,
-		}); err != nil {
-			return fmt.Errorf("escribiendo review de answer %s (attempt %s): %w", ans.AnswerID, attemptID, err)
-		}
+	// Todas las pendientes quedaron revisadas. Una respuesta de baja confianza no se
+	// auto-finaliza: el intento queda para el profesor.
+	if finalizeAtEnd && summary.LowConfidence > 0 {
+		p.logger.Info("review completada con respuestas de baja confianza: release-claim (sin finalize)",
+			"attempt_id", attemptID, "answers", len(pending.Answers),
+			"low_confidence", summary.LowConfidence, "min_confidence", minConfidence)
+		p.releaseClaim(ctx, attemptID, "respuestas de baja confianza, intento queda para el profesor")
+		publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeAttemptAIReviewed, evt.EventID, summary)
+		return nil
+	}
+	if finalizeAtEnd {
+		return p.finalizeAndPublish(ctx, evt.EventID, summary, "todas las respuestas revisadas")
+	}
+
+	// Flujo teacher (o presencia de short_answer): NO finalize. Se libera el candado
+	// y el intento queda ai_reviewed para que el profesor lo vise (plan 040 F4).
+	p.logger.Info("review completada, flujo teacher/short_answer: release-claim (sin finalize)",
+		"attempt_id", attemptID, "answers", len(pending.Answers), "has_short_answer", hasShortAnswer)
+	p.releaseClaim(ctx, attemptID, "revisión completada, intento queda para el profesor")
+	publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeAttemptAIReviewed, evt.EventID, summary)
+	return nil
+}
+
+// reviewedAnswer es lo que una review escrita aporta al resumen del lote.
+type reviewedAnswer struct {
+	verdict       llm.Verdict
+	lowConfidence bool
+}
+
+// reviewAndPost corrige UNA respuesta pendiente y escribe su review en learning. Es
+// seguro en paralelo: cada llamada tiene su propio decorador de votación y no toca
+// estado compartido del lote (el resumen lo suma el caller).
+func (p *AttemptReviewProcessor) reviewAndPost(ctx context.Context, attemptID string, provider llm.LLMProvider, ans m2m.PendingAnswer, lang string, samples int, minConfidence float64) (reviewedAnswer, error) {
+	// Con votación, un decorador por respuesta: su Agreement resume las votaciones
+	// de esa respuesta (una por juicio global o por criterio).
+	reviewer := provider
+	var voter *vote.Provider
+	if samples > 1 {
+		voter = vote.NewProvider(provider, vote.Config{Samples: samples, Temperature: reviewVoteTemperature})
+		reviewer = voter
+	}
+	result, err := p.reviewOne(ctx, reviewer, ans, lang)
+	if err != nil {
+		// Fallo del LLM: transitorio. Reintentar es seguro (aún no escribimos esta
+		// review; las ya escritas no vuelven a aparecer en el GET pending).
+		return reviewedAnswer{}, fmt.Errorf("LLM revisando answer %s (attempt %s): %w", ans.AnswerID, attemptID, err)
+	}
 
-		p.logger.Info("answer revisada por LLM",
+	// Guardia anti-basura: si el verdict no es válido para el tipo de pregunta
+	// (p.ej. "" por un `{}` de qwen3), el LLM no emitió un juicio usable. Se trata
+	// igual que un fallo del provider (mismo carril de retry) y NO se postea una
+	// review IA de 0 puntos «propuesta» — la answer queda para el profesor.
+	if err := validateVerdict(ans.QuestionType, result.Verdict); err != nil {
+		p.logger.Warn("veredicto del LLM inválido, se descarta la propuesta (no se postea review IA)",
 			"attempt_id", attemptID,
 			"answer_id", ans.AnswerID,
 			"question_type", ans.QuestionType,
 			"verdict", string(result.Verdict),
 			"score", result.Score,
-			"points_awarded", points,
 			"provider", provider.Name(),
 		)
+		return reviewedAnswer{}, fmt.Errorf("revisando answer %s (attempt %s): %w", ans.AnswerID, attemptID, err)
 	}
 
-	// Todas las pendientes quedaron revisadas.
-	if finalizeAtEnd {
-		return p.finalize(ctx, attemptID, "todas las respuestas revisadas")
+	points := scaledPoints(result.Score, ans.Points)
+	review := m2m.AnswerReviewRequest{PointsAwarded: points, Feedback: result.Feedback}
+	logFields := []any{
+		"attempt_id", attemptID,
+		"answer_id", ans.AnswerID,
+		"question_type", ans.QuestionType,
+		"verdict", string(result.Verdict),
+		"score", result.Score,
+		"points_awarded", points,
+		"provider", provider.Name(),
+	}
+	if voter != nil {
+		// El carril triturado (short_answer con prep list) no vota: sin agreement.
+		if agreement, ok := voter.Agreement(); ok {
+			review.AIAgreement = &agreement
+			logFields = append(logFields, "samples", samples, "agreement", agreement)
+		}
+	}
+	switch {
+	case result.Confidence != nil:
+		review.AIConfidence = result.Confidence
+		review.NeedsTeacherReview = *result.Confidence < minConfidence
+		logFields = append(logFields, "confidence", *result.Confidence, "needs_teacher_review", review.NeedsTeacherReview)
+	case minConfidence > 0:
+		// Con umbral pero sin señal (api sin votación, backend sin logprobs) no se puede
+		// afirmar que la review lo supera: queda para el profesor, no se finaliza a ciegas.
+		review.NeedsTeacherReview = true
+		logFields = append(logFields, "needs_teacher_review", true)
+		p.logger.Warn("review sin confianza con llm.review.min_confidence activo: queda para el profesor (habilitar votación o logprobs)",
+			"attempt_id", attemptID, "answer_id", ans.AnswerID, "provider", provider.Name(), "min_confidence", minConfidence)
+	}
+	if _, err := p.learning.PostAnswerReview(ctx, attemptID, ans.AnswerID, review); err != nil {
+		return reviewedAnswer{}, fmt.Errorf("escribiendo review de answer %s (attempt %s): %w", ans.AnswerID, attemptID, err)
 	}
 
-	// Flujo teacher (o presencia de short_answer): NO finalize. Se libera el candado
-	// y el intento queda ai_reviewed para que el profesor lo vise (plan 040 F4).
-	p.logger.Info("review completada, flujo teacher/short_answer: release-claim (sin finalize)",
-		"attempt_id", attemptID, "answers", len(pending.Answers), "has_short_answer", hasShortAnswer)
-	p.releaseClaim(ctx, attemptID, "revisión completada, intento queda para el profesor")
-	return nil
+	p.logger.Info("answer revisada por LLM", logFields...)
+	return reviewedAnswer{verdict: result.Verdict, lowConfidence: review.NeedsTeacherReview}, nil
 }
 
 // reviewOne produce el ReviewResult de UNA answer, eligiendo el carril según el prep
@@ -274,7 +483,7 @@ func (p *AttemptReviewProcessor) orchestrate(ctx context.Context, attemptID, mod
 // determinista + pares binarios, reemplaza el juicio global). short_answer con prep
 // de otro content_kind ⇒ prompt global enriquecido con los ítems normalizados. Sin
 // prep (o inválido) o cualquier otro tipo ⇒ flujo global actual intacto.
-func (p *AttemptReviewProcessor) reviewOne(ctx context.Context, provider llm.LLMProvider, ans m2m.PendingAnswer) (llm.ReviewResult, error) {
+func (p *AttemptReviewProcessor) reviewOne(ctx context.Context, provider llm.LLMProvider, ans m2m.PendingAnswer, lang string) (llm.ReviewResult, error) {
 	prep := p.parsePrep(ans)
 
 	req := llm.ReviewRequest{
@@ -283,7 +492,7 @@ func (p *AttemptReviewProcessor) reviewOne(ctx context.Context, provider llm.LLM
 		ExpectedAnswer: ans.ExpectedAnswer,
 		Rubric:         ans.Rubric,
 		StudentAnswer:  ans.StudentAnswer,
-		Language:       reviewLanguage,
+		Language:       lang,
 	}
 
 	if ans.QuestionType == llm.QuestionTypeShortAnswer && prep != nil {
@@ -295,7 +504,7 @@ func (p *AttemptReviewProcessor) reviewOne(ctx context.Context, provider llm.LLM
 				StudentAnswer: ans.StudentAnswer,
 				Items:         prep.Items,
 				ItemsVerbatim: prep.ItemsVerbatim,
-				Language:      reviewLanguage,
+				Language:      lang,
 			})
 		}
 		// term/number/date/free: mejora barata del prompt global (D-042.10 §short_answer,
@@ -318,7 +527,7 @@ func (p *AttemptReviewProcessor) reviewOne(ctx context.Context, provider llm.LLM
 				ExpectedAnswer: ans.ExpectedAnswer,
 				StudentAnswer:  ans.StudentAnswer,
 				Criteria:       criteria,
-				Language:       reviewLanguage,
+				Language:       lang,
 				Logger:         p.logger,
 			})
 		}
@@ -378,6 +587,45 @@ func nonBlankCriteria(criteria []string) []string {
 	return out
 }
 
+// renewClaim renueva el candado del intento cada p.claimRenewEvery hasta que se llama
+// al stop devuelto (que espera a la renovación en curso) o se cancela ctx. Un 409
+// llama a lose(errClaimLost); cualquier otro fallo se loguea y se reintenta en el
+// próximo tick (el candado aún no venció).
+func (p *AttemptReviewProcessor) renewClaim(ctx context.Context, attemptID string, lose context.CancelCauseFunc) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(p.claimRenewEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := p.learning.Claim(ctx, attemptID)
+			switch {
+			case err == nil:
+			case errors.Is(err, m2m.ErrClaimConflict):
+				p.logger.Warn("renovación del candado rechazada, se corta la revisión",
+					"attempt_id", attemptID, "motivo", err.Error())
+				lose(errClaimLost)
+				return
+			default:
+				p.logger.Warn("no se pudo renovar el candado, se reintenta en el próximo tick",
+					"attempt_id", attemptID, "motivo", err.Error())
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
 // releaseClaim libera el candado de mejor esfuerzo: un fallo NO es fatal (el
 // candado vence por TTL del lado de learning), así que se loguea y se sigue. Las
 // reviews ya quedaron escritas; reprocesar por un release fallido sería en vano.
@@ -402,6 +650,31 @@ func (p *AttemptReviewProcessor) finalize(ctx context.Context, attemptID, reason
 	return nil
 }
 
+// finalizeAndPublish finaliza el intento (flow direct) y, solo si el finalize tuvo
+// éxito, publica attempt.ai_reviewed con finalized=true. Un fallo del finalize sube
+// sin publicar: el redelivery repetirá el cierre y publicará entonces.
+func (p *AttemptReviewProcessor) finalizeAndPublish(ctx context.Context, correlationID string, summary dto.AttemptAIReviewedPayload, reason string) error {
+	if err := p.finalize(ctx, summary.AttemptID, reason); err != nil {
+		return err
+	}
+	summary.Finalized = true
+	publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeAttemptAIReviewed, correlationID, summary)
+	return nil
+}
+
+// tallyVerdict suma una review escrita a los conteos del payload attempt.ai_reviewed.
+func tallyVerdict(summary *dto.AttemptAIReviewedPayload, v llm.Verdict) {
+	summary.AnswersReviewed++
+	switch v {
+	case llm.VerdictCorrect:
+		summary.Correct++
+	case llm.VerdictPartial:
+		summary.Partial++
+	default:
+		summary.Incorrect++
+	}
+}
+
 // scaledPoints escala el Score del LLM (fracción 0..1) al puntaje real de la
 // pregunta y lo redondea a 2 decimales (paso mínimo razonable para puntajes
 // escolares; evita colas binarias tipo 4.999999). El Score se acota a [0,1] por
@@ -463,3 +736,45 @@ func settingValueOr(s m2m.SchoolSettings, key, def string) string {
 	}
 	return def
 }
+
+// reviewSamples resuelve llm.review.samples: ausente = 1 (sin votación). Un valor no
+// numérico o < 1 se ignora con aviso (la corrección sigue con una muestra) y uno mayor
+// que maxReviewSamples se acota.
+func (p *AttemptReviewProcessor) reviewSamples(s m2m.SchoolSettings, schoolID string) int {
+	raw := settingValueOr(s, settingKeyReviewSamples, "1")
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || n < 1 {
+		p.logger.Warn("llm.review.samples inválido, se corrige sin votación",
+			"school_id", schoolID, "value", raw)
+		return 1
+	}
+	return min(n, maxReviewSamples)
+}
+
+// reviewMinConfidence resuelve llm.review.min_confidence: ausente = 0 (sin umbral).
+// Un valor no numérico o fuera de 0..1 se ignora con aviso: la corrección sigue sin
+// umbral, como antes del setting.
+func (p *AttemptReviewProcessor) reviewMinConfidence(s m2m.SchoolSettings, schoolID string) float64 {
+	raw := strings.TrimSpace(settingValueOr(s, settingKeyReviewMinConfidence, ""))
+	if raw == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 || v > 1 {
+		p.logger.Warn("llm.review.min_confidence inválido, se corrige sin umbral",
+			"school_id", schoolID, "value", raw)
+		return 0
+	}
+	return v
+}
+
+// settingKeyLanguage es la clave de política por escuela con el idioma de los carriles
+// LLM (es | pt | en; admite región, p.ej. pt-BR). Es común a revisión, preparación y
+// materiales.
+const settingKeyLanguage = "llm.language"
+
+// schoolLanguage resuelve el idioma de la escuela. Ausente o no soportado ⇒ español,
+// el idioma con el que nació el ecosistema.
+func schoolLanguage(s m2m.SchoolSettings) string {
+	return language.OrDefault(settingValueOr(s, settingKeyLanguage, language.Default))
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/EduGoGroup/edugo-shared/messaging/events"
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
@@ -30,7 +33,9 @@ func (m *mockSettingsReader) GetSettings(_ context.Context, _ string) (m2m.Schoo
 
 // mockLearningClient implementa LearningReviewClient y registra las llamadas.
 type mockLearningClient struct {
+	claimMu     sync.Mutex // las renovaciones del candado llegan desde otra goroutine
 	claimErr    error
+	renewErr    error // error de los Claim posteriores al primero (renovaciones)
 	claimCalls  int
 	releaseErr  error
 	releaseCall int
@@ -38,6 +43,7 @@ type mockLearningClient struct {
 	pending    m2m.PendingAnswersResponse
 	pendingErr error
 
+	reviewMu     sync.Mutex // las reviews de un attempt se escriben en paralelo
 	reviewErr    error
 	reviewCalls  []m2m.AnswerReviewRequest
 	reviewAnswer []string // answer_ids en orden
@@ -47,7 +53,12 @@ type mockLearningClient struct {
 }
 
 func (m *mockLearningClient) Claim(_ context.Context, _ string) error {
+	m.claimMu.Lock()
+	defer m.claimMu.Unlock()
 	m.claimCalls++
+	if m.claimCalls > 1 {
+		return m.renewErr
+	}
 	return m.claimErr
 }
 
@@ -64,6 +75,8 @@ func (m *mockLearningClient) GetPendingAnswers(_ context.Context, _ string) (m2m
 }
 
 func (m *mockLearningClient) PostAnswerReview(_ context.Context, _ string, answerID string, review m2m.AnswerReviewRequest) (m2m.AnswerReviewResponse, error) {
+	m.reviewMu.Lock()
+	defer m.reviewMu.Unlock()
 	m.reviewAnswer = append(m.reviewAnswer, answerID)
 	m.reviewCalls = append(m.reviewCalls, review)
 	if m.reviewErr != nil {
@@ -106,6 +119,9 @@ type mockLLMProvider struct {
 	extractIdeas []string
 	extractErr   error
 	extractCalls int
+
+	// confidence es la confianza que reporta ReviewAnswer (nil = sin señal).
+	confidence *float64
 }
 
 func (m *mockLLMProvider) GenerateAssessment(_ context.Context, _ llm.MaterialInput, _ llm.GenerationParams) (json.RawMessage, error) {
@@ -118,7 +134,7 @@ func (m *mockLLMProvider) ReviewAnswer(_ context.Context, req llm.ReviewRequest)
 	if m.failOnCall != 0 && m.calls == m.failOnCall {
 		return llm.ReviewResult{}, m.err
 	}
-	return llm.ReviewResult{Verdict: m.verdict, Score: m.score, Feedback: m.feedback}, nil
+	return llm.ReviewResult{Verdict: m.verdict, Score: m.score, Feedback: m.feedback, Confidence: m.confidence}, nil
 }
 
 func (m *mockLLMProvider) PrepareQuestion(_ context.Context, _ llm.PrepRequest) (json.RawMessage, error) {
@@ -919,3 +935,524 @@ func TestAttemptReviewProcessor_OpenEnded_SinPrep_FlujoGlobal(t *testing.T) {
 		t.Fatal("sin prep, req.Prep debe ser nil")
 	}
 }
+
+func TestAttemptReviewProcessor_IdiomaDeLaEscuela(t *testing.T) {
+	cases := []struct {
+		setting string
+		want    string
+	}{
+		{"", "es"},      // sin setting: default de plataforma
+		{"pt-BR", "pt"}, // la región se descarta
+		{"en", "en"},
+		{"fr", "es"}, // no soportado: default
+	}
+	for _, tc := range cases {
+		pairs := []string{settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowTeacher}
+		if tc.setting != "" {
+			pairs = append(pairs, settingKeyLanguage, tc.setting)
+		}
+		learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+			Answers: []m2m.PendingAnswer{pendingAnswer("a1", 10)},
+		}}
+		provider := &mockLLMProvider{score: 0.9, verdict: llm.VerdictCorrect}
+		p := newProcessor(&mockSettingsReader{settings: settingsWith(pairs...)}, learning, provider)
+
+		if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+			t.Fatalf("llm.language=%q: error inesperado: %v", tc.setting, err)
+		}
+		if got := provider.lastReviewReq.Language; got != tc.want {
+			t.Errorf("llm.language=%q: idioma pedido al LLM %q, esperaba %q", tc.setting, got, tc.want)
+		}
+	}
+}
+
+func TestAttemptReviewProcessor_Votacion(t *testing.T) {
+	cases := []struct {
+		samples   string
+		wantCalls int
+		voted     bool
+	}{
+		{"", 1, false},    // default: una muestra, sin ai_agreement
+		{"3", 3, true},    // votación
+		{"abc", 1, false}, // inválido: sin votación
+		{"50", maxReviewSamples, true},
+	}
+	for _, tc := range cases {
+		pairs := []string{settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowTeacher}
+		if tc.samples != "" {
+			pairs = append(pairs, settingKeyReviewSamples, tc.samples)
+		}
+		learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+			Answers: []m2m.PendingAnswer{pendingAnswer("a1", 10)},
+		}}
+		provider := &mockLLMProvider{score: 0.5, verdict: llm.VerdictPartial}
+		p := newProcessor(&mockSettingsReader{settings: settingsWith(pairs...)}, learning, provider)
+
+		if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+			t.Fatalf("samples=%q: error inesperado: %v", tc.samples, err)
+		}
+		if provider.calls != tc.wantCalls {
+			t.Errorf("samples=%q: %d llamadas a ReviewAnswer, esperaba %d", tc.samples, provider.calls, tc.wantCalls)
+		}
+		agreement := learning.reviewCalls[0].AIAgreement
+		if tc.voted != (agreement != nil) {
+			t.Fatalf("samples=%q: ai_agreement=%v, votada=%v", tc.samples, agreement, tc.voted)
+		}
+		if agreement != nil && *agreement != 1 {
+			t.Errorf("samples=%q: muestras unánimes, agreement %v", tc.samples, *agreement)
+		}
+	}
+}
+
+func TestAttemptReviewProcessor_BajaConfianzaNoFinaliza(t *testing.T) {
+	cases := []struct {
+		name         string
+		threshold    string
+		confidence   float64
+		noSignal     bool
+		wantFinalize bool
+	}{
+		{"bajo el umbral: queda para el profesor", "0.8", 0.6, false, false},
+		{"sobre el umbral: finaliza", "0.8", 0.9, false, true},
+		{"sin umbral: finaliza", "", 0.1, false, true},
+		{"umbral inválido: se ignora", "alto", 0.1, false, true},
+		{"umbral sin señal de confianza: queda para el profesor", "0.8", 0, true, false},
+		{"sin umbral ni señal: finaliza", "", 0, true, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pairs := []string{settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect}
+			if tc.threshold != "" {
+				pairs = append(pairs, settingKeyReviewMinConfidence, tc.threshold)
+			}
+			learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+				Answers:        []m2m.PendingAnswer{pendingAnswer("a1", 10), pendingAnswer("a2", 5)},
+				FlaggedAnswers: new(int),
+			}}
+			confidence := &tc.confidence
+			if tc.noSignal {
+				confidence = nil
+			}
+			provider := &mockLLMProvider{score: 0.5, verdict: llm.VerdictPartial, confidence: confidence}
+			pub := &mockEventPublisher{}
+			p := newProcessor(&mockSettingsReader{settings: settingsWith(pairs...)}, learning, provider).WithEventPublisher(pub)
+
+			if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := learning.finalizeCalls == 1; got != tc.wantFinalize {
+				t.Fatalf("finalize=%d, esperaba finalizar=%v", learning.finalizeCalls, tc.wantFinalize)
+			}
+			if !tc.wantFinalize && learning.releaseCall != 1 {
+				t.Errorf("sin finalize se libera el candado, hubo %d release", learning.releaseCall)
+			}
+			for _, review := range learning.reviewCalls {
+				if tc.noSignal != (review.AIConfidence == nil) || (!tc.noSignal && *review.AIConfidence != tc.confidence) {
+					t.Errorf("ai_confidence %v, esperaba %v (sin señal=%v)", review.AIConfidence, tc.confidence, tc.noSignal)
+				}
+				if review.NeedsTeacherReview == tc.wantFinalize {
+					t.Errorf("needs_teacher_review=%v con finalizar=%v", review.NeedsTeacherReview, tc.wantFinalize)
+				}
+			}
+			payload := pub.events[0].payload.(dto.AttemptAIReviewedPayload)
+			wantLow := 0
+			if !tc.wantFinalize {
+				wantLow = 2
+			}
+			if payload.LowConfidence != wantLow || payload.Finalized != tc.wantFinalize {
+				t.Errorf("payload inesperado: %+v", payload)
+			}
+		})
+	}
+}
+
+// Un redelivery tras un fallo a mitad ya no ve pendientes las respuestas marcadas por
+// la entrega anterior; learning las informa en flagged_answers y el intento no se
+// finaliza aunque lo revisado en esta entrega sea de alta confianza.
+func TestAttemptReviewProcessor_Redelivery_MarcadasAntesNoFinaliza(t *testing.T) {
+	cases := []struct {
+		name    string
+		answers []m2m.PendingAnswer
+	}{
+		{"sin pendientes", nil},
+		{"con pendientes de alta confianza", []m2m.PendingAnswer{pendingAnswer("a2", 5)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			flagged := 1
+			reader := &mockSettingsReader{settings: settingsWith(
+				settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect,
+				settingKeyReviewMinConfidence, "0.8")}
+			learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+				Answers:        tc.answers,
+				FlaggedAnswers: &flagged,
+			}}
+			confidence := 0.95
+			provider := &mockLLMProvider{score: 1, verdict: llm.VerdictCorrect, confidence: &confidence}
+			pub := &mockEventPublisher{}
+			p := newProcessor(reader, learning, provider).WithEventPublisher(pub)
+
+			if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if learning.finalizeCalls != 0 || learning.releaseCall != 1 {
+				t.Fatalf("finalize=%d release=%d, esperaba release-claim sin finalize",
+					learning.finalizeCalls, learning.releaseCall)
+			}
+			payload := pub.events[0].payload.(dto.AttemptAIReviewedPayload)
+			if payload.LowConfidence != 1 || payload.Finalized || payload.AnswersReviewed != len(tc.answers) {
+				t.Errorf("payload inesperado: %+v", payload)
+			}
+		})
+	}
+}
+
+// Un learning sin flagged_answers (contrato anterior) no permite saber si una entrega
+// anterior marcó respuestas: con umbral no se finaliza; sin umbral no hay marcadas
+// posibles y el intento se finaliza como siempre.
+func TestAttemptReviewProcessor_SinFlaggedAnswers(t *testing.T) {
+	cases := []struct {
+		name         string
+		threshold    string
+		answers      []m2m.PendingAnswer
+		wantFinalize bool
+	}{
+		{"con umbral y sin pendientes: queda para el profesor", "0.8", nil, false},
+		{"con umbral y pendientes de alta confianza: queda para el profesor", "0.8", []m2m.PendingAnswer{pendingAnswer("a1", 5)}, false},
+		{"sin umbral: finaliza", "", []m2m.PendingAnswer{pendingAnswer("a1", 5)}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pairs := []string{settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect}
+			if tc.threshold != "" {
+				pairs = append(pairs, settingKeyReviewMinConfidence, tc.threshold)
+			}
+			learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{Answers: tc.answers}}
+			confidence := 0.95
+			provider := &mockLLMProvider{score: 1, verdict: llm.VerdictCorrect, confidence: &confidence}
+			pub := &mockEventPublisher{}
+			p := newProcessor(&mockSettingsReader{settings: settingsWith(pairs...)}, learning, provider).WithEventPublisher(pub)
+
+			if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := learning.finalizeCalls == 1; got != tc.wantFinalize {
+				t.Fatalf("finalize=%d, esperaba finalizar=%v", learning.finalizeCalls, tc.wantFinalize)
+			}
+			if !tc.wantFinalize && learning.releaseCall != 1 {
+				t.Errorf("sin finalize se libera el candado, hubo %d release", learning.releaseCall)
+			}
+			if payload := pub.events[0].payload.(dto.AttemptAIReviewedPayload); payload.Finalized != tc.wantFinalize {
+				t.Errorf("payload inesperado: %+v", payload)
+			}
+		})
+	}
+}
+
+// --- evento de salida attempt.ai_reviewed ---
+
+func TestAttemptReviewProcessor_Direct_PublicaAIReviewedFinalizado(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect)}
+	learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+		Answers: []m2m.PendingAnswer{pendingAnswer("a1", 10), pendingAnswer("a2", 5)},
+	}}
+	provider := &mockLLMProvider{score: 0.5, feedback: "parcial", verdict: llm.VerdictPartial}
+	pub := &mockEventPublisher{}
+	p := newProcessor(reader, learning, provider).WithEventPublisher(pub)
+
+	if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+		t.Fatalf("flujo direct no debe fallar: %v", err)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("esperaba 1 evento publicado, hubo %d", len(pub.events))
+	}
+	got := pub.events[0]
+	if got.eventType != dto.EventTypeAttemptAIReviewed || got.correlationID != "evt-1" {
+		t.Fatalf("evento inesperado: %+v", got)
+	}
+	payload, ok := got.payload.(dto.AttemptAIReviewedPayload)
+	if !ok {
+		t.Fatalf("payload de tipo inesperado: %T", got.payload)
+	}
+	if payload.AttemptID != "attempt-1" || payload.SchoolID != "school-1" || payload.Provider != "mock" {
+		t.Fatalf("ids/provider inesperados: %+v", payload)
+	}
+	if payload.AnswersReviewed != 2 || payload.Partial != 2 || !payload.Finalized {
+		t.Fatalf("conteos inesperados: %+v", payload)
+	}
+}
+
+func TestAttemptReviewProcessor_Teacher_PublicaAIReviewedSinFinalizar(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowTeacher)}
+	learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+		Answers: []m2m.PendingAnswer{pendingAnswer("a1", 10)},
+	}}
+	pub := &mockEventPublisher{}
+	p := newProcessor(reader, learning, &mockLLMProvider{score: 1, verdict: llm.VerdictCorrect}).WithEventPublisher(pub)
+
+	if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+		t.Fatalf("flow teacher no debe fallar: %v", err)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("esperaba 1 evento publicado, hubo %d", len(pub.events))
+	}
+	payload := pub.events[0].payload.(dto.AttemptAIReviewedPayload)
+	if payload.Finalized || payload.Correct != 1 || payload.Flow != reviewFlowTeacher {
+		t.Fatalf("payload inesperado: %+v", payload)
+	}
+}
+
+func TestAttemptReviewProcessor_FinalizeFalla_NoPublica(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect)}
+	learning := &mockLearningClient{
+		pending:     m2m.PendingAnswersResponse{Answers: []m2m.PendingAnswer{pendingAnswer("a1", 10)}},
+		finalizeErr: errors.New("learning 503"),
+	}
+	pub := &mockEventPublisher{}
+	p := newProcessor(reader, learning, &mockLLMProvider{score: 1, verdict: llm.VerdictCorrect}).WithEventPublisher(pub)
+
+	if err := p.Process(context.Background(), validEventPayload(t)); err == nil {
+		t.Fatal("un finalize fallido debe subir como error")
+	}
+	if len(pub.events) != 0 {
+		t.Fatalf("sin finalize no debe publicarse attempt.ai_reviewed, hubo %d", len(pub.events))
+	}
+}
+
+func TestAttemptReviewProcessor_PublishFalla_NoTumbaElEvento(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowTeacher)}
+	learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{
+		Answers: []m2m.PendingAnswer{pendingAnswer("a1", 10)},
+	}}
+	pub := &mockEventPublisher{err: errors.New("channel closed")}
+	p := newProcessor(reader, learning, &mockLLMProvider{score: 1, verdict: llm.VerdictCorrect}).WithEventPublisher(pub)
+
+	if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+		t.Fatalf("un fallo del publish es best-effort y no debe subir: %v", err)
+	}
+}
+
+// --- corrección en paralelo dentro de un attempt ---
+
+// gatedLLMProvider retiene cada ReviewAnswer hasta que se cierra release (o se cancela
+// el ctx) y registra cuántas hubo en curso a la vez. failAnswer falla de inmediato la
+// respuesta del estudiante indicada.
+type gatedLLMProvider struct {
+	*mockLLMProvider
+	release    chan struct{}
+	failAnswer string
+	err        error
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	reviewCalls int
+}
+
+func (g *gatedLLMProvider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	g.mu.Lock()
+	g.reviewCalls++
+	g.inFlight++
+	g.maxInFlight = max(g.maxInFlight, g.inFlight)
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.inFlight--
+		g.mu.Unlock()
+	}()
+	if req.StudentAnswer == g.failAnswer {
+		return llm.ReviewResult{}, g.err
+	}
+	select {
+	case <-g.release:
+		return llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 1}, nil
+	case <-ctx.Done():
+		return llm.ReviewResult{}, ctx.Err()
+	}
+}
+
+func (g *gatedLLMProvider) waitInFlight(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		got := g.inFlight
+		g.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("esperaba %d correcciones en curso", n)
+}
+
+func (g *gatedLLMProvider) waitReviewCalls(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		got := g.reviewCalls
+		g.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("esperaba %d correcciones arrancadas", n)
+}
+
+func (m *mockLearningClient) waitClaimCalls(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.claimMu.Lock()
+		got := m.claimCalls
+		m.claimMu.Unlock()
+		if got >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("esperaba al menos %d claims (renovaciones incluidas)", n)
+}
+
+func answersFor(n int) []m2m.PendingAnswer {
+	answers := make([]m2m.PendingAnswer, n)
+	for i := range answers {
+		answers[i] = pendingAnswer(fmt.Sprintf("a%d", i+1), 10)
+		answers[i].StudentAnswer = fmt.Sprintf("respuesta %d", i+1)
+	}
+	return answers
+}
+
+func TestAttemptReviewProcessor_Concurrencia_AcotadaYFinalizaAlFinal(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect)}
+	learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{Answers: answersFor(6)}}
+	provider := &gatedLLMProvider{mockLLMProvider: &mockLLMProvider{}, release: make(chan struct{})}
+	pub := &mockEventPublisher{}
+	p := newProcessor(reader, learning, provider).WithEventPublisher(pub).WithReviewConcurrency(3)
+
+	done := make(chan error, 1)
+	go func() { done <- p.Process(context.Background(), validEventPayload(t)) }()
+
+	provider.waitInFlight(t, 3)
+	learning.reviewMu.Lock()
+	posted := len(learning.reviewCalls)
+	learning.reviewMu.Unlock()
+	if posted != 0 {
+		t.Fatalf("ninguna review debía escribirse aún, hubo %d", posted)
+	}
+	close(provider.release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if provider.maxInFlight != 3 {
+		t.Errorf("máximo en curso %d, esperaba 3", provider.maxInFlight)
+	}
+	if len(learning.reviewCalls) != 6 {
+		t.Errorf("esperaba 6 reviews escritas, hubo %d", len(learning.reviewCalls))
+	}
+	if learning.finalizeCalls != 1 || learning.releaseCall != 0 {
+		t.Errorf("finalize=%d release=%d, esperaba un finalize al cerrar el lote", learning.finalizeCalls, learning.releaseCall)
+	}
+	payload := pub.events[0].payload.(dto.AttemptAIReviewedPayload)
+	if payload.AnswersReviewed != 6 || !payload.Finalized {
+		t.Errorf("payload inesperado: %+v", payload)
+	}
+}
+
+func TestAttemptReviewProcessor_Concurrencia_FalloCancelaElResto(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect)}
+	learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{Answers: answersFor(5)}}
+	llmErr := errors.New("ollama timeout")
+	// a1 queda retenida; a2 falla: las que no arrancaron no arrancan, y a1 (en curso)
+	// termina y se escribe.
+	provider := &gatedLLMProvider{
+		mockLLMProvider: &mockLLMProvider{},
+		release:         make(chan struct{}),
+		failAnswer:      "respuesta 2",
+		err:             llmErr,
+	}
+	p := newProcessor(reader, learning, provider).WithReviewConcurrency(2)
+
+	done := make(chan error, 1)
+	go func() { done <- p.Process(context.Background(), validEventPayload(t)) }()
+
+	provider.waitReviewCalls(t, 2)
+	provider.waitInFlight(t, 1)
+	close(provider.release)
+
+	err := <-done
+	if !errors.Is(err, llmErr) {
+		t.Fatalf("esperaba el error del LLM, obtuve %v", err)
+	}
+	if classifyError(err) != ErrorTypeTransient {
+		t.Fatalf("fallo del LLM debe clasificar transitorio (retry seguro)")
+	}
+	if provider.reviewCalls != 2 {
+		t.Errorf("tras el fallo no arrancan más correcciones, hubo %d", provider.reviewCalls)
+	}
+	if len(learning.reviewAnswer) != 1 || learning.reviewAnswer[0] != "a1" {
+		t.Errorf("la corrección en curso debía terminar y escribirse, reviews=%v", learning.reviewAnswer)
+	}
+	if learning.finalizeCalls != 0 || learning.releaseCall != 0 {
+		t.Errorf("finalize=%d release=%d, esperaba el lote sin cerrar", learning.finalizeCalls, learning.releaseCall)
+	}
+}
+
+// Un intento largo renueva el candado mientras corrige y cierra el lote normalmente.
+func TestAttemptReviewProcessor_RenuevaElCandadoDuranteElLote(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect)}
+	learning := &mockLearningClient{pending: m2m.PendingAnswersResponse{Answers: answersFor(2)}}
+	provider := &gatedLLMProvider{mockLLMProvider: &mockLLMProvider{}, release: make(chan struct{})}
+	p := newProcessor(reader, learning, provider)
+	p.claimRenewEvery = time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- p.Process(context.Background(), validEventPayload(t)) }()
+
+	provider.waitInFlight(t, 1)
+	learning.waitClaimCalls(t, 3)
+	close(provider.release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(learning.reviewCalls) != 2 || learning.finalizeCalls != 1 {
+		t.Errorf("reviews=%d finalize=%d, esperaba el lote completo", len(learning.reviewCalls), learning.finalizeCalls)
+	}
+}
+
+// Si una renovación recibe 409 (el candado venció y lo tomó el profesor), se corta
+// también la corrección en curso: no se escribe nada más ni se cierra el lote.
+func TestAttemptReviewProcessor_CandadoPerdido_SeAbstiene(t *testing.T) {
+	reader := &mockSettingsReader{settings: settingsWith(
+		settingKeyReviewMode, reviewModeLocal, settingKeyReviewFlow, reviewFlowDirect)}
+	learning := &mockLearningClient{
+		pending:  m2m.PendingAnswersResponse{Answers: answersFor(3)},
+		renewErr: fmt.Errorf("%w: candado de otro", m2m.ErrClaimConflict),
+	}
+	provider := &gatedLLMProvider{mockLLMProvider: &mockLLMProvider{}, release: make(chan struct{})}
+	pub := &mockEventPublisher{}
+	p := newProcessor(reader, learning, provider).WithEventPublisher(pub)
+	p.claimRenewEvery = time.Millisecond
+
+	if err := p.Process(context.Background(), validEventPayload(t)); err != nil {
+		t.Fatalf("candado perdido no es un fallo (ACK), obtuve %v", err)
+	}
+	if provider.reviewCalls != 1 {
+		t.Errorf("tras perder el candado no arrancan más correcciones, hubo %d", provider.reviewCalls)
+	}
+	if len(learning.reviewCalls) != 0 || learning.finalizeCalls != 0 || learning.releaseCall != 0 || len(pub.events) != 0 {
+		t.Errorf("reviews=%d finalize=%d release=%d eventos=%d, esperaba nada escrito",
+			len(learning.reviewCalls), learning.finalizeCalls, learning.releaseCall, len(pub.events))
+	}
+}
@@ -0,0 +1,32 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+)
+
+// EventPublisher es la porción del publisher de salida que usan los processors para
+// anunciar el cierre de su carril (attempt.ai_reviewed, question.prep_saved,
+// material.assessment_delivered/failed). correlationID es el event_id del evento de
+// entrada. Se define como interfaz para mockearla en tests;
+// *publisher.EventPublisher la satisface.
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType, correlationID string, payload any) error
+}
+
+// noopEventPublisher es el publisher por defecto de los processors: sin publisher
+// cableado el carril se comporta como antes (termina en silencio).
+type noopEventPublisher struct{}
+
+func (noopEventPublisher) Publish(context.Context, string, string, any) error { return nil }
+
+// publishBestEffort publica un evento de cierre de carril de mejor esfuerzo: el
+// trabajo ya quedó persistido en learning, así que un fallo del publish NO se
+// propaga (reprocesar rehacería llamadas al LLM en vano); se loguea y se sigue.
+func publishBestEffort(ctx context.Context, pub EventPublisher, log logger.Logger, eventType, correlationID string, payload any) {
+	if err := pub.Publish(ctx, eventType, correlationID, payload); err != nil {
+		log.Warn("no se pudo publicar el evento de cierre de carril (best-effort, se ignora)",
+			"event_type", eventType, "correlation_id", correlationID, "error", err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// publishedEvent es una publicación capturada por mockEventPublisher.
+type publishedEvent struct {
+	eventType     string
+	correlationID string
+	payload       any
+}
+
+// mockEventPublisher implementa EventPublisher y registra lo publicado.
+type mockEventPublisher struct {
+	events []publishedEvent
+	err    error
+}
+
+func (m *mockEventPublisher) Publish(_ context.Context, eventType, correlationID string, payload any) error {
+	m.events = append(m.events, publishedEvent{eventType: eventType, correlationID: correlationID, payload: payload})
+	return m.err
+}
+
+func TestPublishBestEffort_ErrorNoSePropaga(t *testing.T) {
+	pub := &mockEventPublisher{err: errors.New("channel closed")}
+
+	// No debe entrar en pánico ni devolver nada: el fallo solo se loguea.
+	publishBestEffort(context.Background(), pub, newTestLogger(), "attempt.ai_reviewed", "evt-1", struct{}{})
+
+	if len(pub.events) != 1 {
+		t.Fatalf("esperaba 1 intento de publicación, hubo %d", len(pub.events))
+	}
+}
+
+func TestWithEventPublisher_NilConservaNoop(t *testing.T) {
+	p := newProcessor(&mockSettingsReader{}, &mockLearningClient{}, &mockLLMProvider{}).WithEventPublisher(nil)
+	if _, ok := p.publisher.(noopEventPublisher); !ok {
+		t.Fatalf("WithEventPublisher(nil) debe conservar el no-op, got %T", p.publisher)
+	}
+}
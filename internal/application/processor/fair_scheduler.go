@@ -0,0 +1,296 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+)
+
+// Modos de planificación del FairScheduler.
+const (
+	// FairModeRoundRobin alterna entre escuelas con el mismo peso.
+	FairModeRoundRobin = "round_robin"
+	// FairModeWeighted reparte con weighted fair queuing según el peso de cada
+	// escuela (setting SettingKeyFairShareWeight).
+	FairModeWeighted = "weighted"
+)
+
+// SettingKeyFairShareWeight es la clave de school settings con el peso de la escuela
+// en el reparto del worker (número > 0; default 1). Una escuela con peso 2 recibe el
+// doble de turnos que una con peso 1 mientras ambas tengan mensajes en espera.
+const SettingKeyFairShareWeight = "worker.fair_share.weight"
+
+// defaultFairWeightTTL es lo que dura por defecto el peso resuelto de una escuela.
+const defaultFairWeightTTL = time.Minute
+
+// FairSchedulerConfig parametriza un FairScheduler.
+type FairSchedulerConfig struct {
+	// Lane es el carril que planifica (label de métricas y logs).
+	Lane string
+	// Mode es FairModeRoundRobin o FairModeWeighted.
+	Mode string
+	// Concurrency son los mensajes del carril que se procesan a la vez. Debe ser
+	// menor que el prefetch del carril: la diferencia es el buffer que se reordena.
+	Concurrency int
+	// PerSchoolLimit acota los mensajes de una misma escuela en proceso a la vez.
+	PerSchoolLimit int
+	// WeightTTL es lo que dura el peso resuelto de una escuela antes de volver a leer
+	// sus settings (default 1m). El peso 1 por un fallo de lectura también dura
+	// WeightTTL: con M2M caído no se paga una llamada por entrega.
+	WeightTTL time.Duration
+}
+
+// FairScheduler reparte el procesamiento de un carril entre escuelas. Todas las
+// escuelas comparten la cola del carril y un único prefetch: sin reparto, una
+// escuela que sube 40 manuales acapara el carril durante horas.
+//
+// Se coloca delante de Registry.Process: cada entrega recibida espera turno en la
+// fila de su school_id (sin ACK, como el resto del prefetch) y el scheduler elige la
+// siguiente por weighted fair queuing (start-time fair queuing: cada mensaje avanza
+// el reloj virtual de su escuela en 1/peso y se despacha el de menor inicio
+// virtual), respetando Concurrency para el carril y PerSchoolLimit por escuela. Con
+// FairModeRoundRobin todas las escuelas pesan 1. Los mensajes sin school_id (o
+// inválidos, que el registry rechazará) comparten la fila "".
+type FairScheduler struct {
+	cfg      FairSchedulerConfig
+	settings SchoolSettingsReader
+	logger   logger.Logger
+
+	mu          sync.Mutex
+	schools     map[string]*fairSchool
+	running     int
+	buffered    int
+	virtualTime float64
+	seq         uint64
+
+	weightMu sync.Mutex
+	weights  map[string]fairWeight
+}
+
+// fairWeight es el peso cacheado de una escuela.
+type fairWeight struct {
+	value     float64
+	expiresAt time.Time
+}
+
+// fairSchool es la fila de una escuela: tickets en orden de llegada y la
+// finalización virtual del último encolado (inicio virtual del siguiente).
+type fairSchool struct {
+	queue      []*fairTicket
+	running    int
+	lastFinish float64
+}
+
+type fairTicket struct {
+	school  string
+	start   float64
+	seq     uint64
+	granted chan struct{}
+}
+
+// NewFairScheduler crea el scheduler de un carril. settings puede ser nil: todas
+// las escuelas pesan 1 (equivale a round robin).
+func NewFairScheduler(cfg FairSchedulerConfig, settings SchoolSettingsReader, log logger.Logger) *FairScheduler {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PerSchoolLimit <= 0 {
+		cfg.PerSchoolLimit = cfg.Concurrency
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = FairModeRoundRobin
+	}
+	if cfg.WeightTTL <= 0 {
+		cfg.WeightTTL = defaultFairWeightTTL
+	}
+	return &FairScheduler{
+		cfg:      cfg,
+		settings: settings,
+		logger:   log,
+		schools:  make(map[string]*fairSchool),
+		weights:  make(map[string]fairWeight),
+	}
+}
+
+// Wrap envuelve el handler del carril (Registry.Process) con la espera de turno.
+func (s *FairScheduler) Wrap(next func(ctx context.Context, body []byte) error) func(ctx context.Context, body []byte) error {
+	return func(ctx context.Context, body []byte) error {
+		var schoolID string
+		if msg, err := decodeMessage(body); err == nil {
+			schoolID = msg.SchoolID
+		}
+		if err := s.acquire(ctx, schoolID, s.weight(ctx, schoolID)); err != nil {
+			return err
+		}
+		defer s.release(schoolID)
+		return next(ctx, body)
+	}
+}
+
+// weight es el peso de la escuela, cacheado WeightTTL: una lectura de settings por
+// escuela y TTL, no una por entrega.
+func (s *FairScheduler) weight(ctx context.Context, schoolID string) float64 {
+	if s.cfg.Mode != FairModeWeighted || s.settings == nil || schoolID == "" {
+		return 1
+	}
+	now := time.Now()
+	s.weightMu.Lock()
+	cached, ok := s.weights[schoolID]
+	s.weightMu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.value
+	}
+
+	w := s.resolveWeight(ctx, schoolID)
+	s.weightMu.Lock()
+	defer s.weightMu.Unlock()
+	// Las vencidas se descartan al guardar: el cache no crece con cada escuela vista.
+	for id, entry := range s.weights {
+		if !now.Before(entry.expiresAt) {
+			delete(s.weights, id)
+		}
+	}
+	s.weights[schoolID] = fairWeight{value: w, expiresAt: now.Add(s.cfg.WeightTTL)}
+	return w
+}
+
+// resolveWeight lee el peso de los settings de la escuela. Cualquier fallo (M2M
+// caído, valor inválido) cae a 1: el reparto nunca bloquea el procesamiento.
+func (s *FairScheduler) resolveWeight(ctx context.Context, schoolID string) float64 {
+	settings, err := s.settings.GetSettings(ctx, schoolID)
+	if err != nil {
+		s.logger.Debug("peso de reparto no disponible, se usa 1",
+			"lane", s.cfg.Lane, "school_id", schoolID, "error", err.Error())
+		return 1
+	}
+	raw, ok := settings.Get(SettingKeyFairShareWeight)
+	if !ok {
+		return 1
+	}
+	w, err := strconv.ParseFloat(raw, 64)
+	if err != nil || w <= 0 {
+		s.logger.Warn("peso de reparto inválido, se usa 1",
+			"lane", s.cfg.Lane, "school_id", schoolID, "setting", SettingKeyFairShareWeight, "value", raw)
+		return 1
+	}
+	return w
+}
+
+// acquire encola un ticket para schoolID y espera a que el scheduler lo despache.
+// Si ctx se cancela antes (shutdown, carril detenido) retira el ticket y devuelve
+// el error: el consumer no ACKea y el mensaje vuelve a la cola.
+func (s *FairScheduler) acquire(ctx context.Context, schoolID string, weight float64) error {
+	waitStart := time.Now()
+
+	s.mu.Lock()
+	school, ok := s.schools[schoolID]
+	if !ok {
+		school = &fairSchool{}
+		s.schools[schoolID] = school
+	}
+	// Una escuela que estuvo inactiva no acumula crédito: arranca desde el tiempo
+	// virtual actual.
+	start := school.lastFinish
+	if s.virtualTime > start {
+		start = s.virtualTime
+	}
+	s.seq++
+	t := &fairTicket{school: schoolID, start: start, seq: s.seq, granted: make(chan struct{})}
+	school.lastFinish = start + 1/weight
+	school.queue = append(school.queue, t)
+	s.buffered++
+	s.dispatchLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-t.granted:
+		metrics.RecordFairSchedulerWait(s.cfg.Lane, time.Since(waitStart).Seconds())
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-t.granted:
+		// Despachado a la vez que se canceló: devolver el turno.
+		s.releaseLocked(schoolID)
+	default:
+		s.removeLocked(t)
+	}
+	return fmt.Errorf("carril %s: esperando turno de la escuela %q: %w", s.cfg.Lane, schoolID, ctx.Err())
+}
+
+func (s *FairScheduler) release(schoolID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(schoolID)
+}
+
+func (s *FairScheduler) releaseLocked(schoolID string) {
+	s.running--
+	s.schools[schoolID].running--
+	s.dispatchLocked()
+}
+
+// dispatchLocked despacha tickets mientras haya concurrencia libre: entre las
+// escuelas bajo su límite, el ticket de cabeza con menor inicio virtual (a
+// igualdad, el que llegó antes).
+func (s *FairScheduler) dispatchLocked() {
+	for s.running < s.cfg.Concurrency {
+		var next *fairTicket
+		for id, school := range s.schools {
+			if len(school.queue) == 0 {
+				s.forgetIfIdleLocked(id, school)
+				continue
+			}
+			if school.running >= s.cfg.PerSchoolLimit {
+				continue
+			}
+			head := school.queue[0]
+			if next == nil || head.start < next.start || (head.start == next.start && head.seq < next.seq) {
+				next = head
+			}
+		}
+		if next == nil {
+			break
+		}
+		school := s.schools[next.school]
+		school.queue = school.queue[1:]
+		school.running++
+		s.running++
+		s.buffered--
+		if next.start > s.virtualTime {
+			s.virtualTime = next.start
+		}
+		close(next.granted)
+	}
+	metrics.UpdateFairSchedulerBuffered(s.cfg.Lane, s.buffered)
+}
+
+func (s *FairScheduler) removeLocked(t *fairTicket) {
+	school := s.schools[t.school]
+	for i, queued := range school.queue {
+		if queued == t {
+			school.queue = append(school.queue[:i], school.queue[i+1:]...)
+			s.buffered--
+			break
+		}
+	}
+	s.forgetIfIdleLocked(t.school, school)
+	metrics.UpdateFairSchedulerBuffered(s.cfg.Lane, s.buffered)
+}
+
+// forgetIfIdleLocked descarta el estado de una escuela sin mensajes cuyo reloj ya
+// alcanzó el tiempo virtual (olvidarla antes le regalaría turnos): el mapa no crece
+// con cada escuela vista.
+func (s *FairScheduler) forgetIfIdleLocked(schoolID string, school *fairSchool) {
+	if len(school.queue) == 0 && school.running == 0 && school.lastFinish <= s.virtualTime {
+		delete(s.schools, schoolID)
+	}
+}
@@ -0,0 +1,272 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
+)
+
+// weightsReader devuelve el peso de reparto configurado por escuela.
+type weightsReader map[string]string
+
+func (w weightsReader) GetSettings(_ context.Context, schoolID string) (m2m.SchoolSettings, error) {
+	v, ok := w[schoolID]
+	if !ok {
+		return m2m.SchoolSettings{}, errors.New("academic no disponible")
+	}
+	return m2m.SchoolSettings{SchoolID: schoolID, Settings: []m2m.ResolvedSetting{
+		{Key: SettingKeyFairShareWeight, Value: v, Source: "school"},
+	}}, nil
+}
+
+func schoolBody(schoolID, id string) []byte {
+	return []byte(`{"event_type":"material.assessment_requested","event_id":"` + id +
+		`","payload":{"school_id":"` + schoolID + `","job_id":"` + id + `"}}`)
+}
+
+func bufferedCount(s *FairScheduler) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buffered
+}
+
+func runningCount(s *FairScheduler) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func waitCount(t *testing.T, what string, count func() int, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s = %d, want %d", what, count(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitBuffered(t *testing.T, s *FairScheduler, n int) {
+	t.Helper()
+	waitCount(t, "buffered", func() int { return bufferedCount(s) }, n)
+}
+
+func waitRunning(t *testing.T, s *FairScheduler, n int) {
+	t.Helper()
+	waitCount(t, "running", func() int { return runningCount(s) }, n)
+}
+
+// runOrder ocupa la única plaza del scheduler, encola los mensajes en el orden
+// dado (school:id) y devuelve el orden en que se procesan al liberar la plaza.
+func runOrder(t *testing.T, s *FairScheduler, msgs []string) []string {
+	t.Helper()
+	hold := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	handler := s.Wrap(func(_ context.Context, body []byte) error {
+		if strings.Contains(string(body), `"hold"`) {
+			<-hold
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, string(body))
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = handler(context.Background(), schoolBody("hold", "hold"))
+	}()
+	waitRunning(t, s, 1)
+
+	for i, m := range msgs {
+		school, id, _ := strings.Cut(m, ":")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = handler(context.Background(), schoolBody(school, id))
+		}()
+		waitBuffered(t, s, i+1)
+	}
+	close(hold)
+	wg.Wait()
+
+	out := make([]string, 0, len(order))
+	for _, body := range order {
+		msg, _ := decodeMessage([]byte(body))
+		out = append(out, msg.SchoolID+":"+msg.JobID)
+	}
+	return out
+}
+
+func TestFairScheduler_RoundRobinEntreEscuelas(t *testing.T) {
+	s := NewFairScheduler(FairSchedulerConfig{Lane: "material", Mode: FairModeRoundRobin, Concurrency: 1}, nil, newTestLogger())
+
+	got := runOrder(t, s, []string{"a:1", "a:2", "a:3", "a:4", "b:1", "c:1", "b:2"})
+
+	want := "[a:1 b:1 c:1 a:2 b:2 a:3 a:4]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("orden = %v, want %s: una escuela con muchos mensajes no acapara el carril", got, want)
+	}
+}
+
+func TestFairScheduler_PesosDeSchoolSettings(t *testing.T) {
+	settings := weightsReader{"a": "2", "b": "1"}
+	s := NewFairScheduler(FairSchedulerConfig{Lane: "material", Mode: FairModeWeighted, Concurrency: 1}, settings, newTestLogger())
+
+	got := runOrder(t, s, []string{"a:1", "a:2", "a:3", "a:4", "b:1", "b:2"})
+
+	want := "[a:1 b:1 a:2 a:3 b:2 a:4]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("orden = %v, want %s: peso 2 recibe el doble de turnos", got, want)
+	}
+}
+
+func TestFairScheduler_PesoInvalidoOAusenteVale1(t *testing.T) {
+	settings := weightsReader{"a": "no-es-numero"} // b: error de settings
+	s := NewFairScheduler(FairSchedulerConfig{Lane: "material", Mode: FairModeWeighted, Concurrency: 1}, settings, newTestLogger())
+
+	got := runOrder(t, s, []string{"a:1", "a:2", "b:1", "b:2"})
+
+	want := "[a:1 b:1 a:2 b:2]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("orden = %v, want %s", got, want)
+	}
+}
+
+// countingReader cuenta las lecturas de settings por escuela.
+type countingReader struct {
+	weightsReader
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *countingReader) GetSettings(ctx context.Context, schoolID string) (m2m.SchoolSettings, error) {
+	c.mu.Lock()
+	c.calls[schoolID]++
+	c.mu.Unlock()
+	return c.weightsReader.GetSettings(ctx, schoolID)
+}
+
+func (c *countingReader) count(schoolID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[schoolID]
+}
+
+func TestFairScheduler_PesoCacheadoPorTTL(t *testing.T) {
+	settings := &countingReader{weightsReader: weightsReader{"a": "2"}, calls: make(map[string]int)}
+	s := NewFairScheduler(FairSchedulerConfig{
+		Lane: "material", Mode: FairModeWeighted, Concurrency: 1, WeightTTL: 50 * time.Millisecond,
+	}, settings, newTestLogger())
+	handler := s.Wrap(func(context.Context, []byte) error { return nil })
+
+	for i := range 5 {
+		for _, school := range []string{"a", "b"} {
+			if err := handler(context.Background(), schoolBody(school, fmt.Sprint(i))); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+		}
+	}
+	if got := settings.count("a"); got != 1 {
+		t.Errorf("lecturas de settings de a = %d, want 1 por TTL", got)
+	}
+	if got := settings.count("b"); got != 1 {
+		t.Errorf("lecturas de settings de b = %d, want 1: un fallo también se cachea", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := handler(context.Background(), schoolBody("a", "x")); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if got := settings.count("a"); got != 2 {
+		t.Errorf("lecturas de settings de a = %d, want 2 tras vencer el TTL", got)
+	}
+	s.weightMu.Lock()
+	defer s.weightMu.Unlock()
+	if _, ok := s.weights["b"]; ok {
+		t.Error("el peso vencido de b debía descartarse")
+	}
+}
+
+func TestFairScheduler_LimitePorEscuela(t *testing.T) {
+	s := NewFairScheduler(FairSchedulerConfig{Lane: "review", Concurrency: 3, PerSchoolLimit: 1}, nil, newTestLogger())
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	handler := s.Wrap(func(context.Context, []byte) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = handler(context.Background(), schoolBody("a", fmt.Sprint(i)))
+		}()
+	}
+	waitBuffered(t, s, 2)
+	close(release)
+	wg.Wait()
+
+	if maxRunning != 1 {
+		t.Errorf("máximo en proceso de una escuela = %d, want 1 (PerSchoolLimit)", maxRunning)
+	}
+}
+
+func TestFairScheduler_CancelacionRetiraElTicket(t *testing.T) {
+	s := NewFairScheduler(FairSchedulerConfig{Lane: "review", Concurrency: 1}, nil, newTestLogger())
+	hold := make(chan struct{})
+	handler := s.Wrap(func(context.Context, []byte) error {
+		<-hold
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = handler(context.Background(), schoolBody("a", "1"))
+	}()
+	waitRunning(t, s, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- handler(ctx, schoolBody("b", "1")) }()
+	waitBuffered(t, s, 1)
+	cancel()
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled (el mensaje vuelve a la cola)", err)
+	}
+	if n := bufferedCount(s); n != 0 {
+		t.Errorf("buffered = %d tras cancelar, want 0", n)
+	}
+
+	close(hold)
+	<-done
+	if err := handler(context.Background(), schoolBody("c", "1")); err != nil {
+		t.Errorf("el scheduler debe seguir despachando tras una cancelación: %v", err)
+	}
+}
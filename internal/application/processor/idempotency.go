@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/idempotency"
+)
+
+// IdempotencyStore es el store de resultados por event_id que consulta el registry
+// antes de procesar. idempotency.MemoryStore (LRU) e idempotency.FileStore lo
+// satisfacen.
+type IdempotencyStore interface {
+	Get(ctx context.Context, eventID string) (idempotency.Record, bool, error)
+	Put(ctx context.Context, rec idempotency.Record) error
+}
+
+// IdempotencyMiddleware corto-circuita los redeliveries de eventos ya completados:
+// si el event_id del envelope tiene resultado completed, ACKea sin llamar al
+// processor (ahorra los round-trips M2M y las llamadas al LLM con las que cada
+// carril re-deriva su idempotencia de learning). Registra el resultado de cada
+// ejecución; un failed NO corto-circuita, el evento sigue siendo reintentable.
+//
+// Es de mejor esfuerzo: un mensaje sin event_id no se deduplica, y un fallo del
+// store se loguea y el evento se procesa normalmente (la idempotencia de learning
+// sigue siendo la garantía de fondo).
+func IdempotencyMiddleware(store IdempotencyStore, log logger.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			if msg.EventID == "" {
+				return next(ctx, msg)
+			}
+
+			rec, ok, err := store.Get(ctx, msg.EventID)
+			if err != nil {
+				log.Warn("store de idempotencia inaccesible, se procesa igual",
+					append(msg.logFields(), "error", err.Error())...)
+			} else if ok && rec.Outcome == idempotency.OutcomeCompleted {
+				log.Info("evento ya completado (redelivery), se ACKea sin reprocesar",
+					append(msg.logFields(), "completed_at", rec.RecordedAt)...)
+				return nil
+			}
+
+			procErr := next(ctx, msg)
+
+			outcome := idempotency.OutcomeCompleted
+			if procErr != nil {
+				outcome = idempotency.OutcomeFailed
+			}
+			if err := store.Put(ctx, idempotency.Record{
+				EventID:   msg.EventID,
+				EventType: msg.EventType,
+				Outcome:   outcome,
+			}); err != nil {
+				log.Warn("no se pudo registrar el resultado en el store de idempotencia",
+					append(msg.logFields(), "outcome", string(outcome), "error", err.Error())...)
+			}
+			return procErr
+		}
+	}
+}
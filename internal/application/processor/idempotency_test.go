@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/idempotency"
+)
+
+// failingIdempotencyStore simula un store caído.
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Get(context.Context, string) (idempotency.Record, bool, error) {
+	return idempotency.Record{}, false, errors.New("disco lleno")
+}
+func (failingIdempotencyStore) Put(context.Context, idempotency.Record) error {
+	return errors.New("disco lleno")
+}
+
+func newIdempotentRegistry(store IdempotencyStore, p Processor) *Registry {
+	registry := NewRegistry(newTestLogger())
+	registry.Register(p)
+	registry.Use(IdempotencyMiddleware(store, newTestLogger()))
+	return registry
+}
+
+func TestIdempotency_CompletadoSeACKeaSinReprocesar(t *testing.T) {
+	calls := 0
+	p := &funcProcessor{eventType: "test_event", fn: func(context.Context, []byte) error {
+		calls++
+		return nil
+	}}
+	registry := newIdempotentRegistry(idempotency.NewMemoryStore(10, 0), p)
+	payload := []byte(`{"event_id":"evt-1","event_type":"test_event"}`)
+
+	for i := 0; i < 2; i++ {
+		if err := registry.Process(context.Background(), payload); err != nil {
+			t.Fatalf("entrega %d: unexpected error: %v", i+1, err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("el redelivery de un evento completado no debe reprocesarse; calls=%d", calls)
+	}
+}
+
+func TestIdempotency_FallidoSigueReintentable(t *testing.T) {
+	calls := 0
+	p := &funcProcessor{eventType: "test_event", fn: func(context.Context, []byte) error {
+		calls++
+		if calls == 1 {
+			return errors.New("learning 503")
+		}
+		return nil
+	}}
+	store := idempotency.NewMemoryStore(10, 0)
+	registry := newIdempotentRegistry(store, p)
+	payload := []byte(`{"event_id":"evt-1","event_type":"test_event"}`)
+
+	if err := registry.Process(context.Background(), payload); err == nil {
+		t.Fatal("la primera entrega debía fallar")
+	}
+	rec, ok, _ := store.Get(context.Background(), "evt-1")
+	if !ok || rec.Outcome != idempotency.OutcomeFailed {
+		t.Fatalf("debe registrarse el resultado failed, got %+v ok=%v", rec, ok)
+	}
+
+	if err := registry.Process(context.Background(), payload); err != nil {
+		t.Fatalf("el reintento debe procesarse: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("un failed no debe corto-circuitar; calls=%d", calls)
+	}
+}
+
+func TestIdempotency_SinEventIDNoDeduplica(t *testing.T) {
+	calls := 0
+	p := &funcProcessor{eventType: "test_event", fn: func(context.Context, []byte) error {
+		calls++
+		return nil
+	}}
+	store := idempotency.NewMemoryStore(10, 0)
+	registry := newIdempotentRegistry(store, p)
+
+	for i := 0; i < 2; i++ {
+		_ = registry.Process(context.Background(), []byte(`{"event_type":"test_event"}`))
+	}
+
+	if calls != 2 || store.Len() != 0 {
+		t.Errorf("sin event_id no se deduplica ni se registra; calls=%d len=%d", calls, store.Len())
+	}
+}
+
+func TestIdempotency_StoreCaidoProcesaIgual(t *testing.T) {
+	mock := &mockProcessor{eventType: "test_event"}
+	registry := newIdempotentRegistry(failingIdempotencyStore{}, mock)
+
+	if err := registry.Process(context.Background(), []byte(`{"event_id":"evt-1","event_type":"test_event"}`)); err != nil {
+		t.Fatalf("un store caído no debe tumbar el evento: %v", err)
+	}
+	if !mock.processCalled {
+		t.Error("el processor debe ejecutarse aunque el store falle")
+	}
+}
@@ -9,8 +9,10 @@ import (
 
 	"github.com/EduGoGroup/edugo-shared/logger"
 	"github.com/EduGoGroup/edugo-shared/messaging/events"
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
 	"github.com/EduGoGroup/edugo-worker/internal/chunking"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline/reduce"
@@ -29,10 +31,6 @@ const (
 	pipelineModeOn  = "on"  // riel encendido para la escuela
 )
 
-// materialLanguage es el idioma que se pide al LLM en ambas llamadas del pipeline
-// (regla global del ecosistema: español).
-const materialLanguage = "es"
-
 // maxSummaryWords es el techo de palabras del summary encadenable de la llamada A
 // (D-043.7): escrito para otro modelo, mínimo en tokens. Un summary más largo se trata
 // como salida no válida del LLM (transitorio), nunca se persiste.
@@ -151,14 +149,29 @@ type MaterialLLMProvider interface {
 // (compuesta, no reimplementada) y el loop de fase 1 (LLM local): por cada chunk
 // pendiente lee (DigestChunk), valida artefactos y summary, propone candidatas
 // (ProposeCandidates), descarta las inválidas y persiste el resto. Idempotente y
-// reanudable: los 409 del carril son guardas de estado, no fallos.
+// reanudable: los 409 del carril son guardas de estado, no fallos. Publica
+// material.assessment_delivered al entregar el draft y material.assessment_failed al
+// marcar el job failed (si hay publisher).
 type MaterialPipelineProcessor struct {
-	settings SchoolSettingsReader
-	pipeline MaterialPipelineClient
-	provider MaterialLLMProvider
-	phase0   *MaterialPipelinePhase0
-	reduce   ReduceDeps
-	logger   logger.Logger
+	settings  SchoolSettingsReader
+	pipeline  MaterialPipelineClient
+	provider  MaterialLLMProvider
+	phase0    *MaterialPipelinePhase0
+	reduce    ReduceDeps
+	publisher EventPublisher
+	logger    logger.Logger
+}
+
+// materialJobRef identifica el job del evento en curso: los ids que viajan en los
+// eventos de salida del carril y el event_id de entrada como correlation_id.
+type materialJobRef struct {
+	JobID         string
+	MaterialID    string
+	SchoolID      string
+	CorrelationID string
+	// Language es el idioma de la escuela (setting llm.language): el fallback de la
+	// detección por chunk.
+	Language string
 }
 
 // NewMaterialPipelineProcessor construye el processor y COMPONE la fase 0 con las
@@ -181,13 +194,22 @@ func NewMaterialPipelineProcessor(
 	// es su superconjunto, así que el mismo cliente satisface ambas.
 	phase0 := NewMaterialPipelinePhase0(pipeline, download, extractor, chunkCfg, maxDownloadBytes, log)
 	return &MaterialPipelineProcessor{
-		settings: settings,
-		pipeline: pipeline,
-		provider: provider,
-		phase0:   phase0,
-		reduce:   reduceDeps,
-		logger:   log,
+		settings:  settings,
+		pipeline:  pipeline,
+		provider:  provider,
+		phase0:    phase0,
+		reduce:    reduceDeps,
+		publisher: noopEventPublisher{},
+		logger:    log,
+	}
+}
+
+// WithEventPublisher cablea el publisher de eventos de salida. nil deja el no-op.
+func (p *MaterialPipelineProcessor) WithEventPublisher(pub EventPublisher) *MaterialPipelineProcessor {
+	if pub != nil {
+		p.publisher = pub
 	}
+	return p
 }
 
 // EventType satisface processor.Processor.
@@ -224,7 +246,17 @@ func (p *MaterialPipelineProcessor) Process(ctx context.Context, payload []byte)
 		return nil
 	}
 
-	return p.orchestrate(ctx, jobID)
+	// Todas las llamadas LLM del job van al mismo host del pool local: el modelo y el
+	// prefijo del prompt ya están cargados ahí. La escuela fija la versión de los
+	// prompts del pipeline.
+	ctx = llm.WithSchool(llm.WithAffinity(ctx, jobID), schoolID)
+	return p.orchestrate(ctx, materialJobRef{
+		JobID:         jobID,
+		MaterialID:    evt.Payload.MaterialID,
+		SchoolID:      schoolID,
+		CorrelationID: evt.EventID,
+		Language:      schoolLanguage(settings),
+	})
 }
 
 // orchestrate ejecuta el pipeline para un job cuya escuela tiene el riel encendido:
@@ -239,7 +271,8 @@ func (p *MaterialPipelineProcessor) Process(ctx context.Context, payload []byte)
 // Un error permanente marca el job failed (best-effort) antes de subir para que caiga al
 // DLQ con rastro; uno transitorio se deja subir intacto (el redelivery reanuda por status
 // sin marcar nada: las pasadas del reduce saltan lo terminal).
-func (p *MaterialPipelineProcessor) orchestrate(ctx context.Context, jobID string) error {
+func (p *MaterialPipelineProcessor) orchestrate(ctx context.Context, ref materialJobRef) error {
+	jobID := ref.JobID
 	job, err := p.pipeline.GetJob(ctx, jobID)
 	if err != nil {
 		// 404 = job borrado: permanente (→ DLQ). No se marca failed (no hay job que marcar).
@@ -261,22 +294,22 @@ func (p *MaterialPipelineProcessor) orchestrate(ctx context.Context, jobID strin
 		}
 		// done de fase 1: reanudar directo en la fase 2 (reduce), sin repetir fase 0/1.
 		p.logger.Info("job done de fase 1, se reanuda en la fase 2 (reduce)", "job_id", jobID)
-		return p.runPhase2(ctx, jobID)
+		return p.runPhase2(ctx, ref)
 	}
 
 	// Fase 0 (determinista, sin LLM): idempotente y reanudable; se salta sola si el job
 	// ya está porcionado. Sus errores permanentes (sentinels de PDF) marcan failed.
 	if err := p.phase0.Run(ctx, jobID); err != nil {
-		return p.failIfPermanent(ctx, jobID, 0, err)
+		return p.failIfPermanent(ctx, ref, 0, err)
 	}
 
 	// Fase 1 (LLM local): loop de chunks pendientes. Cierra el job en done/phase1.
-	if err := p.runPhase1(ctx, jobID); err != nil {
+	if err := p.runPhase1(ctx, ref); err != nil {
 		return err
 	}
 
 	// Fase 2 (reduce): encadenada tras la fase 1 recién completa.
-	return p.runPhase2(ctx, jobID)
+	return p.runPhase2(ctx, ref)
 }
 
 // phase2Delivered indica si un job en `done` ya pasó la fase 2 (draft entregado): la
@@ -292,14 +325,16 @@ func phase2Delivered(job *m2m.PipelineJob) bool {
 // RE-INVOCABLE por status sin mecanismo nuevo: cada pasada salta lo terminal, así que un
 // fallo a mitad no corrompe —el redelivery reanuda donde quedó—. Contrato de errores
 // idéntico a la fase 1: un permanente marca el job failed (best-effort) antes de subir al
-// DLQ; un transitorio sube intacto para que el evento se reintente.
-func (p *MaterialPipelineProcessor) runPhase2(ctx context.Context, jobID string) error {
+// DLQ; un transitorio sube intacto para que el evento se reintente. Tras la entrega
+// publica material.assessment_delivered (best-effort).
+func (p *MaterialPipelineProcessor) runPhase2(ctx context.Context, ref materialJobRef) error {
 	const phase = int16(2)
+	jobID := ref.JobID
 
 	// Pasada 1 — dedupe (letras → significado → LLM residual, D-044.2).
 	dedupeRep, err := p.reduce.Dedupe.Run(ctx, jobID)
 	if err != nil {
-		return p.failIfPermanent(ctx, jobID, phase, fmt.Errorf("reduce: dedupe del job %s: %w", jobID, err))
+		return p.failIfPermanent(ctx, ref, phase, fmt.Errorf("reduce: dedupe del job %s: %w", jobID, err))
 	}
 	p.logger.Info("fase 2 · pasada 1 (dedupe) completa",
 		"job_id", jobID, "candidatas", dedupeRep.Candidates, "procesadas", dedupeRep.Processed,
@@ -308,7 +343,7 @@ func (p *MaterialPipelineProcessor) runPhase2(ctx context.Context, jobID string)
 	// Pasada 2 — relevancia (LLM, una candidata por llamada, D-044.3).
 	relRep, err := p.reduce.Relevance.Run(ctx, jobID)
 	if err != nil {
-		return p.failIfPermanent(ctx, jobID, phase, fmt.Errorf("reduce: relevancia del job %s: %w", jobID, err))
+		return p.failIfPermanent(ctx, ref, phase, fmt.Errorf("reduce: relevancia del job %s: %w", jobID, err))
 	}
 	p.logger.Info("fase 2 · pasada 2 (relevancia) completa",
 		"job_id", jobID, "puntuadas", relRep.Scored, "dropped_irrelevant", relRep.DroppedIrrelevant,
@@ -317,7 +352,7 @@ func (p *MaterialPipelineProcessor) runPhase2(ctx context.Context, jobID string)
 	// Pasada 3 — calidad (determinista Go, gratis, D-044.3).
 	qualRep, err := p.reduce.Quality.Run(ctx, jobID)
 	if err != nil {
-		return p.failIfPermanent(ctx, jobID, phase, fmt.Errorf("reduce: calidad del job %s: %w", jobID, err))
+		return p.failIfPermanent(ctx, ref, phase, fmt.Errorf("reduce: calidad del job %s: %w", jobID, err))
 	}
 	p.logger.Info("fase 2 · pasada 3 (calidad) completa",
 		"job_id", jobID, "validas", qualRep.Valid, "dropped_invalid", qualRep.DroppedInvalid)
@@ -327,7 +362,7 @@ func (p *MaterialPipelineProcessor) runPhase2(ctx context.Context, jobID string)
 	target := p.reduce.TargetQuestionsDefault
 	selRep, err := p.reduce.Selection.Run(ctx, jobID, target)
 	if err != nil {
-		return p.failIfPermanent(ctx, jobID, phase, fmt.Errorf("reduce: selección del job %s: %w", jobID, err))
+		return p.failIfPermanent(ctx, ref, phase, fmt.Errorf("reduce: selección del job %s: %w", jobID, err))
 	}
 	p.logger.Info("fase 2 · pasada 4 (selección) completa",
 		"job_id", jobID, "seleccionadas", selRep.Selected, "target", target,
@@ -337,16 +372,27 @@ func (p *MaterialPipelineProcessor) runPhase2(ctx context.Context, jobID string)
 	// fase 2 server-side (assessment_id + done/phase2). 422 (sin selected) → permanente.
 	assessmentID, questions, err := p.pipeline.DeliverJob(ctx, jobID)
 	if err != nil {
-		return p.failIfPermanent(ctx, jobID, phase, fmt.Errorf("reduce: entrega del job %s: %w", jobID, err))
+		return p.failIfPermanent(ctx, ref, phase, fmt.Errorf("reduce: entrega del job %s: %w", jobID, err))
 	}
 	p.logger.Info("fase 2 completa: draft entregado (assessment creado, job en done fase 2)",
 		"job_id", jobID, "assessment_id", assessmentID, "preguntas", questions, "seleccionadas", selRep.Selected)
+	publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeMaterialAssessmentDelivered, ref.CorrelationID, dto.MaterialAssessmentDeliveredPayload{
+		JobID:        jobID,
+		MaterialID:   ref.MaterialID,
+		SchoolID:     ref.SchoolID,
+		AssessmentID: assessmentID,
+		Provider:     p.provider.Name(),
+		Candidates:   dedupeRep.Candidates,
+		Selected:     selRep.Selected,
+		Questions:    questions,
+	})
 	return nil
 }
 
 // runPhase1 recorre los chunks pendientes hasta agotarlos y cierra el job en done.
 // Respeta la cancelación del contexto entre chunks (shutdown ordenado).
-func (p *MaterialPipelineProcessor) runPhase1(ctx context.Context, jobID string) error {
+func (p *MaterialPipelineProcessor) runPhase1(ctx context.Context, ref materialJobRef) error {
+	jobID := ref.JobID
 	for {
 		if err := ctx.Err(); err != nil {
 			return err
@@ -354,7 +400,7 @@ func (p *MaterialPipelineProcessor) runPhase1(ctx context.Context, jobID string)
 
 		next, err := p.pipeline.GetNextPendingChunk(ctx, jobID)
 		if err != nil {
-			return p.failIfPermanent(ctx, jobID, 1, fmt.Errorf("leyendo el siguiente chunk pendiente del job %s: %w", jobID, err))
+			return p.failIfPermanent(ctx, ref, 1, fmt.Errorf("leyendo el siguiente chunk pendiente del job %s: %w", jobID, err))
 		}
 		if next == nil {
 			// No quedan pendientes: cerrar el job. 409 = otro worker ya lo cerró (ACK).
@@ -363,14 +409,14 @@ func (p *MaterialPipelineProcessor) runPhase1(ctx context.Context, jobID string)
 					p.logger.Info("el job ya estaba cerrado por otro worker (409 en el PATCH done), ACK", "job_id", jobID)
 					return nil
 				}
-				return p.failIfPermanent(ctx, jobID, 1, fmt.Errorf("cerrando el job %s (done): %w", jobID, err))
+				return p.failIfPermanent(ctx, ref, 1, fmt.Errorf("cerrando el job %s (done): %w", jobID, err))
 			}
 			p.logger.Info("fase 1 completa: job en done (todas las porciones procesadas)", "job_id", jobID)
 			return nil
 		}
 
-		if err := p.processChunk(ctx, jobID, next); err != nil {
-			return p.failIfPermanent(ctx, jobID, 1, err)
+		if err := p.processChunk(ctx, jobID, ref.Language, next); err != nil {
+			return p.failIfPermanent(ctx, ref, 1, err)
 		}
 	}
 }
@@ -386,9 +432,16 @@ func (p *MaterialPipelineProcessor) runPhase1(ctx context.Context, jobID string)
 //   - Un 409 en el PUT (chunk ya cerrado por redelivery) también devuelve nil (continuar).
 //
 // Jamás se persiste una salida malformada del LLM (envenenaría la generación posterior).
-func (p *MaterialPipelineProcessor) processChunk(ctx context.Context, jobID string, chunk *m2m.NextChunk) error {
+//
+// El idioma de ambas llamadas se detecta del texto del chunk, con el de la escuela
+// (schoolLang) como fallback: un material en inglés de una escuela hispana produce
+// ideas y preguntas en inglés. Se decide por chunk y no por job para que la
+// reanudación no dependa de estado previo.
+func (p *MaterialPipelineProcessor) processChunk(ctx context.Context, jobID, schoolLang string, chunk *m2m.NextChunk) error {
+	lang := language.Detect(chunk.ChunkText, schoolLang)
+
 	// A ("leer") con reintento por calidad y aislamiento del envenenado.
-	digest, artifactsJSON, err := p.digestWithQualityRetry(ctx, jobID, chunk)
+	digest, artifactsJSON, err := p.digestWithQualityRetry(ctx, jobID, lang, chunk)
 	if err != nil {
 		return err
 	}
@@ -400,7 +453,7 @@ func (p *MaterialPipelineProcessor) processChunk(ctx context.Context, jobID stri
 	// B ("preguntar"): trabaja SOLO con los artefactos (nunca el texto crudo), con
 	// reintento por calidad. Un fallo de CALIDAD persistente NO tumba el evento: se
 	// persiste sin candidatas (el digest ES válido); un fallo de INFRA sube transitorio.
-	valid, err := p.proposeWithQualityRetry(ctx, jobID, chunk, digest.Artifacts)
+	valid, err := p.proposeWithQualityRetry(ctx, jobID, lang, chunk, digest.Artifacts)
 	if err != nil {
 		return err
 	}
@@ -432,7 +485,7 @@ func (p *MaterialPipelineProcessor) processChunk(ctx context.Context, jobID stri
 // evento). El caso "la llamada respondió pero ninguna candidata cumple el contrato" NO es
 // fallo: se filtra y se devuelve la lista (posiblemente vacía) sin reintentar (sesgo
 // multiple_select conocido, D-043.7).
-func (p *MaterialPipelineProcessor) proposeWithQualityRetry(ctx context.Context, jobID string, chunk *m2m.NextChunk, artifacts materialpipeline.ChunkArtifactsV1) ([]m2m.CandidatePayload, error) {
+func (p *MaterialPipelineProcessor) proposeWithQualityRetry(ctx context.Context, jobID, lang string, chunk *m2m.NextChunk, artifacts materialpipeline.ChunkArtifactsV1) ([]m2m.CandidatePayload, error) {
 	var lastQualityErr error
 	for attempt := 0; attempt <= llmQualityRetries; attempt++ {
 		var tempOverride *float64
@@ -446,7 +499,7 @@ func (p *MaterialPipelineProcessor) proposeWithQualityRetry(ctx context.Context,
 
 		candidates, err := p.provider.ProposeCandidates(ctx, llm.ProposeCandidatesInput{
 			Artifacts:   artifacts,
-			Language:    materialLanguage,
+			Language:    lang,
 			Temperature: tempOverride,
 		})
 		if err != nil {
@@ -482,7 +535,7 @@ func (p *MaterialPipelineProcessor) proposeWithQualityRetry(ctx context.Context,
 //   - (digest≠nil, artifactsJSON, nil) → éxito: procesar la fase B.
 //   - (nil, nil, nil) → chunk aislado (o carrera 409 al aislarlo): continuar SIN fase B.
 //   - (nil, nil, err) → fallo de INFRA (del digest o al marcar failed): transitorio, sube.
-func (p *MaterialPipelineProcessor) digestWithQualityRetry(ctx context.Context, jobID string, chunk *m2m.NextChunk) (*llm.DigestChunkResult, json.RawMessage, error) {
+func (p *MaterialPipelineProcessor) digestWithQualityRetry(ctx context.Context, jobID, lang string, chunk *m2m.NextChunk) (*llm.DigestChunkResult, json.RawMessage, error) {
 	var lastQualityErr error
 	for attempt := 0; attempt <= llmQualityRetries; attempt++ {
 		var tempOverride *float64
@@ -494,7 +547,7 @@ func (p *MaterialPipelineProcessor) digestWithQualityRetry(ctx context.Context,
 				"intento", attempt+1, "temp_retry", llmRetryTemperature, "motivo", lastQualityErr.Error())
 		}
 
-		digest, artifactsJSON, err := p.attemptDigest(ctx, jobID, chunk, tempOverride)
+		digest, artifactsJSON, err := p.attemptDigest(ctx, jobID, lang, chunk, tempOverride)
 		if err == nil {
 			return digest, artifactsJSON, nil
 		}
@@ -519,11 +572,11 @@ func (p *MaterialPipelineProcessor) digestWithQualityRetry(ctx context.Context,
 // artefactos que no cumplen el contrato tras normalizar o summary inválido vía
 // ErrInvalidChunkArtifacts) permiten distinguirlos de los de INFRA, que suben sin
 // sentinel. tempOverride, si != nil, fuerza la temperatura solo en esta pasada.
-func (p *MaterialPipelineProcessor) attemptDigest(ctx context.Context, jobID string, chunk *m2m.NextChunk, tempOverride *float64) (*llm.DigestChunkResult, json.RawMessage, error) {
+func (p *MaterialPipelineProcessor) attemptDigest(ctx context.Context, jobID, lang string, chunk *m2m.NextChunk, tempOverride *float64) (*llm.DigestChunkResult, json.RawMessage, error) {
 	digest, err := p.provider.DigestChunk(ctx, llm.DigestChunkInput{
 		ChunkText:   chunk.ChunkText,
 		PrevSummary: chunk.PrevSummary,
-		Language:    materialLanguage,
+		Language:    lang,
 		Temperature: tempOverride,
 	})
 	if err != nil {
@@ -609,11 +662,13 @@ func (p *MaterialPipelineProcessor) filterValidCandidates(jobID string, chunk *m
 // para que el mensaje caiga al DLQ con rastro del último error. Ignora el error del PATCH
 // (best-effort): si falla, el redelivery/DLQ nativo sigue operando. Devuelve el error
 // original intacto para que retry.go lo clasifique. Los transitorios NO marcan failed:
-// el redelivery reanuda el job donde quedó.
-func (p *MaterialPipelineProcessor) failIfPermanent(ctx context.Context, jobID string, phase int16, err error) error {
+// el redelivery reanuda el job donde quedó. Un permanente publica además
+// material.assessment_failed (best-effort), se haya podido marcar el job o no.
+func (p *MaterialPipelineProcessor) failIfPermanent(ctx context.Context, ref materialJobRef, phase int16, err error) error {
 	if classifyError(err) != ErrorTypePermanent {
 		return err
 	}
+	jobID := ref.JobID
 	msg := err.Error()
 	if perr := p.pipeline.UpdateJobStatus(ctx, jobID, jobStatusFailed, phase, &msg); perr != nil {
 		p.logger.Warn("no se pudo marcar el job como failed (best-effort, se ignora)",
@@ -622,6 +677,14 @@ func (p *MaterialPipelineProcessor) failIfPermanent(ctx context.Context, jobID s
 		p.logger.Info("job marcado como failed antes de subir al DLQ (error permanente)",
 			"job_id", jobID, "phase", phase, "last_error", msg)
 	}
+	publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeMaterialAssessmentFailed, ref.CorrelationID, dto.MaterialAssessmentFailedPayload{
+		JobID:      jobID,
+		MaterialID: ref.MaterialID,
+		SchoolID:   ref.SchoolID,
+		Provider:   p.provider.Name(),
+		Phase:      phase,
+		Error:      msg,
+	})
 	return err
 }
 
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/EduGoGroup/edugo-shared/messaging/events"
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
 	"github.com/EduGoGroup/edugo-worker/internal/chunking"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
@@ -188,8 +190,10 @@ type proposeOutcome struct {
 // mockMaterialProvider implementa MaterialLLMProvider con salidas fijas. Si
 // digestOutcomes no está vacío, define la salida por número de llamada (tiene prioridad
 // sobre digest/digestErr); si se agotan, repite la última. Registra la temperatura
-// recibida en cada llamada (digestTemps) para verificar el jitter del reintento.
+// recibida en cada llamada (digestTemps) para verificar el jitter del reintento y el
+// idioma pedido (languages, en orden de llamada).
 type mockMaterialProvider struct {
+	languages      []string
 	log            *[]string
 	digest         *llm.DigestChunkResult
 	digestErr      error
@@ -209,6 +213,7 @@ func (m *mockMaterialProvider) DigestChunk(_ context.Context, in llm.DigestChunk
 		*m.log = append(*m.log, "DigestChunk")
 	}
 	m.digestTemps = append(m.digestTemps, in.Temperature)
+	m.languages = append(m.languages, in.Language)
 	idx := m.digestCalls
 	m.digestCalls++
 	if len(m.digestOutcomes) > 0 {
@@ -229,6 +234,7 @@ func (m *mockMaterialProvider) ProposeCandidates(_ context.Context, in llm.Propo
 		*m.log = append(*m.log, "ProposeCandidates")
 	}
 	m.proposeTemps = append(m.proposeTemps, in.Temperature)
+	m.languages = append(m.languages, in.Language)
 	idx := m.proposeCalls
 	m.proposeCalls++
 	if len(m.proposeOutcomes) > 0 {
@@ -422,6 +428,24 @@ func TestMaterialProcess_FullFlow_Order(t *testing.T) {
 	}
 }
 
+func TestMaterialProcess_IdiomaPorChunk(t *testing.T) {
+	// Escuela en portugués: un chunk sin evidencia usa el idioma de la escuela; uno
+	// claramente en inglés se lee y pregunta en inglés.
+	english := pendingChunk("c2")
+	english.ChunkText = "Photosynthesis is the process by which plants convert light energy into chemical energy. It takes place in the chloroplasts."
+	pipe := &mockMaterialPipeline{job: processingJob(), pending: []*m2m.NextChunk{pendingChunk("c1"), english}}
+	prov := &mockMaterialProvider{digest: validDigest(), candidates: []materialpipeline.CandidatePayloadV1{validCandidate()}}
+	settings := &mockSettingsReader{settings: settingsWith(settingKeyPipelineMode, pipelineModeOn, settingKeyLanguage, "pt-BR")}
+
+	if err := newMaterialProcessor(settings, pipe, prov).Process(context.Background(), materialEventJSON("job-1", "mat-1", "school-1")); err != nil {
+		t.Fatalf("flujo completo devolvió error: %v", err)
+	}
+	want := []string{"pt", "pt", "en", "en"} // digest + propose por chunk
+	if !slices.Equal(prov.languages, want) {
+		t.Fatalf("idiomas pedidos = %v, se esperaba %v", prov.languages, want)
+	}
+}
+
 func TestMaterialProcess_ArtifactsNotValidable_IsolatesChunk(t *testing.T) {
 	var seq []string
 	badDigest := &llm.DigestChunkResult{
@@ -962,3 +986,51 @@ func TestMaterialProcess_ReducePassTransient_PropagatesNoDeliver(t *testing.T) {
 		}
 	}
 }
+
+func TestMaterialProcess_Delivered_PublishesEvent(t *testing.T) {
+	pipe := &mockMaterialPipeline{job: processingJob(), pending: []*m2m.NextChunk{pendingChunk("c1")},
+		deliverAssessmentID: "assess-1", deliverQuestions: 7}
+	prov := &mockMaterialProvider{digest: validDigest(), candidates: []materialpipeline.CandidatePayloadV1{validCandidate()}}
+	pub := &mockEventPublisher{}
+
+	if err := newMaterialProcessor(onSettings(), pipe, prov).WithEventPublisher(pub).Process(context.Background(), materialEventJSON("job-1", "mat-1", "school-1")); err != nil {
+		t.Fatalf("flujo completo devolvió error: %v", err)
+	}
+	if len(pub.events) != 1 || pub.events[0].eventType != dto.EventTypeMaterialAssessmentDelivered {
+		t.Fatalf("esperaba 1 material.assessment_delivered, got %+v", pub.events)
+	}
+	payload := pub.events[0].payload.(dto.MaterialAssessmentDeliveredPayload)
+	if payload.JobID != "job-1" || payload.MaterialID != "mat-1" || payload.SchoolID != "school-1" ||
+		payload.AssessmentID != "assess-1" || payload.Questions != 7 || payload.Provider != "mock-local" {
+		t.Fatalf("payload inesperado: %+v", payload)
+	}
+}
+
+func TestMaterialProcess_PermanentError_PublishesFailed(t *testing.T) {
+	pipe := &mockMaterialPipeline{job: processingJob(), pendingErr: m2m.ErrLearningPermanent}
+	pub := &mockEventPublisher{}
+
+	err := newMaterialProcessor(onSettings(), pipe, &mockMaterialProvider{}).WithEventPublisher(pub).Process(context.Background(), materialEventJSON("job-1", "mat-1", "school-1"))
+	if err == nil || classifyError(err) != ErrorTypePermanent {
+		t.Fatalf("un 4xx permanente debería subir como permanente, got %v", err)
+	}
+	if len(pub.events) != 1 || pub.events[0].eventType != dto.EventTypeMaterialAssessmentFailed {
+		t.Fatalf("esperaba 1 material.assessment_failed, got %+v", pub.events)
+	}
+	payload := pub.events[0].payload.(dto.MaterialAssessmentFailedPayload)
+	if payload.JobID != "job-1" || payload.Phase != 1 || payload.Error == "" {
+		t.Fatalf("payload inesperado: %+v", payload)
+	}
+}
+
+func TestMaterialProcess_TransientError_DoesNotPublish(t *testing.T) {
+	pipe := &mockMaterialPipeline{job: processingJob(), pendingErr: errors.New("learning 503")}
+	pub := &mockEventPublisher{}
+
+	if err := newMaterialProcessor(onSettings(), pipe, &mockMaterialProvider{}).WithEventPublisher(pub).Process(context.Background(), materialEventJSON("job-1", "mat-1", "school-1")); err == nil {
+		t.Fatal("un transitorio debe subir como error")
+	}
+	if len(pub.events) != 0 {
+		t.Fatalf("un transitorio no debe publicar eventos, got %+v", pub.events)
+	}
+}
@@ -0,0 +1,214 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+)
+
+// Message es la vista del mensaje que recorre la cadena de middlewares: el body
+// crudo (lo que recibe Processor.Process) más los campos del envelope que
+// necesitan los interceptores. Los ids de correlación se extraen de mejor
+// esfuerzo del payload: vacíos si el carril no los trae.
+type Message struct {
+	EventType  string
+	EventID    string
+	SchoolID   string
+	AttemptID  string
+	QuestionID string
+	JobID      string
+	Body       []byte
+}
+
+// Handler procesa un Message. Es el eslabón de la cadena de middlewares; el
+// último de la cadena delega en Processor.Process.
+type Handler func(ctx context.Context, msg Message) error
+
+// Middleware envuelve un Handler con comportamiento transversal (métricas,
+// recovery, deadlines, logging, rate limiting). Se registran con Registry.Use.
+type Middleware func(next Handler) Handler
+
+// ErrProcessorPanic marca un panic recuperado dentro de un processor. Permanente
+// (→ DLQ): un panic es un bug, reintentar el mismo mensaje lo repite.
+var ErrProcessorPanic = errors.New("panic en processor")
+
+// ErrProcessingDeadline marca un mensaje que agotó el deadline de su event_type
+// (DeadlineMiddleware). Permanente (→ DLQ): el mismo trabajo volvería a agotarlo, y
+// reintentarlo solo quema los escalones de reintento.
+var ErrProcessingDeadline = errors.New("deadline de procesamiento agotado")
+
+// decodeMessage extrae del mensaje JSON el envelope que usan el routing y los
+// middlewares. Solo event_type es obligatorio; los ids del payload son de mejor
+// esfuerzo (un payload que no sea objeto no falla aquí: lo valida el processor).
+func decodeMessage(payload []byte) (Message, error) {
+	var base struct {
+		EventType string          `json:"event_type"`
+		EventID   string          `json:"event_id"`
+		Payload   json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(payload, &base); err != nil {
+		return Message{}, fmt.Errorf("invalid message format: %w", err)
+	}
+	if base.EventType == "" {
+		return Message{}, fmt.Errorf("missing event_type field in message")
+	}
+
+	var ids struct {
+		SchoolID   string `json:"school_id"`
+		AttemptID  string `json:"attempt_id"`
+		QuestionID string `json:"question_id"`
+		JobID      string `json:"job_id"`
+	}
+	_ = json.Unmarshal(base.Payload, &ids)
+
+	return Message{
+		EventType:  base.EventType,
+		EventID:    base.EventID,
+		SchoolID:   ids.SchoolID,
+		AttemptID:  ids.AttemptID,
+		QuestionID: ids.QuestionID,
+		JobID:      ids.JobID,
+		Body:       payload,
+	}, nil
+}
+
+// logFields devuelve el contexto estructurado del mensaje para el logger: siempre
+// event_type/event_id y, si vienen, los ids de correlación del carril.
+func (m Message) logFields() []interface{} {
+	fields := []interface{}{"event_type", m.EventType, "event_id", m.EventID}
+	for _, kv := range [][2]string{
+		{"school_id", m.SchoolID},
+		{"attempt_id", m.AttemptID},
+		{"question_id", m.QuestionID},
+		{"job_id", m.JobID},
+	} {
+		if kv[1] != "" {
+			fields = append(fields, kv[0], kv[1])
+		}
+	}
+	return fields
+}
+
+// LoggingMiddleware registra la recepción y el resultado de cada evento con el
+// contexto estructurado del mensaje (event_type, school_id, job_id/attempt_id...)
+// y la duración. Reemplaza los logs sueltos del handler de main.
+func LoggingMiddleware(log logger.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			l := log.With(msg.logFields()...)
+			l.Info("evento recibido", "size", len(msg.Body))
+
+			start := time.Now()
+			err := next(ctx, msg)
+			elapsed := time.Since(start).Milliseconds()
+			if err != nil {
+				l.Error("error procesando evento", "duration_ms", elapsed, "error", err.Error())
+				return err
+			}
+			l.Info("evento procesado exitosamente", "duration_ms", elapsed)
+			return nil
+		}
+	}
+}
+
+// MetricsMiddleware registra worker_events_processed_total y
+// worker_processing_duration_seconds por event_type. El status distingue éxito de
+// error transitorio/permanente según classifyError.
+func MetricsMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			metrics.RecordEventProcessing(msg.EventType, processingStatus(err), time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// processingStatus traduce el resultado de un processor al label status.
+func processingStatus(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case classifyError(err) == ErrorTypePermanent:
+		return "permanent_error"
+	default:
+		return "transient_error"
+	}
+}
+
+// RecoveryMiddleware convierte un panic del processor en ErrProcessorPanic
+// (permanente) para que el consumer no muera y el mensaje vaya al DLQ. Loguea el
+// stack para diagnosticar el bug.
+func RecoveryMiddleware(log logger.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) (err error) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Error("panic recuperado en processor",
+						append(msg.logFields(), "panic", fmt.Sprint(rec), "stack", string(debug.Stack()))...)
+					err = fmt.Errorf("%w (%s): %v", ErrProcessorPanic, msg.EventType, rec)
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// DeadlineMiddleware acota el tiempo de procesamiento por event_type: usa el
+// timeout de byEventType si existe o defaultTimeout si no. Un timeout <= 0 deja el
+// contexto sin deadline. Vencer ESTE deadline devuelve ErrProcessingDeadline
+// (permanente): un job que no entra en su deadline no entra tampoco al reintentarlo.
+// Los timeouts propios de una llamada (HTTP, LLM) siguen siendo transitorios.
+func DeadlineMiddleware(byEventType map[string]time.Duration, defaultTimeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			timeout, ok := byEventType[msg.EventType]
+			if !ok {
+				timeout = defaultTimeout
+			}
+			if timeout <= 0 {
+				return next(ctx, msg)
+			}
+			ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrProcessingDeadline)
+			defer cancel()
+			err := next(ctx, msg)
+			if err != nil && errors.Is(context.Cause(ctx), ErrProcessingDeadline) {
+				return fmt.Errorf("%w (%s, %s): %v", ErrProcessingDeadline, msg.EventType, timeout, err)
+			}
+			return err
+		}
+	}
+}
+
+// EventRateLimiter es la porción del rate limiter por event_type que usa el
+// middleware. *ratelimiter.MultiRateLimiter la satisface.
+type EventRateLimiter interface {
+	Wait(ctx context.Context, eventType string) error
+	Tokens(eventType string) float64
+}
+
+// RateLimitMiddleware espera un token del limiter del event_type antes de
+// procesar y registra la espera y los tokens restantes en métricas.
+func RateLimitMiddleware(limiter EventRateLimiter) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			start := time.Now()
+			if err := limiter.Wait(ctx, msg.EventType); err != nil {
+				return fmt.Errorf("rate limiter interrupted: %w", err)
+			}
+			metrics.RecordRateLimiterWait(msg.EventType, time.Since(start).Seconds())
+			metrics.RecordRateLimiterAllowed(msg.EventType)
+			if tokens := limiter.Tokens(msg.EventType); tokens >= 0 {
+				metrics.UpdateRateLimiterTokens(msg.EventType, tokens)
+			}
+			return next(ctx, msg)
+		}
+	}
+}
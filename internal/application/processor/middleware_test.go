@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// funcProcessor es un processor cuyo Process delega en una función.
+type funcProcessor struct {
+	eventType string
+	fn        func(ctx context.Context, payload []byte) error
+}
+
+func (f *funcProcessor) EventType() string { return f.eventType }
+func (f *funcProcessor) Process(ctx context.Context, payload []byte) error {
+	return f.fn(ctx, payload)
+}
+
+// recordingMiddleware anota su nombre antes y después de llamar al siguiente.
+func recordingMiddleware(name string, trace *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			*trace = append(*trace, name+">")
+			err := next(ctx, msg)
+			*trace = append(*trace, "<"+name)
+			return err
+		}
+	}
+}
+
+func TestRegistry_Use_OrdenDeLaCadena(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	var trace []string
+	registry.Register(&funcProcessor{eventType: "test_event", fn: func(context.Context, []byte) error {
+		trace = append(trace, "processor")
+		return nil
+	}})
+	registry.Use(recordingMiddleware("a", &trace), recordingMiddleware("b", &trace))
+
+	if err := registry.Process(context.Background(), []byte(`{"event_type":"test_event"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a> b> processor <b <a"
+	if got := strings.Join(trace, " "); got != want {
+		t.Errorf("orden de la cadena = %q, se esperaba %q", got, want)
+	}
+}
+
+func TestRegistry_Use_RecibeContextoDelMensaje(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	registry.Register(&mockProcessor{eventType: "attempt.review_requested"})
+	var got Message
+	registry.Use(func(next Handler) Handler {
+		return func(ctx context.Context, msg Message) error {
+			got = msg
+			return next(ctx, msg)
+		}
+	})
+
+	payload := []byte(`{"event_id":"evt-1","event_type":"attempt.review_requested","payload":{"attempt_id":"att-1","school_id":"sch-1"}}`)
+	if err := registry.Process(context.Background(), payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.EventID != "evt-1" || got.AttemptID != "att-1" || got.SchoolID != "sch-1" || got.JobID != "" {
+		t.Errorf("contexto del mensaje inesperado: %+v", got)
+	}
+	if string(got.Body) != string(payload) {
+		t.Errorf("el body debe ser el mensaje crudo")
+	}
+}
+
+func TestDecodeMessage_PayloadNoObjetoNoFalla(t *testing.T) {
+	msg, err := decodeMessage([]byte(`{"event_type":"test_event","payload":"texto"}`))
+	if err != nil {
+		t.Fatalf("un payload que no es objeto no debe fallar el routing: %v", err)
+	}
+	if msg.EventType != "test_event" {
+		t.Errorf("event_type = %q", msg.EventType)
+	}
+}
+
+func TestRecoveryMiddleware_PanicAErrorPermanente(t *testing.T) {
+	h := RecoveryMiddleware(newTestLogger())(func(context.Context, Message) error {
+		panic("nil map")
+	})
+
+	err := h(context.Background(), Message{EventType: "test_event"})
+	if !errors.Is(err, ErrProcessorPanic) {
+		t.Fatalf("esperaba ErrProcessorPanic, got %v", err)
+	}
+	if classifyError(err) != ErrorTypePermanent {
+		t.Errorf("un panic debe clasificarse como permanente")
+	}
+}
+
+func TestDeadlineMiddleware_PorEventType(t *testing.T) {
+	mw := DeadlineMiddleware(map[string]time.Duration{"slow": time.Hour}, time.Minute)
+
+	var deadline time.Time
+	var hasDeadline bool
+	h := mw(func(ctx context.Context, _ Message) error {
+		deadline, hasDeadline = ctx.Deadline()
+		return nil
+	})
+
+	_ = h(context.Background(), Message{EventType: "slow"})
+	if !hasDeadline || time.Until(deadline) < 59*time.Minute {
+		t.Errorf("slow debe usar su timeout propio (1h), deadline=%v", deadline)
+	}
+
+	_ = h(context.Background(), Message{EventType: "other"})
+	if !hasDeadline || time.Until(deadline) > time.Minute {
+		t.Errorf("other debe usar el default (1m), deadline=%v", deadline)
+	}
+}
+
+func TestDeadlineMiddleware_SinTimeoutNoFijaDeadline(t *testing.T) {
+	h := DeadlineMiddleware(nil, 0)(func(ctx context.Context, _ Message) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("timeout 0 no debe fijar deadline")
+		}
+		return nil
+	})
+	_ = h(context.Background(), Message{EventType: "x"})
+}
+
+// Agotar el deadline del event_type es permanente (no quema los reintentos); un
+// timeout propio del handler (p.ej. una llamada HTTP) sigue siendo transitorio.
+func TestDeadlineMiddleware_AgotadoEsPermanente(t *testing.T) {
+	h := DeadlineMiddleware(nil, 10*time.Millisecond)(func(ctx context.Context, _ Message) error {
+		<-ctx.Done()
+		return fmt.Errorf("llamando al LLM: %w", ctx.Err())
+	})
+	err := h(context.Background(), Message{EventType: "material.assessment_requested"})
+	if !errors.Is(err, ErrProcessingDeadline) || classifyError(err) != ErrorTypePermanent {
+		t.Fatalf("esperaba ErrProcessingDeadline permanente, obtuve %v", err)
+	}
+
+	h = DeadlineMiddleware(nil, time.Hour)(func(_ context.Context, _ Message) error {
+		return fmt.Errorf("request HTTP: %w", context.DeadlineExceeded)
+	})
+	err = h(context.Background(), Message{EventType: "x"})
+	if errors.Is(err, ErrProcessingDeadline) || classifyError(err) != ErrorTypeTransient {
+		t.Fatalf("un timeout propio del handler debe seguir transitorio, obtuve %v", err)
+	}
+}
+
+// fakeRateLimiter implementa EventRateLimiter.
+type fakeRateLimiter struct {
+	waitErr error
+	waited  []string
+}
+
+func (f *fakeRateLimiter) Wait(_ context.Context, eventType string) error {
+	f.waited = append(f.waited, eventType)
+	return f.waitErr
+}
+func (f *fakeRateLimiter) Tokens(string) float64 { return 1 }
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := &fakeRateLimiter{}
+	called := false
+	h := RateLimitMiddleware(limiter)(func(context.Context, Message) error {
+		called = true
+		return nil
+	})
+
+	if err := h(context.Background(), Message{EventType: "test_rl"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called || len(limiter.waited) != 1 || limiter.waited[0] != "test_rl" {
+		t.Errorf("debe esperar al limiter del event_type y continuar; waited=%v called=%v", limiter.waited, called)
+	}
+
+	limiter.waitErr = context.Canceled
+	called = false
+	if err := h(context.Background(), Message{EventType: "test_rl"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("esperaba el error del limiter, got %v", err)
+	}
+	if called {
+		t.Error("no debe procesar si el limiter fue interrumpido")
+	}
+}
+
+func TestProcessingStatus(t *testing.T) {
+	if s := processingStatus(nil); s != "success" {
+		t.Errorf("nil → %q", s)
+	}
+	if s := processingStatus(ErrMalformedEvent); s != "permanent_error" {
+		t.Errorf("malformado → %q", s)
+	}
+	if s := processingStatus(errors.New("timeout")); s != "transient_error" {
+		t.Errorf("genérico → %q", s)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/EduGoGroup/edugo-shared/logger"
 	"github.com/EduGoGroup/edugo-shared/messaging/events"
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/questionprep"
@@ -25,9 +26,6 @@ import (
 // más coherente. Mismos valores off|local|api que el carril de revisión.
 const settingKeyPrepMode = settingKeyReviewMode
 
-// prepLanguage es el idioma que se pide al LLM para la preparación (regla global).
-const prepLanguage = "es"
-
 // ErrMalformedPrepEvent marca un evento question.prep_requested indecodificable o
 // inválido. Permanente (→ DLQ): reintentar no lo arregla. classifyError lo trata
 // como ErrMalformedEvent (mismo carril permanente) porque lo envuelve.
@@ -54,11 +52,13 @@ type LearningPrepClient interface {
 // asistida por LLM de una pregunta (plan 042 D-042.4). Orquestador PURO: cero SQL,
 // todo por M2M. Lee la fuente fresca, resuelve la política de la escuela (off = ack),
 // pide UNA llamada de preparación al LLM del modo, valida el artefacto contra el
-// contrato v1 y lo escribe con el source_hash con el que trabajó.
+// contrato v1 y lo escribe con el source_hash con el que trabajó. Tras persistir
+// publica question.prep_saved (si hay publisher).
 type QuestionPrepProcessor struct {
 	settings  SchoolSettingsReader
 	learning  LearningPrepClient
 	providers map[string]llm.LLMProvider
+	publisher EventPublisher
 	logger    logger.Logger
 }
 
@@ -75,10 +75,19 @@ func NewQuestionPrepProcessor(
 		settings:  settings,
 		learning:  learning,
 		providers: providers,
+		publisher: noopEventPublisher{},
 		logger:    log,
 	}
 }
 
+// WithEventPublisher cablea el publisher de eventos de salida. nil deja el no-op.
+func (p *QuestionPrepProcessor) WithEventPublisher(pub EventPublisher) *QuestionPrepProcessor {
+	if pub != nil {
+		p.publisher = pub
+	}
+	return p
+}
+
 // EventType satisface processor.Processor.
 func (p *QuestionPrepProcessor) EventType() string { return events.EventTypeQuestionPrepRequested }
 
@@ -118,13 +127,15 @@ func (p *QuestionPrepProcessor) Process(ctx context.Context, payload []byte) err
 		return nil
 	}
 
-	return p.orchestrate(ctx, mode, evt.Payload.Reason, src)
+	return p.orchestrate(llm.WithSchool(ctx, src.SchoolID), evt, mode, schoolLanguage(settings), src)
 }
 
-// orchestrate ejecuta la preparación con la política resuelta. Idempotente por
+// orchestrate ejecuta la preparación con la política resuelta (lang: idioma de la
+// escuela, el mismo del feedback de corrección que el prep alimenta). Idempotente por
 // naturaleza (D-042.5): preparar dos veces produce el mismo artefacto y el PUT ancla
 // por hash, así que reprocesar tras un fallo transitorio es seguro.
-func (p *QuestionPrepProcessor) orchestrate(ctx context.Context, mode, reason string, src m2m.PrepSourceResponse) error {
+func (p *QuestionPrepProcessor) orchestrate(ctx context.Context, evt events.QuestionPrepRequestedEvent, mode, lang string, src m2m.PrepSourceResponse) error {
+	reason := evt.Payload.Reason
 	provider, ok := p.providers[mode]
 	if !ok || provider == nil {
 		// mode desconocido o provider no disponible: config errónea, permanente.
@@ -144,7 +155,7 @@ func (p *QuestionPrepProcessor) orchestrate(ctx context.Context, mode, reason st
 		CorrectAnswer: deref(src.CorrectAnswer),
 		Explanation:   deref(src.Explanation),
 		Feedback:      feedback,
-		Language:      prepLanguage,
+		Language:      lang,
 	}
 
 	rawPrep, err := provider.PrepareQuestion(ctx, req)
@@ -155,7 +166,8 @@ func (p *QuestionPrepProcessor) orchestrate(ctx context.Context, mode, reason st
 
 	// Validación de contrato ANTES del PUT: un prep inválido jamás se persiste
 	// (envenenaría la corrección). Se trata como fallo del provider (transitorio).
-	if _, verr := questionprep.Validate(rawPrep, src.QuestionType); verr != nil {
+	prep, verr := questionprep.Validate(rawPrep, src.QuestionType)
+	if verr != nil {
 		p.logger.Warn("prep del LLM inválido, se descarta (no se persiste)",
 			"question_id", src.QuestionID,
 			"question_type", src.QuestionType,
@@ -192,6 +204,18 @@ func (p *QuestionPrepProcessor) orchestrate(ctx context.Context, mode, reason st
 		"consumed_feedback", consumedFeedback,
 		"provider", provider.Name(),
 	)
+	publishBestEffort(ctx, p.publisher, p.logger, dto.EventTypeQuestionPrepSaved, evt.EventID, dto.QuestionPrepSavedPayload{
+		QuestionID:       src.QuestionID,
+		AssessmentID:     evt.Payload.AssessmentID,
+		SchoolID:         src.SchoolID,
+		QuestionType:     src.QuestionType,
+		Reason:           reason,
+		Provider:         provider.Name(),
+		Mode:             mode,
+		ConsumedFeedback: consumedFeedback,
+		Items:            len(prep.Items),
+		Criteria:         len(prep.Criteria),
+	})
 	return nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/EduGoGroup/edugo-shared/messaging/events"
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
@@ -243,3 +244,35 @@ func TestQuestionPrep_PrepSourceError_Transient(t *testing.T) {
 		t.Fatal("un fallo de red no debe ser permanente")
 	}
 }
+
+func TestQuestionPrep_HappyPath_PublishesPrepSaved(t *testing.T) {
+	settings := &mockSettingsReader{settings: settingsWith(settingKeyReviewMode, reviewModeLocal)}
+	learning := &mockPrepLearning{source: prepSource(llm.QuestionTypeShortAnswer, "h1")}
+	pub := &mockEventPublisher{}
+	p := newPrepProcessor(settings, learning, &mockPrepProvider{raw: json.RawMessage(validListPrep)}).WithEventPublisher(pub)
+
+	if err := p.Process(context.Background(), prepEvent("q1", "a1", "created")); err != nil {
+		t.Fatalf("esperaba éxito, got: %v", err)
+	}
+	if len(pub.events) != 1 || pub.events[0].eventType != dto.EventTypeQuestionPrepSaved {
+		t.Fatalf("esperaba 1 question.prep_saved, got %+v", pub.events)
+	}
+	payload := pub.events[0].payload.(dto.QuestionPrepSavedPayload)
+	if payload.QuestionID != "q1" || payload.SchoolID != "s1" || payload.Reason != "created" || payload.Items != 3 || payload.Provider != "mock-prep" {
+		t.Fatalf("payload inesperado: %+v", payload)
+	}
+}
+
+func TestQuestionPrep_HashConflict_DoesNotPublish(t *testing.T) {
+	settings := &mockSettingsReader{settings: settingsWith(settingKeyReviewMode, reviewModeLocal)}
+	learning := &mockPrepLearning{source: prepSource(llm.QuestionTypeShortAnswer, "h1"), saveErr: m2m.ErrPrepHashConflict}
+	pub := &mockEventPublisher{}
+	p := newPrepProcessor(settings, learning, &mockPrepProvider{raw: json.RawMessage(validListPrep)}).WithEventPublisher(pub)
+
+	if err := p.Process(context.Background(), prepEvent("q1", "a1", "updated")); err != nil {
+		t.Fatalf("409 debe ACKear, got: %v", err)
+	}
+	if len(pub.events) != 0 {
+		t.Fatalf("un prep descartado (409) no debe publicarse, got %+v", pub.events)
+	}
+}
@@ -2,19 +2,29 @@ package processor
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/EduGoGroup/edugo-shared/logger"
 )
 
+// ErrEventTypeNotInLane marca un mensaje cuyo event_type no pertenece al carril que
+// lo recibió (lanes[].event_types): un binding de más en el broker o un publisher que
+// enruta mal. Permanente (→ DLQ del carril): procesarlo ahí saltaría la concurrencia,
+// el reparto y la pausa de su carril, y reintentarlo no cambia la cola.
+var ErrEventTypeNotInLane = errors.New("event_type fuera del carril")
+
 // Registry mantiene un registro de processors por event type
 //
 // Permite registrar processors y rutear mensajes al processor correcto
-// basado en el campo event_type del mensaje JSON.
+// basado en el campo event_type del mensaje JSON. Alrededor de cada processor
+// corre la cadena de middlewares registrada con Use (métricas, recovery,
+// deadlines, logging, rate limiting), idéntica para todos los consumers.
 type Registry struct {
-	processors map[string]Processor
-	logger     logger.Logger
+	processors  map[string]Processor
+	middlewares []Middleware
+	logger      logger.Logger
 }
 
 // NewRegistry crea un nuevo registry vacío
@@ -40,38 +50,72 @@ func (r *Registry) Register(p Processor) {
 	r.logger.Info("processor registered", "event_type", eventType)
 }
 
+// Use añade middlewares a la cadena que envuelve a todos los processors. El
+// primero registrado es el más externo: Use(a, b) ejecuta a → b → processor.
+// Debe llamarse durante el bootstrap, antes de empezar a consumir.
+func (r *Registry) Use(mw ...Middleware) {
+	r.middlewares = append(r.middlewares, mw...)
+}
+
 // Process procesa un mensaje usando el processor correcto
 //
 // Extrae el event_type del mensaje JSON, busca el processor registrado
-// y delega el procesamiento. Si no hay processor para el event_type,
-// retorna error para que el mensaje sea enviado al DLQ.
+// y delega el procesamiento a través de la cadena de middlewares. Si no hay
+// processor para el event_type, retorna error para que el mensaje sea enviado
+// al DLQ.
 func (r *Registry) Process(ctx context.Context, payload []byte) error {
-	// Extraer event_type del mensaje
-	var base struct {
-		EventType string `json:"event_type"`
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		return err
 	}
+	return r.process(ctx, msg)
+}
 
-	if err := json.Unmarshal(payload, &base); err != nil {
-		return fmt.Errorf("invalid message format: %w", err)
+// ProcessLane procesa un mensaje recibido por un carril que acepta solo eventTypes.
+// Un event_type ajeno al carril se rechaza con ErrEventTypeNotInLane sin llegar al
+// processor, aunque el registry lo conozca.
+func (r *Registry) ProcessLane(ctx context.Context, payload []byte, eventTypes []string) error {
+	msg, err := decodeMessage(payload)
+	if err != nil {
+		return err
 	}
-
-	if base.EventType == "" {
-		return fmt.Errorf("missing event_type field in message")
+	if !slices.Contains(eventTypes, msg.EventType) {
+		r.logger.Warn("event_type fuera del carril, se envía a la DLQ",
+			"event_type", msg.EventType,
+			"event_id", msg.EventID,
+			"lane_event_types", eventTypes,
+		)
+		return fmt.Errorf("%w: %s (acepta %v)", ErrEventTypeNotInLane, msg.EventType, eventTypes)
 	}
+	return r.process(ctx, msg)
+}
 
+func (r *Registry) process(ctx context.Context, msg Message) error {
 	// Buscar processor
-	processor, ok := r.processors[base.EventType]
+	processor, ok := r.processors[msg.EventType]
 	if !ok {
 		r.logger.Warn("no processor registered for event type",
-			"event_type", base.EventType,
+			"event_type", msg.EventType,
 			"available_processors", r.RegisteredTypes(),
 		)
-		return fmt.Errorf("no processor registered for event_type: %s", base.EventType)
+		return fmt.Errorf("no processor registered for event_type: %s", msg.EventType)
 	}
 
-	// Procesar con el processor correcto
-	r.logger.Debug("routing to processor", "event_type", base.EventType)
-	return processor.Process(ctx, payload)
+	// Procesar con el processor correcto, envuelto en la cadena de middlewares
+	r.logger.Debug("routing to processor", "event_type", msg.EventType)
+	return r.chain(processor)(ctx, msg)
+}
+
+// chain compone los middlewares alrededor del processor (el primero registrado
+// queda como el más externo).
+func (r *Registry) chain(p Processor) Handler {
+	h := Handler(func(ctx context.Context, msg Message) error {
+		return p.Process(ctx, msg.Body)
+	})
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	return h
 }
 
 // RegisteredTypes retorna la lista de event types registrados
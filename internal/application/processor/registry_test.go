@@ -172,6 +172,35 @@ func TestRegistry_Process_UnknownEventType(t *testing.T) {
 	}
 }
 
+func TestRegistry_ProcessLane_RechazaEventTypeAjeno(t *testing.T) {
+	registry := NewRegistry(newTestLogger())
+	review := &mockProcessor{eventType: "attempt.review_requested"}
+	prep := &mockProcessor{eventType: "question.prep_requested"}
+	registry.Register(review)
+	registry.Register(prep)
+
+	payload, _ := json.Marshal(map[string]interface{}{"event_type": "question.prep_requested"})
+	err := registry.ProcessLane(context.Background(), payload, []string{"attempt.review_requested"})
+
+	if !errors.Is(err, ErrEventTypeNotInLane) {
+		t.Fatalf("expected ErrEventTypeNotInLane, got: %v", err)
+	}
+	if !IsPermanentError(err) {
+		t.Error("un event_type fuera del carril debe ir a la DLQ sin reintentos")
+	}
+	if prep.processCalled {
+		t.Error("el processor no debe correr fuera de su carril")
+	}
+
+	payload, _ = json.Marshal(map[string]interface{}{"event_type": "attempt.review_requested"})
+	if err := registry.ProcessLane(context.Background(), payload, []string{"attempt.review_requested"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !review.processCalled {
+		t.Error("el event_type del carril debe procesarse")
+	}
+}
+
 func TestRegistry_Process_InvalidJSON(t *testing.T) {
 	logger := newTestLogger()
 	registry := NewRegistry(logger)
@@ -28,6 +28,12 @@ func DefaultRetryConfig(log logger.Logger) RetryConfig {
 	return cfg
 }
 
+// IsPermanentError indica si reintentar no arregla err según el clasificador del
+// worker. Lo usan los consumers con reintentos diferidos para mandar directo a la DLQ.
+func IsPermanentError(err error) bool {
+	return classifyError(err) == ErrorTypePermanent
+}
+
 // WithRetry delega al shared retry.WithRetry.
 var WithRetry = retry.WithRetry
 
@@ -51,6 +57,21 @@ func classifyError(err error) ErrorType {
 		return ErrorTypePermanent
 	}
 
+	// Panic recuperado por RecoveryMiddleware: es un bug, reintentar lo repite.
+	if errors.Is(err, ErrProcessorPanic) {
+		return ErrorTypePermanent
+	}
+
+	// Mensaje en un carril que no acepta su event_type: la cola no va a cambiar.
+	if errors.Is(err, ErrEventTypeNotInLane) {
+		return ErrorTypePermanent
+	}
+
+	// Deadline del event_type agotado: el reintento lo volvería a agotar.
+	if errors.Is(err, ErrProcessingDeadline) {
+		return ErrorTypePermanent
+	}
+
 	// 4xx permanente de learning (request malformada, answer inexistente, scope
 	// insuficiente): reintentar no lo arregla.
 	if errors.Is(err, m2m.ErrLearningPermanent) {
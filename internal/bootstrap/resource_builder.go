@@ -3,6 +3,9 @@ package bootstrap
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,11 +21,23 @@ import (
 	"github.com/EduGoGroup/edugo-worker/internal/infrastructure"
 	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/health"
 	httpInfra "github.com/EduGoGroup/edugo-worker/internal/infrastructure/http"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/idempotency"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/consumer"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/memory"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/publisher"
 	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/nlp"
 	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/pdf"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/ratelimiter"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	llmapi "github.com/EduGoGroup/edugo-worker/internal/llm/api"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/availability"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/cache"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/instrument"
 	"github.com/EduGoGroup/edugo-worker/internal/llm/ollama"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/openaicompat"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/resilience"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/scheduler"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline/reduce"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -32,15 +47,19 @@ type Resources struct {
 	Logger                 logger.Logger
 	RabbitMQConn           *rabbit.Connection
 	RabbitMQChannel        *amqp.Channel
+	EventPublisher         *publisher.EventPublisher
 	AuthClient             *client.AuthClient
 	SettingsClient         *m2m.SettingsClient
 	LearningClient         *m2m.LearningClient
 	LearningPrepClient     *m2m.LearningPrepClient
 	LearningPipelineClient *m2m.LearningPipelineClient
 	LLMProvider            llm.LLMProvider
+	LLMMonitor             *availability.Monitor
 	Embedder               llm.Embedder
 	LifecycleManager       *lifecycle.Manager
 	ProcessorRegistry      *processor.Registry
+	LaneController         *consumer.LaneController
+	FairSchedulers         map[string]*processor.FairScheduler
 	MetricsServer          *httpInfra.MetricsServer
 	HealthChecker          *health.Checker
 	SharedMetrics          *sharedMetrics.Metrics
@@ -56,6 +75,9 @@ type ResourceBuilder struct {
 	rabbitSharedConn *rabbit.Connection
 	rabbitConn       *amqp.Connection
 	rabbitChannel    *amqp.Channel
+	publisherChannel *amqp.Channel
+	memoryBroker     *memory.Broker
+	eventPublisher   *publisher.EventPublisher
 
 	// Servicios de infraestructura externa
 	pdfExtractor pdf.Extractor
@@ -70,7 +92,10 @@ type ResourceBuilder struct {
 	llmProvider            llm.LLMProvider
 	llmProviders           map[string]llm.LLMProvider
 	embedder               llm.Embedder
+	llmMonitor             *availability.Monitor
 	processorRegistry      *processor.Registry
+	laneController         *consumer.LaneController
+	fairSchedulers         map[string]*processor.FairScheduler
 	metricsServer          *httpInfra.MetricsServer
 	healthChecker          *health.Checker
 	sharedMetrics          *sharedMetrics.Metrics
@@ -172,6 +197,66 @@ func (b *ResourceBuilder) WithRabbitMQ() *ResourceBuilder {
 	return b
 }
 
+// WithMemoryBroker usa un broker en memoria en lugar de RabbitMQ (modo --local):
+// WithEventPublisher publica sobre él. Reemplaza a WithRabbitMQ.
+func (b *ResourceBuilder) WithMemoryBroker(broker *memory.Broker) *ResourceBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.memoryBroker = broker
+	return b
+}
+
+// WithEventPublisher configura el publisher de eventos de cierre de carril
+// (attempt.ai_reviewed, question.prep_saved, material.assessment_delivered/failed).
+// Abre un canal AMQP PROPIO: el canal del wrapper lo usa setupRabbitMQ y los
+// consumers gestionan los suyos. Requiere WithRabbitMQ o WithMemoryBroker.
+func (b *ResourceBuilder) WithEventPublisher() *ResourceBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	exchanges := b.config.GetExchangesConfigWithDefaults()
+	publisherExchanges := publisher.Exchanges{
+		Assessments: exchanges.Assessments,
+		Materials:   exchanges.Materials,
+	}
+
+	if b.memoryBroker != nil {
+		b.eventPublisher = publisher.NewEventPublisher(b.memoryBroker.Channel(), publisherExchanges)
+		b.logger.Info("✅ Event publisher initialized (in-memory broker)")
+		return b
+	}
+
+	if b.rabbitConn == nil {
+		b.err = fmt.Errorf("RabbitMQ connection required before event publisher (call WithRabbitMQ first)")
+		return b
+	}
+
+	ch, err := b.rabbitConn.Channel()
+	if err != nil {
+		b.err = fmt.Errorf("failed to open RabbitMQ publisher channel: %w", err)
+		return b
+	}
+	b.publisherChannel = ch
+
+	b.eventPublisher = publisher.NewEventPublisher(ch, publisherExchanges)
+
+	// Registrar cleanup: el canal se cierra antes que la conexión (LIFO).
+	b.addCleanup(func() error {
+		b.logger.Info("closing RabbitMQ publisher channel")
+		if err := b.publisherChannel.Close(); err != nil {
+			return fmt.Errorf("failed to close RabbitMQ publisher channel: %w", err)
+		}
+		return nil
+	})
+
+	b.logger.Info("✅ Event publisher initialized",
+		"assessments_exchange", exchanges.Assessments,
+		"materials_exchange", exchanges.Materials)
+	return b
+}
+
 // WithAuthClient configura el cliente de autenticación
 func (b *ResourceBuilder) WithAuthClient() *ResourceBuilder {
 	if b.err != nil {
@@ -314,6 +399,31 @@ func (b *ResourceBuilder) WithM2MClients() *ResourceBuilder {
 	return b
 }
 
+// decorateLocalProvider arma la cadena del provider local, de adentro hacia afuera:
+//   - instrumentación (latencia, tokens, resultado) pegada al provider real: mide solo
+//     las llamadas que llegan al backend.
+//   - monitor de disponibilidad, por dentro del breaker y del scheduler: observa solo
+//     lo que respondió (o no) el backend. Un ErrCircuitOpen, una espera de cupo o de
+//     turno que vence no dicen nada de su salud: con la cola larga, contarlas
+//     pausaría los carriles con el backend sano.
+//   - breaker y bulkhead (una llamada rechazada no llega al backend).
+//   - scheduler por prioridad de carril (espera su turno antes de gastar cupo).
+//
+// monitor, guard y sched pueden ser nil (deshabilitados).
+func decorateLocalProvider(probed llm.LLMProvider, labels instrument.Labels, monitor *availability.Monitor, guard *resilience.Guard, sched *scheduler.Scheduler) llm.LLMProvider {
+	var p llm.LLMProvider = instrument.NewProvider(probed, labels)
+	if monitor != nil {
+		p = availability.Observe(p, monitor)
+	}
+	if guard != nil {
+		p = resilience.NewProvider(p, guard)
+	}
+	if sched != nil {
+		p = scheduler.NewProvider(p, sched)
+	}
+	return p
+}
+
 // Contrato M2M hacia learning: la audience es común, pero cada riel mintea su token
 // con su propio scope (revisión: plan 040 F2; preparación: plan 042 F2).
 const (
@@ -339,14 +449,74 @@ func (b *ResourceBuilder) WithLLMProvider() *ResourceBuilder {
 
 	llmCfg := b.config.GetLLMConfigWithDefaults()
 
-	// Provider local (Ollama). Es también el default histórico expuesto en
-	// Resources.LLMProvider.
-	localProvider := ollama.New(ollama.Config{
-		BaseURL:     llmCfg.Local.BaseURL,
-		Model:       llmCfg.Local.Model,
-		Timeout:     llmCfg.Local.Timeout,
-		Temperature: llmCfg.Local.Temperature,
-	})
+	// Versiones de los prompts: un registry para todos los providers (y para la clave
+	// del cache), así una escuela en rollout recibe la misma versión en local y en API.
+	promptRegistry, err := buildPromptRegistry(llmCfg.Prompts, b.logger)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	// Provider local (Ollama u OpenAI-compatible según llm.local.backend). Es también
+	// el default histórico expuesto en Resources.LLMProvider.
+	probedProvider, err := buildLocalProvider(llmCfg.Local, b.ollamaPool("local", llmCfg.Local.BaseURLs, llmCfg.Local.Model, llmCfg.Monitor), promptRegistry, b.logger)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	// Monitor de disponibilidad del provider local: sondea su Ping y observa cada
+	// llamada (decorador). WithProcessors le cuelga la pausa de los carriles
+	// dependientes; main lo arranca cuando los consumers están registrados.
+	if llmCfg.Monitor.Enabled {
+		b.llmMonitor = availability.NewMonitor(availability.Config{
+			Provider:         "local",
+			Interval:         llmCfg.Monitor.Interval,
+			ProbeTimeout:     llmCfg.Monitor.ProbeTimeout,
+			FailureThreshold: llmCfg.Monitor.FailureThreshold,
+		}, probedProvider, b.logger)
+	}
+
+	// Breaker y bulkhead por backend. El bulkhead del local lo comparte el cliente de
+	// embeddings si apunta al mismo host.
+	var localBulkhead *resilience.Bulkhead
+	var localGuard *resilience.Guard
+	if llmCfg.Resilience.Enabled {
+		localBulkhead = resilience.NewBulkhead(llmCfg.Resilience.MaxInFlight, llmCfg.Resilience.MaxBulkInFlight)
+		localGuard = resilience.NewGuard(llmGuardConfig(llmCfg.Resilience, "llm_local"), localBulkhead)
+	}
+
+	// Scheduler por prioridad de carril. El del local lo comparte el cliente de
+	// embeddings si apunta al mismo host.
+	var localScheduler *scheduler.Scheduler
+	if llmCfg.Scheduler.Enabled {
+		if err := b.validateSchedulerPriorities(llmCfg.Scheduler); err != nil {
+			b.err = err
+			return b
+		}
+		localScheduler = scheduler.New(llmSchedulerConfig(llmCfg.Scheduler, "local"))
+	}
+
+	localProvider := decorateLocalProvider(probedProvider, instrument.Labels{
+		Provider: llmCfg.Local.Backend,
+		Model:    llmCfg.Local.Model,
+	}, b.llmMonitor, localGuard, localScheduler)
+
+	// Cache de respuestas (por fuera del monitor: un acierto no dice nada de la
+	// disponibilidad). Lo comparten el provider local y el cliente de embeddings.
+	var responseCache cache.Store
+	if llmCfg.Cache.Enabled {
+		responseCache, err = buildCacheStore(llmCfg.Cache)
+		if err != nil {
+			b.err = err
+			return b
+		}
+		localProvider = cache.NewProvider(localProvider, responseCache, cache.Config{
+			Temperature: llmCfg.Local.Temperature,
+			Logger:      b.logger,
+			Prompts:     promptRegistry,
+		})
+	}
 	b.llmProvider = localProvider
 
 	// Mapa de providers por mode del carril de revisión (plan 040 F2): el processor
@@ -355,38 +525,268 @@ func (b *ResourceBuilder) WithLLMProvider() *ResourceBuilder {
 	// soportado), se omite la clave "api" y el processor errará claro solo si una
 	// escuela pide mode=api sin provider disponible —sin romper el carril local—.
 	b.llmProviders = map[string]llm.LLMProvider{"local": localProvider}
-	if apiProvider, err := BuildAPIProvider(llmCfg.API); err != nil {
+	if apiProvider, err := BuildAPIProvider(llmCfg.API, promptRegistry); err != nil {
 		b.logger.Warn("provider LLM por API no disponible (mode=api fallará hasta corregir config)",
 			"error", err.Error(), "api_provider", llmCfg.API.Provider)
 	} else {
-		b.llmProviders["api"] = apiProvider
+		var provider llm.LLMProvider = instrument.NewProvider(apiProvider, instrument.Labels{
+			Provider: llmCfg.API.Provider,
+			Model:    llmCfg.API.Model,
+		})
+		if llmCfg.Resilience.Enabled {
+			bulkhead := resilience.NewBulkhead(llmCfg.Resilience.MaxInFlight, llmCfg.Resilience.MaxBulkInFlight)
+			provider = resilience.NewProvider(provider, resilience.NewGuard(llmGuardConfig(llmCfg.Resilience, "llm_api"), bulkhead))
+		}
+		if llmCfg.Scheduler.Enabled {
+			provider = scheduler.NewProvider(provider, scheduler.New(llmSchedulerConfig(llmCfg.Scheduler, "api")))
+		}
+		b.llmProviders["api"] = provider
 	}
 
 	// Cliente de embeddings local (plan 044 D-044.1). Pieza separada del provider LLM:
 	// el reduce (F1c) lo consumirá para medir significado antes de gastar LLM. Aquí solo
 	// se construye y se expone en Resources; el cableado a un processor es de F1c.
-	b.embedder = ollama.NewEmbedder(ollama.EmbedConfig{
-		BaseURL: llmCfg.Embed.BaseURL,
-		Model:   llmCfg.Embed.Model,
-		Timeout: llmCfg.Embed.Timeout,
+	embedder, err := buildEmbedder(llmCfg.Embed, b.ollamaPool("embed", llmCfg.Embed.BaseURLs, llmCfg.Embed.Model, llmCfg.Monitor))
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.embedder = instrument.NewEmbedder(embedder, instrument.Labels{
+		Provider: llmCfg.Embed.Backend,
+		Model:    llmCfg.Embed.Model,
 	})
+	if llmCfg.Resilience.Enabled {
+		bulkhead := localBulkhead
+		if !sameLLMHosts(llmCfg.Embed.BaseURL, llmCfg.Embed.BaseURLs, llmCfg.Local.BaseURL, llmCfg.Local.BaseURLs) {
+			bulkhead = resilience.NewBulkhead(llmCfg.Resilience.MaxInFlight, llmCfg.Resilience.MaxBulkInFlight)
+		}
+		b.embedder = resilience.NewEmbedder(b.embedder, resilience.NewGuard(llmGuardConfig(llmCfg.Resilience, "llm_embed"), bulkhead))
+	}
+	if llmCfg.Scheduler.Enabled {
+		embedScheduler := localScheduler
+		if !sameLLMHosts(llmCfg.Embed.BaseURL, llmCfg.Embed.BaseURLs, llmCfg.Local.BaseURL, llmCfg.Local.BaseURLs) {
+			embedScheduler = scheduler.New(llmSchedulerConfig(llmCfg.Scheduler, "embed"))
+		}
+		b.embedder = scheduler.NewEmbedder(b.embedder, embedScheduler)
+	}
+	if responseCache != nil {
+		b.embedder = cache.NewEmbedder(b.embedder, responseCache, b.logger)
+	}
 
 	b.logger.Info("✅ LLM providers initialized (selección por mode de escuela: local|api)",
 		"local_provider", localProvider.Name(),
+		"local_backend", llmCfg.Local.Backend,
 		"local_base_url", llmCfg.Local.BaseURL,
+		"local_base_urls", llmCfg.Local.BaseURLs,
+		"local_monitor", llmCfg.Monitor.Enabled,
+		"local_cache", llmCfg.Cache.Enabled,
+		"resilience", llmCfg.Resilience.Enabled,
+		"scheduler", llmCfg.Scheduler.Enabled,
 		"api_provider", llmCfg.API.Provider,
 		"api_available", b.llmProviders["api"] != nil,
+		"embed_backend", llmCfg.Embed.Backend,
 		"embed_model", llmCfg.Embed.Model,
 		"embed_base_url", llmCfg.Embed.BaseURL,
+		"embed_base_urls", llmCfg.Embed.BaseURLs,
 	)
 	return b
 }
 
+// probedProvider es el provider local: además de las llamadas expone Ping, la sonda
+// del monitor de disponibilidad.
+type probedProvider interface {
+	llm.LLMProvider
+	Ping(ctx context.Context) error
+}
+
+// buildLocalProvider construye el provider local del backend configurado. pool es el
+// reparto entre hosts de llm.local.base_urls (nil = solo base_url); registry, las
+// versiones de los prompts (nil = builtin).
+func buildLocalProvider(cfg config.LLMLocalConfig, pool *ollama.Pool, registry *prompts.Registry, log logger.Logger) (probedProvider, error) {
+	if cfg.Backend != config.LLMBackendOllama && len(cfg.BaseURLs) > 0 {
+		return nil, fmt.Errorf("llm.local.base_urls: solo soportado con backend %s", config.LLMBackendOllama)
+	}
+	switch cfg.Backend {
+	case config.LLMBackendOllama:
+		return ollama.New(ollama.Config{
+			BaseURL:     cfg.BaseURL,
+			Pool:        pool,
+			Model:       cfg.Model,
+			Timeout:     cfg.Timeout,
+			Temperature: cfg.Temperature,
+			Budget:      localTokenBudget(cfg),
+			Logger:      log,
+			Prompts:     registry,
+			Logprobs:    cfg.Logprobs,
+		}), nil
+	case config.LLMBackendOpenAI:
+		p, err := openaicompat.New(openaicompat.Config{
+			BaseURL:        cfg.BaseURL,
+			Model:          cfg.Model,
+			APIKey:         cfg.APIKey,
+			Timeout:        cfg.Timeout,
+			Temperature:    cfg.Temperature,
+			ResponseFormat: cfg.ResponseFormat,
+			Prompts:        registry,
+			Logprobs:       cfg.Logprobs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("llm.local: %w", err)
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("llm.local.backend: backend desconocido %q (%s|%s)", cfg.Backend, config.LLMBackendOllama, config.LLMBackendOpenAI)
+	}
+}
+
+// buildPromptRegistry construye el registry de versiones de prompts de llm.prompts.
+// Una versión inexistente o un rollout inválido falla el arranque.
+func buildPromptRegistry(cfg config.LLMPromptsConfig, log logger.Logger) (*prompts.Registry, error) {
+	defaults := make(map[prompts.ID]string, len(cfg.Versions))
+	for id, version := range cfg.Versions {
+		defaults[prompts.ID(id)] = version
+	}
+	rollouts := make(map[prompts.ID]prompts.Rollout, len(cfg.Rollouts))
+	for id, rollout := range cfg.Rollouts {
+		rollouts[prompts.ID(id)] = prompts.Rollout{
+			Version: rollout.Version,
+			Schools: rollout.Schools,
+			Percent: rollout.Percent,
+		}
+	}
+	registry, err := prompts.New(prompts.Config{Dir: cfg.Dir, Defaults: defaults, Rollouts: rollouts, Logger: log})
+	if err != nil {
+		return nil, fmt.Errorf("llm.prompts: %w", err)
+	}
+	return registry, nil
+}
+
+// buildCacheStore construye el store del cache de respuestas LLM configurado.
+func buildCacheStore(cfg config.LLMCacheConfig) (cache.Store, error) {
+	switch cfg.Store {
+	case config.LLMCacheStoreMemory:
+		return cache.NewMemoryStore(cfg.MaxEntries, cfg.TTL), nil
+	case config.LLMCacheStoreDisk:
+		store, err := cache.NewDiskStore(cfg.Dir, cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("llm.cache: %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("llm.cache.store: store desconocido %q (%s|%s)", cfg.Store, config.LLMCacheStoreMemory, config.LLMCacheStoreDisk)
+	}
+}
+
+// ollamaPool construye el pool de hosts Ollama de baseURLs y arranca sus sondas (con
+// el intervalo y el timeout del monitor) hasta el shutdown. nil si no hay lista: el
+// cliente usa su base_url.
+func (b *ResourceBuilder) ollamaPool(name string, baseURLs []string, model string, monitor config.LLMMonitorConfig) *ollama.Pool {
+	if len(baseURLs) == 0 {
+		return nil
+	}
+	pool := ollama.NewPool(ollama.PoolConfig{
+		Name:         name,
+		BaseURLs:     baseURLs,
+		Model:        model,
+		ProbeTimeout: monitor.ProbeTimeout,
+		Logger:       b.logger,
+	})
+	ctx, cancel := context.WithCancel(b.ctx)
+	pool.Start(ctx, monitor.Interval)
+	b.addCleanup(func() error {
+		cancel()
+		return nil
+	})
+	return pool
+}
+
+// sameLLMHosts indica si dos clientes LLM apuntan a los mismos hosts (comparten
+// bulkhead y scheduler).
+func sameLLMHosts(baseURL string, baseURLs []string, otherBaseURL string, otherBaseURLs []string) bool {
+	if len(baseURLs) > 0 || len(otherBaseURLs) > 0 {
+		return slices.Equal(baseURLs, otherBaseURLs)
+	}
+	return baseURL == otherBaseURL
+}
+
+// llmGuardConfig es la config del breaker de un backend LLM.
+func llmGuardConfig(cfg config.LLMResilienceConfig, name string) resilience.Config {
+	return resilience.Config{
+		Name:        name,
+		MaxFailures: cfg.MaxFailures,
+		OpenTimeout: cfg.OpenTimeout,
+	}
+}
+
+// llmSchedulerConfig es la config del scheduler de un backend LLM.
+func llmSchedulerConfig(cfg config.LLMSchedulerConfig, name string) scheduler.Config {
+	return scheduler.Config{
+		Name:        name,
+		Concurrency: cfg.Concurrency,
+		Priorities:  cfg.Priorities,
+	}
+}
+
+// validateSchedulerPriorities falla si llm.scheduler.priorities nombra un carril que
+// no existe (salvo la clase default): una errata dejaría al carril con la prioridad
+// más baja sin aviso.
+func (b *ResourceBuilder) validateSchedulerPriorities(cfg config.LLMSchedulerConfig) error {
+	classes := make([]string, 0, len(cfg.Priorities))
+	for class := range cfg.Priorities {
+		if class != scheduler.DefaultClass {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+	_, err := b.resolveLaneNames("llm.scheduler.priorities", classes, len(b.config.LLM.Scheduler.Priorities) > 0)
+	return err
+}
+
+// localTokenBudget traduce la config al presupuesto de contexto del provider local.
+// context_window negativo lo desactiva (valor cero de llm.TokenBudget).
+func localTokenBudget(cfg config.LLMLocalConfig) llm.TokenBudget {
+	if cfg.ContextWindow < 0 {
+		return llm.TokenBudget{}
+	}
+	return llm.TokenBudget{
+		ContextWindow: cfg.ContextWindow,
+		OutputReserve: cfg.OutputReserve,
+		MinContext:    cfg.MinContext,
+	}
+}
+
+// buildEmbedder construye el cliente de embeddings del backend configurado. pool es
+// el reparto entre hosts de llm.embed.base_urls (nil = solo base_url).
+func buildEmbedder(cfg config.LLMEmbedConfig, pool *ollama.Pool) (llm.Embedder, error) {
+	if cfg.Backend != config.LLMBackendOllama && len(cfg.BaseURLs) > 0 {
+		return nil, fmt.Errorf("llm.embed.base_urls: solo soportado con backend %s", config.LLMBackendOllama)
+	}
+	switch cfg.Backend {
+	case config.LLMBackendOllama:
+		return ollama.NewEmbedder(ollama.EmbedConfig{
+			BaseURL: cfg.BaseURL,
+			Pool:    pool,
+			Model:   cfg.Model,
+			Timeout: cfg.Timeout,
+		}), nil
+	case config.LLMBackendOpenAI:
+		return openaicompat.NewEmbedder(openaicompat.EmbedConfig{
+			BaseURL: cfg.BaseURL,
+			Model:   cfg.Model,
+			APIKey:  cfg.APIKey,
+			Timeout: cfg.Timeout,
+		}), nil
+	default:
+		return nil, fmt.Errorf("llm.embed.backend: backend desconocido %q (%s|%s)", cfg.Backend, config.LLMBackendOllama, config.LLMBackendOpenAI)
+	}
+}
+
 // buildAPIProvider construye el provider por API a demanda (plan 040/041 lo usa
 // cuando la política de una escuela pide modo "api"). Se expone para no atar el
 // import del paquete api solo al harness. Devuelve error si la config no permite
-// construirlo (proveedor no soportado, etc.).
-func BuildAPIProvider(cfg config.LLMAPIConfig) (llm.LLMProvider, error) {
+// construirlo (proveedor no soportado, etc.). registry son las versiones de los
+// prompts (nil = builtin).
+func BuildAPIProvider(cfg config.LLMAPIConfig, registry *prompts.Registry) (llm.LLMProvider, error) {
 	return llmapi.New(llmapi.Config{
 		Provider:  cfg.Provider,
 		APIKey:    cfg.APIKey,
@@ -394,6 +794,7 @@ func BuildAPIProvider(cfg config.LLMAPIConfig) (llm.LLMProvider, error) {
 		BaseURL:   cfg.BaseURL,
 		Timeout:   cfg.Timeout,
 		MaxTokens: cfg.MaxTokens,
+		Prompts:   registry,
 	})
 }
 
@@ -486,13 +887,30 @@ func (b *ResourceBuilder) WithProcessors() *ResourceBuilder {
 		return b
 	}
 
+	// Publisher de eventos de cierre de carril: opcional. Sin WithEventPublisher los
+	// processors usan su no-op (se evita pasar un *EventPublisher nil como interfaz).
+	var eventPublisher processor.EventPublisher
+	if b.eventPublisher != nil {
+		eventPublisher = b.eventPublisher
+	}
+
+	idemStore, err := b.buildIdempotencyStore()
+	if err != nil {
+		b.err = err
+		return b
+	}
+
 	b.processorRegistry = processor.NewRegistry(b.logger)
+	b.processorRegistry.Use(b.buildMiddlewares(idemStore)...)
 	b.processorRegistry.Register(processor.NewAttemptReviewProcessor(
-		b.settingsClient, b.learningClient, b.llmProviders, b.logger))
+		b.settingsClient, b.learningClient, b.llmProviders, b.logger).
+		WithEventPublisher(eventPublisher).
+		WithReviewConcurrency(b.config.GetLLMConfigWithDefaults().Review.Concurrency))
 	// Carril de preparación (plan 042 F2): comparte registry (enruta por event_type),
 	// pero consume su propia cola (canal por riel, main.go arranca su consumer).
 	b.processorRegistry.Register(processor.NewQuestionPrepProcessor(
-		b.settingsClient, b.learningPrepClient, b.llmProviders, b.logger))
+		b.settingsClient, b.learningPrepClient, b.llmProviders, b.logger).
+		WithEventPublisher(eventPublisher))
 
 	// Carril material→evaluación (plan 043 F3c): compone la fase 0 determinista + el loop
 	// de fase 1 (LLM local). Los parámetros de descarga/porcionado vienen de la config del
@@ -521,19 +939,241 @@ func (b *ResourceBuilder) WithProcessors() *ResourceBuilder {
 		mpCfg.DownloadMaxBytes,
 		reduceDeps,
 		b.logger,
-	))
+	).WithEventPublisher(eventPublisher))
+
+	if err := b.validateLaneEventTypes(); err != nil {
+		b.err = err
+		return b
+	}
+
+	// Control de pausa por carril: main registra cada consumer y envuelve su handler;
+	// la API de administración lo opera.
+	b.laneController = consumer.NewLaneController(b.logger)
+	if b.llmMonitor != nil {
+		if err := b.pauseLanesWhileLLMDown(); err != nil {
+			b.err = err
+			return b
+		}
+	}
+
+	if err := b.buildFairSchedulers(); err != nil {
+		b.err = err
+		return b
+	}
 
 	b.logger.Info("✅ Processor registry initialized (carriles revisión 040 + preparación 042 + materiales 043/044)",
 		"count", b.processorRegistry.Count())
 	return b
 }
 
-// relevanceScorer es el subconjunto de un provider LLM que puntúa relevancia (pasada 2
-// del reduce, D-044.3). NO está en llm.LLMProvider (es propio del carril 044); los
-// providers concretos (ollama/api) lo satisfacen. Se asserta desde el mapa de providers
-// —cuyo tipo estático es llm.LLMProvider, sin ScoreRelevance— para pasarlo a la pasada 2.
-type relevanceScorer interface {
-	ScoreRelevance(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error)
+// validateLaneEventTypes falla si un carril acepta un event_type sin processor
+// registrado: sus mensajes solo podrían terminar en la DLQ.
+func (b *ResourceBuilder) validateLaneEventTypes() error {
+	registered := b.processorRegistry.RegisteredTypes()
+	sort.Strings(registered)
+	for _, lane := range b.config.GetLanesConfigWithDefaults() {
+		for _, eventType := range lane.EventTypes {
+			if i := sort.SearchStrings(registered, eventType); i == len(registered) || registered[i] != eventType {
+				return fmt.Errorf("lanes.%s: event_type %q sin processor registrado (registrados: %s)",
+					lane.Name, eventType, strings.Join(registered, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// resolveLaneNames valida que names (de la clave key) sean carriles de `lanes:`.
+// Si names es el default de la clave (explicit false) descarta en silencio los
+// carriles que la config no declara; si se configuró, un carril desconocido es un
+// error.
+func (b *ResourceBuilder) resolveLaneNames(key string, names []string, explicit bool) ([]string, error) {
+	lanes := b.config.GetLanesConfigWithDefaults()
+	known := make(map[string]bool, len(lanes))
+	declared := make([]string, 0, len(lanes))
+	for _, lane := range lanes {
+		known[lane.Name] = true
+		declared = append(declared, lane.Name)
+	}
+
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		switch {
+		case known[name]:
+			resolved = append(resolved, name)
+		case explicit:
+			return nil, fmt.Errorf("%s: carril desconocido %q (%s)", key, name, strings.Join(declared, "|"))
+		}
+	}
+	return resolved, nil
+}
+
+// buildFairSchedulers crea el reparto por escuela de los carriles configurados. main
+// envuelve el handler de esos carriles y les sube el prefetch al buffer. Falla si la
+// config nombra un carril o un modo inexistente.
+func (b *ResourceBuilder) buildFairSchedulers() error {
+	fairCfg := b.config.GetFairSchedulingConfigWithDefaults()
+	if !fairCfg.Enabled {
+		b.logger.Info("⚠️  Reparto por escuela deshabilitado")
+		return nil
+	}
+	switch fairCfg.Mode {
+	case processor.FairModeRoundRobin, processor.FairModeWeighted:
+	default:
+		return fmt.Errorf("fair_scheduling.mode: modo desconocido %q (round_robin|weighted)", fairCfg.Mode)
+	}
+
+	// Guard de interfaz nil: un *m2m.SettingsClient nil no debe llegar como
+	// SchoolSettingsReader no-nil.
+	var settings processor.SchoolSettingsReader
+	if b.settingsClient != nil {
+		settings = b.settingsClient
+	}
+
+	fairLanes, err := b.resolveLaneNames("fair_scheduling.lanes", fairCfg.Lanes, len(b.config.FairScheduling.Lanes) > 0)
+	if err != nil {
+		return err
+	}
+	// lanes[].concurrency, si está, reemplaza a la concurrencia común del reparto.
+	concurrency := make(map[string]int)
+	for _, lane := range b.config.GetLanesConfigWithDefaults() {
+		if lane.Concurrency > 0 {
+			concurrency[lane.Name] = lane.Concurrency
+		}
+	}
+
+	b.fairSchedulers = make(map[string]*processor.FairScheduler, len(fairLanes))
+	for _, lane := range fairLanes {
+		laneConcurrency := fairCfg.Concurrency
+		if n, ok := concurrency[lane]; ok {
+			laneConcurrency = n
+		}
+		b.fairSchedulers[lane] = processor.NewFairScheduler(processor.FairSchedulerConfig{
+			Lane:           lane,
+			Mode:           fairCfg.Mode,
+			Concurrency:    laneConcurrency,
+			PerSchoolLimit: fairCfg.PerSchoolLimit,
+		}, settings, b.logger)
+	}
+	b.logger.Info("✅ Reparto por escuela habilitado",
+		"mode", fairCfg.Mode, "lanes", fairLanes, "buffer", fairCfg.BufferFor(fairCfg.Concurrency),
+		"concurrency", fairCfg.Concurrency, "per_school_limit", fairCfg.PerSchoolLimit)
+	return nil
+}
+
+// pauseLanesWhileLLMDown suscribe los carriles que dependen del provider local al
+// monitor de disponibilidad: caído → PauseBy, disponible → ResumeBy, con su propio
+// titular para no pisar una pausa manual. Falla si la config nombra un carril
+// inexistente.
+func (b *ResourceBuilder) pauseLanesWhileLLMDown() error {
+	lanes, err := b.resolveLaneNames("llm.monitor.lanes",
+		b.config.GetLLMConfigWithDefaults().Monitor.Lanes, len(b.config.LLM.Monitor.Lanes) > 0)
+	if err != nil {
+		return err
+	}
+
+	const holder = "llm:local"
+	b.llmMonitor.OnChange(func(up bool) {
+		for _, lane := range lanes {
+			op := b.laneController.PauseBy
+			if up {
+				op = b.laneController.ResumeBy
+			}
+			if err := op(lane, holder); err != nil {
+				b.logger.Error("no se pudo cambiar la pausa del carril por disponibilidad del LLM",
+					"lane", lane, "llm_up", up, "error", err.Error())
+			}
+		}
+	})
+	b.logger.Info("LLM availability monitor wired", "provider", "local", "lanes", lanes)
+	return nil
+}
+
+// buildMiddlewares arma la cadena de interceptores del registry, idéntica para los
+// tres consumers. Orden (externo → interno): logging con contexto del evento,
+// idempotencia (un redelivery completado se ACKea antes de esperar al limiter),
+// rate limiting (la espera no cuenta como procesamiento), métricas, recovery de
+// panics (antes de métricas/logging para que vean el error) y deadline por
+// event_type. idemStore nil omite la idempotencia.
+func (b *ResourceBuilder) buildMiddlewares(idemStore processor.IdempotencyStore) []processor.Middleware {
+	mws := []processor.Middleware{processor.LoggingMiddleware(b.logger)}
+
+	if idemStore != nil {
+		mws = append(mws, processor.IdempotencyMiddleware(idemStore, b.logger))
+	}
+
+	if limiter := b.buildRateLimiter(); limiter != nil {
+		mws = append(mws, processor.RateLimitMiddleware(limiter))
+	}
+
+	procCfg := b.config.GetProcessingConfigWithDefaults()
+	mws = append(mws,
+		processor.MetricsMiddleware(),
+		processor.RecoveryMiddleware(b.logger),
+		processor.DeadlineMiddleware(procCfg.TimeoutsByEventType(), procCfg.DefaultTimeout),
+	)
+	return mws
+}
+
+// buildIdempotencyStore crea el store de idempotencia según config, o nil si está
+// deshabilitado. El backend file registra su cierre como cleanup.
+func (b *ResourceBuilder) buildIdempotencyStore() (processor.IdempotencyStore, error) {
+	idemCfg := b.config.GetIdempotencyConfigWithDefaults()
+	if !idemCfg.Enabled {
+		b.logger.Info("⚠️  Idempotency store deshabilitado")
+		return nil, nil
+	}
+
+	switch idemCfg.Backend {
+	case config.IdempotencyBackendMemory:
+		b.logger.Info("✅ Idempotency store habilitado",
+			"backend", idemCfg.Backend, "capacity", idemCfg.Capacity, "ttl", idemCfg.TTL.String())
+		return idempotency.NewMemoryStore(idemCfg.Capacity, idemCfg.TTL), nil
+	case config.IdempotencyBackendFile:
+		store, err := idempotency.NewFileStore(idemCfg.FilePath, idemCfg.Capacity, idemCfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open idempotency store: %w", err)
+		}
+		b.addCleanup(func() error {
+			b.logger.Info("closing idempotency store")
+			return store.Close()
+		})
+		b.logger.Info("✅ Idempotency store habilitado",
+			"backend", idemCfg.Backend, "path", idemCfg.FilePath, "capacity", idemCfg.Capacity, "ttl", idemCfg.TTL.String())
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency backend: %q", idemCfg.Backend)
+	}
+}
+
+// buildRateLimiter crea el rate limiter por event_type desde config, o nil si está
+// deshabilitado.
+func (b *ResourceBuilder) buildRateLimiter() *ratelimiter.MultiRateLimiter {
+	rateLimiterCfg := b.config.GetRateLimiterConfigWithDefaults()
+	if !rateLimiterCfg.Enabled {
+		b.logger.Info("⚠️  Rate limiter deshabilitado")
+		return nil
+	}
+
+	// Convertir configuración a formato esperado por MultiRateLimiter
+	configs := make(map[string]ratelimiter.Config)
+	for eventType, eventCfg := range rateLimiterCfg.ByEventType {
+		configs[eventType] = ratelimiter.Config{
+			RequestsPerSecond: eventCfg.RequestsPerSecond,
+			BurstSize:         eventCfg.BurstSize,
+		}
+	}
+
+	// Configuración por defecto
+	defaultCfg := &ratelimiter.Config{
+		RequestsPerSecond: rateLimiterCfg.Default.RequestsPerSecond,
+		BurstSize:         rateLimiterCfg.Default.BurstSize,
+	}
+
+	b.logger.Info("✅ Rate limiter habilitado",
+		"configured_events", len(configs),
+		"default_rps", defaultCfg.RequestsPerSecond,
+		"default_burst", defaultCfg.BurstSize)
+	return ratelimiter.NewMulti(configs, defaultCfg)
 }
 
 // cachingChunkTextResolver envuelve GetChunkText con una caché en memoria por chunk_id
@@ -576,16 +1216,16 @@ func (r *cachingChunkTextResolver) ChunkText(ctx context.Context, chunkID string
 // fija b.err si el provider local no expone ScoreRelevance.
 func (b *ResourceBuilder) buildReduceDeps(localProvider llm.LLMProvider, mpCfg config.MaterialPipelineConfig) (processor.ReduceDeps, bool) {
 	// La relevancia necesita ScoreRelevance (fuera de llm.LLMProvider): assert del local.
-	localScorer, ok := localProvider.(relevanceScorer)
+	localScorer, ok := localProvider.(llm.RelevanceScorer)
 	if !ok {
 		b.err = fmt.Errorf("el provider LLM local no implementa ScoreRelevance (reduce fase 2, D-044.3)")
 		return processor.ReduceDeps{}, false
 	}
 	// El provider por API es opcional (RelevanceMode="api"); si falta o no puntúa, la
 	// relevancia cae a local por candidata sin romper el carril.
-	var apiScorer relevanceScorer
+	var apiScorer llm.RelevanceScorer
 	if api := b.llmProviders["api"]; api != nil {
-		if s, ok := api.(relevanceScorer); ok {
+		if s, ok := api.(llm.RelevanceScorer); ok {
 			apiScorer = s
 		} else {
 			b.logger.Warn("provider LLM por API no implementa ScoreRelevance; relevancia mode=api caerá a local")
@@ -638,6 +1278,13 @@ func (b *ResourceBuilder) WithHealthChecks() *ResourceBuilder {
 		b.logger.Info("registered RabbitMQ health check")
 	}
 
+	// Disponibilidad del provider local: no crítica (degraded → ready_degraded); con
+	// el provider caído los carriles dependientes ya están pausados.
+	if b.llmMonitor != nil {
+		checker.Register(b.llmMonitor)
+		b.logger.Info("registered LLM availability health check")
+	}
+
 	// Guardar referencia
 	b.healthChecker = checker
 
@@ -667,17 +1314,16 @@ func (b *ResourceBuilder) WithMetricsServer() *ResourceBuilder {
 
 	b.logger.Info("initializing metrics server", "port", metricsCfg.Port)
 
-	// Crear servidor de métricas con health checker si está disponible
-	var metricsServer *httpInfra.MetricsServer
+	// Crear servidor de métricas con health checker y API de administración si están disponibles
+	serverCfg := httpInfra.MetricsServerConfig{
+		Port:          metricsCfg.Port,
+		HealthChecker: b.healthChecker,
+		Admin:         b.buildAdminHandler(),
+	}
 	if b.healthChecker != nil {
-		metricsServer = httpInfra.NewMetricsServerWithConfig(httpInfra.MetricsServerConfig{
-			Port:          metricsCfg.Port,
-			HealthChecker: b.healthChecker,
-		})
 		b.logger.Info("metrics server configured with health endpoints")
-	} else {
-		metricsServer = httpInfra.NewMetricsServer(metricsCfg.Port)
 	}
+	metricsServer := httpInfra.NewMetricsServerWithConfig(serverCfg)
 
 	// Iniciar servidor en goroutine
 	go func() {
@@ -702,6 +1348,26 @@ func (b *ResourceBuilder) WithMetricsServer() *ResourceBuilder {
 	return b
 }
 
+// buildAdminHandler arma la API de administración (/admin/*). Devuelve nil (no se
+// monta) si está deshabilitada, si falta el token (nunca se expone sin auth) o si
+// aún no hay registry/carriles (WithProcessors no se llamó).
+func (b *ResourceBuilder) buildAdminHandler() *httpInfra.AdminHandler {
+	adminCfg := b.config.Admin
+	if !adminCfg.Enabled {
+		return nil
+	}
+	if adminCfg.Token == "" {
+		b.logger.Warn("admin API habilitada sin token (WORKER_ADMIN_TOKEN): no se monta")
+		return nil
+	}
+	if b.processorRegistry == nil || b.laneController == nil {
+		b.logger.Warn("admin API requiere WithProcessors antes de WithMetricsServer: no se monta")
+		return nil
+	}
+	b.logger.Info("admin API enabled", "endpoints", "/admin/processors, /admin/consumers, /admin/lanes/{lane}/pause|resume")
+	return httpInfra.NewAdminHandler(adminCfg.Token, b.processorRegistry, b.laneController)
+}
+
 // Build construye y retorna Resources con su función de cleanup
 func (b *ResourceBuilder) Build() (*Resources, func() error, error) {
 	// Verificar si hubo errores durante la construcción
@@ -727,15 +1393,19 @@ func (b *ResourceBuilder) Build() (*Resources, func() error, error) {
 		Logger:                 b.logger,
 		RabbitMQConn:           b.rabbitSharedConn,
 		RabbitMQChannel:        b.rabbitChannel,
+		EventPublisher:         b.eventPublisher,
 		AuthClient:             b.authClient,
 		SettingsClient:         b.settingsClient,
 		LearningClient:         b.learningClient,
 		LearningPrepClient:     b.learningPrepClient,
 		LearningPipelineClient: b.learningPipelineClient,
 		LLMProvider:            b.llmProvider,
+		LLMMonitor:             b.llmMonitor,
 		Embedder:               b.embedder,
 		LifecycleManager:       b.lifecycleManager,
 		ProcessorRegistry:      b.processorRegistry,
+		LaneController:         b.laneController,
+		FairSchedulers:         b.fairSchedulers,
 		MetricsServer:          b.metricsServer,
 		HealthChecker:          b.healthChecker,
 		SharedMetrics:          b.sharedMetrics,
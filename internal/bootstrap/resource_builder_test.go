@@ -2,9 +2,21 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/application/processor"
 	"github.com/EduGoGroup/edugo-worker/internal/config"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/memory"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/availability"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/instrument"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/resilience"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/scheduler"
 )
 
 func TestNewResourceBuilder(t *testing.T) {
@@ -165,3 +177,225 @@ func TestResourceBuilder_CleanupOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceBuilder_EventPublisherSobreBrokerEnMemoria(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level: "info",
+		},
+	}
+
+	builder := NewResourceBuilder(ctx, cfg).
+		WithLogger().
+		WithMemoryBroker(memory.NewBroker()).
+		WithEventPublisher()
+
+	if builder.err != nil {
+		t.Fatalf("unexpected error: %v", builder.err)
+	}
+	if builder.eventPublisher == nil {
+		t.Error("expected event publisher over the in-memory broker")
+	}
+	if builder.publisherChannel != nil {
+		t.Error("expected no AMQP publisher channel in memory mode")
+	}
+}
+
+type fakeProcessor struct{ eventType string }
+
+func (p fakeProcessor) EventType() string                         { return p.eventType }
+func (p fakeProcessor) Process(_ context.Context, _ []byte) error { return nil }
+
+func TestResourceBuilder_ValidateLaneEventTypes(t *testing.T) {
+	t.Parallel()
+	cfg := &config.Config{Lanes: []config.LaneConfig{
+		{Name: "review"},
+		{Name: "grading", Queue: "edugo.attempt.grading", EventTypes: []string{"attempt.grading_requested"}},
+	}}
+	builder := NewResourceBuilder(context.Background(), cfg).WithLogger()
+	builder.processorRegistry = processor.NewRegistry(builder.logger)
+	builder.processorRegistry.Register(fakeProcessor{eventType: "attempt.review_requested"})
+
+	err := builder.validateLaneEventTypes()
+	if err == nil {
+		t.Fatal("expected error for an event_type without processor")
+	}
+	if !strings.Contains(err.Error(), `lanes.grading: event_type "attempt.grading_requested"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	builder.processorRegistry.Register(fakeProcessor{eventType: "attempt.grading_requested"})
+	if err := builder.validateLaneEventTypes(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestResourceBuilder_ValidateSchedulerPriorities(t *testing.T) {
+	t.Parallel()
+	cfg := &config.Config{
+		Lanes: []config.LaneConfig{{Name: "review"}, {Name: "material"}},
+		LLM: config.LLMConfig{Scheduler: config.LLMSchedulerConfig{
+			Priorities: map[string]int{"review": 0, "materials": 2, "default": 1},
+		}},
+	}
+	builder := NewResourceBuilder(context.Background(), cfg).WithLogger()
+
+	err := builder.validateSchedulerPriorities(cfg.LLM.Scheduler)
+	if err == nil || !strings.Contains(err.Error(), `llm.scheduler.priorities: carril desconocido "materials"`) {
+		t.Fatalf("expected error for an unknown lane, got %v", err)
+	}
+
+	cfg.LLM.Scheduler.Priorities = map[string]int{"review": 0, "material": 2, "default": 1}
+	if err := builder.validateSchedulerPriorities(cfg.LLM.Scheduler); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBuildLocalProvider_PorBackend(t *testing.T) {
+	t.Parallel()
+	for backend, want := range map[string]string{
+		config.LLMBackendOllama: "ollama:m",
+		config.LLMBackendOpenAI: "openai-compat:m",
+	} {
+		p, err := buildLocalProvider(config.LLMLocalConfig{Backend: backend, Model: "m"}, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("backend %s: unexpected error: %v", backend, err)
+		}
+		if p.Name() != want {
+			t.Errorf("backend %s: expected %s, got %s", backend, want, p.Name())
+		}
+	}
+	if _, err := buildLocalProvider(config.LLMLocalConfig{Backend: "tgi"}, nil, nil, nil); err == nil {
+		t.Error("expected error for an unknown backend")
+	}
+	if _, err := buildLocalProvider(config.LLMLocalConfig{
+		Backend:  config.LLMBackendOpenAI,
+		BaseURLs: []string{"http://a:8000", "http://b:8000"},
+	}, nil, nil, nil); err == nil {
+		t.Error("expected error for base_urls on the openai backend")
+	}
+}
+
+func TestBuildPromptRegistry(t *testing.T) {
+	t.Parallel()
+	registry, err := buildPromptRegistry(config.LLMPromptsConfig{
+		Rollouts: map[string]config.LLMPromptRolloutConfig{
+			"review_open_ended": {Version: "v1", Schools: []string{"school-pilot"}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := registry.Version(prompts.ReviewOpenEnded, "school-pilot"); got != "v1" {
+		t.Errorf("expected the pilot school on v1, got %s", got)
+	}
+	if got := registry.Version(prompts.ReviewOpenEnded, "other"); got != prompts.Builtin {
+		t.Errorf("expected other schools on %s, got %s", prompts.Builtin, got)
+	}
+	if _, err := buildPromptRegistry(config.LLMPromptsConfig{
+		Versions: map[string]string{"review_open_ended": "v9"},
+	}, nil); err == nil {
+		t.Error("expected error for an unknown prompt version")
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{})        {}
+func (nopLogger) Info(string, ...interface{})         {}
+func (nopLogger) Warn(string, ...interface{})         {}
+func (nopLogger) Error(string, ...interface{})        {}
+func (nopLogger) Fatal(string, ...interface{})        {}
+func (nopLogger) Sync() error                         { return nil }
+func (l nopLogger) With(...interface{}) logger.Logger { return l }
+
+// blockingProvider retiene ReviewAnswer hasta que se cierra release y devuelve err.
+type blockingProvider struct {
+	llm.LLMProvider
+	started chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (p *blockingProvider) Name() string { return "fake:m" }
+
+func (p *blockingProvider) ReviewAnswer(context.Context, llm.ReviewRequest) (llm.ReviewResult, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 1}, p.err
+}
+
+func TestDecorateLocalProvider_ColaYBreakerNoTumbanElMonitor(t *testing.T) {
+	inner := &blockingProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	monitor := availability.NewMonitor(availability.Config{Provider: "local-test", FailureThreshold: 2}, nil, nopLogger{})
+	guard := resilience.NewGuard(resilience.Config{Name: "llm_test_chain", MaxFailures: 1, OpenTimeout: time.Hour}, nil)
+	sched := scheduler.New(scheduler.Config{Name: "test-chain", Concurrency: 1})
+	p := decorateLocalProvider(inner, instrument.Labels{Provider: "test-chain", Model: "m"}, monitor, guard, sched)
+
+	// Una llamada ocupa el único turno; la siguiente vence esperando en la cola.
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{})
+		done <- err
+	}()
+	<-inner.started
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if _, err := p.ReviewAnswer(ctx, llm.ReviewRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("esperaba vencer en la cola del scheduler: %v", err)
+		}
+		cancel()
+	}
+	if !monitor.Up() {
+		t.Fatal("esperar turno con el backend sano no debe marcarlo caído")
+	}
+
+	// Un fallo real abre el breaker (1 de 2 para el monitor); los rechazos del breaker
+	// abierto no llegan al backend y no cuentan.
+	inner.err = errors.New("connection refused")
+	close(inner.release)
+	if err := <-done; err == nil {
+		t.Fatal("esperaba el error del backend")
+	}
+	for range 3 {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err == nil {
+			t.Fatal("con el breaker abierto esperaba error")
+		}
+	}
+	if !monitor.Up() {
+		t.Error("los rechazos del breaker abierto no deben contar como fallos del backend")
+	}
+}
+
+// scoringProvider es un provider local que sí puntúa relevancia.
+type scoringProvider struct {
+	llm.LLMProvider
+}
+
+func (scoringProvider) Name() string { return "fake:m" }
+
+func (scoringProvider) ScoreRelevance(context.Context, llm.RelevanceRequest) (llm.RelevanceResult, error) {
+	return llm.RelevanceResult{Score: 0.8}, nil
+}
+
+func TestDecorateLocalProvider_ScoreRelevanceSoloSiElLocalLaTiene(t *testing.T) {
+	monitor := availability.NewMonitor(availability.Config{Provider: "local-rel", FailureThreshold: 2}, nil, nopLogger{})
+	guard := resilience.NewGuard(resilience.Config{Name: "llm_test_chain_rel"}, nil)
+	sched := scheduler.New(scheduler.Config{Name: "test-chain-rel", Concurrency: 1})
+	labels := instrument.Labels{Provider: "test-chain-rel", Model: "m"}
+
+	scorer, ok := decorateLocalProvider(scoringProvider{}, labels, monitor, guard, sched).(llm.RelevanceScorer)
+	if !ok {
+		t.Fatal("la cadena debe exponer ScoreRelevance del provider local")
+	}
+	if res, err := scorer.ScoreRelevance(context.Background(), llm.RelevanceRequest{}); err != nil || res.Score != 0.8 {
+		t.Fatalf("resultado inesperado: %+v %v", res, err)
+	}
+
+	plain := &blockingProvider{}
+	if _, ok := decorateLocalProvider(plain, labels, monitor, guard, sched).(llm.RelevanceScorer); ok {
+		t.Error("sin ScoreRelevance en el local la cadena no debe exponerla: el assert de bootstrap tiene que fallar")
+	}
+}
@@ -9,7 +9,7 @@ import (
 // (sin importar) la heurística de internal/infrastructure/nlp/fallback para
 // mantener este paquete puro. Reconoce: líneas TODO EN MAYÚSCULAS, numeración
 // (1., 1.2, romanos I., II.…), palabras clave de capítulo/sección/tema/unidad/
-// parte (es-ES e inglés) y líneas cortas sin punto final.
+// parte (español, portugués e inglés) y líneas cortas sin punto final.
 func isTitleLine(line string) bool {
 	line = strings.TrimSpace(line)
 	if len(line) == 0 || len(line) >= 100 {
@@ -37,12 +37,14 @@ func isTitleLine(line string) bool {
 		}
 	}
 
-	// Palabras clave de encabezado (insensible a mayúsculas), es-ES e inglés.
+	// Palabras clave de encabezado (insensible a mayúsculas): es, pt y en.
 	lower := strings.ToLower(line)
 	keywordPrefixes := []string{
 		"chapter ", "capítulo ", "capitulo ",
-		"sección ", "seccion ", "section ",
-		"tema ", "unidad ", "parte ", "part ",
+		"sección ", "seccion ", "seção ", "secção ", "secao ", "section ",
+		"tema ", "unidad ", "unidade ", "unit ",
+		"lección ", "leccion ", "lição ", "licao ", "lesson ",
+		"parte ", "part ",
 	}
 	for _, kw := range keywordPrefixes {
 		if strings.HasPrefix(lower, kw) {
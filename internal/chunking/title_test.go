@@ -19,6 +19,9 @@ func TestIsTitleLine(t *testing.T) {
 		{"keyword tema", "Tema 4 — La fotosíntesis", true},
 		{"keyword unidad", "Unidad 2", true},
 		{"keyword inglés chapter", "Chapter One", true},
+		{"keyword portugués seção", "Seção 2 — A respiração celular", true},
+		{"keyword portugués unidade", "Unidade 5", true},
+		{"keyword inglés lesson", "Lesson 3: the water cycle", true},
 		{"línea corta sin punto", "Los verbos irregulares", true},
 		{"frase normal con punto", "La célula es la unidad básica de la vida.", false},
 		{"párrafo largo", "En este apartado analizaremos con cierto detalle cómo las plantas transforman la energía luminosa en energía química mediante un proceso conocido", false},
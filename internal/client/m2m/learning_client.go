@@ -62,13 +62,35 @@ type PendingAnswersResponse struct {
 	SchoolID     string          `json:"school_id"`
 	Status       string          `json:"status"`
 	Answers      []PendingAnswer `json:"answers"`
+	// FlaggedAnswers cuenta las respuestas del intento YA revisadas con
+	// needs_teacher_review (no aparecen en Answers). Un redelivery las necesita para
+	// no finalizar un intento que una entrega anterior dejó para el profesor. nil = el
+	// learning no lo informa (anterior al contrato de confianza): no se sabe si hay
+	// marcadas, y no es lo mismo que 0.
+	FlaggedAnswers *int `json:"flagged_answers"`
 }
 
 // AnswerReviewRequest es el body de POST answers/{answerID}/review. Idempotente
 // (upsert) del lado de learning: reintentar es seguro.
+//
+// AIAgreement, AIConfidence y NeedsTeacherReview son del contrato de confianza de
+// learning (el mismo que informa FlaggedAnswers). Un learning anterior los ignora: la
+// review se guarda sin marca y el profesor no la ve destacada. El worker igual deja
+// sin finalizar el intento con respuestas de baja confianza, así que lo único que se
+// pierde es la marca.
 type AnswerReviewRequest struct {
 	PointsAwarded float64 `json:"points_awarded"`
 	Feedback      string  `json:"feedback"`
+	// AIAgreement es la fracción de muestras que coincidieron con el veredicto cuando
+	// la escuela corrige por votación (llm.review.samples > 1). nil = una sola muestra:
+	// se omite del body y learning lo trata como ausente.
+	AIAgreement *float64 `json:"ai_agreement,omitempty"`
+	// AIConfidence es la confianza del veredicto (0..1: logprobs, votación o el juicio
+	// menos firme del carril). nil = el carril no produjo señal; se omite.
+	AIConfidence *float64 `json:"ai_confidence,omitempty"`
+	// NeedsTeacherReview marca la review como de baja confianza (bajo el umbral
+	// llm.review.min_confidence de la escuela): el profesor debe visarla.
+	NeedsTeacherReview bool `json:"needs_teacher_review,omitempty"`
 }
 
 // AnswerReviewResponse es la respuesta de POST review.
@@ -100,6 +122,8 @@ type LearningClientConfig struct {
 	BaseURL string
 	// Timeout de la request HTTP (default 5s).
 	Timeout time.Duration
+	// Transport opcional (nil = http.DefaultTransport).
+	Transport http.RoundTripper
 	// TokenProvider firma/obtiene el service JWT (audience edugo-api-learning,
 	// scope attempts.review).
 	TokenProvider TokenProvider
@@ -113,7 +137,7 @@ func NewLearningClient(cfg LearningClientConfig) *LearningClient {
 	}
 	return &LearningClient{
 		baseURL:       strings.TrimRight(cfg.BaseURL, "/"),
-		httpClient:    &http.Client{Timeout: timeout},
+		httpClient:    &http.Client{Timeout: timeout, Transport: cfg.Transport},
 		tokenProvider: cfg.TokenProvider,
 	}
 }
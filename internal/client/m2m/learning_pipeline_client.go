@@ -205,6 +205,8 @@ type LearningPipelineClientConfig struct {
 	BaseURL string
 	// Timeout de la request HTTP (default 5s).
 	Timeout time.Duration
+	// Transport opcional (nil = http.DefaultTransport).
+	Transport http.RoundTripper
 	// TokenProvider firma/obtiene el service JWT (audience edugo-api-learning, scope
 	// materials.pipeline).
 	TokenProvider TokenProvider
@@ -218,7 +220,7 @@ func NewLearningPipelineClient(cfg LearningPipelineClientConfig) *LearningPipeli
 	}
 	return &LearningPipelineClient{
 		baseURL:       strings.TrimRight(cfg.BaseURL, "/"),
-		httpClient:    &http.Client{Timeout: timeout},
+		httpClient:    &http.Client{Timeout: timeout, Transport: cfg.Transport},
 		tokenProvider: cfg.TokenProvider,
 	}
 }
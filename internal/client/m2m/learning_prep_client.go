@@ -65,6 +65,8 @@ type LearningPrepClientConfig struct {
 	BaseURL string
 	// Timeout de la request HTTP (default 5s).
 	Timeout time.Duration
+	// Transport opcional (nil = http.DefaultTransport).
+	Transport http.RoundTripper
 	// TokenProvider firma/obtiene el service JWT (audience edugo-api-learning, scope
 	// questions.prep — distinto del de revisión).
 	TokenProvider TokenProvider
@@ -78,7 +80,7 @@ func NewLearningPrepClient(cfg LearningPrepClientConfig) *LearningPrepClient {
 	}
 	return &LearningPrepClient{
 		baseURL:       strings.TrimRight(cfg.BaseURL, "/"),
-		httpClient:    &http.Client{Timeout: timeout},
+		httpClient:    &http.Client{Timeout: timeout, Transport: cfg.Transport},
 		tokenProvider: cfg.TokenProvider,
 	}
 }
@@ -168,7 +168,7 @@ func TestLearningClient_GetPendingAnswers(t *testing.T) {
 		if got := r.Header.Get("Authorization"); got != "Bearer t" {
 			t.Errorf("Authorization esperado 'Bearer t', hubo %q", got)
 		}
-		_, _ = w.Write([]byte(`{"attempt_id":"att-1","answers":[{"answer_id":"a1","points":10}]}`))
+		_, _ = w.Write([]byte(`{"attempt_id":"att-1","answers":[{"answer_id":"a1","points":10}],"flagged_answers":2}`))
 	}))
 	defer srv.Close()
 
@@ -177,7 +177,7 @@ func TestLearningClient_GetPendingAnswers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetPendingAnswers falló: %v", err)
 	}
-	if len(resp.Answers) != 1 || resp.Answers[0].AnswerID != "a1" || resp.Answers[0].Points != 10 {
+	if len(resp.Answers) != 1 || resp.Answers[0].AnswerID != "a1" || resp.Answers[0].Points != 10 || resp.FlaggedAnswers == nil || *resp.FlaggedAnswers != 2 {
 		t.Fatalf("respuesta inesperada: %+v", resp)
 	}
 }
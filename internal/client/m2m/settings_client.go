@@ -48,6 +48,9 @@ type SettingsClientConfig struct {
 	BaseURL string
 	// Timeout de la request HTTP (default 5s).
 	Timeout time.Duration
+	// Transport opcional de las requests (nil = http.DefaultTransport): los tests
+	// reproducen academic desde un cassette.
+	Transport http.RoundTripper
 	// CacheTTL del valor resuelto por school_id (default 60s, TTL corto: el
 	// riesgo de config leída por M2M en runtime se mitiga con caché corta,
 	// design 039 §7).
@@ -88,7 +91,7 @@ func NewSettingsClient(cfg SettingsClientConfig) *SettingsClient {
 	}
 	return &SettingsClient{
 		baseURL:       strings.TrimRight(cfg.BaseURL, "/"),
-		httpClient:    &http.Client{Timeout: timeout},
+		httpClient:    &http.Client{Timeout: timeout, Transport: cfg.Transport},
 		tokenProvider: cfg.TokenProvider,
 		cacheTTL:      ttl,
 		cache:         make(map[string]settingsCacheEntry),
@@ -22,7 +22,12 @@ type Config struct {
 	Health           HealthConfig           `mapstructure:"health"`
 	CircuitBreakers  CircuitBreakersConfig  `mapstructure:"circuit_breakers"`
 	RateLimiter      RateLimiterConfig      `mapstructure:"rate_limiter"`
+	Processing       ProcessingConfig       `mapstructure:"processing"`
+	Idempotency      IdempotencyConfig      `mapstructure:"idempotency"`
 	Shutdown         ShutdownConfig         `mapstructure:"shutdown"`
+	Admin            AdminConfig            `mapstructure:"admin"`
+	FairScheduling   FairSchedulingConfig   `mapstructure:"fair_scheduling"`
+	Lanes            []LaneConfig           `mapstructure:"lanes"`
 }
 
 type MessagingConfig struct {
@@ -81,6 +86,65 @@ type QueuesConfig struct {
 	// generación de una evaluación desde un material. Canal propio por riel (D-043): NO
 	// comparte cola con revisión ni preparación.
 	MaterialAssessmentRequested string `mapstructure:"material_assessment_requested"`
+	// Retry configura, por carril, los reintentos diferidos en el broker (colas de
+	// retry con TTL que devuelven el mensaje a su cola al expirar).
+	Retry QueueRetryConfig `mapstructure:"retry"`
+}
+
+// QueueRetryConfig agrupa los reintentos diferidos de cada carril. Los campos se
+// llaman como la cola del carril en QueuesConfig.
+type QueueRetryConfig struct {
+	AttemptReviewRequested      RetryTiersConfig `mapstructure:"attempt_review_requested"`
+	QuestionPrepRequested       RetryTiersConfig `mapstructure:"question_prep_requested"`
+	MaterialAssessmentRequested RetryTiersConfig `mapstructure:"material_assessment_requested"`
+}
+
+// RetryTiersConfig configura los reintentos diferidos de una cola. Con Enabled, un
+// fallo transitorio se re-publica en la cola de retry del siguiente escalón (TTL =
+// Tiers[n]) en vez de reintentarse en proceso; agotados los escalones, el mensaje
+// va a la DLQ del carril. Sin Enabled el carril usa el consumer compartido.
+type RetryTiersConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Tiers son los retrasos de cada escalón, en orden. Default 10s, 1m, 10m.
+	Tiers []time.Duration `mapstructure:"tiers"`
+}
+
+// LaneConfig declara un carril del worker: la cola que consume, los bindings que
+// la alimentan, su DLQ, cuánto consume a la vez y los event_type que acepta. main
+// declara la topología y crea un consumer por carril a partir de esta lista: sumar
+// un carril es sumar una entrada, no otro bloque de consumer en main.
+type LaneConfig struct {
+	// Name identifica el carril en la API de administración, las métricas,
+	// fair_scheduling.lanes y llm.monitor.lanes.
+	Name string `mapstructure:"name"`
+	// Consumer es el consumer tag. Default edugo-worker-<name>.
+	Consumer string `mapstructure:"consumer"`
+	// Queue es la cola del carril (durable, con prioridad y dead-letter a DLQ).
+	Queue string `mapstructure:"queue"`
+	// Bindings son los exchange + routing key que enrutan mensajes a Queue. Un
+	// exchange que no existe se declara topic.
+	Bindings []LaneBindingConfig `mapstructure:"bindings"`
+	// DLQ es la cola muerta del carril (y su routing key en el DLX). Default
+	// Queue + ".dlq".
+	DLQ string `mapstructure:"dlq"`
+	// Prefetch son los mensajes entregados sin ACK. Default prefetch_count, o el
+	// buffer de fair_scheduling (FairSchedulingConfig.BufferFor) si el carril tiene
+	// reparto por escuela.
+	Prefetch int `mapstructure:"prefetch"`
+	// Concurrency acota los mensajes que se procesan a la vez (0 = Prefetch). En un
+	// carril con reparto por escuela reemplaza a fair_scheduling.concurrency.
+	Concurrency int `mapstructure:"concurrency"`
+	// EventTypes son los event_type que acepta el carril. Cada uno necesita un
+	// processor registrado: el worker no arranca si falta alguno.
+	EventTypes []string `mapstructure:"event_types"`
+	// Retry son los reintentos diferidos del carril.
+	Retry RetryTiersConfig `mapstructure:"retry"`
+}
+
+// LaneBindingConfig es un binding de la cola de un carril.
+type LaneBindingConfig struct {
+	Exchange   string `mapstructure:"exchange"`
+	RoutingKey string `mapstructure:"routing_key"`
 }
 
 type ExchangeConfig struct {
@@ -145,6 +209,13 @@ type MetricsConfig struct {
 	Port    int  `mapstructure:"port"`
 }
 
+// AdminConfig configura la API de administración (/admin/*) que se monta en el
+// servidor de métricas. Sin Token la API no se monta aunque esté habilitada.
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token"` // bearer token; env WORKER_ADMIN_TOKEN
+}
+
 type HealthConfig struct {
 	Timeouts HealthTimeoutsConfig `mapstructure:"timeouts"`
 }
@@ -257,31 +328,176 @@ type ServiceJWTConfig struct {
 // la política, que se lee vía M2M. Estos valores se inyectan al constructor del
 // provider —el provider NUNCA lee env directo—.
 type LLMConfig struct {
-	Local LLMLocalConfig `mapstructure:"local"`
-	API   LLMAPIConfig   `mapstructure:"api"`
-	Embed LLMEmbedConfig `mapstructure:"embed"`
+	Local   LLMLocalConfig   `mapstructure:"local"`
+	API     LLMAPIConfig     `mapstructure:"api"`
+	Embed   LLMEmbedConfig   `mapstructure:"embed"`
+	Monitor LLMMonitorConfig `mapstructure:"monitor"`
+	Cache   LLMCacheConfig   `mapstructure:"cache"`
+	// Resilience son el circuit breaker y el bulkhead de cada backend (local, API,
+	// embeddings).
+	Resilience LLMResilienceConfig `mapstructure:"resilience"`
+	// Scheduler ordena las llamadas de los carriles por prioridad.
+	Scheduler LLMSchedulerConfig `mapstructure:"scheduler"`
+	// Prompts elige la versión de cada prompt y la reparte entre escuelas.
+	Prompts LLMPromptsConfig `mapstructure:"prompts"`
+	// Review configura el carril de corrección de attempts.
+	Review LLMReviewConfig `mapstructure:"review"`
+}
+
+// LLMReviewConfig configura la corrección de un attempt.
+type LLMReviewConfig struct {
+	// Concurrency son las respuestas de un mismo attempt que se corrigen a la vez. Las
+	// llamadas siguen acotadas por resilience.max_in_flight y el scheduler. Default 4.
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+// LLMPromptsConfig configura las versiones de los prompts (internal/llm/prompts). Las
+// claves son ids de prompt (review_open_ended, digest_summary…); las versiones son
+// "builtin" (el builder en Go) o el nombre de una plantilla embebida o de Dir.
+type LLMPromptsConfig struct {
+	// Dir tiene plantillas <id>/<version>.tmpl que se suman a las embebidas. Opcional.
+	Dir string `mapstructure:"dir"`
+	// Versions es la versión por defecto de cada prompt (ausente = builtin).
+	Versions map[string]string `mapstructure:"versions"`
+	// Rollouts son las versiones candidatas por prompt, para un grupo de escuelas.
+	Rollouts map[string]LLMPromptRolloutConfig `mapstructure:"rollouts"`
 }
 
-// LLMEmbedConfig configura el cliente de embeddings local (Ollama, plan 044 D-044.1).
+// LLMPromptRolloutConfig reparte una versión candidata de un prompt: las escuelas de
+// Schools y un Percent estable del resto la reciben.
+type LLMPromptRolloutConfig struct {
+	Version string   `mapstructure:"version"`
+	Schools []string `mapstructure:"schools"`
+	Percent int      `mapstructure:"percent"`
+}
+
+// LLMSchedulerConfig configura el scheduler de llamadas LLM: todas las llamadas a un
+// backend esperan turno y, al liberarse un cupo, pasa primero la del carril más
+// prioritario. El local lo comparten revisión, preparación y materiales.
+type LLMSchedulerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Concurrency son las llamadas en curso a la vez por backend. Default
+	// resilience.max_in_flight; si es menor, manda este tope y el del bulkhead (con su
+	// cupo de lote) no llega a actuar.
+	Concurrency int `mapstructure:"concurrency"`
+	// Priorities es la prioridad de cada carril (menor número, antes). La clase
+	// "default" cubre las llamadas sin carril; si falta, toma la prioridad más baja.
+	// Default review: 0, prep: 1, material: 2.
+	Priorities map[string]int `mapstructure:"priorities"`
+}
+
+// LLMResilienceConfig configura el circuit breaker y el tope de llamadas en vuelo de
+// cada backend LLM. Los carriles comparten el provider local: el tope de lote deja
+// cupos libres para revisión y preparación mientras corre un job de materiales.
+type LLMResilienceConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxFailures son los fallos de infraestructura consecutivos que abren el breaker.
+	// Default 5.
+	MaxFailures int `mapstructure:"max_failures"`
+	// OpenTimeout es lo que el breaker queda abierto antes de probar. Default 30s.
+	OpenTimeout time.Duration `mapstructure:"open_timeout"`
+	// MaxInFlight acota las llamadas en vuelo por backend. Default 4.
+	MaxInFlight int `mapstructure:"max_in_flight"`
+	// MaxBulkInFlight acota las del carril de materiales (digest, candidatas,
+	// relevancia, embeddings). Default 2.
+	MaxBulkInFlight int `mapstructure:"max_bulk_in_flight"`
+}
+
+// Stores del cache de respuestas LLM (llm.cache.store).
+const (
+	LLMCacheStoreMemory = "memory"
+	LLMCacheStoreDisk   = "disk"
+)
+
+// LLMCacheConfig configura el cache de respuestas del provider local y del cliente de
+// embeddings: las redeliveries repiten prompts idénticos y a temperatura 0 la
+// respuesta es la misma. Las llamadas con temperatura > 0 no se cachean.
+type LLMCacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store: memory (LRU, default) | disk (un archivo por entrada en Dir).
+	Store string `mapstructure:"store"`
+	// Dir del store disk.
+	Dir string `mapstructure:"dir"`
+	// MaxEntries acota el store memory. Default 10000.
+	MaxEntries int `mapstructure:"max_entries"`
+	// TTL de cada entrada. Default 24h.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// LLMMonitorConfig configura el monitor de disponibilidad del provider local: una
+// sonda periódica (GET /api/tags) más los fallos observados en las llamadas. Con el
+// provider caído se pausan los carriles de Lanes y se reanudan solos al volver.
+type LLMMonitorConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`
+	ProbeTimeout     time.Duration `mapstructure:"probe_timeout"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // fallos consecutivos → down
+	// Lanes son los carriles que se pausan con el provider local caído. Default
+	// material: review/prep también sirven escuelas con mode=api.
+	Lanes []string `mapstructure:"lanes"`
+}
+
+// Backends del provider local y del cliente de embeddings (llm.local.backend,
+// llm.embed.backend).
+const (
+	// LLMBackendOllama habla con Ollama (/api/generate, /api/embed). Default.
+	LLMBackendOllama = "ollama"
+	// LLMBackendOpenAI habla con un servidor OpenAI-compatible
+	// (/v1/chat/completions, /v1/embeddings): vLLM, llama.cpp server, LM Studio.
+	LLMBackendOpenAI = "openai"
+)
+
+// LLMEmbedConfig configura el cliente de embeddings local (plan 044 D-044.1).
 // Pieza SEPARADA del provider LLM: modelo de embeddings chico y dedicado, endpoint
-// distinto (/api/embed). Sin temperatura (embeder es determinista). Env:
-// LLM_EMBED_BASE_URL, LLM_EMBED_MODEL, LLM_EMBED_TIMEOUT.
+// distinto (/api/embed en Ollama, /v1/embeddings en OpenAI-compatible). Sin
+// temperatura (embeder es determinista). Env: LLM_EMBED_BASE_URL, LLM_EMBED_MODEL,
+// LLM_EMBED_TIMEOUT, LLM_EMBED_BACKEND, LLM_EMBED_API_KEY.
 type LLMEmbedConfig struct {
-	BaseURL string        `mapstructure:"base_url"`
-	Model   string        `mapstructure:"model"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	BaseURL string `mapstructure:"base_url"`
+	// BaseURLs reparte los lotes entre varios hosts Ollama (ver LLMLocalConfig.BaseURLs).
+	BaseURLs []string      `mapstructure:"base_urls"`
+	Model    string        `mapstructure:"model"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// Backend: ollama (default) | openai.
+	Backend string `mapstructure:"backend"`
+	// APIKey opcional del backend openai (vLLM --api-key).
+	APIKey string `mapstructure:"api_key"`
 }
 
-// LLMLocalConfig configura el provider local (Ollama). Env: LLM_LOCAL_BASE_URL,
-// LLM_LOCAL_MODEL.
+// LLMLocalConfig configura el provider local (Ollama u OpenAI-compatible). Env:
+// LLM_LOCAL_BASE_URL, LLM_LOCAL_MODEL, LLM_LOCAL_BACKEND, LLM_LOCAL_API_KEY.
 type LLMLocalConfig struct {
-	BaseURL string        `mapstructure:"base_url"`
-	Model   string        `mapstructure:"model"`
-	Timeout time.Duration `mapstructure:"timeout"`
+	BaseURL string `mapstructure:"base_url"`
+	// BaseURLs son varios hosts Ollama de inferencia; si está, reemplaza a BaseURL. Las
+	// requests van al host sano con menos requests en curso (las de un mismo job de
+	// materiales, siempre al mismo), un host sale del reparto si falla su sonda
+	// /api/tags y una request que falla por el host se reintenta una vez en otro. Solo
+	// backend ollama.
+	BaseURLs []string      `mapstructure:"base_urls"`
+	Model    string        `mapstructure:"model"`
+	Timeout  time.Duration `mapstructure:"timeout"`
 	// Temperature del muestreo local. Default 0 (greedy determinista): la
 	// corrección pide JSON estructurado, no prosa creativa, y el determinismo la
 	// hace reproducible. Env: LLM_LOCAL_TEMPERATURE.
 	Temperature float64 `mapstructure:"temperature"`
+	// Backend: ollama (default) | openai.
+	Backend string `mapstructure:"backend"`
+	// APIKey opcional del backend openai (vLLM --api-key).
+	APIKey string `mapstructure:"api_key"`
+	// ResponseFormat del backend openai: json_object (default) | json_schema | none.
+	ResponseFormat string `mapstructure:"response_format"`
+	// ContextWindow es el num_ctx máximo por request del backend ollama (lo que el
+	// host soporta en memoria). Los prompts que no caben se recortan (llm.TokenBudget).
+	// Default 8192; -1 desactiva el presupuesto. Env: LLM_LOCAL_CONTEXT_WINDOW.
+	ContextWindow int `mapstructure:"context_window"`
+	// OutputReserve son los tokens de la ventana reservados para la salida. Default 1024.
+	OutputReserve int `mapstructure:"output_reserve"`
+	// MinContext es el piso del num_ctx por request. Default 4096.
+	MinContext int `mapstructure:"min_context"`
+	// Logprobs pide los logprobs de los juicios de corrección: la confianza del
+	// veredicto sale de la probabilidad de sus tokens (llm.review.min_confidence).
+	// Default false (sin señal de logprobs). Env: LLM_LOCAL_LOGPROBS.
+	Logprobs bool `mapstructure:"logprobs"`
 }
 
 // LLMAPIConfig configura el provider por API (Claude/Gemini). Env:
@@ -300,6 +516,44 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("RABBITMQ_URL is required")
 	}
 	// NLP.APIKey es opcional - si no está, usamos SmartFallback
+	return validateLanes(c.GetLanesConfigWithDefaults())
+}
+
+// validateLanes revisa la forma de los carriles. Que cada event_type tenga
+// processor se valida al armar el registry (bootstrap).
+func validateLanes(lanes []LaneConfig) error {
+	names := make(map[string]bool, len(lanes))
+	queues := make(map[string]string, len(lanes))
+	for i, l := range lanes {
+		switch {
+		case l.Name == "":
+			return fmt.Errorf("lanes[%d]: name requerido", i)
+		case names[l.Name]:
+			return fmt.Errorf("lanes: carril %q duplicado", l.Name)
+		case l.Queue == "":
+			return fmt.Errorf("lanes.%s: queue requerida", l.Name)
+		case queues[l.Queue] != "":
+			return fmt.Errorf("lanes.%s: la cola %q ya es del carril %q", l.Name, l.Queue, queues[l.Queue])
+		case len(l.EventTypes) == 0:
+			return fmt.Errorf("lanes.%s: event_types requerido", l.Name)
+		case len(l.Bindings) == 0:
+			return fmt.Errorf("lanes.%s: bindings requerido", l.Name)
+		}
+		for _, b := range l.Bindings {
+			if b.Exchange == "" || b.RoutingKey == "" {
+				return fmt.Errorf("lanes.%s: binding sin exchange o routing_key", l.Name)
+			}
+		}
+		if l.Retry.Enabled {
+			for _, tier := range l.Retry.Tiers {
+				if tier <= 0 {
+					return fmt.Errorf("lanes.%s: escalón de retry inválido %s", l.Name, tier)
+				}
+			}
+		}
+		names[l.Name] = true
+		queues[l.Queue] = l.Name
+	}
 	return nil
 }
 
@@ -494,6 +748,21 @@ func (c *Config) GetLLMConfigWithDefaults() LLMConfig {
 	if cfg.Local.Timeout == 0 {
 		cfg.Local.Timeout = 120 * time.Second
 	}
+	if cfg.Local.Backend == "" {
+		cfg.Local.Backend = LLMBackendOllama
+	}
+	if cfg.Local.ContextWindow == 0 {
+		cfg.Local.ContextWindow = 8192
+	}
+	if cfg.Local.OutputReserve == 0 {
+		cfg.Local.OutputReserve = 1024
+	}
+	if cfg.Local.MinContext == 0 {
+		cfg.Local.MinContext = 4096
+	}
+	if cfg.Embed.Backend == "" {
+		cfg.Embed.Backend = LLMBackendOllama
+	}
 	if cfg.API.Provider == "" {
 		cfg.API.Provider = "anthropic"
 	}
@@ -513,6 +782,54 @@ func (c *Config) GetLLMConfigWithDefaults() LLMConfig {
 	if cfg.Embed.Model == "" {
 		cfg.Embed.Model = "embeddinggemma"
 	}
+	if cfg.Cache.Store == "" {
+		cfg.Cache.Store = LLMCacheStoreMemory
+	}
+	if cfg.Cache.MaxEntries == 0 {
+		cfg.Cache.MaxEntries = 10000
+	}
+	if cfg.Cache.TTL == 0 {
+		cfg.Cache.TTL = 24 * time.Hour
+	}
+	if cfg.Resilience.MaxFailures == 0 {
+		cfg.Resilience.MaxFailures = 5
+	}
+	if cfg.Resilience.OpenTimeout == 0 {
+		cfg.Resilience.OpenTimeout = 30 * time.Second
+	}
+	if cfg.Resilience.MaxInFlight == 0 {
+		cfg.Resilience.MaxInFlight = 4
+	}
+	if cfg.Resilience.MaxBulkInFlight == 0 {
+		cfg.Resilience.MaxBulkInFlight = 2
+	}
+	// El scheduler va por fuera del bulkhead: con menos turnos que max_in_flight el
+	// bulkhead (y su tope de lote) nunca actúa. Por defecto coinciden.
+	if cfg.Scheduler.Concurrency == 0 {
+		cfg.Scheduler.Concurrency = cfg.Resilience.MaxInFlight
+	}
+	if len(cfg.Scheduler.Priorities) == 0 {
+		cfg.Scheduler.Priorities = map[string]int{"review": 0, "prep": 1, "material": 2}
+	}
+	if cfg.Review.Concurrency == 0 {
+		cfg.Review.Concurrency = 4
+	}
+	// Monitor de disponibilidad: el carril de materiales usa SOLO el local (ADR 0036
+	// §4), así que por defecto es el único que se pausa. Revisión/preparación lo usan
+	// solo para las escuelas con mode=local: pausarlos detendría también a las de
+	// mode=api, que no tocan el local (sus fallos van por el retry del consumer).
+	if cfg.Monitor.Interval == 0 {
+		cfg.Monitor.Interval = 15 * time.Second
+	}
+	if cfg.Monitor.ProbeTimeout == 0 {
+		cfg.Monitor.ProbeTimeout = 5 * time.Second
+	}
+	if cfg.Monitor.FailureThreshold == 0 {
+		cfg.Monitor.FailureThreshold = 3
+	}
+	if len(cfg.Monitor.Lanes) == 0 {
+		cfg.Monitor.Lanes = []string{"material"}
+	}
 	if cfg.Embed.Timeout == 0 {
 		cfg.Embed.Timeout = 60 * time.Second
 	}
@@ -593,9 +910,19 @@ func (c *Config) GetQueuesConfigWithDefaults() QueuesConfig {
 	if cfg.MaterialAssessmentRequested == "" {
 		cfg.MaterialAssessmentRequested = "edugo.material.assessment.requested"
 	}
+	cfg.Retry.AttemptReviewRequested = cfg.Retry.AttemptReviewRequested.withDefaults()
+	cfg.Retry.QuestionPrepRequested = cfg.Retry.QuestionPrepRequested.withDefaults()
+	cfg.Retry.MaterialAssessmentRequested = cfg.Retry.MaterialAssessmentRequested.withDefaults()
 	return cfg
 }
 
+func (c RetryTiersConfig) withDefaults() RetryTiersConfig {
+	if len(c.Tiers) == 0 {
+		c.Tiers = []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}
+	}
+	return c
+}
+
 // PrepDLQName es el nombre de la cola muerta del carril de preparación. Sigue la
 // convención del carril de revisión (cola + ".dlq"): dead-letters propios por riel.
 func (c QueuesConfig) PrepDLQName() string {
@@ -617,6 +944,119 @@ func (c QueuesConfig) MaterialAssessmentDLQName() string {
 	return q + ".dlq"
 }
 
+// GetLanesConfigWithDefaults retorna los carriles con valores por defecto. Sin
+// `lanes:` en la configuración, los tres carriles históricos (review, prep,
+// material) se derivan de messaging.rabbitmq.queues y dlq; en un carril declarado
+// con uno de esos nombres, los campos vacíos toman los mismos valores (así siguen
+// valiendo los overrides por entorno de las colas).
+func (c *Config) GetLanesConfigWithDefaults() []LaneConfig {
+	legacy := c.legacyLanes()
+	lanes := c.Lanes
+	if len(lanes) == 0 {
+		lanes = legacy
+	}
+
+	prefetch := c.Messaging.RabbitMQ.PrefetchCount
+	if prefetch == 0 {
+		prefetch = 5
+	}
+	fair := c.GetFairSchedulingConfigWithDefaults()
+	fairLanes := make(map[string]bool, len(fair.Lanes))
+	if fair.Enabled {
+		for _, name := range fair.Lanes {
+			fairLanes[name] = true
+		}
+	}
+
+	out := make([]LaneConfig, 0, len(lanes))
+	for _, l := range lanes {
+		for _, base := range legacy {
+			if base.Name == l.Name {
+				l = l.inherit(base)
+			}
+		}
+		if l.Consumer == "" {
+			l.Consumer = "edugo-worker-" + l.Name
+		}
+		if l.DLQ == "" && l.Queue != "" {
+			l.DLQ = l.Queue + ".dlq"
+		}
+		if l.Prefetch == 0 {
+			l.Prefetch = prefetch
+			if fairLanes[l.Name] {
+				concurrency := fair.Concurrency
+				if l.Concurrency > 0 {
+					concurrency = l.Concurrency
+				}
+				l.Prefetch = fair.BufferFor(concurrency)
+			}
+		}
+		l.Retry = l.Retry.withDefaults()
+		out = append(out, l)
+	}
+	return out
+}
+
+// inherit completa los campos vacíos de l con los de base.
+func (l LaneConfig) inherit(base LaneConfig) LaneConfig {
+	if l.Consumer == "" {
+		l.Consumer = base.Consumer
+	}
+	if l.Queue == "" {
+		l.Queue = base.Queue
+	}
+	if len(l.Bindings) == 0 {
+		l.Bindings = base.Bindings
+	}
+	if l.DLQ == "" {
+		l.DLQ = base.DLQ
+	}
+	if len(l.EventTypes) == 0 {
+		l.EventTypes = base.EventTypes
+	}
+	if !l.Retry.Enabled && len(l.Retry.Tiers) == 0 {
+		l.Retry = base.Retry
+	}
+	return l
+}
+
+// legacyLanes son los carriles de revisión (plan 040), preparación (plan 042 F2a)
+// y material→evaluación (plan 043 F3c) tal como se cableaban antes de `lanes:`.
+func (c *Config) legacyLanes() []LaneConfig {
+	queues := c.GetQueuesConfigWithDefaults()
+	exchanges := c.GetExchangesConfigWithDefaults()
+	dlq := c.GetDLQConfigWithDefaults()
+	return []LaneConfig{
+		{
+			Name:       "review",
+			Consumer:   "edugo-worker",
+			Queue:      queues.AttemptReviewRequested,
+			Bindings:   []LaneBindingConfig{{Exchange: exchanges.Assessments, RoutingKey: "attempt.review_requested"}},
+			DLQ:        dlq.DLXRoutingKey,
+			EventTypes: []string{"attempt.review_requested"},
+			Retry:      queues.Retry.AttemptReviewRequested,
+		},
+		{
+			Name:       "prep",
+			Consumer:   "edugo-worker-prep",
+			Queue:      queues.QuestionPrepRequested,
+			Bindings:   []LaneBindingConfig{{Exchange: exchanges.Assessments, RoutingKey: "question.prep_requested"}},
+			DLQ:        queues.PrepDLQName(),
+			EventTypes: []string{"question.prep_requested"},
+			Retry:      queues.Retry.QuestionPrepRequested,
+		},
+		{
+			Name:       "material",
+			Consumer:   "edugo-worker-material",
+			Queue:      queues.MaterialAssessmentRequested,
+			Bindings:   []LaneBindingConfig{{Exchange: exchanges.Materials, RoutingKey: "material.assessment_requested"}},
+			DLQ:        queues.MaterialAssessmentDLQName(),
+			EventTypes: []string{"material.assessment_requested"},
+			Retry:      queues.Retry.MaterialAssessmentRequested,
+		},
+	}
+}
+
 // GetCircuitBreakerConfigWithDefaults retorna la configuración de un circuit breaker con valores por defecto
 func (c *CircuitBreakerConfig) GetWithDefaults() CircuitBreakerConfig {
 	cfg := *c
@@ -663,6 +1103,123 @@ func (c *Config) GetRateLimiterConfigWithDefaults() RateLimiterConfig {
 	return cfg
 }
 
+// ProcessingConfig configura los deadlines de procesamiento por event_type que
+// aplica el DeadlineMiddleware del registry.
+type ProcessingConfig struct {
+	// DefaultTimeout aplica a los event_type sin entrada en Timeouts. Default 0 = sin
+	// deadline: el pipeline de material es batch y puede durar horas.
+	DefaultTimeout time.Duration `mapstructure:"default_timeout"`
+	// Timeouts es una lista (no un mapa) porque viper parte las claves por "." y
+	// los event_type llevan punto (p.ej. material.assessment_requested).
+	Timeouts []EventTimeoutConfig `mapstructure:"timeouts"`
+}
+
+// EventTimeoutConfig es el deadline de procesamiento de un event_type.
+type EventTimeoutConfig struct {
+	EventType string        `mapstructure:"event_type"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+// GetProcessingConfigWithDefaults retorna la configuración de procesamiento con valores por defecto
+func (c *Config) GetProcessingConfigWithDefaults() ProcessingConfig {
+	return c.Processing
+}
+
+// TimeoutsByEventType indexa Timeouts por event_type.
+func (c ProcessingConfig) TimeoutsByEventType() map[string]time.Duration {
+	out := make(map[string]time.Duration, len(c.Timeouts))
+	for _, t := range c.Timeouts {
+		out[t.EventType] = t.Timeout
+	}
+	return out
+}
+
+// FairSchedulingConfig configura el reparto por escuela de los carriles
+// compartidos (processor.FairScheduler).
+type FairSchedulingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Mode es round_robin o weighted (pesos del setting worker.fair_share.weight).
+	Mode string `mapstructure:"mode"`
+	// Lanes son los carriles con reparto (review, prep, material).
+	Lanes []string `mapstructure:"lanes"`
+	// Buffer es el prefetch de cada carril con reparto: los mensajes entregados que
+	// el scheduler puede reordenar. Reemplaza a prefetch_count en esos carriles.
+	// Default y tope: la concurrencia del carril × FairBufferFactor. Lo que espera en
+	// el buffer está entregado sin ACK: un buffer grande lo retiene de las demás
+	// réplicas y alarga la espera de cada mensaje sin mejorar el reparto.
+	Buffer int `mapstructure:"buffer"`
+	// Concurrency son los mensajes que procesa a la vez cada carril con reparto.
+	Concurrency int `mapstructure:"concurrency"`
+	// PerSchoolLimit acota los mensajes de una escuela en proceso a la vez por carril.
+	PerSchoolLimit int `mapstructure:"per_school_limit"`
+}
+
+// FairBufferFactor acota el buffer de un carril con reparto: hasta FairBufferFactor
+// mensajes en espera por cada uno en proceso alcanzan para elegir entre escuelas.
+const FairBufferFactor = 4
+
+// BufferFor es el buffer de un carril con reparto que procesa concurrency mensajes a
+// la vez: Buffer recortado a concurrency × FairBufferFactor (o ese tope si Buffer es 0).
+func (c FairSchedulingConfig) BufferFor(concurrency int) int {
+	limit := concurrency * FairBufferFactor
+	if c.Buffer > 0 && c.Buffer < limit {
+		return c.Buffer
+	}
+	return limit
+}
+
+// GetFairSchedulingConfigWithDefaults retorna la configuración de reparto por escuela con valores por defecto
+func (c *Config) GetFairSchedulingConfigWithDefaults() FairSchedulingConfig {
+	cfg := c.FairScheduling
+	if cfg.Mode == "" {
+		cfg.Mode = "weighted"
+	}
+	if len(cfg.Lanes) == 0 {
+		cfg.Lanes = []string{"review", "material"}
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.PerSchoolLimit == 0 {
+		cfg.PerSchoolLimit = 2
+	}
+	return cfg
+}
+
+// Backends del store de idempotencia.
+const (
+	IdempotencyBackendMemory = "memory" // LRU en memoria (no sobrevive a reinicios)
+	IdempotencyBackendFile   = "file"   // log append-only en disco
+)
+
+// IdempotencyConfig configura el store de resultados por event_id que consulta el
+// registry para ACKear redeliveries de eventos ya completados.
+type IdempotencyConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Backend  string        `mapstructure:"backend"`   // memory | file
+	Capacity int           `mapstructure:"capacity"`  // event_id retenidos (LRU)
+	TTL      time.Duration `mapstructure:"ttl"`       // vigencia de un resultado
+	FilePath string        `mapstructure:"file_path"` // solo backend file
+}
+
+// GetIdempotencyConfigWithDefaults retorna la configuración de idempotencia con valores por defecto
+func (c *Config) GetIdempotencyConfigWithDefaults() IdempotencyConfig {
+	cfg := c.Idempotency
+	if cfg.Backend == "" {
+		cfg.Backend = IdempotencyBackendMemory
+	}
+	if cfg.Capacity == 0 {
+		cfg.Capacity = 10000
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.FilePath == "" {
+		cfg.FilePath = "data/idempotency.jsonl"
+	}
+	return cfg
+}
+
 // ShutdownConfig configuración del graceful shutdown
 type ShutdownConfig struct {
 	Timeout         time.Duration `mapstructure:"timeout"`
@@ -463,3 +463,172 @@ func TestGetActiveNLPConfig_SinConfiguracion_AplicaDefaults(t *testing.T) {
 	assert.Equal(t, 30*time.Second, timeout, "Debería aplicar timeout por defecto")
 	assert.Equal(t, "", baseURL)
 }
+
+func TestGetProcessingConfigWithDefaults(t *testing.T) {
+	cfg := &Config{
+		Processing: ProcessingConfig{
+			Timeouts: []EventTimeoutConfig{
+				{EventType: "material.assessment_requested", Timeout: time.Hour},
+			},
+		},
+	}
+
+	result := cfg.GetProcessingConfigWithDefaults()
+
+	assert.Zero(t, result.DefaultTimeout, "Sin default_timeout no hay deadline")
+	byType := result.TimeoutsByEventType()
+	assert.Equal(t, time.Hour, byType["material.assessment_requested"])
+	_, exists := byType["attempt.review_requested"]
+	assert.False(t, exists, "Un event_type sin entrada usa el default")
+}
+
+func TestGetIdempotencyConfigWithDefaults(t *testing.T) {
+	cfg := &Config{Idempotency: IdempotencyConfig{Enabled: true}}
+
+	result := cfg.GetIdempotencyConfigWithDefaults()
+
+	assert.True(t, result.Enabled)
+	assert.Equal(t, IdempotencyBackendMemory, result.Backend, "Backend por defecto debería ser memory")
+	assert.Equal(t, 10000, result.Capacity)
+	assert.Equal(t, 24*time.Hour, result.TTL)
+	assert.Equal(t, "data/idempotency.jsonl", result.FilePath)
+}
+
+func TestGetQueuesConfigWithDefaults_RetryTiers(t *testing.T) {
+	cfg := &Config{}
+	cfg.Messaging.RabbitMQ.Queues.Retry.QuestionPrepRequested = RetryTiersConfig{
+		Enabled: true,
+		Tiers:   []time.Duration{5 * time.Second, 30 * time.Second},
+	}
+
+	result := cfg.GetQueuesConfigWithDefaults()
+
+	assert.False(t, result.Retry.AttemptReviewRequested.Enabled, "Sin config el carril no usa colas de retry")
+	assert.Equal(t, []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}, result.Retry.AttemptReviewRequested.Tiers)
+	assert.True(t, result.Retry.QuestionPrepRequested.Enabled)
+	assert.Equal(t, []time.Duration{5 * time.Second, 30 * time.Second}, result.Retry.QuestionPrepRequested.Tiers, "Los escalones configurados se respetan")
+}
+
+func TestGetFairSchedulingConfigWithDefaults(t *testing.T) {
+	cfg := &Config{FairScheduling: FairSchedulingConfig{Enabled: true, Concurrency: 8}}
+
+	result := cfg.GetFairSchedulingConfigWithDefaults()
+
+	assert.True(t, result.Enabled)
+	assert.Equal(t, "weighted", result.Mode)
+	assert.Equal(t, []string{"review", "material"}, result.Lanes, "Por defecto reparte los carriles compartidos por todas las escuelas")
+	assert.Equal(t, 0, result.Buffer, "Sin buffer configurado lo deriva cada carril de su concurrencia")
+	assert.Equal(t, 8, result.Concurrency, "La concurrencia configurada se respeta")
+	assert.Equal(t, 2, result.PerSchoolLimit)
+}
+
+func TestFairSchedulingConfig_BufferFor(t *testing.T) {
+	assert.Equal(t, 16, FairSchedulingConfig{}.BufferFor(4), "Default: concurrencia × FairBufferFactor")
+	assert.Equal(t, 10, FairSchedulingConfig{Buffer: 10}.BufferFor(4), "Un buffer menor al tope se respeta")
+	assert.Equal(t, 16, FairSchedulingConfig{Buffer: 50}.BufferFor(4), "Un buffer mayor se recorta al tope")
+}
+
+func TestGetLLMConfigWithDefaults_SchedulerSigueAlBulkhead(t *testing.T) {
+	result := (&Config{}).GetLLMConfigWithDefaults()
+	assert.Equal(t, result.Resilience.MaxInFlight, result.Scheduler.Concurrency, "Por defecto los turnos del scheduler cubren el bulkhead")
+
+	cfg := &Config{LLM: LLMConfig{Resilience: LLMResilienceConfig{MaxInFlight: 8}}}
+	assert.Equal(t, 8, cfg.GetLLMConfigWithDefaults().Scheduler.Concurrency)
+
+	cfg.LLM.Scheduler.Concurrency = 3
+	assert.Equal(t, 3, cfg.GetLLMConfigWithDefaults().Scheduler.Concurrency, "La concurrencia configurada se respeta")
+}
+
+func TestGetLanesConfigWithDefaults_SinLanesDerivaLosCarrilesHistoricos(t *testing.T) {
+	cfg := &Config{FairScheduling: FairSchedulingConfig{Enabled: true}}
+	cfg.Messaging.RabbitMQ.PrefetchCount = 20
+	cfg.Messaging.RabbitMQ.Queues.MaterialAssessmentRequested = "staging.material.requested"
+
+	lanes := cfg.GetLanesConfigWithDefaults()
+
+	assert.Len(t, lanes, 3)
+	review, prep, material := lanes[0], lanes[1], lanes[2]
+	assert.Equal(t, "review", review.Name)
+	assert.Equal(t, "edugo-worker", review.Consumer)
+	assert.Equal(t, "edugo.attempt.review_requested.dlq", review.DLQ)
+	assert.Equal(t, []LaneBindingConfig{{Exchange: "edugo.assessments", RoutingKey: "attempt.review_requested"}}, review.Bindings)
+	assert.Equal(t, 16, review.Prefetch, "Con reparto por escuela el prefetch es el buffer")
+	assert.Equal(t, "edugo-worker-prep", prep.Consumer)
+	assert.Equal(t, 20, prep.Prefetch, "Sin reparto el prefetch es prefetch_count")
+	assert.Equal(t, []string{"question.prep_requested"}, prep.EventTypes)
+	assert.Equal(t, "staging.material.requested", material.Queue)
+	assert.Equal(t, "staging.material.requested.dlq", material.DLQ)
+	assert.Equal(t, "edugo.materials", material.Bindings[0].Exchange)
+}
+
+func TestGetLanesConfigWithDefaults_BufferSigueALaConcurrenciaDelCarril(t *testing.T) {
+	cfg := &Config{
+		FairScheduling: FairSchedulingConfig{Enabled: true, Lanes: []string{"review", "material"}, Buffer: 50},
+		Lanes:          []LaneConfig{{Name: "review"}, {Name: "material", Concurrency: 2}},
+	}
+
+	lanes := cfg.GetLanesConfigWithDefaults()
+
+	assert.Equal(t, 16, lanes[0].Prefetch, "El buffer se recorta a fair_scheduling.concurrency × FairBufferFactor")
+	assert.Equal(t, 8, lanes[1].Prefetch, "lanes[].concurrency reemplaza a la del reparto también en el tope")
+}
+
+func TestGetLanesConfigWithDefaults_LaneDeclarado(t *testing.T) {
+	cfg := &Config{Lanes: []LaneConfig{
+		{Name: "material", Concurrency: 2, Retry: RetryTiersConfig{Enabled: true}},
+		{
+			Name:       "grading",
+			Queue:      "edugo.grading.requested",
+			Bindings:   []LaneBindingConfig{{Exchange: "edugo.grading", RoutingKey: "grading.requested"}},
+			EventTypes: []string{"grading.requested"},
+		},
+	}}
+	cfg.Messaging.RabbitMQ.Queues.MaterialAssessmentRequested = "env.material"
+
+	lanes := cfg.GetLanesConfigWithDefaults()
+
+	assert.Len(t, lanes, 2, "Con lanes declarados solo se consumen esos carriles")
+	material, grading := lanes[0], lanes[1]
+	assert.Equal(t, "env.material", material.Queue, "Un carril histórico hereda su cola de messaging.rabbitmq.queues")
+	assert.Equal(t, "edugo-worker-material", material.Consumer)
+	assert.Equal(t, 2, material.Concurrency)
+	assert.True(t, material.Retry.Enabled)
+	assert.Equal(t, []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}, material.Retry.Tiers)
+	assert.Equal(t, "edugo-worker-grading", grading.Consumer)
+	assert.Equal(t, "edugo.grading.requested.dlq", grading.DLQ)
+	assert.Equal(t, 5, grading.Prefetch)
+	assert.NoError(t, validateLanes(lanes))
+}
+
+func TestValidate_LanesInvalidos(t *testing.T) {
+	base := func() *Config {
+		cfg := &Config{}
+		cfg.Messaging.RabbitMQ.URL = "amqp://localhost"
+		return cfg
+	}
+	lane := func(name, queue string) LaneConfig {
+		return LaneConfig{
+			Name:       name,
+			Queue:      queue,
+			Bindings:   []LaneBindingConfig{{Exchange: "x", RoutingKey: "k"}},
+			EventTypes: []string{"k"},
+		}
+	}
+
+	cases := map[string][]LaneConfig{
+		"carril duplicado":  {lane("a", "q1"), lane("a", "q2")},
+		"cola compartida":   {lane("a", "q1"), lane("b", "q1")},
+		"sin event_types":   {{Name: "a", Queue: "q", Bindings: []LaneBindingConfig{{Exchange: "x", RoutingKey: "k"}}}},
+		"sin bindings":      {{Name: "a", Queue: "q", EventTypes: []string{"k"}}},
+		"binding sin clave": {{Name: "a", Queue: "q", EventTypes: []string{"k"}, Bindings: []LaneBindingConfig{{Exchange: "x"}}}},
+	}
+	for name, lanes := range cases {
+		cfg := base()
+		cfg.Lanes = lanes
+		assert.Error(t, cfg.Validate(), name)
+	}
+
+	cfg := base()
+	cfg.Lanes = []LaneConfig{lane("a", "q1"), lane("b", "q2")}
+	assert.NoError(t, cfg.Validate())
+}
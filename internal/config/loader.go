@@ -8,6 +8,38 @@ import (
 )
 
 func Load() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LocalRabbitMQURL es la URL con la que LoadLocal completa la config: el modo local
+// usa un broker en memoria y no se conecta a RabbitMQ.
+const LocalRabbitMQURL = "memory://local"
+
+// LoadLocal carga la config del modo local (--local). Sin RABBITMQ_URL usa
+// LocalRabbitMQURL, así la validación pasa sin tocar el entorno del proceso.
+func LoadLocal() (*Config, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Messaging.RabbitMQ.URL == "" {
+		cfg.Messaging.RabbitMQ.URL = LocalRabbitMQURL
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// load lee la config (archivos + env) sin validarla.
+func load() (*Config, error) {
 	env := os.Getenv("APP_ENV")
 	if env == "" {
 		env = "local"
@@ -53,16 +85,37 @@ func Load() (*Config, error) {
 			// target_questions por M2M.
 			"material_pipeline.target_questions_default": "MATERIAL_PIPELINE_TARGET_QUESTIONS_DEFAULT",
 			// LLM (plan 039 D-039.3): credenciales/URL/modelo de EduGo, no por escuela.
-			"llm.local.base_url": "LLM_LOCAL_BASE_URL",
-			"llm.local.model":    "LLM_LOCAL_MODEL",
-			"llm.api.provider":   "LLM_API_PROVIDER",
-			"llm.api.api_key":    "LLM_API_KEY",
-			"llm.api.model":      "LLM_API_MODEL",
-			// Embeddings local (plan 044 D-044.1): host/modelo/timeout del cliente Ollama
-			// de embeddings, separado del provider LLM.
+			"llm.local.base_url":       "LLM_LOCAL_BASE_URL",
+			"llm.local.model":          "LLM_LOCAL_MODEL",
+			"llm.local.backend":        "LLM_LOCAL_BACKEND",
+			"llm.local.api_key":        "LLM_LOCAL_API_KEY",
+			"llm.local.context_window": "LLM_LOCAL_CONTEXT_WINDOW",
+			"llm.local.logprobs":       "LLM_LOCAL_LOGPROBS",
+			"llm.api.provider":         "LLM_API_PROVIDER",
+			"llm.api.api_key":          "LLM_API_KEY",
+			"llm.api.model":            "LLM_API_MODEL",
+			// Embeddings local (plan 044 D-044.1): host/modelo/timeout del cliente de
+			// embeddings, separado del provider LLM.
 			"llm.embed.base_url": "LLM_EMBED_BASE_URL",
 			"llm.embed.model":    "LLM_EMBED_MODEL",
 			"llm.embed.timeout":  "LLM_EMBED_TIMEOUT",
+			"llm.embed.backend":  "LLM_EMBED_BACKEND",
+			"llm.embed.api_key":  "LLM_EMBED_API_KEY",
+			// Cache de respuestas LLM: local (memoria) o compartido entre corridas (disco).
+			"llm.cache.enabled": "LLM_CACHE_ENABLED",
+			"llm.cache.store":   "LLM_CACHE_STORE",
+			"llm.cache.dir":     "LLM_CACHE_DIR",
+			// Circuit breaker y tope de llamadas en vuelo por backend LLM.
+			"llm.resilience.enabled":            "LLM_RESILIENCE_ENABLED",
+			"llm.resilience.max_in_flight":      "LLM_MAX_IN_FLIGHT",
+			"llm.resilience.max_bulk_in_flight": "LLM_MAX_BULK_IN_FLIGHT",
+			// Scheduler de llamadas LLM por prioridad de carril.
+			"llm.scheduler.enabled":     "LLM_SCHEDULER_ENABLED",
+			"llm.scheduler.concurrency": "LLM_SCHEDULER_CONCURRENCY",
+			// Respuestas de un attempt corregidas a la vez.
+			"llm.review.concurrency": "LLM_REVIEW_CONCURRENCY",
+			// API de administración (pausa/reanudación por carril): bearer token.
+			"admin.token": "WORKER_ADMIN_TOKEN",
 		}),
 	)
 
@@ -75,9 +128,5 @@ func Load() (*Config, error) {
 		cfg.Logging.Env = env
 	}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, err
-	}
-
 	return &cfg, nil
 }
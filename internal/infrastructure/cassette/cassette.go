@@ -0,0 +1,229 @@
+// Package cassette graba y reproduce tráfico HTTP (cassettes) para correr los
+// clientes LLM (Ollama, API, embeddings) y M2M sin los servicios reales. Un Recorder
+// es un http.RoundTripper que se inyecta por el campo Transport de la config de cada
+// cliente:
+//
+//   - ModeRecord deja pasar las requests al transporte real y guarda cada interacción
+//     en el archivo del cassette (se reescribe tras cada una: un harness que sale con
+//     os.Exit no pierde lo grabado).
+//   - ModeReplay no sale a la red: responde con la interacción grabada que coincide en
+//     método, path y body normalizado (JSON canónico), en el orden en que se grabó.
+//     Una request sin interacción grabada falla: el cassette quedó viejo.
+//
+// Así una salida real del modelo queda congelada como fixture de regresión y corre
+// offline en CI. El cassette NO guarda headers de request ni la query (ahí viajan las
+// credenciales: Authorization, x-api-key, ?key= de Gemini).
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode es el modo de un Recorder.
+type Mode string
+
+// Modos soportados.
+const (
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+// Interaction es un par request/response grabado.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request es la parte de la request con la que se empareja.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response es la respuesta grabada.
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// file es el formato del cassette en disco.
+type file struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder graba o reproduce las requests que pasan por él. Seguro para uso
+// concurrente.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	played       []bool
+}
+
+// New abre el cassette de path en mode. next es el transporte real del modo record
+// (nil = http.DefaultTransport). En replay el archivo debe existir; en record se
+// empieza vacío y se sobrescribe.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next}
+	switch mode {
+	case ModeRecord:
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("creating cassette dir: %w", err)
+		}
+	case ModeReplay:
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		var f file
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+		}
+		r.interactions = f.Interactions
+		r.played = make([]bool, len(f.Interactions))
+	default:
+		return nil, fmt.Errorf("cassette: modo desconocido %q (%s|%s)", mode, ModeRecord, ModeReplay)
+	}
+	return r, nil
+}
+
+// RoundTrip implementa http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := Request{Method: req.Method, Path: req.URL.Path, Body: normalizeBody(body)}
+	if r.mode == ModeReplay {
+		return r.replay(req, key)
+	}
+	return r.record(req, key)
+}
+
+// Remaining devuelve las interacciones grabadas que aún no se reprodujeron.
+func (r *Recorder) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, played := range r.played {
+		if !played {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Recorder) replay(req *http.Request, key Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, it := range r.interactions {
+		if r.played[i] || it.Request != key {
+			continue
+		}
+		r.played[i] = true
+		return it.Response.toHTTP(req), nil
+	}
+	return nil, fmt.Errorf("cassette %s: sin interacción grabada para %s %s", r.path, key.Method, key.Path)
+}
+
+func (r *Recorder) record(req *http.Request, key Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response to record: %w", err)
+	}
+	recorded := Response{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: string(respBody)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, Interaction{Request: key, Response: recorded})
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return recorded.toHTTP(req), nil
+}
+
+// save reescribe el cassette completo (bajo mu).
+func (r *Recorder) save() error {
+	raw, err := json.MarshalIndent(file{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cassette: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(raw, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+func (resp Response) toHTTP(req *http.Request) *http.Response {
+	header := make(http.Header)
+	if resp.ContentType != "" {
+		header.Set("Content-Type", resp.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+// readBody lee el body de req y lo deja rebobinado para el transporte real.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading request to match: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// normalizeBody deja un body JSON en forma canónica (claves ordenadas, sin espacios)
+// para que el orden de los campos no rompa el emparejamiento. Un body que no es JSON
+// se compara tal cual (sin espacios en los bordes).
+func normalizeBody(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return string(body)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(canonical)
+}
@@ -0,0 +1,110 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secreto")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(raw), nil
+}
+
+func TestRecorder_GrabaYReproduce(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if n == 2 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		_, _ = io.WriteString(w, `{"n":`+string(rune('0'+n))+`}`)
+	}))
+	path := filepath.Join(t.TempDir(), "cassettes", "generate.json")
+
+	rec, err := New(path, ModeRecord, nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: rec}
+	for range 3 {
+		_, _, err := post(t, client, srv.URL+"/api/generate?key=secreto", `{"model":"m","prompt":"p"}`)
+		require.NoError(t, err)
+	}
+	srv.Close()
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secreto", "el cassette no guarda headers ni query")
+
+	// Replay sin servidor: mismas respuestas, en el orden grabado, y el body
+	// emparejado sin importar el orden de las claves.
+	play, err := New(path, ModeReplay, nil)
+	require.NoError(t, err)
+	client = &http.Client{Transport: play}
+	for i, want := range []struct {
+		status int
+		body   string
+	}{{http.StatusOK, `{"n":1}`}, {http.StatusBadGateway, `{"n":2}`}, {http.StatusOK, `{"n":3}`}} {
+		status, body, err := post(t, client, "http://offline.invalid/api/generate", `{ "prompt": "p", "model": "m" }`)
+		require.NoError(t, err, "request %d", i)
+		assert.Equal(t, want.status, status)
+		assert.Equal(t, want.body, body)
+	}
+	assert.Zero(t, play.Remaining())
+
+	_, _, err = post(t, client, "http://offline.invalid/api/generate", `{"model":"m","prompt":"p"}`)
+	assert.Error(t, err, "agotadas las interacciones, una request más falla")
+}
+
+func TestRecorder_ReplayEstricto(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"interactions":[{
+		"request":{"method":"POST","path":"/api/embed","body":"{\"input\":[\"a\"],\"model\":\"m\"}"},
+		"response":{"status":200,"body":"{}"}}]}`), 0o644))
+
+	play, err := New(path, ModeReplay, nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: play}
+
+	for name, tc := range map[string]struct{ method, path, body string }{
+		"otro body":   {http.MethodPost, "/api/embed", `{"input":["b"],"model":"m"}`},
+		"otro path":   {http.MethodPost, "/api/generate", `{"input":["a"],"model":"m"}`},
+		"otro método": {http.MethodPut, "/api/embed", `{"input":["a"],"model":"m"}`},
+	} {
+		req, err := http.NewRequest(tc.method, "http://offline.invalid"+tc.path, strings.NewReader(tc.body))
+		require.NoError(t, err)
+		_, err = client.Do(req)
+		assert.Error(t, err, name)
+	}
+	assert.Equal(t, 1, play.Remaining(), "una request que no coincide no consume interacciones")
+
+	status, _, err := post(t, client, "http://offline.invalid/api/embed", `{"model":"m","input":["a"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestNew_Errores(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "no-existe.json"), ModeReplay, nil)
+	assert.Error(t, err, "replay exige el cassette")
+
+	_, err = New(filepath.Join(t.TempDir(), "c.json"), "live", nil)
+	assert.Error(t, err)
+}
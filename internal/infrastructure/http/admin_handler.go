@@ -0,0 +1,134 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/consumer"
+)
+
+// ProcessorLister es la porción del registry que expone la API de administración.
+// *processor.Registry la satisface.
+type ProcessorLister interface {
+	RegisteredTypes() []string
+}
+
+// LaneManager es la porción del control de carriles que expone la API de
+// administración. *consumer.LaneController la satisface.
+type LaneManager interface {
+	Snapshot() []consumer.LaneStatus
+	Pause(lane string) error
+	Resume(lane string) error
+}
+
+// AdminHandler maneja los endpoints /admin/* del servidor de métricas: inspección
+// de processors y consumers, y pausa/reanudación del consumo por carril. Todos los
+// endpoints exigen el header Authorization: Bearer <token>.
+type AdminHandler struct {
+	token      string
+	processors ProcessorLister
+	lanes      LaneManager
+}
+
+// NewAdminHandler crea el handler de administración. token no puede estar vacío:
+// sin token la API no se monta (ver MetricsServerConfig.Admin).
+func NewAdminHandler(token string, processors ProcessorLister, lanes LaneManager) *AdminHandler {
+	return &AdminHandler{
+		token:      token,
+		processors: processors,
+		lanes:      lanes,
+	}
+}
+
+// ProcessorsResponse representa la respuesta de GET /admin/processors
+type ProcessorsResponse struct {
+	EventTypes []string `json:"event_types"`
+}
+
+// ConsumersResponse representa la respuesta de GET /admin/consumers
+type ConsumersResponse struct {
+	Consumers []consumer.LaneStatus `json:"consumers"`
+}
+
+// adminError representa la respuesta de error de la API de administración
+type adminError struct {
+	Error string `json:"error"`
+}
+
+// Register monta las rutas de administración en mux.
+func (h *AdminHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/processors", h.authorize(h.Processors))
+	mux.HandleFunc("GET /admin/consumers", h.authorize(h.Consumers))
+	mux.HandleFunc("POST /admin/lanes/{lane}/pause", h.authorize(h.Pause))
+	mux.HandleFunc("POST /admin/lanes/{lane}/resume", h.authorize(h.Resume))
+}
+
+// authorize valida el bearer token en tiempo constante.
+func (h *AdminHandler) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || h.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, adminError{Error: "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Processors maneja GET /admin/processors - event types registrados
+func (h *AdminHandler) Processors(w http.ResponseWriter, r *http.Request) {
+	types := h.processors.RegisteredTypes()
+	sort.Strings(types)
+	writeJSON(w, http.StatusOK, ProcessorsResponse{EventTypes: types})
+}
+
+// Consumers maneja GET /admin/consumers - carriles con su cola, pausa, consumer
+// abierto y en proceso
+func (h *AdminHandler) Consumers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ConsumersResponse{Consumers: h.lanes.Snapshot()})
+}
+
+// Pause maneja POST /admin/lanes/{lane}/pause - cancela el consumer del carril
+// (basic.cancel); los mensajes entregados y no procesados vuelven a la cola. La
+// respuesta es el estado del carril (consuming=false).
+func (h *AdminHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, h.lanes.Pause)
+}
+
+// Resume maneja POST /admin/lanes/{lane}/resume - suelta la pausa manual y, si no
+// queda otro titular, abre un consumer nuevo. Si el consumer no se pudo abrir
+// responde 503: el carril queda sin pausa pero sin consumir, y repetir el resume lo
+// reintenta.
+func (h *AdminHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, h.lanes.Resume)
+}
+
+func (h *AdminHandler) setPaused(w http.ResponseWriter, r *http.Request, op func(string) error) {
+	name := r.PathValue("lane")
+	if err := op(name); err != nil {
+		status := http.StatusServiceUnavailable
+		if errors.Is(err, consumer.ErrUnknownLane) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, adminError{Error: err.Error()})
+		return
+	}
+	for _, st := range h.lanes.Snapshot() {
+		if st.Lane == name {
+			writeJSON(w, http.StatusOK, st)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, consumer.LaneStatus{Lane: name})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/consumer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "s3cret"
+
+type nopLogger struct{}
+
+func (l *nopLogger) Debug(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Info(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Warn(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Error(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Fatal(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Sync() error                                     { return nil }
+func (l *nopLogger) With(keysAndValues ...interface{}) logger.Logger { return l }
+
+type staticProcessors []string
+
+func (p staticProcessors) RegisteredTypes() []string { return append([]string(nil), p...) }
+
+type nopConsumer struct{}
+
+func (nopConsumer) Stop()       {}
+func (nopConsumer) Wait() error { return nil }
+
+func nopStart() (consumer.LaneConsumer, error) { return nopConsumer{}, nil }
+
+func newAdminMux(t *testing.T) (*http.ServeMux, *consumer.LaneController) {
+	t.Helper()
+	lanes := consumer.NewLaneController(&nopLogger{})
+	lanes.Register(consumer.LaneReview, "edugo-worker", "edugo.attempt.review_requested", nopStart)
+	lanes.Register(consumer.LaneMaterial, "edugo-worker-material", "edugo.material.assessment.requested", nopStart)
+
+	mux := http.NewServeMux()
+	NewAdminHandler(testAdminToken,
+		staticProcessors{"question.prep_requested", "attempt.review_requested"}, lanes).Register(mux)
+	return mux, lanes
+}
+
+func doAdmin(mux *http.ServeMux, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminHandler_RequiereToken(t *testing.T) {
+	mux, _ := newAdminMux(t)
+
+	for _, token := range []string{"", "otro"} {
+		rec := doAdmin(mux, http.MethodGet, "/admin/consumers", token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	}
+
+	rec := doAdmin(mux, http.MethodPost, "/admin/lanes/material/pause", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "sin token no se puede pausar")
+}
+
+func TestAdminHandler_Processors(t *testing.T) {
+	mux, _ := newAdminMux(t)
+
+	rec := doAdmin(mux, http.MethodGet, "/admin/processors", testAdminToken)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp ProcessorsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"attempt.review_requested", "question.prep_requested"}, resp.EventTypes)
+}
+
+func TestAdminHandler_PausaYReanudaCarril(t *testing.T) {
+	mux, lanes := newAdminMux(t)
+
+	rec := doAdmin(mux, http.MethodPost, "/admin/lanes/material/pause", testAdminToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var st consumer.LaneStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &st))
+	assert.Equal(t, consumer.LaneMaterial, st.Lane)
+	assert.True(t, st.Paused)
+
+	rec = doAdmin(mux, http.MethodGet, "/admin/consumers", testAdminToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp ConsumersResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Consumers, 2)
+	for _, c := range resp.Consumers {
+		assert.Equal(t, c.Lane == consumer.LaneMaterial, c.Paused, "solo materiales queda pausado")
+	}
+
+	rec = doAdmin(mux, http.MethodPost, "/admin/lanes/material/resume", testAdminToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	for _, c := range lanes.Snapshot() {
+		assert.False(t, c.Paused)
+	}
+}
+
+// countingStart cuenta los consumers abiertos y cancelados de un carril.
+type countingStart struct {
+	opened, stopped int
+	err             error
+}
+
+func (s *countingStart) start() (consumer.LaneConsumer, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.opened++
+	return stopCounter{s}, nil
+}
+
+type stopCounter struct{ s *countingStart }
+
+func (c stopCounter) Stop()       { c.s.stopped++ }
+func (c stopCounter) Wait() error { return nil }
+
+func TestAdminHandler_PausaCancelaElConsumer(t *testing.T) {
+	lanes := consumer.NewLaneController(&nopLogger{})
+	material := &countingStart{}
+	lanes.Register(consumer.LaneMaterial, "edugo-worker-material", "edugo.material.assessment.requested", material.start)
+	require.NoError(t, lanes.Start(consumer.LaneMaterial))
+	mux := http.NewServeMux()
+	NewAdminHandler(testAdminToken, staticProcessors{}, lanes).Register(mux)
+
+	rec := doAdmin(mux, http.MethodPost, "/admin/lanes/material/pause", testAdminToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var st consumer.LaneStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &st))
+	assert.False(t, st.Consuming, "la pausa responde con el consumer ya cancelado")
+	assert.Equal(t, 1, material.stopped)
+
+	material.err = errors.New("conexión cerrada")
+	rec = doAdmin(mux, http.MethodPost, "/admin/lanes/material/resume", testAdminToken)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "el consumer no se pudo reabrir")
+
+	material.err = nil
+	rec = doAdmin(mux, http.MethodPost, "/admin/lanes/material/resume", testAdminToken)
+	require.Equal(t, http.StatusOK, rec.Code, "repetir el resume reintenta")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &st))
+	assert.True(t, st.Consuming)
+	assert.Equal(t, 2, material.opened, "reanudar abre un consumer nuevo")
+}
+
+func TestAdminHandler_CarrilDesconocidoYMetodo(t *testing.T) {
+	mux, _ := newAdminMux(t)
+
+	rec := doAdmin(mux, http.MethodPost, "/admin/lanes/inexistente/pause", testAdminToken)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = doAdmin(mux, http.MethodGet, "/admin/lanes/material/pause", testAdminToken)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, "pausar exige POST")
+}
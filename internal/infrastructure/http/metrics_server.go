@@ -20,6 +20,8 @@ type MetricsServer struct {
 type MetricsServerConfig struct {
 	Port          int
 	HealthChecker *health.Checker
+	// Admin monta los endpoints /admin/* (autenticados). nil = sin API de administración.
+	Admin *AdminHandler
 }
 
 // NewMetricsServer crea una nueva instancia del servidor de métricas
@@ -46,6 +48,10 @@ func NewMetricsServerWithConfig(cfg MetricsServerConfig) *MetricsServer {
 		})
 	}
 
+	if cfg.Admin != nil {
+		cfg.Admin.Register(mux)
+	}
+
 	return &MetricsServer{
 		server: &http.Server{
 			Addr:         fmt.Sprintf(":%d", cfg.Port),
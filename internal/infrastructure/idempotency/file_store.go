@@ -0,0 +1,166 @@
+package idempotency
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore es un Store persistente: un log append-only de records en JSON lines
+// delante de un MemoryStore. Sobrevive a reinicios del worker (los redeliveries
+// tras un deploy siguen corto-circuitando). Al abrir reproduce el log en memoria
+// (el último record de cada event_id gana) y compacta el archivo cuando el log
+// crece al doble de la capacidad.
+type FileStore struct {
+	mem  *MemoryStore
+	path string
+
+	mu    sync.Mutex
+	file  *os.File
+	lines int
+}
+
+// NewFileStore abre (o crea) el log en path y carga su contenido. capacity y ttl
+// tienen la misma semántica que en NewMemoryStore. Las líneas corruptas (p.ej. una
+// escritura truncada por un crash) se ignoran.
+func NewFileStore(path string, capacity int, ttl time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creando directorio del store de idempotencia: %w", err)
+	}
+
+	s := &FileStore{mem: NewMemoryStore(capacity, ttl), path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get satisface Store.
+func (s *FileStore) Get(ctx context.Context, eventID string) (Record, bool, error) {
+	return s.mem.Get(ctx, eventID)
+}
+
+// Put satisface Store: persiste el record en el log antes de publicarlo en memoria.
+func (s *FileStore) Put(ctx context.Context, rec Record) error {
+	if rec.RecordedAt.IsZero() {
+		rec.RecordedAt = s.mem.now()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("serializando record de idempotencia: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("store de idempotencia cerrado")
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("escribiendo store de idempotencia: %w", err)
+	}
+	s.lines++
+	if err := s.mem.Put(ctx, rec); err != nil {
+		return err
+	}
+	if s.lines > 2*s.mem.capacity {
+		return s.compactLocked()
+	}
+	return nil
+}
+
+// Close cierra el archivo del log.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// load reproduce el log existente en el MemoryStore.
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("abriendo store de idempotencia: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.EventID == "" {
+			continue
+		}
+		s.mem.put(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("leyendo store de idempotencia: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked()
+}
+
+// compactLocked reescribe el log con solo los records vigentes (temporal + rename
+// atómico) y lo reabre en modo append.
+func (s *FileStore) compactLocked() error {
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			return fmt.Errorf("cerrando store de idempotencia: %w", err)
+		}
+		s.file = nil
+	}
+
+	records := s.mem.snapshot()
+	tmp := s.path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("compactando store de idempotencia: %w", err)
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			_ = out.Close()
+			return fmt.Errorf("compactando store de idempotencia: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("compactando store de idempotencia: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("compactando store de idempotencia: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("compactando store de idempotencia: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("reabriendo store de idempotencia: %w", err)
+	}
+	s.file = f
+	s.lines = len(records)
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package idempotency guarda el resultado de los eventos ya procesados, indexado
+// por el event_id del envelope, para que un redelivery de un evento completado se
+// ACKee sin volver a pagar los round-trips M2M ni las llamadas al LLM.
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Outcome es el resultado registrado de un evento.
+type Outcome string
+
+const (
+	// OutcomeCompleted: el processor terminó sin error. Un redelivery se ACKea directo.
+	OutcomeCompleted Outcome = "completed"
+	// OutcomeFailed: el último intento falló. Es informativo: el evento SIGUE siendo
+	// reintentable (no corto-circuita).
+	OutcomeFailed Outcome = "failed"
+)
+
+// Record es la entrada del store para un event_id.
+type Record struct {
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Outcome    Outcome   `json:"outcome"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Store es el contrato de un store de idempotencia. Get devuelve ok=false si el
+// event_id no está (o venció).
+type Store interface {
+	Get(ctx context.Context, eventID string) (Record, bool, error)
+	Put(ctx context.Context, rec Record) error
+}
+
+// MemoryStore es un Store en memoria con política LRU y TTL opcional. Acotado por
+// capacidad: al llenarse desaloja el event_id usado hace más tiempo. No sobrevive a
+// un reinicio (para eso, FileStore).
+type MemoryStore struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	order *list.List // frente = más reciente
+	items map[string]*list.Element
+}
+
+// NewMemoryStore crea un MemoryStore. capacity <= 0 usa 10000; ttl <= 0 desactiva
+// el vencimiento (solo desalojo por LRU).
+func NewMemoryStore(capacity int, ttl time.Duration) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &MemoryStore{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get satisface Store.
+func (s *MemoryStore) Get(_ context.Context, eventID string) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[eventID]
+	if !ok {
+		return Record{}, false, nil
+	}
+	rec := el.Value.(Record)
+	if s.expired(rec) {
+		s.order.Remove(el)
+		delete(s.items, eventID)
+		return Record{}, false, nil
+	}
+	s.order.MoveToFront(el)
+	return rec, true, nil
+}
+
+// Put satisface Store. Sobrescribe el resultado previo del mismo event_id.
+func (s *MemoryStore) Put(_ context.Context, rec Record) error {
+	if rec.RecordedAt.IsZero() {
+		rec.RecordedAt = s.now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(rec)
+	return nil
+}
+
+// put inserta sin tomar el lock (lo usa también la carga de FileStore).
+func (s *MemoryStore) put(rec Record) {
+	if el, ok := s.items[rec.EventID]; ok {
+		el.Value = rec
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[rec.EventID] = s.order.PushFront(rec)
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(Record).EventID)
+	}
+}
+
+// Len devuelve el número de event_id retenidos.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// snapshot devuelve los records vigentes del más antiguo al más reciente.
+func (s *MemoryStore) snapshot() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Record, 0, s.order.Len())
+	for el := s.order.Back(); el != nil; el = el.Prev() {
+		rec := el.Value.(Record)
+		if !s.expired(rec) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func (s *MemoryStore) expired(rec Record) bool {
+	return s.ttl > 0 && s.now().Sub(rec.RecordedAt) > s.ttl
+}
@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_PutGet(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10, 0)
+
+	_, ok, err := s.Get(ctx, "evt-1")
+	require.NoError(t, err)
+	assert.False(t, ok, "un event_id desconocido no debe estar")
+
+	require.NoError(t, s.Put(ctx, Record{EventID: "evt-1", EventType: "x", Outcome: OutcomeFailed}))
+	require.NoError(t, s.Put(ctx, Record{EventID: "evt-1", EventType: "x", Outcome: OutcomeCompleted}))
+
+	rec, ok, err := s.Get(ctx, "evt-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, OutcomeCompleted, rec.Outcome, "el último resultado gana")
+	assert.False(t, rec.RecordedAt.IsZero())
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestMemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2, 0)
+
+	require.NoError(t, s.Put(ctx, Record{EventID: "a", Outcome: OutcomeCompleted}))
+	require.NoError(t, s.Put(ctx, Record{EventID: "b", Outcome: OutcomeCompleted}))
+	_, _, _ = s.Get(ctx, "a") // a pasa a ser el más reciente
+	require.NoError(t, s.Put(ctx, Record{EventID: "c", Outcome: OutcomeCompleted}))
+
+	_, okA, _ := s.Get(ctx, "a")
+	_, okB, _ := s.Get(ctx, "b")
+	_, okC, _ := s.Get(ctx, "c")
+	assert.True(t, okA)
+	assert.False(t, okB, "b era el menos usado y debe desalojarse")
+	assert.True(t, okC)
+}
+
+func TestMemoryStore_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore(10, time.Hour)
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Put(ctx, Record{EventID: "evt-1", Outcome: OutcomeCompleted}))
+	now = now.Add(2 * time.Hour)
+
+	_, ok, err := s.Get(ctx, "evt-1")
+	require.NoError(t, err)
+	assert.False(t, ok, "un resultado vencido no debe corto-circuitar")
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestFileStore_SurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sub", "idem.jsonl")
+
+	s, err := NewFileStore(path, 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Put(ctx, Record{EventID: "evt-1", EventType: "x", Outcome: OutcomeCompleted}))
+	require.NoError(t, s.Put(ctx, Record{EventID: "evt-2", EventType: "x", Outcome: OutcomeFailed}))
+	require.NoError(t, s.Close())
+
+	reopened, err := NewFileStore(path, 10, 0)
+	require.NoError(t, err)
+	defer func() { _ = reopened.Close() }()
+
+	rec, ok, err := reopened.Get(ctx, "evt-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, OutcomeCompleted, rec.Outcome)
+
+	rec, ok, _ = reopened.Get(ctx, "evt-2")
+	require.True(t, ok)
+	assert.Equal(t, OutcomeFailed, rec.Outcome)
+}
+
+func TestFileStore_IgnoresCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idem.jsonl")
+	content := `{"event_id":"evt-1","outcome":"completed","recorded_at":"2026-03-01T12:00:00Z"}` + "\n" + `{"event_id":"evt-2","outc`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	s, err := NewFileStore(path, 10, 0)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	_, ok, _ := s.Get(context.Background(), "evt-1")
+	assert.True(t, ok)
+	_, ok, _ = s.Get(context.Background(), "evt-2")
+	assert.False(t, ok, "una línea truncada se ignora")
+}
+
+func TestFileStore_CompactsLog(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "idem.jsonl")
+
+	s, err := NewFileStore(path, 2, 0)
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, s.Put(ctx, Record{EventID: id, Outcome: OutcomeCompleted}))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := 0
+	for _, b := range data {
+		if b == '\n' {
+			lines++
+		}
+	}
+	assert.LessOrEqual(t, lines, 4, "el log debe compactarse al superar 2×capacidad")
+}
+
+func TestFileStore_PutAfterClose(t *testing.T) {
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "idem.jsonl"), 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	assert.Error(t, s.Put(context.Background(), Record{EventID: "x", Outcome: OutcomeCompleted}))
+}
@@ -0,0 +1,23 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+)
+
+// LimitConcurrency acota a n los mensajes que next procesa a la vez en un carril
+// (lanes[].concurrency). Los consumers procesan cada entrega en su goroutine, así
+// que sin límite la concurrencia es el prefetch. Si ctx se cancela mientras espera
+// turno devuelve el error: el consumer no ACKea y el mensaje vuelve a la cola.
+func LimitConcurrency(n int, next func(ctx context.Context, body []byte) error) func(ctx context.Context, body []byte) error {
+	slots := make(chan struct{}, n)
+	return func(ctx context.Context, body []byte) error {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return fmt.Errorf("esperando turno de procesamiento: %w", ctx.Err())
+		}
+		defer func() { <-slots }()
+		return next(ctx, body)
+	}
+}
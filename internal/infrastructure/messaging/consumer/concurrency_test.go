@@ -0,0 +1,62 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitConcurrency_AcotaLosMensajesEnProceso(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	release := make(chan struct{})
+	handler := LimitConcurrency(2, func(context.Context, []byte) error {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = handler(context.Background(), nil)
+		}()
+	}
+	assert.Eventually(t, func() bool { return running.Load() == 2 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestLimitConcurrency_CancelacionLiberaLaEspera(t *testing.T) {
+	started, hold := make(chan struct{}), make(chan struct{})
+	handler := LimitConcurrency(1, func(context.Context, []byte) error {
+		close(started)
+		<-hold
+		return nil
+	})
+	go func() { _ = handler(context.Background(), nil) }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := handler(ctx, nil)
+	close(hold)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "el mensaje vuelve a la cola sin procesarse")
+}
@@ -0,0 +1,331 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+)
+
+// Nombres de los carriles del worker: uno por consumer/cola.
+const (
+	LaneReview   = "review"
+	LanePrep     = "prep"
+	LaneMaterial = "material"
+)
+
+// HolderAdmin es el titular de las pausas manuales (API de administración).
+const HolderAdmin = "admin"
+
+// ErrUnknownLane se devuelve al operar sobre un carril no registrado.
+var ErrUnknownLane = errors.New("carril desconocido")
+
+// LaneConsumer es un consumer de carril ya consumiendo su cola. Stop cancela el
+// consumo (basic.cancel) y Wait espera a los mensajes en proceso y cierra el canal:
+// lo entregado por prefetch y no procesado vuelve a la cola. Lo satisfacen el
+// consumer compartido y el de reintentos diferidos.
+type LaneConsumer interface {
+	Stop()
+	Wait() error
+}
+
+// StartFunc abre un consumer NUEVO sobre la cola del carril (basic.consume). El
+// LaneController la llama al arrancar y en cada reanudación: un consumer detenido
+// no se reutiliza (ConsumeWithDLQ admite una sola llamada por instancia).
+type StartFunc func() (LaneConsumer, error)
+
+// LaneStatus es la foto de un carril para la API de administración.
+type LaneStatus struct {
+	Lane     string     `json:"lane"`
+	Consumer string     `json:"consumer"`
+	Queue    string     `json:"queue"`
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	// PausedBy son los titulares de la pausa (admin, llm:local...).
+	PausedBy []string `json:"paused_by,omitempty"`
+	// Consuming indica si el carril tiene un consumer abierto en el broker: false
+	// mientras está pausado o si no se pudo reabrir al reanudar.
+	Consuming bool `json:"consuming"`
+	// InFlight son los mensajes que el processor está procesando ahora.
+	InFlight int `json:"in_flight"`
+}
+
+// lane es el estado interno de un carril. Está pausado mientras tenga algún
+// titular en holders. op serializa la apertura y cancelación de su consumer sin
+// retener mu mientras se habla con el broker.
+type lane struct {
+	consumer string
+	queue    string
+	holders  map[string]struct{}
+	pausedAt time.Time
+	inFlight int
+
+	op      sync.Mutex
+	start   StartFunc
+	current LaneConsumer
+	started bool
+	closed  bool
+}
+
+func (l *lane) paused() bool { return len(l.holders) > 0 }
+
+// LaneController pausa y reanuda el consumo por carril sin reiniciar el pod.
+//
+// Pausar cancela el consumer del carril (basic.cancel): el broker deja de
+// entregarle mensajes y, al cerrarse su canal cuando terminan los que ya se estaban
+// procesando, los entregados por prefetch vuelven a la cola. Nada queda retenido
+// sin ACK mientras dura la pausa, así que una pausa larga no choca con el
+// consumer_timeout del broker. Reanudar abre un consumer nuevo con la StartFunc del
+// carril.
+//
+// Cada pausa tiene un titular (la API de administración, el monitor de
+// disponibilidad del LLM...): el carril sigue pausado hasta que TODOS la sueltan,
+// así un titular no reanuda lo que pausó otro.
+type LaneController struct {
+	logger logger.Logger
+
+	mu    sync.Mutex
+	lanes map[string]*lane
+
+	// drains son los consumers cancelados que todavía esperan a sus mensajes en
+	// proceso; Close los espera.
+	drains sync.WaitGroup
+}
+
+// NewLaneController crea un controller sin carriles; main registra cada carril con
+// Register y lo arranca con Start.
+func NewLaneController(log logger.Logger) *LaneController {
+	return &LaneController{logger: log, lanes: make(map[string]*lane)}
+}
+
+// Register da de alta un carril con su consumer, su cola y la función que abre su
+// consumer. Registrar de nuevo un carril actualiza esos datos y conserva su estado
+// de pausa.
+func (c *LaneController) Register(name, consumerName, queue string, start StartFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l, ok := c.lanes[name]; ok {
+		l.consumer, l.queue, l.start = consumerName, queue, start
+		return
+	}
+	c.lanes[name] = &lane{consumer: consumerName, queue: queue, start: start, holders: make(map[string]struct{})}
+	metrics.SetLanePaused(name, false)
+	metrics.UpdateLaneInFlight(name, 0)
+}
+
+// Start arranca el consumo del carril. Si ya está pausado (p.ej. el monitor del LLM
+// lo pausó antes) el consumer se abre recién al reanudarlo.
+func (c *LaneController) Start(name string) error {
+	l, err := c.lane(name)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	l.started = true
+	c.mu.Unlock()
+	return c.reconcile(name, l)
+}
+
+// Close detiene todos los carriles para el shutdown: cancela sus consumers y ya no
+// los reabre aunque se suelte una pausa. Con wait espera además a los mensajes en
+// proceso, incluidos los de consumers cancelados por una pausa.
+func (c *LaneController) Close(wait bool) {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.lanes))
+	for name, l := range c.lanes {
+		l.closed = true
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		l, _ := c.lane(name)
+		_ = c.reconcile(name, l)
+	}
+	if wait {
+		c.drains.Wait()
+	}
+}
+
+// Wrap envuelve el handler de un consumer con el conteo de mensajes en proceso del
+// carril. El carril debe estar registrado.
+func (c *LaneController) Wrap(name string, next func(ctx context.Context, body []byte) error) func(ctx context.Context, body []byte) error {
+	return func(ctx context.Context, body []byte) error {
+		if err := c.acquire(name); err != nil {
+			return err
+		}
+		defer c.release(name)
+		return next(ctx, body)
+	}
+}
+
+func (c *LaneController) acquire(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.lanes[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLane, name)
+	}
+	l.inFlight++
+	metrics.UpdateLaneInFlight(name, l.inFlight)
+	return nil
+}
+
+func (c *LaneController) release(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.lanes[name]
+	l.inFlight--
+	metrics.UpdateLaneInFlight(name, l.inFlight)
+}
+
+// Pause detiene el consumo del carril como pausa manual (HolderAdmin). Es
+// idempotente.
+func (c *LaneController) Pause(name string) error {
+	return c.PauseBy(name, HolderAdmin)
+}
+
+// Resume suelta la pausa manual del carril. Si otro titular lo mantiene pausado
+// (p.ej. el LLM caído) el carril sigue pausado. Es idempotente.
+func (c *LaneController) Resume(name string) error {
+	return c.ResumeBy(name, HolderAdmin)
+}
+
+// PauseBy pausa el carril a nombre de holder y cancela su consumer. Es idempotente
+// por titular.
+func (c *LaneController) PauseBy(name, holder string) error {
+	l, err := c.lane(name)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if _, held := l.holders[holder]; !held {
+		if !l.paused() {
+			l.pausedAt = time.Now()
+			metrics.SetLanePaused(name, true)
+		}
+		l.holders[holder] = struct{}{}
+		c.logger.Warn("carril pausado", "lane", name, "queue", l.queue, "by", holder, "in_flight", l.inFlight)
+	}
+	c.mu.Unlock()
+	return c.reconcile(name, l)
+}
+
+// ResumeBy suelta la pausa de holder; cuando no le queda ningún titular el carril
+// abre un consumer nuevo. Es idempotente; si el consumer no pudo abrirse, repetirlo
+// lo reintenta.
+func (c *LaneController) ResumeBy(name, holder string) error {
+	l, err := c.lane(name)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if _, held := l.holders[holder]; held {
+		delete(l.holders, holder)
+		if l.paused() {
+			c.logger.Info("pausa soltada, el carril sigue pausado por otro titular",
+				"lane", name, "by", holder, "paused_by", sortedHolders(l))
+		} else {
+			metrics.SetLanePaused(name, false)
+			c.logger.Info("carril reanudado", "lane", name, "queue", l.queue, "by", holder,
+				"paused_for", time.Since(l.pausedAt).String())
+		}
+	}
+	c.mu.Unlock()
+	return c.reconcile(name, l)
+}
+
+// reconcile abre o cancela el consumer del carril según su estado: abierto si está
+// arrancado, sin pausa y sin Close; cancelado si no. La espera de los mensajes en
+// proceso de un consumer cancelado corre aparte para que pausar no se bloquee
+// detrás de un mensaje largo.
+func (c *LaneController) reconcile(name string, l *lane) error {
+	l.op.Lock()
+	defer l.op.Unlock()
+
+	c.mu.Lock()
+	want := l.started && !l.closed && !l.paused()
+	current, start := l.current, l.start
+	c.mu.Unlock()
+
+	switch {
+	case want && current == nil:
+		opened, err := start()
+		if err != nil {
+			c.logger.Error("no se pudo abrir el consumer del carril", "lane", name, "error", err.Error())
+			return fmt.Errorf("abriendo consumer del carril %s: %w", name, err)
+		}
+		c.mu.Lock()
+		l.current = opened
+		c.mu.Unlock()
+		c.logger.Info("consumer del carril abierto", "lane", name, "queue", l.queue)
+
+	case !want && current != nil:
+		current.Stop()
+		c.mu.Lock()
+		l.current = nil
+		c.mu.Unlock()
+		c.logger.Info("consumer del carril cancelado", "lane", name, "queue", l.queue)
+
+		c.drains.Add(1)
+		go func() {
+			defer c.drains.Done()
+			if err := current.Wait(); err != nil {
+				c.logger.Warn("consumer del carril detenido con error", "lane", name, "error", err.Error())
+			}
+		}()
+	}
+	return nil
+}
+
+func (c *LaneController) lane(name string) (*lane, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.lanes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLane, name)
+	}
+	return l, nil
+}
+
+func sortedHolders(l *lane) []string {
+	out := make([]string, 0, len(l.holders))
+	for h := range l.holders {
+		out = append(out, h)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Snapshot devuelve el estado de todos los carriles ordenados por nombre.
+func (c *LaneController) Snapshot() []LaneStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]LaneStatus, 0, len(c.lanes))
+	for name, l := range c.lanes {
+		st := LaneStatus{
+			Lane:      name,
+			Consumer:  l.consumer,
+			Queue:     l.queue,
+			Paused:    l.paused(),
+			Consuming: l.current != nil,
+			InFlight:  l.inFlight,
+		}
+		if l.paused() {
+			pausedAt := l.pausedAt
+			st.PausedAt = &pausedAt
+			st.PausedBy = sortedHolders(l)
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Lane < out[j].Lane })
+	return out
+}
@@ -0,0 +1,225 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nopLogger es un logger que no hace nada (para tests)
+type nopLogger struct{}
+
+func (l *nopLogger) Debug(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Info(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Warn(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Error(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Fatal(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Sync() error                                     { return nil }
+func (l *nopLogger) With(keysAndValues ...interface{}) logger.Logger { return l }
+
+// fakeConsumer registra lo que el LaneController hace con un consumer abierto.
+type fakeConsumer struct {
+	stopped atomic.Bool
+	waited  atomic.Bool
+}
+
+func (f *fakeConsumer) Stop()       { f.stopped.Store(true) }
+func (f *fakeConsumer) Wait() error { f.waited.Store(true); return nil }
+
+// fakeStarter abre un fakeConsumer nuevo en cada llamada, como main con cada
+// reanudación.
+type fakeStarter struct {
+	mu     sync.Mutex
+	opened []*fakeConsumer
+	err    error
+}
+
+func (s *fakeStarter) start() (LaneConsumer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	f := &fakeConsumer{}
+	s.opened = append(s.opened, f)
+	return f, nil
+}
+
+func (s *fakeStarter) consumers() []*fakeConsumer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*fakeConsumer(nil), s.opened...)
+}
+
+func newTestController() *LaneController {
+	c := NewLaneController(&nopLogger{})
+	c.Register(LaneReview, "edugo-worker", "edugo.attempt.review_requested", (&fakeStarter{}).start)
+	c.Register(LaneMaterial, "edugo-worker-material", "edugo.material.assessment.requested", (&fakeStarter{}).start)
+	return c
+}
+
+func laneStatus(t *testing.T, c *LaneController, name string) LaneStatus {
+	t.Helper()
+	for _, st := range c.Snapshot() {
+		if st.Lane == name {
+			return st
+		}
+	}
+	t.Fatalf("carril %s no registrado", name)
+	return LaneStatus{}
+}
+
+func TestLaneController_PausaCancelaYResumeReabre(t *testing.T) {
+	starter := &fakeStarter{}
+	c := NewLaneController(&nopLogger{})
+	c.Register(LaneMaterial, "edugo-worker-material", "edugo.material.assessment.requested", starter.start)
+
+	require.NoError(t, c.Start(LaneMaterial))
+	require.Len(t, starter.consumers(), 1)
+	assert.True(t, laneStatus(t, c, LaneMaterial).Consuming)
+
+	require.NoError(t, c.Pause(LaneMaterial))
+	first := starter.consumers()[0]
+	assert.True(t, first.stopped.Load(), "pausar cancela el consumer: no queda nada retenido sin ACK")
+	assert.Eventually(t, first.waited.Load, time.Second, 5*time.Millisecond,
+		"el consumer cancelado espera lo que estaba en proceso y cierra su canal")
+	assert.False(t, laneStatus(t, c, LaneMaterial).Consuming)
+
+	require.NoError(t, c.Resume(LaneMaterial))
+	opened := starter.consumers()
+	require.Len(t, opened, 2, "reanudar abre un consumer nuevo")
+	assert.False(t, opened[1].stopped.Load())
+	assert.True(t, laneStatus(t, c, LaneMaterial).Consuming)
+}
+
+func TestLaneController_PausadoAntesDeArrancar(t *testing.T) {
+	starter := &fakeStarter{}
+	c := NewLaneController(&nopLogger{})
+	c.Register(LaneMaterial, "edugo-worker-material", "edugo.material.assessment.requested", starter.start)
+
+	require.NoError(t, c.PauseBy(LaneMaterial, "llm:local"))
+	require.NoError(t, c.Start(LaneMaterial))
+	assert.Empty(t, starter.consumers(), "un carril pausado no abre consumer al arrancar")
+
+	require.NoError(t, c.ResumeBy(LaneMaterial, "llm:local"))
+	assert.Len(t, starter.consumers(), 1)
+}
+
+func TestLaneController_ErrorAlReabrirSeReintenta(t *testing.T) {
+	starter := &fakeStarter{}
+	c := NewLaneController(&nopLogger{})
+	c.Register(LaneReview, "edugo-worker", "edugo.attempt.review_requested", starter.start)
+	require.NoError(t, c.Start(LaneReview))
+	require.NoError(t, c.Pause(LaneReview))
+
+	starter.mu.Lock()
+	starter.err = errors.New("canal cerrado")
+	starter.mu.Unlock()
+	require.Error(t, c.Resume(LaneReview))
+	st := laneStatus(t, c, LaneReview)
+	assert.False(t, st.Paused)
+	assert.False(t, st.Consuming, "la falla queda visible en el snapshot")
+
+	starter.mu.Lock()
+	starter.err = nil
+	starter.mu.Unlock()
+	require.NoError(t, c.Resume(LaneReview), "repetir la reanudación reintenta abrir el consumer")
+	assert.True(t, laneStatus(t, c, LaneReview).Consuming)
+}
+
+func TestLaneController_CloseNoReabre(t *testing.T) {
+	starter := &fakeStarter{}
+	c := NewLaneController(&nopLogger{})
+	c.Register(LaneReview, "edugo-worker", "edugo.attempt.review_requested", starter.start)
+	require.NoError(t, c.Start(LaneReview))
+	require.NoError(t, c.Pause(LaneReview))
+
+	c.Close(true)
+	require.NoError(t, c.Resume(LaneReview))
+	opened := starter.consumers()
+	require.Len(t, opened, 1, "tras Close una reanudación no abre otro consumer")
+	assert.True(t, opened[0].waited.Load(), "Close(true) espera a los consumers cancelados")
+}
+
+func TestLaneController_PausaNoAfectaOtrosCarriles(t *testing.T) {
+	c := newTestController()
+	require.NoError(t, c.Pause(LaneMaterial))
+
+	called := false
+	err := c.Wrap(LaneReview, func(context.Context, []byte) error {
+		called = true
+		return nil
+	})(context.Background(), nil)
+
+	require.NoError(t, err)
+	assert.True(t, called, "revisión sigue corriendo con materiales pausado")
+}
+
+func TestLaneController_CuentaInFlight(t *testing.T) {
+	c := newTestController()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := c.Wrap(LaneReview, func(context.Context, []byte) error {
+		close(started)
+		<-release
+		return errors.New("falla del processor")
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- handler(context.Background(), nil) }()
+	<-started
+
+	assert.Equal(t, 1, laneStatus(t, c, LaneReview).InFlight)
+	close(release)
+	assert.EqualError(t, <-done, "falla del processor", "el error del processor se propaga intacto")
+	assert.Equal(t, 0, laneStatus(t, c, LaneReview).InFlight)
+}
+
+func TestLaneController_CarrilDesconocido(t *testing.T) {
+	c := newTestController()
+
+	assert.ErrorIs(t, c.Pause("inexistente"), ErrUnknownLane)
+	assert.ErrorIs(t, c.Resume("inexistente"), ErrUnknownLane)
+	assert.ErrorIs(t, c.Wrap("inexistente", func(context.Context, []byte) error { return nil })(context.Background(), nil), ErrUnknownLane)
+}
+
+func TestLaneController_SnapshotYPausaIdempotente(t *testing.T) {
+	c := newTestController()
+	require.NoError(t, c.Pause(LaneReview))
+	require.NoError(t, c.Pause(LaneReview))
+
+	snap := c.Snapshot()
+	require.Len(t, snap, 2)
+	assert.Equal(t, LaneMaterial, snap[0].Lane, "ordenado por nombre")
+	assert.Equal(t, LaneReview, snap[1].Lane)
+	assert.True(t, snap[1].Paused)
+	assert.NotNil(t, snap[1].PausedAt)
+	assert.Equal(t, "edugo.attempt.review_requested", snap[1].Queue)
+	assert.False(t, snap[0].Paused)
+	assert.Nil(t, snap[0].PausedAt)
+
+	require.NoError(t, c.Resume(LaneReview))
+	require.NoError(t, c.Resume(LaneReview))
+	assert.False(t, laneStatus(t, c, LaneReview).Paused)
+}
+
+func TestLaneController_PausaPorVariosTitulares(t *testing.T) {
+	c := newTestController()
+	require.NoError(t, c.PauseBy(LaneMaterial, "llm:local"))
+	require.NoError(t, c.Pause(LaneMaterial))
+
+	require.NoError(t, c.Resume(LaneMaterial))
+	st := laneStatus(t, c, LaneMaterial)
+	assert.True(t, st.Paused, "la reanudación manual no suelta la pausa del LLM")
+	assert.Equal(t, []string{"llm:local"}, st.PausedBy)
+
+	require.NoError(t, c.ResumeBy(LaneMaterial, "llm:local"))
+	assert.False(t, laneStatus(t, c, LaneMaterial).Paused)
+}
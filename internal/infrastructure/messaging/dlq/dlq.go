@@ -0,0 +1,175 @@
+// Package dlq inspecciona y re-publica los mensajes de las colas muertas del worker
+// (una por riel: revisión, preparación y material→evaluación). Es la base de la
+// herramienta cmd/dlq, que reemplaza el replay a mano desde la UI de RabbitMQ.
+package dlq
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/publisher"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/retryqueue"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Death es una entrada del header x-death que RabbitMQ agrega al dead-letterear un
+// mensaje: en qué cola murió, por qué, cuántas veces y con qué exchange/routing key
+// había sido publicado.
+type Death struct {
+	Queue       string    `json:"queue"`
+	Reason      string    `json:"reason"`
+	Count       int64     `json:"count"`
+	Time        time.Time `json:"time"`
+	Exchange    string    `json:"exchange"`
+	RoutingKeys []string  `json:"routing_keys"`
+}
+
+// Entry es un mensaje de una DLQ con el envelope decodificado y el destino original
+// resuelto. Los ids de correlación se extraen de mejor esfuerzo (vacíos si el carril
+// no los trae o el body no es JSON).
+type Entry struct {
+	Queue       string    `json:"queue"`
+	DeliveryTag uint64    `json:"delivery_tag"`
+	EventType   string    `json:"event_type"`
+	EventID     string    `json:"event_id"`
+	SchoolID    string    `json:"school_id,omitempty"`
+	AttemptID   string    `json:"attempt_id,omitempty"`
+	QuestionID  string    `json:"question_id,omitempty"`
+	JobID       string    `json:"job_id,omitempty"`
+	Deaths      []Death   `json:"x_death,omitempty"`
+	DeadAt      time.Time `json:"dead_at"`
+	// Exchange y RoutingKey son el destino original al que se re-publica.
+	Exchange   string `json:"exchange"`
+	RoutingKey string `json:"routing_key"`
+
+	delivery amqp.Delivery
+}
+
+// NewEntry decodifica un mensaje leído de la cola muerta queue.
+//
+// El destino original sale de los headers de origen de retryqueue si el mensaje
+// pasó por las colas de retry; si no, de la muerte más antigua de x-death (la
+// primera publicación del mensaje). Si no hay x-death (el consumer compartido re-publica
+// al DLX él mismo tras agotar reintentos, sin dead-letter nativo) se deriva del
+// event_type: la routing key de los carriles de entrada es el propio event_type y
+// el exchange se resuelve por prefijo con exchanges.
+//
+// DeadAt es la hora de la muerte más reciente; sin x-death cae al timestamp del
+// envelope y, en último caso, al de las propiedades AMQP.
+func NewEntry(queue string, d amqp.Delivery, exchanges publisher.Exchanges) Entry {
+	var env struct {
+		EventType string    `json:"event_type"`
+		EventID   string    `json:"event_id"`
+		Timestamp time.Time `json:"timestamp"`
+		Payload   struct {
+			SchoolID   string `json:"school_id"`
+			AttemptID  string `json:"attempt_id"`
+			QuestionID string `json:"question_id"`
+			JobID      string `json:"job_id"`
+		} `json:"payload"`
+	}
+	_ = json.Unmarshal(d.Body, &env)
+	if env.EventType == "" {
+		env.EventType = d.Type
+	}
+
+	e := Entry{
+		Queue:       queue,
+		DeliveryTag: d.DeliveryTag,
+		EventType:   env.EventType,
+		EventID:     env.EventID,
+		SchoolID:    env.Payload.SchoolID,
+		AttemptID:   env.Payload.AttemptID,
+		QuestionID:  env.Payload.QuestionID,
+		JobID:       env.Payload.JobID,
+		Deaths:      parseDeaths(d.Headers),
+		delivery:    d,
+	}
+
+	switch {
+	case len(e.Deaths) > 0:
+		e.DeadAt = e.Deaths[0].Time
+	case !env.Timestamp.IsZero():
+		e.DeadAt = env.Timestamp
+	default:
+		e.DeadAt = d.Timestamp
+	}
+
+	// Un mensaje que pasó por las colas de retry muere desde el exchange por defecto:
+	// su destino original viaja en los headers que agrega retryqueue.
+	if exchange, ok := d.Headers[retryqueue.OriginExchangeHeader].(string); ok && exchange != "" {
+		if key, ok := d.Headers[retryqueue.OriginRoutingKeyHeader].(string); ok && key != "" {
+			e.Exchange, e.RoutingKey = exchange, key
+		}
+	}
+	if n := len(e.Deaths); n > 0 && e.RoutingKey == "" {
+		first := e.Deaths[n-1]
+		if first.Exchange != "" && len(first.RoutingKeys) > 0 {
+			e.Exchange = first.Exchange
+			e.RoutingKey = first.RoutingKeys[0]
+		}
+	}
+	if e.RoutingKey == "" && e.EventType != "" {
+		if exchange, err := exchanges.For(e.EventType); err == nil {
+			e.Exchange = exchange
+			e.RoutingKey = e.EventType
+		}
+	}
+	return e
+}
+
+// parseDeaths lee el header x-death (lista de tablas, la más reciente primero).
+// Los campos con tipo inesperado se ignoran.
+func parseDeaths(headers amqp.Table) []Death {
+	raw, ok := headers["x-death"].([]interface{})
+	if !ok {
+		return nil
+	}
+	deaths := make([]Death, 0, len(raw))
+	for _, item := range raw {
+		t, ok := item.(amqp.Table)
+		if !ok {
+			continue
+		}
+		var d Death
+		d.Queue, _ = t["queue"].(string)
+		d.Reason, _ = t["reason"].(string)
+		d.Exchange, _ = t["exchange"].(string)
+		d.Time, _ = t["time"].(time.Time)
+		switch c := t["count"].(type) {
+		case int64:
+			d.Count = c
+		case int32:
+			d.Count = int64(c)
+		case int:
+			d.Count = int64(c)
+		}
+		if keys, ok := t["routing-keys"].([]interface{}); ok {
+			for _, k := range keys {
+				if s, ok := k.(string); ok {
+					d.RoutingKeys = append(d.RoutingKeys, s)
+				}
+			}
+		}
+		deaths = append(deaths, d)
+	}
+	return deaths
+}
+
+// DeathCount suma las muertes registradas en x-death (todas las colas).
+func (e Entry) DeathCount() int64 {
+	var n int64
+	for _, d := range e.Deaths {
+		n += d.Count
+	}
+	return n
+}
+
+// Reason es el motivo de la muerte más reciente (rejected, expired...), vacío sin
+// x-death.
+func (e Entry) Reason() string {
+	if len(e.Deaths) == 0 {
+		return ""
+	}
+	return e.Deaths[0].Reason
+}
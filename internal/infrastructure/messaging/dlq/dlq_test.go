@@ -0,0 +1,291 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/publisher"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/retryqueue"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testExchanges = publisher.Exchanges{Assessments: "edugo.assessments", Materials: "edugo.materials"}
+
+// fakeAcker registra los ack/nack de las entregas por delivery tag.
+type fakeAcker struct {
+	acked    []uint64
+	requeued []uint64
+}
+
+func (a *fakeAcker) Ack(tag uint64, _ bool) error { a.acked = append(a.acked, tag); return nil }
+func (a *fakeAcker) Nack(tag uint64, _ bool, requeue bool) error {
+	if requeue {
+		a.requeued = append(a.requeued, tag)
+	}
+	return nil
+}
+func (a *fakeAcker) Reject(tag uint64, requeue bool) error { return a.Nack(tag, false, requeue) }
+
+type published struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+// fakeChannel simula una DLQ: Get entrega los mensajes en orden y Publish los captura.
+type fakeChannel struct {
+	acker      *fakeAcker
+	queue      []amqp.Delivery
+	published  []published
+	publishErr error
+}
+
+func newFakeChannel(bodies ...amqp.Delivery) *fakeChannel {
+	ch := &fakeChannel{acker: &fakeAcker{}}
+	for i, d := range bodies {
+		d.DeliveryTag = uint64(i + 1)
+		d.Acknowledger = ch.acker
+		ch.queue = append(ch.queue, d)
+	}
+	return ch
+}
+
+func (c *fakeChannel) Get(string, bool) (amqp.Delivery, bool, error) {
+	if len(c.queue) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	d := c.queue[0]
+	c.queue = c.queue[1:]
+	return d, true, nil
+}
+
+func (c *fakeChannel) PublishWithContext(_ context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published = append(c.published, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+var deadAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func reviewDelivery(schoolID, attemptID string) amqp.Delivery {
+	return amqp.Delivery{
+		ContentType: "application/json",
+		Body: []byte(`{"event_id":"evt-` + attemptID + `","event_type":"attempt.review_requested",` +
+			`"payload":{"attempt_id":"` + attemptID + `","school_id":"` + schoolID + `"}}`),
+		Headers: amqp.Table{
+			"x-death": []interface{}{
+				amqp.Table{
+					"queue":        "edugo.attempt.review_requested",
+					"reason":       "rejected",
+					"count":        int64(1),
+					"time":         deadAt,
+					"exchange":     "edugo.assessments",
+					"routing-keys": []interface{}{"attempt.review_requested"},
+				},
+			},
+			"x-first-death-queue": "edugo.attempt.review_requested",
+			"x-tenant":            "keep",
+		},
+	}
+}
+
+func materialDeliveryWithoutXDeath(jobID string) amqp.Delivery {
+	return amqp.Delivery{
+		Body: []byte(`{"event_id":"evt-m","event_type":"material.assessment_requested","timestamp":"2026-03-01T10:00:00Z",` +
+			`"payload":{"job_id":"` + jobID + `","school_id":"s-2"}}`),
+	}
+}
+
+func TestNewEntry_ConXDeath(t *testing.T) {
+	e := NewEntry("edugo.attempt.review_requested.dlq", reviewDelivery("s-1", "a-1"), testExchanges)
+
+	assert.Equal(t, "attempt.review_requested", e.EventType)
+	assert.Equal(t, "evt-a-1", e.EventID)
+	assert.Equal(t, "s-1", e.SchoolID)
+	assert.Equal(t, "a-1", e.AttemptID)
+	require.Len(t, e.Deaths, 1)
+	assert.Equal(t, int64(1), e.DeathCount())
+	assert.Equal(t, "rejected", e.Reason())
+	assert.Equal(t, deadAt, e.DeadAt)
+	assert.Equal(t, "edugo.assessments", e.Exchange)
+	assert.Equal(t, "attempt.review_requested", e.RoutingKey)
+}
+
+func TestNewEntry_SinXDeathDerivaDestinoDelEventType(t *testing.T) {
+	e := NewEntry("edugo.material.assessment.requested.dlq", materialDeliveryWithoutXDeath("j-1"), testExchanges)
+
+	assert.Empty(t, e.Deaths)
+	assert.Equal(t, "j-1", e.JobID)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), e.DeadAt, "sin x-death cae al timestamp del envelope")
+	assert.Equal(t, "edugo.materials", e.Exchange)
+	assert.Equal(t, "material.assessment_requested", e.RoutingKey)
+}
+
+func TestNewEntry_TrasColasDeRetryUsaElOrigen(t *testing.T) {
+	d := reviewDelivery("s-1", "a-1")
+	d.Headers["x-death"] = []interface{}{
+		amqp.Table{"queue": "edugo.attempt.review_requested", "reason": "rejected", "count": int64(1), "time": deadAt,
+			"exchange": "", "routing-keys": []interface{}{"edugo.attempt.review_requested.retry.10m"}},
+		amqp.Table{"queue": "edugo.attempt.review_requested.retry.10m", "reason": "expired", "count": int64(1), "time": deadAt,
+			"exchange": "", "routing-keys": []interface{}{"edugo.attempt.review_requested.retry.10m"}},
+	}
+	d.Headers[retryqueue.RetryCountHeader] = int32(3)
+	d.Headers[retryqueue.OriginExchangeHeader] = "edugo.assessments"
+	d.Headers[retryqueue.OriginRoutingKeyHeader] = "attempt.review_requested"
+
+	e := NewEntry("edugo.attempt.review_requested.dlq", d, testExchanges)
+	assert.Equal(t, "edugo.assessments", e.Exchange)
+	assert.Equal(t, "attempt.review_requested", e.RoutingKey)
+
+	ch := newFakeChannel(d)
+	r := NewReplayer(ch, testExchanges)
+	entries, err := r.Scan(context.Background(), "q.dlq", 0)
+	require.NoError(t, err)
+	r.Replay(context.Background(), entries, Filter{}, false)
+
+	require.Len(t, ch.published, 1)
+	assert.NotContains(t, ch.published[0].msg.Headers, retryqueue.RetryCountHeader, "el replay empieza de nuevo los escalones")
+	assert.NotContains(t, ch.published[0].msg.Headers, retryqueue.OriginExchangeHeader)
+}
+
+func TestNewEntry_BodyInvalido(t *testing.T) {
+	e := NewEntry("q.dlq", amqp.Delivery{Body: []byte("no-json")}, testExchanges)
+
+	assert.Empty(t, e.EventType)
+	assert.Empty(t, e.RoutingKey, "sin event_type ni x-death no hay destino")
+}
+
+func TestFilter_Match(t *testing.T) {
+	e := NewEntry("q.dlq", reviewDelivery("s-1", "a-1"), testExchanges)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"vacío selecciona todo", Filter{}, true},
+		{"event_type coincide", Filter{EventTypes: []string{"question.prep_requested", "attempt.review_requested"}}, true},
+		{"event_type distinto", Filter{EventTypes: []string{"material.assessment_requested"}}, false},
+		{"school_id distinto", Filter{SchoolID: "s-2"}, false},
+		{"attempt_id coincide", Filter{AttemptID: "a-1"}, true},
+		{"job_id no aplica al carril", Filter{JobID: "j-1"}, false},
+		{"dentro de la ventana", Filter{Since: deadAt.Add(-time.Hour), Until: deadAt.Add(time.Hour)}, true},
+		{"since inclusivo", Filter{Since: deadAt}, true},
+		{"until exclusivo", Filter{Until: deadAt}, false},
+		{"anterior a la ventana", Filter{Since: deadAt.Add(time.Minute)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := tt.filter.Match(e)
+			assert.Equal(t, tt.want, ok)
+			if !ok {
+				assert.NotEmpty(t, reason, "un descarte debe explicar el motivo")
+			}
+		})
+	}
+}
+
+func TestReplayer_ReplaySeleccionados(t *testing.T) {
+	ch := newFakeChannel(reviewDelivery("s-1", "a-1"), reviewDelivery("s-2", "a-2"), materialDeliveryWithoutXDeath("j-1"))
+	r := NewReplayer(ch, testExchanges)
+	r.now = func() time.Time { return deadAt.Add(time.Hour) }
+
+	entries, err := r.Scan(context.Background(), "q.dlq", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	decisions := r.Replay(context.Background(), entries, Filter{SchoolID: "s-1"}, false)
+
+	require.Len(t, decisions, 3)
+	assert.Equal(t, ActionReplayed, decisions[0].Action)
+	assert.Equal(t, ActionSkipped, decisions[1].Action)
+	assert.Equal(t, ActionSkipped, decisions[2].Action)
+
+	require.Len(t, ch.published, 1)
+	pub := ch.published[0]
+	assert.Equal(t, "edugo.assessments", pub.exchange)
+	assert.Equal(t, "attempt.review_requested", pub.key)
+	assert.Equal(t, amqp.Persistent, pub.msg.DeliveryMode)
+	assert.NotContains(t, pub.msg.Headers, "x-death", "los headers de dead-letter no se re-publican")
+	assert.NotContains(t, pub.msg.Headers, "x-first-death-queue")
+	assert.Equal(t, "keep", pub.msg.Headers["x-tenant"], "los headers de negocio se conservan")
+	assert.Equal(t, "q.dlq", pub.msg.Headers["x-dlq-replayed-from"])
+
+	assert.Equal(t, []uint64{1}, ch.acker.acked)
+	assert.Equal(t, []uint64{2, 3}, ch.acker.requeued, "los no seleccionados vuelven a la DLQ")
+}
+
+func TestReplayer_DryRunNoPublica(t *testing.T) {
+	ch := newFakeChannel(reviewDelivery("s-1", "a-1"), materialDeliveryWithoutXDeath("j-1"))
+	r := NewReplayer(ch, testExchanges)
+
+	entries, err := r.Scan(context.Background(), "q.dlq", 0)
+	require.NoError(t, err)
+	decisions := r.Replay(context.Background(), entries, Filter{}, true)
+
+	assert.Equal(t, ActionWouldReplay, decisions[0].Action)
+	assert.Equal(t, ActionWouldReplay, decisions[1].Action)
+	assert.Empty(t, ch.published)
+	assert.Empty(t, ch.acker.acked)
+	assert.Equal(t, []uint64{1, 2}, ch.acker.requeued)
+}
+
+func TestReplayer_PublishFallidoQuedaEnDLQ(t *testing.T) {
+	ch := newFakeChannel(reviewDelivery("s-1", "a-1"))
+	ch.publishErr = errors.New("channel closed")
+	r := NewReplayer(ch, testExchanges)
+
+	entries, err := r.Scan(context.Background(), "q.dlq", 0)
+	require.NoError(t, err)
+	decisions := r.Replay(context.Background(), entries, Filter{}, false)
+
+	assert.Equal(t, ActionFailed, decisions[0].Action)
+	assert.Contains(t, decisions[0].Reason, "channel closed")
+	assert.Empty(t, ch.acker.acked)
+	assert.Equal(t, []uint64{1}, ch.acker.requeued)
+}
+
+func TestReplayer_SinDestinoFalla(t *testing.T) {
+	ch := newFakeChannel(amqp.Delivery{Body: []byte(`{"event_type":"unknown.event"}`)})
+	r := NewReplayer(ch, testExchanges)
+
+	entries, err := r.Scan(context.Background(), "q.dlq", 0)
+	require.NoError(t, err)
+	decisions := r.Replay(context.Background(), entries, Filter{}, false)
+
+	assert.Equal(t, ActionFailed, decisions[0].Action)
+	assert.Empty(t, ch.published)
+}
+
+func TestReplayer_ScanRespetaLimit(t *testing.T) {
+	ch := newFakeChannel(reviewDelivery("s-1", "a-1"), reviewDelivery("s-1", "a-2"), reviewDelivery("s-1", "a-3"))
+	r := NewReplayer(ch, testExchanges)
+
+	entries, err := r.Scan(context.Background(), "q.dlq", 2)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	r.Release(entries)
+	assert.Equal(t, []uint64{1, 2}, ch.acker.requeued)
+}
+
+func TestWriteDecisions_Resumen(t *testing.T) {
+	e := NewEntry("q.dlq", reviewDelivery("s-1", "a-1"), testExchanges)
+	var buf bytes.Buffer
+
+	require.NoError(t, WriteDecisions(&buf, []Decision{
+		{Entry: e, Action: ActionReplayed},
+		{Entry: e, Action: ActionSkipped, Reason: "school_id no coincide"},
+	}))
+
+	out := buf.String()
+	assert.Contains(t, out, "attempt:a-1")
+	assert.Contains(t, out, "edugo.assessments/attempt.review_requested")
+	assert.Contains(t, out, "total=2 replayed=1 would_replay=0 skipped=1 failed=0")
+}
@@ -0,0 +1,51 @@
+package dlq
+
+import (
+	"fmt"
+	"time"
+)
+
+// Filter selecciona los mensajes a re-publicar. Los campos vacíos no filtran; los
+// no vacíos se combinan con AND. Since/Until acotan Entry.DeadAt (Since inclusive,
+// Until exclusivo).
+type Filter struct {
+	EventTypes []string
+	SchoolID   string
+	JobID      string
+	AttemptID  string
+	Since      time.Time
+	Until      time.Time
+}
+
+// Match indica si la entrada pasa el filtro y, si no, el primer criterio que la
+// descarta (para el reporte por mensaje).
+func (f Filter) Match(e Entry) (bool, string) {
+	if len(f.EventTypes) > 0 && !contains(f.EventTypes, e.EventType) {
+		return false, fmt.Sprintf("event_type %q no seleccionado", e.EventType)
+	}
+	if f.SchoolID != "" && e.SchoolID != f.SchoolID {
+		return false, "school_id no coincide"
+	}
+	if f.JobID != "" && e.JobID != f.JobID {
+		return false, "job_id no coincide"
+	}
+	if f.AttemptID != "" && e.AttemptID != f.AttemptID {
+		return false, "attempt_id no coincide"
+	}
+	if !f.Since.IsZero() && e.DeadAt.Before(f.Since) {
+		return false, "anterior a la ventana"
+	}
+	if !f.Until.IsZero() && !e.DeadAt.Before(f.Until) {
+		return false, "posterior a la ventana"
+	}
+	return true, ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
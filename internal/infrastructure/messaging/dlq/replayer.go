@@ -0,0 +1,164 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/publisher"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Channel es la porción de *amqp.Channel que usa el Replayer. Se define como
+// interfaz para poder simular la cola en tests sin RabbitMQ.
+type Channel interface {
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// Action es la decisión tomada sobre un mensaje de la DLQ.
+type Action string
+
+const (
+	// ActionReplayed: re-publicado a su destino original y ACKeado en la DLQ.
+	ActionReplayed Action = "replayed"
+	// ActionWouldReplay: pasa el filtro pero es un dry-run; queda en la DLQ.
+	ActionWouldReplay Action = "would_replay"
+	// ActionSkipped: no pasa el filtro; queda en la DLQ.
+	ActionSkipped Action = "skipped"
+	// ActionFailed: pasa el filtro pero no se pudo re-publicar; queda en la DLQ.
+	ActionFailed Action = "failed"
+)
+
+// Decision es la línea del reporte por mensaje.
+type Decision struct {
+	Entry
+	Action Action `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Replayer lee mensajes de una DLQ y re-publica los seleccionados a su exchange y
+// routing key originales.
+//
+// Los mensajes se leen con basic.get SIN auto-ack y se retienen hasta el final:
+// mientras siguen sin ACK el broker no los vuelve a entregar, así que un Scan recorre
+// cada mensaje una sola vez. Los que no se re-publican se devuelven a la cola con
+// Release (nack + requeue), conservando su orden.
+type Replayer struct {
+	ch        Channel
+	exchanges publisher.Exchanges
+	now       func() time.Time
+}
+
+// NewReplayer construye el Replayer. exchanges resuelve el destino de los mensajes
+// sin x-death (ver NewEntry).
+func NewReplayer(ch Channel, exchanges publisher.Exchanges) *Replayer {
+	return &Replayer{ch: ch, exchanges: exchanges, now: time.Now}
+}
+
+// Scan lee hasta limit mensajes de la DLQ queue (limit <= 0 = hasta vaciarla). Las
+// entradas devueltas quedan SIN ACK: el llamador debe pasarlas por Replay o Release.
+// Si falla a mitad, devuelve a la cola lo ya leído.
+func (r *Replayer) Scan(ctx context.Context, queue string, limit int) ([]Entry, error) {
+	var entries []Entry
+	for limit <= 0 || len(entries) < limit {
+		if err := ctx.Err(); err != nil {
+			r.Release(entries)
+			return nil, err
+		}
+		d, ok, err := r.ch.Get(queue, false)
+		if err != nil {
+			r.Release(entries)
+			return nil, fmt.Errorf("leyendo DLQ %s: %w", queue, err)
+		}
+		if !ok {
+			break
+		}
+		entries = append(entries, NewEntry(queue, d, r.exchanges))
+	}
+	return entries, nil
+}
+
+// Replay decide cada entrada con filter: las seleccionadas se re-publican (salvo
+// dryRun) y se ACKean en la DLQ; el resto se devuelve a la cola. Todas las entradas
+// quedan resueltas al volver (no hace falta Release). Un fallo de publish no corta
+// el recorrido: se reporta como ActionFailed y el mensaje queda en la DLQ.
+func (r *Replayer) Replay(ctx context.Context, entries []Entry, filter Filter, dryRun bool) []Decision {
+	decisions := make([]Decision, 0, len(entries))
+	for _, e := range entries {
+		decisions = append(decisions, r.replayOne(ctx, e, filter, dryRun))
+	}
+	return decisions
+}
+
+func (r *Replayer) replayOne(ctx context.Context, e Entry, filter Filter, dryRun bool) Decision {
+	if ok, why := filter.Match(e); !ok {
+		r.release(e)
+		return Decision{Entry: e, Action: ActionSkipped, Reason: why}
+	}
+	if e.RoutingKey == "" {
+		r.release(e)
+		return Decision{Entry: e, Action: ActionFailed, Reason: "sin destino original (ni x-death ni event_type conocido)"}
+	}
+	if dryRun {
+		r.release(e)
+		return Decision{Entry: e, Action: ActionWouldReplay, Reason: "dry-run"}
+	}
+
+	if err := r.ch.PublishWithContext(ctx, e.Exchange, e.RoutingKey, false, false, r.republishing(e)); err != nil {
+		r.release(e)
+		return Decision{Entry: e, Action: ActionFailed, Reason: err.Error()}
+	}
+	if err := e.delivery.Ack(false); err != nil {
+		// Ya se re-publicó: el mensaje queda duplicado en la DLQ, pero reprocesarlo
+		// es seguro (los carriles son idempotentes).
+		return Decision{Entry: e, Action: ActionReplayed, Reason: "re-publicado, pero el ACK en la DLQ falló: " + err.Error()}
+	}
+	return Decision{Entry: e, Action: ActionReplayed}
+}
+
+// Release devuelve las entradas a la DLQ (nack + requeue) sin tocarlas.
+func (r *Replayer) Release(entries []Entry) {
+	for _, e := range entries {
+		r.release(e)
+	}
+}
+
+func (r *Replayer) release(e Entry) {
+	if e.delivery.Acknowledger != nil {
+		_ = e.delivery.Nack(false, true)
+	}
+}
+
+// republishing reconstruye el mensaje para su destino original. Conserva body,
+// propiedades y headers de negocio; descarta los headers de dead-letter de RabbitMQ
+// (x-death, x-first-death-*, x-last-death-*) y los de retryqueue (el replay empieza
+// de nuevo los escalones de retry) y marca el origen del replay.
+func (r *Replayer) republishing(e Entry) amqp.Publishing {
+	d := e.delivery
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		if k == "x-death" || strings.HasPrefix(k, "x-first-death-") || strings.HasPrefix(k, "x-last-death-") ||
+			strings.HasPrefix(k, "x-retry-") {
+			continue
+		}
+		headers[k] = v
+	}
+	headers["x-dlq-replayed-from"] = e.Queue
+	headers["x-dlq-replayed-at"] = r.now().UTC().Format(time.RFC3339)
+
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
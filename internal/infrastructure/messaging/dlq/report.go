@@ -0,0 +1,77 @@
+package dlq
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteEntries escribe el listado de una DLQ: una fila por mensaje con event_type,
+// ids de correlación, muertes (x-death) y destino original.
+func WriteEntries(w io.Writer, entries []Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "QUEUE\tTAG\tEVENT_TYPE\tEVENT_ID\tSCHOOL_ID\tREF\tDEATHS\tREASON\tDEAD_AT\tTARGET")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.Queue, e.DeliveryTag, dash(e.EventType), dash(e.EventID), dash(e.SchoolID),
+			dash(e.ref()), e.DeathCount(), dash(e.Reason()), formatTime(e.DeadAt), dash(e.target()))
+	}
+	return tw.Flush()
+}
+
+// WriteDecisions escribe el reporte de decisión por mensaje de un replay y un
+// resumen por acción.
+func WriteDecisions(w io.Writer, decisions []Decision) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "QUEUE\tTAG\tEVENT_TYPE\tEVENT_ID\tSCHOOL_ID\tREF\tACTION\tTARGET\tREASON")
+	counts := map[Action]int{}
+	for _, d := range decisions {
+		counts[d.Action]++
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Queue, d.DeliveryTag, dash(d.EventType), dash(d.EventID), dash(d.SchoolID),
+			dash(d.ref()), d.Action, dash(d.target()), dash(d.Reason))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\ntotal=%d %s=%d %s=%d %s=%d %s=%d\n", len(decisions),
+		ActionReplayed, counts[ActionReplayed], ActionWouldReplay, counts[ActionWouldReplay],
+		ActionSkipped, counts[ActionSkipped], ActionFailed, counts[ActionFailed])
+	return err
+}
+
+// ref es el id del recurso del carril: attempt, question o job.
+func (e Entry) ref() string {
+	switch {
+	case e.AttemptID != "":
+		return "attempt:" + e.AttemptID
+	case e.JobID != "":
+		return "job:" + e.JobID
+	case e.QuestionID != "":
+		return "question:" + e.QuestionID
+	default:
+		return ""
+	}
+}
+
+func (e Entry) target() string {
+	if e.RoutingKey == "" {
+		return ""
+	}
+	return e.Exchange + "/" + e.RoutingKey
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,431 @@
+// Package memory es un broker en memoria con la porción de AMQP que usa el worker:
+// exchanges, colas con bindings por routing key, prefetch, ACK/NACK, dead-letter
+// (x-dead-letter-exchange/routing-key, con su x-death) y colas con TTL
+// (x-message-ttl). Channel satisface retryqueue.Channel y publisher.Channel, así que
+// el modo --local de cmd corre los consumers y el publisher reales sobre él, con la
+// misma semántica de DLQ y reintentos diferidos que contra RabbitMQ.
+//
+// No persiste nada ni intenta imitar AMQP más allá de eso: los bindings son por
+// routing key exacta (los carriles bindean routing key = event_type, sin comodines)
+// y se ignoran prioridades y demás argumentos de cola.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Argumentos de cola que el broker interpreta.
+const (
+	argDeadLetterExchange   = "x-dead-letter-exchange"
+	argDeadLetterRoutingKey = "x-dead-letter-routing-key"
+	argMessageTTL           = "x-message-ttl"
+)
+
+// Motivos de dead-letter en x-death (los mismos que usa RabbitMQ).
+const (
+	reasonRejected = "rejected"
+	reasonExpired  = "expired"
+)
+
+// Errores del broker. Como en AMQP, declarar o publicar contra algo inexistente
+// es un error del canal.
+var (
+	ErrExchangeNotFound = errors.New("exchange inexistente")
+	ErrQueueNotFound    = errors.New("cola inexistente")
+)
+
+// Message es un mensaje con su destino original.
+type Message struct {
+	Exchange   string
+	RoutingKey string
+	amqp.Publishing
+
+	id          uint64
+	redelivered bool
+}
+
+// QueueStats son los contadores de una cola.
+type QueueStats struct {
+	// Ready son los mensajes en cola (en una cola con TTL, los que esperan expirar).
+	Ready int
+	// Unacked son los entregados a un consumer sin ACK/NACK todavía.
+	Unacked int
+	// Enqueued son los mensajes que llegaron a la cola desde que se declaró.
+	Enqueued int
+	// Acked son los confirmados con ACK.
+	Acked int
+	// DeadLettered son los que salieron por dead-letter (NACK sin requeue o TTL).
+	DeadLettered int
+}
+
+type queue struct {
+	name     string
+	args     amqp.Table
+	ttl      time.Duration
+	ready    []Message
+	stats    QueueStats
+	watchers int
+}
+
+type binding struct {
+	exchange, key string
+}
+
+// unacked es una entrega pendiente de ACK/NACK.
+type unacked struct {
+	queue   *queue
+	msg     Message
+	channel *Channel
+	cons    *consumer
+}
+
+// Broker guarda exchanges, colas, bindings y entregas pendientes. Es seguro para
+// uso concurrente.
+type Broker struct {
+	mu         sync.Mutex
+	exchanges  map[string]string
+	queues     map[string]*queue
+	bindings   map[binding][]string
+	pending    map[uint64]*unacked
+	unroutable []Message
+	expiring   int
+	lastID     uint64
+	// changed se cierra y se reemplaza en cada cambio de estado: las entregas y
+	// WaitIdle esperan en él en vez de sondear.
+	changed chan struct{}
+}
+
+// NewBroker crea un broker vacío con el exchange por defecto ("", que enruta a la
+// cola con el nombre de la routing key).
+func NewBroker() *Broker {
+	return &Broker{
+		exchanges: map[string]string{"": "direct"},
+		queues:    make(map[string]*queue),
+		bindings:  make(map[binding][]string),
+		pending:   make(map[uint64]*unacked),
+		changed:   make(chan struct{}),
+	}
+}
+
+// Channel abre un canal sobre el broker.
+func (b *Broker) Channel() *Channel {
+	return &Channel{broker: b}
+}
+
+func (b *Broker) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *Broker) declareExchange(name, kind string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.exchanges[name]; !ok {
+		b.exchanges[name] = kind
+	}
+}
+
+func (b *Broker) declareQueue(name string, args amqp.Table) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.queues[name]; ok {
+		return
+	}
+	q := &queue{name: name, args: args}
+	switch ttl := args[argMessageTTL].(type) {
+	case int64:
+		q.ttl = time.Duration(ttl) * time.Millisecond
+	case int32:
+		q.ttl = time.Duration(ttl) * time.Millisecond
+	case int:
+		q.ttl = time.Duration(ttl) * time.Millisecond
+	}
+	b.queues[name] = q
+}
+
+func (b *Broker) bind(queueName, key, exchange string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.queues[queueName]; !ok {
+		return fmt.Errorf("%w: %s", ErrQueueNotFound, queueName)
+	}
+	if _, ok := b.exchanges[exchange]; !ok {
+		return fmt.Errorf("%w: %s", ErrExchangeNotFound, exchange)
+	}
+	k := binding{exchange: exchange, key: key}
+	for _, name := range b.bindings[k] {
+		if name == queueName {
+			return nil
+		}
+	}
+	b.bindings[k] = append(b.bindings[k], queueName)
+	return nil
+}
+
+// publishLocked enruta m. Un exchange con nombre sin binding para la routing key
+// descarta el mensaje (como RabbitMQ sin mandatory) y lo deja en Unroutable.
+func (b *Broker) publishLocked(m Message) error {
+	if _, ok := b.exchanges[m.Exchange]; !ok {
+		return fmt.Errorf("%w: %s", ErrExchangeNotFound, m.Exchange)
+	}
+	var targets []string
+	if m.Exchange == "" {
+		targets = []string{m.RoutingKey}
+	} else {
+		targets = b.bindings[binding{exchange: m.Exchange, key: m.RoutingKey}]
+	}
+
+	routed := false
+	for _, name := range targets {
+		q, ok := b.queues[name]
+		if !ok {
+			continue
+		}
+		routed = true
+		b.lastID++
+		copied := m
+		copied.id = b.lastID
+		b.enqueueLocked(q, copied)
+	}
+	if !routed {
+		b.unroutable = append(b.unroutable, m)
+	}
+	return nil
+}
+
+func (b *Broker) enqueueLocked(q *queue, m Message) {
+	q.ready = append(q.ready, m)
+	q.stats.Ready++
+	q.stats.Enqueued++
+	if q.ttl > 0 {
+		// Las colas con TTL (las de retry) no tienen consumers: el mensaje espera
+		// su TTL y sale por dead-letter.
+		b.expiring++
+		time.AfterFunc(q.ttl, func() { b.expire(q, m.id) })
+	}
+	b.notifyLocked()
+}
+
+func (b *Broker) expire(q *queue, id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expiring--
+	for i, m := range q.ready {
+		if m.id == id {
+			q.ready = append(q.ready[:i], q.ready[i+1:]...)
+			q.stats.Ready--
+			b.deadLetterLocked(q, m, reasonExpired)
+			break
+		}
+	}
+	b.notifyLocked()
+}
+
+// deadLetterLocked re-publica m según el dead-letter de su cola, con el x-death que
+// agrega RabbitMQ (cmd/dlq lee de ahí el destino original). Sin
+// x-dead-letter-exchange el mensaje se descarta.
+func (b *Broker) deadLetterLocked(q *queue, m Message, reason string) {
+	q.stats.DeadLettered++
+	exchange, ok := q.args[argDeadLetterExchange].(string)
+	if !ok {
+		return
+	}
+	key := m.RoutingKey
+	if k, ok := q.args[argDeadLetterRoutingKey].(string); ok {
+		key = k
+	}
+
+	headers := amqp.Table{}
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	death := amqp.Table{
+		"queue":        q.name,
+		"reason":       reason,
+		"exchange":     m.Exchange,
+		"routing-keys": []interface{}{m.RoutingKey},
+		"count":        int64(1),
+		"time":         time.Now(),
+	}
+	deaths, _ := headers["x-death"].([]interface{})
+	headers["x-death"] = append([]interface{}{death}, deaths...)
+	if _, ok := headers["x-first-death-queue"]; !ok {
+		headers["x-first-death-queue"] = q.name
+		headers["x-first-death-reason"] = reason
+		headers["x-first-death-exchange"] = m.Exchange
+	}
+
+	dead := Message{Exchange: exchange, RoutingKey: key, Publishing: m.Publishing}
+	dead.Headers = headers
+	_ = b.publishLocked(dead)
+}
+
+// next saca el siguiente mensaje de q para cons si hay mensajes y el consumer está
+// bajo su prefetch. Si no, devuelve el canal de cambios para esperar.
+func (b *Broker) next(q *queue, cons *consumer) (amqp.Delivery, <-chan struct{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(q.ready) == 0 || (cons.prefetch > 0 && cons.unacked >= cons.prefetch) {
+		return amqp.Delivery{}, b.changed, false
+	}
+	m := q.ready[0]
+	q.ready = q.ready[1:]
+	q.stats.Ready--
+	q.stats.Unacked++
+	cons.unacked++
+	b.lastID++
+	tag := b.lastID
+	b.pending[tag] = &unacked{queue: q, msg: m, channel: cons.channel, cons: cons}
+	b.notifyLocked()
+	return delivery(cons, tag, m), nil, true
+}
+
+func delivery(cons *consumer, tag uint64, m Message) amqp.Delivery {
+	return amqp.Delivery{
+		Acknowledger:    cons.channel,
+		Headers:         m.Headers,
+		ContentType:     m.ContentType,
+		ContentEncoding: m.ContentEncoding,
+		DeliveryMode:    m.DeliveryMode,
+		Priority:        m.Priority,
+		CorrelationId:   m.CorrelationId,
+		ReplyTo:         m.ReplyTo,
+		Expiration:      m.Expiration,
+		MessageId:       m.MessageId,
+		Timestamp:       m.Timestamp,
+		Type:            m.Type,
+		UserId:          m.UserId,
+		AppId:           m.AppId,
+		ConsumerTag:     cons.tag,
+		DeliveryTag:     tag,
+		Redelivered:     m.redelivered,
+		Exchange:        m.Exchange,
+		RoutingKey:      m.RoutingKey,
+		Body:            m.Body,
+	}
+}
+
+// settle cierra una entrega: ACK, NACK con requeue (vuelve al frente de la cola) o
+// NACK sin requeue (dead-letter).
+func (b *Broker) settle(tag uint64, ack, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pending[tag]
+	if !ok {
+		return fmt.Errorf("delivery tag %d desconocido", tag)
+	}
+	b.settleLocked(tag, p, ack, requeue)
+	return nil
+}
+
+func (b *Broker) settleLocked(tag uint64, p *unacked, ack, requeue bool) {
+	delete(b.pending, tag)
+	p.queue.stats.Unacked--
+	p.cons.unacked--
+	switch {
+	case ack:
+		p.queue.stats.Acked++
+	case requeue:
+		m := p.msg
+		m.redelivered = true
+		p.queue.ready = append([]Message{m}, p.queue.ready...)
+		p.queue.stats.Ready++
+	default:
+		b.deadLetterLocked(p.queue, p.msg, reasonRejected)
+	}
+	b.notifyLocked()
+}
+
+// requeueChannel devuelve a su cola las entregas pendientes de ch (al cerrar un
+// canal, como AMQP).
+func (b *Broker) requeueChannel(ch *Channel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for tag, p := range b.pending {
+		if p.channel == ch {
+			b.settleLocked(tag, p, false, true)
+		}
+	}
+}
+
+func (b *Broker) watch(q *queue, delta int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q.watchers += delta
+	b.notifyLocked()
+}
+
+// WaitIdle espera a que el broker quede en reposo: sin mensajes en cola ni
+// pendientes de ACK en las colas con consumers y sin mensajes esperando su TTL
+// (reintentos diferidos en curso). Las colas sin consumer, como las DLQ, no cuentan.
+// Devuelve el error de ctx si vence antes.
+func (b *Broker) WaitIdle(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		idle := b.expiring == 0
+		for _, q := range b.queues {
+			if q.watchers > 0 && (q.stats.Ready > 0 || q.stats.Unacked > 0) {
+				idle = false
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+		if idle {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Queues devuelve los nombres de las colas declaradas, ordenados.
+func (b *Broker) Queues() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.queues))
+	for name := range b.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Messages devuelve una copia de los mensajes en cola (p.ej. los de una DLQ).
+func (b *Broker) Messages(queueName string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[queueName]
+	if !ok {
+		return nil
+	}
+	return append([]Message(nil), q.ready...)
+}
+
+// Stats devuelve los contadores de una cola (cero si no existe).
+func (b *Broker) Stats(queueName string) QueueStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if q, ok := b.queues[queueName]; ok {
+		return q.stats
+	}
+	return QueueStats{}
+}
+
+// Unroutable devuelve lo publicado en exchanges sin binding para su routing key.
+// En modo local son los eventos de cierre de carril (attempt.ai_reviewed, ...), que
+// en producción consumen otros servicios.
+func (b *Broker) Unroutable() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.unroutable...)
+}
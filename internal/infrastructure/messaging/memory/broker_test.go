@@ -0,0 +1,270 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/messaging/retryqueue"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (l *nopLogger) Debug(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Info(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Warn(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Error(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Fatal(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Sync() error                                     { return nil }
+func (l *nopLogger) With(keysAndValues ...interface{}) logger.Logger { return l }
+
+const (
+	testExchange = "edugo.assessments"
+	testKey      = "attempt.review_requested"
+	testQueue    = "edugo.attempt.review_requested"
+	testDLX      = "edugo_dlx"
+	testDLQ      = testQueue + ".dlq"
+)
+
+// newTopology declara la cola de un carril con su DLQ, como setupRabbitMQ.
+func newTopology(t *testing.T) (*Broker, *Channel) {
+	t.Helper()
+	b := NewBroker()
+	ch := b.Channel()
+	require.NoError(t, ch.ExchangeDeclare(testExchange, "topic", true, false, false, false, nil))
+	require.NoError(t, ch.ExchangeDeclare(testDLX, "direct", true, false, false, false, nil))
+	_, err := ch.QueueDeclare(testQueue, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    testDLX,
+		"x-dead-letter-routing-key": testDLQ,
+	})
+	require.NoError(t, err)
+	require.NoError(t, ch.QueueBind(testQueue, testKey, testExchange, false, nil))
+	_, err = ch.QueueDeclare(testDLQ, true, false, false, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, ch.QueueBind(testDLQ, testDLQ, testDLX, false, nil))
+	return b, ch
+}
+
+func publish(t *testing.T, ch *Channel, body string) {
+	t.Helper()
+	require.NoError(t, ch.PublishWithContext(context.Background(), testExchange, testKey, false, false,
+		amqp.Publishing{ContentType: "application/json", Body: []byte(body)}))
+}
+
+func receive(t *testing.T, deliveries <-chan amqp.Delivery) amqp.Delivery {
+	t.Helper()
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("sin entrega")
+		return amqp.Delivery{}
+	}
+}
+
+func TestBroker_EnrutaPorBindingYConfirma(t *testing.T) {
+	b, ch := newTopology(t)
+	publish(t, ch, `{"n":1}`)
+
+	deliveries, err := ch.Consume(testQueue, "c", false, false, false, false, nil)
+	require.NoError(t, err)
+	d := receive(t, deliveries)
+	assert.Equal(t, `{"n":1}`, string(d.Body))
+	assert.Equal(t, testExchange, d.Exchange)
+	assert.Equal(t, testKey, d.RoutingKey)
+	assert.Equal(t, 1, b.Stats(testQueue).Unacked)
+
+	require.NoError(t, d.Ack(false))
+	assert.Equal(t, QueueStats{Enqueued: 1, Acked: 1}, b.Stats(testQueue))
+	require.NoError(t, b.WaitIdle(context.Background()))
+}
+
+func TestChannel_ModoConfirmAckeaCadaPublicacion(t *testing.T) {
+	_, ch := newTopology(t)
+	require.NoError(t, ch.Confirm(false))
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 2))
+
+	assert.Equal(t, uint64(1), ch.GetNextPublishSeqNo())
+	publish(t, ch, `{"n":1}`)
+	publish(t, ch, `{"n":2}`)
+	assert.Equal(t, amqp.Confirmation{DeliveryTag: 1, Ack: true}, <-confirms)
+	assert.Equal(t, amqp.Confirmation{DeliveryTag: 2, Ack: true}, <-confirms)
+
+	require.NoError(t, ch.Close())
+	_, open := <-confirms
+	assert.False(t, open, "cerrar el canal cierra los confirms")
+}
+
+func TestBroker_SinBindingQuedaUnroutable(t *testing.T) {
+	b, ch := newTopology(t)
+	require.NoError(t, ch.PublishWithContext(context.Background(), testExchange, "attempt.ai_reviewed", false, false, amqp.Publishing{}))
+
+	require.Len(t, b.Unroutable(), 1)
+	assert.Equal(t, "attempt.ai_reviewed", b.Unroutable()[0].RoutingKey)
+
+	err := ch.PublishWithContext(context.Background(), "no.existe", testKey, false, false, amqp.Publishing{})
+	assert.ErrorIs(t, err, ErrExchangeNotFound)
+}
+
+func TestBroker_NackSinRequeueVaAlDeadLetterConXDeath(t *testing.T) {
+	b, ch := newTopology(t)
+	publish(t, ch, `{}`)
+	deliveries, err := ch.Consume(testQueue, "c", false, false, false, false, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, receive(t, deliveries).Nack(false, false))
+
+	dead := b.Messages(testDLQ)
+	require.Len(t, dead, 1)
+	deaths, ok := dead[0].Headers["x-death"].([]interface{})
+	require.True(t, ok)
+	death := deaths[0].(amqp.Table)
+	assert.Equal(t, testQueue, death["queue"])
+	assert.Equal(t, "rejected", death["reason"])
+	assert.Equal(t, testExchange, death["exchange"])
+	assert.Equal(t, []interface{}{testKey}, death["routing-keys"])
+	assert.Equal(t, 1, b.Stats(testQueue).DeadLettered)
+}
+
+func TestBroker_NackConRequeueVuelveRedelivered(t *testing.T) {
+	_, ch := newTopology(t)
+	publish(t, ch, `{"n":1}`)
+	publish(t, ch, `{"n":2}`)
+	deliveries, err := ch.Consume(testQueue, "c", false, false, false, false, nil)
+	require.NoError(t, err)
+
+	first := receive(t, deliveries)
+	require.NoError(t, first.Nack(false, true))
+	// El segundo ya pudo salir de la cola antes del Nack: se busca el reentregado.
+	var again amqp.Delivery
+	for i := 0; i < 2; i++ {
+		if d := receive(t, deliveries); d.Redelivered {
+			again = d
+		}
+	}
+	assert.Equal(t, `{"n":1}`, string(again.Body))
+}
+
+func TestBroker_PrefetchAcotaLasEntregasSinAck(t *testing.T) {
+	b, ch := newTopology(t)
+	for i := 0; i < 3; i++ {
+		publish(t, ch, `{}`)
+	}
+	require.NoError(t, ch.Qos(2, 0, false))
+	deliveries, err := ch.Consume(testQueue, "c", false, false, false, false, nil)
+	require.NoError(t, err)
+
+	first := receive(t, deliveries)
+	receive(t, deliveries)
+	select {
+	case <-deliveries:
+		t.Fatal("entregó más que el prefetch")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, 1, b.Stats(testQueue).Ready)
+
+	require.NoError(t, first.Ack(false))
+	receive(t, deliveries)
+}
+
+func TestBroker_CloseDevuelveLasEntregasSinAck(t *testing.T) {
+	b, ch := newTopology(t)
+	publish(t, ch, `{}`)
+	consumerCh := b.Channel()
+	deliveries, err := consumerCh.Consume(testQueue, "c", false, false, false, false, nil)
+	require.NoError(t, err)
+	receive(t, deliveries)
+
+	require.NoError(t, consumerCh.Close())
+
+	_, open := <-deliveries
+	assert.False(t, open, "cerrar el canal cierra sus entregas")
+	assert.Equal(t, QueueStats{Ready: 1, Enqueued: 1}, b.Stats(testQueue))
+	assert.ErrorIs(t, consumerCh.PublishWithContext(context.Background(), testExchange, testKey, false, false, amqp.Publishing{}), amqp.ErrClosed)
+}
+
+func TestBroker_TTLDevuelveElMensajePorDeadLetter(t *testing.T) {
+	b, ch := newTopology(t)
+	_, err := ch.QueueDeclare("q.retry.10ms", true, false, false, false, amqp.Table{
+		"x-message-ttl":             int64(10),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": testQueue,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ch.PublishWithContext(context.Background(), "", "q.retry.10ms", false, false, amqp.Publishing{Body: []byte(`{}`)}))
+	assert.Equal(t, 1, b.Stats("q.retry.10ms").Ready)
+
+	require.NoError(t, b.WaitIdle(context.Background()))
+	assert.Equal(t, 1, b.Stats(testQueue).Ready, "al expirar vuelve a la cola del carril")
+	require.Len(t, b.Messages(testQueue), 1)
+	assert.Equal(t, "expired", b.Messages(testQueue)[0].Headers["x-first-death-reason"])
+}
+
+var errPermanent = errors.New("evento malformado")
+
+// TestBroker_ConConsumerDeRetryQueue corre el consumer de carril real sobre el
+// broker: reintentos diferidos por colas con TTL y DLQ al agotar los escalones o
+// ante un error permanente.
+func TestBroker_ConConsumerDeRetryQueue(t *testing.T) {
+	b, ch := newTopology(t)
+	c := retryqueue.NewChannelConsumer(func() (retryqueue.Channel, error) { return b.Channel(), nil }, retryqueue.Config{
+		Name:          "edugo-worker",
+		PrefetchCount: 5,
+		DLXExchange:   testDLX,
+		DLQName:       testDLQ,
+		Tiers:         []time.Duration{5 * time.Millisecond, 10 * time.Millisecond},
+		IsPermanent:   func(err error) bool { return errors.Is(err, errPermanent) },
+	}, &nopLogger{})
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	handler := func(_ context.Context, body []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[string(body)]++
+		switch string(body) {
+		case "recupera":
+			if attempts["recupera"] < 3 {
+				return errors.New("ollama caído")
+			}
+			return nil
+		case "siempre-falla":
+			return errors.New("timeout")
+		case "malformado":
+			return errPermanent
+		}
+		return nil
+	}
+	require.NoError(t, c.ConsumeWithDLQ(context.Background(), testQueue, handler))
+
+	for _, body := range []string{"ok", "recupera", "siempre-falla", "malformado"} {
+		publish(t, ch, body)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, b.WaitIdle(ctx))
+	c.Stop()
+	require.NoError(t, c.Wait())
+
+	assert.Equal(t, map[string]int{"ok": 1, "recupera": 3, "siempre-falla": 3, "malformado": 1}, attempts)
+	dead := b.Messages(testDLQ)
+	require.Len(t, dead, 2)
+	bodies := []string{string(dead[0].Body), string(dead[1].Body)}
+	assert.ElementsMatch(t, []string{"siempre-falla", "malformado"}, bodies)
+	for _, m := range dead {
+		if string(m.Body) == "siempre-falla" {
+			assert.Equal(t, int32(2), m.Headers[retryqueue.RetryCountHeader])
+			assert.Equal(t, testExchange, m.Headers[retryqueue.OriginExchangeHeader])
+			assert.Equal(t, testKey, m.Headers[retryqueue.OriginRoutingKeyHeader])
+		}
+	}
+	assert.Equal(t, 2, b.Stats(retryqueue.QueueName(testQueue, 5*time.Millisecond)).Enqueued)
+	assert.Equal(t, 2, b.Stats(retryqueue.QueueName(testQueue, 10*time.Millisecond)).Enqueued)
+}
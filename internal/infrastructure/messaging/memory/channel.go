@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Channel es un canal sobre el Broker. Satisface retryqueue.Channel (consumers de
+// carril) y publisher.Channel (EventPublisher), y es el Acknowledger de las
+// entregas que produce. Cerrarlo cancela sus consumers y devuelve a la cola las
+// entregas sin ACK, como en AMQP.
+type Channel struct {
+	broker *Broker
+
+	mu        sync.Mutex
+	prefetch  int
+	consumers map[string]*consumer
+	closed    bool
+
+	// confirming, publishSeq y confirms implementan el modo confirm: cada
+	// publicación recibe su número de secuencia y, como se enruta en el acto, su
+	// basic.ack inmediato.
+	confirming bool
+	publishSeq uint64
+	confirms   []chan amqp.Confirmation
+}
+
+// consumer bombea los mensajes de una cola a su canal de entregas respetando el
+// prefetch.
+type consumer struct {
+	tag        string
+	channel    *Channel
+	prefetch   int
+	unacked    int
+	deliveries chan amqp.Delivery
+	stop       chan struct{}
+	stopOnce   sync.Once
+	done       chan struct{}
+}
+
+func (c *consumer) cancel() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// Qos fija el prefetch de los consumers que se creen después en el canal.
+func (c *Channel) Qos(prefetchCount, _ int, _ bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefetch = prefetchCount
+	return nil
+}
+
+// ExchangeDeclare declara un exchange. Es idempotente.
+func (c *Channel) ExchangeDeclare(name, kind string, _, _, _, _ bool, _ amqp.Table) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	c.broker.declareExchange(name, kind)
+	return nil
+}
+
+// QueueDeclare declara una cola. Interpreta x-dead-letter-exchange,
+// x-dead-letter-routing-key y x-message-ttl; si la cola ya existe conserva sus
+// argumentos originales.
+func (c *Channel) QueueDeclare(name string, _, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	if err := c.check(); err != nil {
+		return amqp.Queue{}, err
+	}
+	c.broker.declareQueue(name, args)
+	stats := c.broker.Stats(name)
+	return amqp.Queue{Name: name, Messages: stats.Ready}, nil
+}
+
+// QueueBind enruta a name lo publicado en exchange con routing key key.
+func (c *Channel) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	return c.broker.bind(name, key, exchange)
+}
+
+// PublishWithContext publica un mensaje en exchange con routing key key.
+func (c *Channel) PublishWithContext(ctx context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.broker.mu.Lock()
+	err := c.broker.publishLocked(Message{Exchange: exchange, RoutingKey: key, Publishing: msg})
+	c.broker.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.confirming {
+		c.publishSeq++
+		for _, confirm := range c.confirms {
+			confirm <- amqp.Confirmation{DeliveryTag: c.publishSeq, Ack: true}
+		}
+	}
+	return nil
+}
+
+// Confirm pone el canal en modo confirm (publisher confirms).
+func (c *Channel) Confirm(_ bool) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.confirming = true
+	return nil
+}
+
+// NotifyPublish registra confirm para recibir los confirms de las publicaciones;
+// se cierra al cerrar el canal.
+func (c *Channel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		close(confirm)
+		return confirm
+	}
+	c.confirms = append(c.confirms, confirm)
+	return confirm
+}
+
+// GetNextPublishSeqNo devuelve el número de secuencia de la próxima publicación en
+// modo confirm (0 fuera de ese modo).
+func (c *Channel) GetNextPublishSeqNo() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.confirming {
+		return 0
+	}
+	return c.publishSeq + 1
+}
+
+// Consume empieza a entregar los mensajes de queueName. Las entregas requieren
+// ACK/NACK explícito (autoAck no se soporta: el worker nunca lo usa).
+func (c *Channel) Consume(queueName, tag string, autoAck, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	if autoAck {
+		return nil, fmt.Errorf("autoAck no soportado por el broker en memoria")
+	}
+	c.broker.mu.Lock()
+	q, ok := c.broker.queues[queueName]
+	c.broker.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, queueName)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	if c.consumers == nil {
+		c.consumers = make(map[string]*consumer)
+	}
+	if _, dup := c.consumers[tag]; dup {
+		return nil, fmt.Errorf("consumer tag %q duplicado en el canal", tag)
+	}
+	cons := &consumer{
+		tag:        tag,
+		channel:    c,
+		prefetch:   c.prefetch,
+		deliveries: make(chan amqp.Delivery),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	c.consumers[tag] = cons
+	c.broker.watch(q, 1)
+	go c.pump(q, cons)
+	return cons.deliveries, nil
+}
+
+// pump entrega los mensajes de q a cons hasta que se cancele. Un mensaje sacado de
+// la cola y no entregado antes de cancelar vuelve a la cola.
+func (c *Channel) pump(q *queue, cons *consumer) {
+	defer close(cons.done)
+	defer close(cons.deliveries)
+	defer c.broker.watch(q, -1)
+	for {
+		d, changed, ok := c.broker.next(q, cons)
+		if !ok {
+			select {
+			case <-changed:
+				continue
+			case <-cons.stop:
+				return
+			}
+		}
+		select {
+		case cons.deliveries <- d:
+		case <-cons.stop:
+			_ = c.broker.settle(d.DeliveryTag, false, true)
+			return
+		}
+	}
+}
+
+// Cancel detiene el consumer tag: deja de entregar y cierra su canal de entregas.
+// Las entregas ya hechas siguen esperando su ACK/NACK.
+func (c *Channel) Cancel(tag string, _ bool) error {
+	c.mu.Lock()
+	cons, ok := c.consumers[tag]
+	delete(c.consumers, tag)
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("consumer tag %q desconocido", tag)
+	}
+	cons.cancel()
+	<-cons.done
+	return nil
+}
+
+// Close cancela los consumers del canal y devuelve a la cola sus entregas sin ACK.
+func (c *Channel) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	consumers := c.consumers
+	c.consumers = nil
+	for _, confirm := range c.confirms {
+		close(confirm)
+	}
+	c.confirms = nil
+	c.mu.Unlock()
+
+	for _, cons := range consumers {
+		cons.cancel()
+		<-cons.done
+	}
+	c.broker.requeueChannel(c)
+	return nil
+}
+
+// Ack confirma una entrega (amqp.Acknowledger).
+func (c *Channel) Ack(tag uint64, _ bool) error {
+	return c.broker.settle(tag, true, false)
+}
+
+// Nack rechaza una entrega: con requeue vuelve al frente de su cola; sin requeue
+// sale por el dead-letter de la cola (amqp.Acknowledger).
+func (c *Channel) Nack(tag uint64, _ bool, requeue bool) error {
+	return c.broker.settle(tag, false, requeue)
+}
+
+// Reject equivale a Nack de una sola entrega (amqp.Acknowledger).
+func (c *Channel) Reject(tag uint64, requeue bool) error {
+	return c.broker.settle(tag, false, requeue)
+}
+
+func (c *Channel) check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return amqp.ErrClosed
+	}
+	return nil
+}
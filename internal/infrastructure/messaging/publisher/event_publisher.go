@@ -0,0 +1,112 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// appID identifica al worker como emisor en las propiedades AMQP del mensaje.
+const appID = "edugo-worker"
+
+// Channel es la porción de *amqp.Channel que usa el publisher. Se define como
+// interfaz para poder capturar las publicaciones en tests sin RabbitMQ.
+type Channel interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// Exchanges son los exchanges topic a los que se publican los eventos de salida:
+// los del carril de evaluaciones (revisión/preparación) van a Assessments y los del
+// carril de materiales a Materials. La routing key es siempre el event_type.
+type Exchanges struct {
+	Assessments string
+	Materials   string
+}
+
+// EventPublisher publica los eventos de cierre de carril (dto.LaneEvent) en el
+// exchange topic que corresponde a su event_type. Es seguro para uso concurrente
+// (los tres consumers comparten la instancia): serializa el acceso al canal.
+type EventPublisher struct {
+	ch        Channel
+	exchanges Exchanges
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// NewEventPublisher construye el publisher sobre un canal AMQP propio (no el del
+// consumer). No declara exchanges: setupRabbitMQ ya los declara al arrancar.
+func NewEventPublisher(ch Channel, exchanges Exchanges) *EventPublisher {
+	return &EventPublisher{
+		ch:        ch,
+		exchanges: exchanges,
+		now:       time.Now,
+	}
+}
+
+// Publish arma el envelope del evento (event_id nuevo, versión, timestamp UTC y
+// correlation_id del evento de entrada) y lo publica persistente con routing key
+// = eventType. Un event_type sin exchange conocido es un error de programación.
+func (p *EventPublisher) Publish(ctx context.Context, eventType, correlationID string, payload any) error {
+	exchange, err := p.exchangeFor(eventType)
+	if err != nil {
+		return err
+	}
+
+	evt := dto.LaneEvent{
+		EventID:       uuid.NewString(),
+		EventType:     eventType,
+		EventVersion:  dto.LaneEventVersion,
+		Timestamp:     p.now().UTC(),
+		CorrelationID: correlationID,
+		Payload:       payload,
+	}
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("serializando evento %s: %w", eventType, err)
+	}
+
+	msg := amqp.Publishing{
+		ContentType:   "application/json",
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     evt.EventID,
+		CorrelationId: correlationID,
+		Type:          eventType,
+		Timestamp:     evt.Timestamp,
+		AppId:         appID,
+		Body:          body,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.ch.PublishWithContext(ctx, exchange, eventType, false, false, msg); err != nil {
+		return fmt.Errorf("publicando evento %s en %s: %w", eventType, exchange, err)
+	}
+	return nil
+}
+
+// exchangeFor resuelve el exchange del event_type por su prefijo de dominio.
+func (p *EventPublisher) exchangeFor(eventType string) (string, error) {
+	return p.exchanges.For(eventType)
+}
+
+// For resuelve el exchange de un event_type por su prefijo de dominio: attempt.* y
+// question.* van a Assessments, material.* a Materials. Vale tanto para los eventos
+// de salida como para los de entrada (la herramienta de DLQ lo usa para devolver un
+// mensaje a su exchange original).
+func (e Exchanges) For(eventType string) (string, error) {
+	switch {
+	case strings.HasPrefix(eventType, "attempt."), strings.HasPrefix(eventType, "question."):
+		return e.Assessments, nil
+	case strings.HasPrefix(eventType, "material."):
+		return e.Materials, nil
+	default:
+		return "", fmt.Errorf("event_type %q sin exchange de salida", eventType)
+	}
+}
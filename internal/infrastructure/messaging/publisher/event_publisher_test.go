@@ -0,0 +1,105 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/application/dto"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChannel captura las publicaciones en lugar de enviarlas a RabbitMQ.
+type fakeChannel struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+	calls    int
+	err      error
+}
+
+func (f *fakeChannel) PublishWithContext(_ context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	f.calls++
+	f.exchange = exchange
+	f.key = key
+	f.msg = msg
+	return f.err
+}
+
+func newTestPublisher(ch Channel) *EventPublisher {
+	p := NewEventPublisher(ch, Exchanges{Assessments: "edugo.assessments", Materials: "edugo.materials"})
+	p.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+	return p
+}
+
+func TestPublish_AttemptReviewed_EnvelopeAndRouting(t *testing.T) {
+	// Arrange
+	ch := &fakeChannel{}
+	p := newTestPublisher(ch)
+
+	// Act
+	err := p.Publish(context.Background(), dto.EventTypeAttemptAIReviewed, "evt-in-1",
+		dto.AttemptAIReviewedPayload{AttemptID: "att-1", AnswersReviewed: 3, Provider: "ollama"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "edugo.assessments", ch.exchange)
+	assert.Equal(t, dto.EventTypeAttemptAIReviewed, ch.key)
+	assert.Equal(t, "evt-in-1", ch.msg.CorrelationId)
+	assert.Equal(t, amqp.Persistent, ch.msg.DeliveryMode)
+	assert.Equal(t, "application/json", ch.msg.ContentType)
+	assert.NotEmpty(t, ch.msg.MessageId)
+
+	var got struct {
+		EventID       string                       `json:"event_id"`
+		EventType     string                       `json:"event_type"`
+		EventVersion  string                       `json:"event_version"`
+		Timestamp     time.Time                    `json:"timestamp"`
+		CorrelationID string                       `json:"correlation_id"`
+		Payload       dto.AttemptAIReviewedPayload `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(ch.msg.Body, &got))
+	assert.Equal(t, ch.msg.MessageId, got.EventID)
+	assert.Equal(t, dto.EventTypeAttemptAIReviewed, got.EventType)
+	assert.Equal(t, dto.LaneEventVersion, got.EventVersion)
+	assert.Equal(t, "evt-in-1", got.CorrelationID)
+	assert.Equal(t, "att-1", got.Payload.AttemptID)
+	assert.Equal(t, 3, got.Payload.AnswersReviewed)
+	assert.Equal(t, "ollama", got.Payload.Provider)
+}
+
+func TestPublish_RoutesByDomain(t *testing.T) {
+	cases := map[string]string{
+		dto.EventTypeQuestionPrepSaved:           "edugo.assessments",
+		dto.EventTypeMaterialAssessmentDelivered: "edugo.materials",
+		dto.EventTypeMaterialAssessmentFailed:    "edugo.materials",
+	}
+	for eventType, exchange := range cases {
+		t.Run(eventType, func(t *testing.T) {
+			ch := &fakeChannel{}
+			require.NoError(t, newTestPublisher(ch).Publish(context.Background(), eventType, "c", struct{}{}))
+			assert.Equal(t, exchange, ch.exchange)
+			assert.Equal(t, eventType, ch.key)
+		})
+	}
+}
+
+func TestPublish_UnknownEventType(t *testing.T) {
+	ch := &fakeChannel{}
+	err := newTestPublisher(ch).Publish(context.Background(), "student.enrolled", "c", struct{}{})
+
+	require.Error(t, err)
+	assert.Equal(t, 0, ch.calls, "no debe publicar un event_type sin exchange")
+}
+
+func TestPublish_ChannelError(t *testing.T) {
+	ch := &fakeChannel{err: errors.New("channel closed")}
+	err := newTestPublisher(ch).Publish(context.Background(), dto.EventTypeQuestionPrepSaved, "c", struct{}{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel closed")
+}
@@ -0,0 +1,121 @@
+package retryqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// defaultConfirmTimeout es la espera por defecto del confirm de una copia de retry.
+const defaultConfirmTimeout = 5 * time.Second
+
+var (
+	// errPublishNacked indica que el broker rechazó la copia (basic.nack).
+	errPublishNacked = errors.New("el broker rechazó la publicación")
+	// errConfirmsClosed indica que el canal se cerró antes de confirmar.
+	errConfirmsClosed = errors.New("canal cerrado antes del confirm")
+)
+
+// confirmer publica en un canal en modo confirm y espera el basic.ack del broker
+// de cada publicación. Los handlers publican en paralelo sobre el mismo canal:
+// pubMu mantiene juntos el número de secuencia y la publicación, y dispatch reparte
+// los confirms por delivery tag.
+type confirmer struct {
+	ch      Channel
+	timeout time.Duration
+
+	pubMu sync.Mutex
+
+	mu      sync.Mutex
+	waiting map[uint64]chan bool
+	closed  bool
+}
+
+// newConfirmer pone ch en modo confirm. buffer dimensiona el canal de confirms
+// (como mucho hay una publicación pendiente por mensaje en proceso).
+func newConfirmer(ch Channel, buffer int, timeout time.Duration) (*confirmer, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("error activando publisher confirms: %w", err)
+	}
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+	c := &confirmer{ch: ch, timeout: timeout, waiting: make(map[uint64]chan bool)}
+	go c.dispatch(ch.NotifyPublish(make(chan amqp.Confirmation, max(buffer, 1))))
+	return c, nil
+}
+
+// publish publica msg y espera su confirm. Devuelve error si la publicación falla,
+// el broker la rechaza, el canal se cierra o el confirm no llega a tiempo: en esos
+// casos el llamador no puede dar la copia por hecha.
+func (c *confirmer) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	done := make(chan bool, 1)
+
+	c.pubMu.Lock()
+	tag := c.ch.GetNextPublishSeqNo()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.pubMu.Unlock()
+		return errConfirmsClosed
+	}
+	c.waiting[tag] = done
+	c.mu.Unlock()
+	err := c.ch.PublishWithContext(ctx, exchange, key, false, false, msg)
+	c.pubMu.Unlock()
+	if err != nil {
+		c.forget(tag)
+		return err
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case ack, ok := <-done:
+		switch {
+		case !ok:
+			return errConfirmsClosed
+		case !ack:
+			return errPublishNacked
+		}
+		return nil
+	case <-timer.C:
+		c.forget(tag)
+		return fmt.Errorf("sin confirm del broker tras %s", c.timeout)
+	case <-ctx.Done():
+		c.forget(tag)
+		return ctx.Err()
+	}
+}
+
+func (c *confirmer) forget(tag uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.waiting, tag)
+}
+
+// dispatch entrega cada confirm a quien espera su delivery tag. Al cerrarse el
+// canal despierta a los que siguen esperando con errConfirmsClosed.
+func (c *confirmer) dispatch(confirms <-chan amqp.Confirmation) {
+	for conf := range confirms {
+		c.mu.Lock()
+		done, ok := c.waiting[conf.DeliveryTag]
+		delete(c.waiting, conf.DeliveryTag)
+		c.mu.Unlock()
+		if ok {
+			done <- conf.Ack
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for tag, done := range c.waiting {
+		close(done)
+		delete(c.waiting, tag)
+	}
+}
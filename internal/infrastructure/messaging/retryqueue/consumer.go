@@ -0,0 +1,375 @@
+// Package retryqueue implementa reintentos diferidos en el broker: cada carril tiene
+// colas de retry con TTL (un escalón por reintento, p.ej. 10s/1m/10m) cuyo
+// dead-letter devuelve el mensaje a la cola del carril al expirar. Un fallo
+// transitorio ya no se reintenta en proceso ni se re-encola en caliente (con Ollama
+// caído eso era un bucle): espera en el broker sin ocupar el prefetch. Agotados los
+// escalones, o ante un error permanente, el mensaje va a la DLQ del carril.
+package retryqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	rabbit "github.com/EduGoGroup/edugo-shared/messaging/rabbit"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers que el consumer agrega al re-publicar en una cola de retry.
+const (
+	// RetryCountHeader son los reintentos ya hechos (1 en el primer escalón).
+	RetryCountHeader = "x-retry-count"
+	// OriginExchangeHeader y OriginRoutingKeyHeader guardan el destino original del
+	// mensaje: tras pasar por una cola de retry, x-death solo refleja el salto por la
+	// cola de retry. cmd/dlq los usa para re-publicar.
+	OriginExchangeHeader   = "x-retry-origin-exchange"
+	OriginRoutingKeyHeader = "x-retry-origin-routing-key"
+)
+
+// Motivos de envío a la DLQ (label de métricas).
+const (
+	reasonPermanent = "permanent"
+	reasonExhausted = "retries_exhausted"
+)
+
+// Channel es la porción de *amqp.Channel que usa el consumer.
+type Channel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	GetNextPublishSeqNo() uint64
+	Close() error
+}
+
+// Config parametriza el consumer de un carril.
+type Config struct {
+	// Name es el consumer tag (como rabbit.ConsumerConfig.Name).
+	Name          string
+	PrefetchCount int
+	// Concurrency son los mensajes que se procesan a la vez (lanes[].concurrency).
+	// 0 = 1: secuencial, como el consumer compartido sin concurrencia configurada.
+	Concurrency int
+	// DLXExchange y DLQName son el dead-letter del carril: la cola del carril ya los
+	// tiene como x-dead-letter-exchange/routing-key, así que un Nack sin requeue cae
+	// en la DLQ con su x-death.
+	DLXExchange string
+	DLQName     string
+	// Tiers son los retrasos de cada escalón de retry, en orden.
+	Tiers []time.Duration
+	// IsPermanent indica si reintentar no arregla el error (va directo a la DLQ).
+	// Nil trata todo error como transitorio.
+	IsPermanent func(error) bool
+	// ConfirmTimeout es la espera del confirm del broker al copiar un mensaje en la
+	// cola de retry (0 = 5s).
+	ConfirmTimeout time.Duration
+}
+
+// Consumer consume la cola de un carril con reintentos diferidos. Es intercambiable
+// con el consumer compartido (ConsumeWithDLQ/Stop/Wait); main elige uno u otro por
+// carril según messaging.rabbitmq.queues.retry.
+type Consumer struct {
+	cfg    Config
+	open   func() (Channel, error)
+	logger logger.Logger
+
+	mu       sync.Mutex
+	ch       Channel
+	confirms *confirmer
+	running  bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	loopDone chan struct{}
+	inFlight sync.WaitGroup
+}
+
+// NewConsumer crea el consumer sobre la conexión compartida; abre su propio canal
+// al empezar a consumir.
+func NewConsumer(conn *rabbit.Connection, cfg Config, log logger.Logger) *Consumer {
+	return newConsumer(func() (Channel, error) {
+		return conn.GetConnection().Channel()
+	}, cfg, log)
+}
+
+// NewChannelConsumer crea el consumer sobre los canales que abre open, p.ej. los
+// del broker en memoria del modo --local.
+func NewChannelConsumer(open func() (Channel, error), cfg Config, log logger.Logger) *Consumer {
+	return newConsumer(open, cfg, log)
+}
+
+func newConsumer(open func() (Channel, error), cfg Config, log logger.Logger) *Consumer {
+	return &Consumer{
+		cfg:    cfg,
+		open:   open,
+		logger: log,
+		stop:   make(chan struct{}),
+	}
+}
+
+// QueueName es el nombre de la cola de retry de queue para un escalón de ttl. Lleva
+// el TTL en el nombre: cambiar los escalones declara colas nuevas en vez de chocar
+// con los argumentos de las existentes (PRECONDITION_FAILED).
+func QueueName(queue string, ttl time.Duration) string {
+	return queue + ".retry." + formatTTL(ttl)
+}
+
+func formatTTL(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	case d%time.Second == 0:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	default:
+		return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+	}
+}
+
+// ConsumeWithDLQ declara la DLQ y las colas de retry de queue y empieza a consumir
+// en segundo plano. Solo puede llamarse una vez por consumer.
+func (c *Consumer) ConsumeWithDLQ(ctx context.Context, queue string, handler rabbit.MessageHandler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return errors.New("consumer ya está consumiendo")
+	}
+
+	ch, err := c.open()
+	if err != nil {
+		return fmt.Errorf("error abriendo canal: %w", err)
+	}
+	if err := c.declare(ch, queue); err != nil {
+		_ = ch.Close()
+		return err
+	}
+	if err := ch.Qos(c.cfg.PrefetchCount, 0, false); err != nil {
+		_ = ch.Close()
+		return fmt.Errorf("error configurando prefetch: %w", err)
+	}
+	confirms, err := newConfirmer(ch, c.cfg.PrefetchCount, c.cfg.ConfirmTimeout)
+	if err != nil {
+		_ = ch.Close()
+		return err
+	}
+	deliveries, err := ch.Consume(queue, c.cfg.Name, false, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return fmt.Errorf("error consumiendo %s: %w", queue, err)
+	}
+
+	c.ch = ch
+	c.confirms = confirms
+	c.running = true
+	c.loopDone = make(chan struct{})
+	go c.loop(ctx, queue, deliveries, handler)
+	return nil
+}
+
+// declare crea la DLQ del carril (como hace el consumer compartido) y una cola de
+// retry por escalón: TTL por cola y dead-letter al exchange por defecto con la cola
+// del carril como routing key, así el mensaje vuelve a su cola al expirar.
+func (c *Consumer) declare(ch Channel, queue string) error {
+	if err := ch.ExchangeDeclare(c.cfg.DLXExchange, "direct", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declarando DLX %s: %w", c.cfg.DLXExchange, err)
+	}
+	if _, err := ch.QueueDeclare(c.cfg.DLQName, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("error declarando DLQ %s: %w", c.cfg.DLQName, err)
+	}
+	if err := ch.QueueBind(c.cfg.DLQName, c.cfg.DLQName, c.cfg.DLXExchange, false, nil); err != nil {
+		return fmt.Errorf("error binding DLQ %s: %w", c.cfg.DLQName, err)
+	}
+	for _, ttl := range c.cfg.Tiers {
+		name := QueueName(queue, ttl)
+		if _, err := ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+			"x-message-ttl":             ttl.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		}); err != nil {
+			return fmt.Errorf("error declarando cola de retry %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Consumer) loop(ctx context.Context, queue string, deliveries <-chan amqp.Delivery, handler rabbit.MessageHandler) {
+	defer close(c.loopDone)
+	// Un mensaje por goroutine, hasta Concurrency a la vez: el resto del prefetch
+	// espera entregado sin ACK. Al detenerse, los que no tomaron turno vuelven a la
+	// cola al cerrar el canal.
+	slots := make(chan struct{}, max(c.cfg.Concurrency, 1))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stop:
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			case <-c.stop:
+				return
+			}
+			c.inFlight.Add(1)
+			go func() {
+				defer c.inFlight.Done()
+				defer func() { <-slots }()
+				c.handle(ctx, queue, d, handler)
+			}()
+		}
+	}
+}
+
+// handle procesa una entrega y decide su destino:
+//   - éxito: ACK.
+//   - contexto cancelado (shutdown): Nack con requeue, no consume un escalón.
+//   - error permanente o escalones agotados: Nack sin requeue → DLQ del carril.
+//   - error transitorio: copia en la cola de retry del siguiente escalón y, con el
+//     confirm del broker, ACK. Si la copia falla, el broker la rechaza o el confirm
+//     no llega a tiempo, Nack con requeue: el original vuelve a la cola sin consumir
+//     un escalón. Si la copia sí llegó pero su confirm no, el mensaje se procesa dos
+//     veces (la idempotencia por event_id lo absorbe), pero no se pierde.
+func (c *Consumer) handle(ctx context.Context, queue string, d amqp.Delivery, handler rabbit.MessageHandler) {
+	err := handler(ctx, d.Body)
+	if err == nil {
+		c.ack(d)
+		return
+	}
+	if ctx.Err() != nil {
+		c.nack(d, true)
+		return
+	}
+
+	retries := RetryCount(d.Headers)
+	switch {
+	case c.cfg.IsPermanent != nil && c.cfg.IsPermanent(err):
+		c.deadLetter(queue, d, reasonPermanent, retries, err)
+		return
+	case retries >= len(c.cfg.Tiers):
+		c.deadLetter(queue, d, reasonExhausted, retries, err)
+		return
+	}
+
+	ttl := c.cfg.Tiers[retries]
+	target := QueueName(queue, ttl)
+	if pubErr := c.confirms.publish(ctx, "", target, retryPublishing(d, retries+1)); pubErr != nil {
+		c.logger.Error("no se pudo confirmar la copia en la cola de retry, se re-encola",
+			"queue", queue, "retry_queue", target, "error", pubErr.Error())
+		c.nack(d, true)
+		return
+	}
+	c.ack(d)
+	metrics.RecordMessageRetry(queue, retries+1)
+	c.logger.Warn("fallo transitorio, reintento diferido",
+		"queue", queue, "retry", retries+1, "of", len(c.cfg.Tiers), "delay", ttl.String(), "error", err.Error())
+}
+
+func (c *Consumer) deadLetter(queue string, d amqp.Delivery, reason string, retries int, err error) {
+	c.nack(d, false)
+	metrics.RecordMessageDeadLettered(queue, reason)
+	c.logger.Error("mensaje enviado a la DLQ",
+		"queue", queue, "dlq", c.cfg.DLQName, "reason", reason, "retries", retries, "error", err.Error())
+}
+
+func (c *Consumer) ack(d amqp.Delivery) {
+	if err := d.Ack(false); err != nil {
+		c.logger.Warn("error en ACK", "delivery_tag", d.DeliveryTag, "error", err.Error())
+	}
+}
+
+func (c *Consumer) nack(d amqp.Delivery, requeue bool) {
+	if err := d.Nack(false, requeue); err != nil {
+		c.logger.Warn("error en NACK", "delivery_tag", d.DeliveryTag, "requeue", requeue, "error", err.Error())
+	}
+}
+
+// RetryCount lee el header x-retry-count (0 si falta o tiene tipo inesperado).
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[RetryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// retryPublishing copia el mensaje para la cola de retry. Conserva body,
+// propiedades y headers de negocio; descarta los headers de dead-letter de RabbitMQ
+// (los pone de nuevo la cola de retry al expirar) y fija el contador y el destino
+// original (solo en el primer reintento: después el mensaje llega del exchange por
+// defecto).
+func retryPublishing(d amqp.Delivery, retries int) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		if k == "x-death" || strings.HasPrefix(k, "x-first-death-") || strings.HasPrefix(k, "x-last-death-") {
+			continue
+		}
+		headers[k] = v
+	}
+	headers[RetryCountHeader] = int32(retries)
+	if _, ok := headers[OriginRoutingKeyHeader]; !ok {
+		headers[OriginExchangeHeader] = d.Exchange
+		headers[OriginRoutingKeyHeader] = d.RoutingKey
+	}
+
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
+
+// Stop deja de recibir mensajes nuevos; los que se están procesando terminan (ver
+// Wait). Los entregados y aún no procesados vuelven a la cola al cerrar el canal.
+func (c *Consumer) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.ch != nil {
+			if err := c.ch.Cancel(c.cfg.Name, false); err != nil {
+				c.logger.Warn("error cancelando consumer", "consumer", c.cfg.Name, "error", err.Error())
+			}
+		}
+	})
+}
+
+// Wait espera a que terminen los mensajes en proceso y cierra el canal.
+func (c *Consumer) Wait() error {
+	c.mu.Lock()
+	ch, loopDone := c.ch, c.loopDone
+	c.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+	<-loopDone
+	c.inFlight.Wait()
+	return ch.Close()
+}
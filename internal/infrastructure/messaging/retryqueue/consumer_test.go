@@ -0,0 +1,389 @@
+package retryqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (l *nopLogger) Debug(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Info(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Warn(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Error(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Fatal(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Sync() error                                     { return nil }
+func (l *nopLogger) With(keysAndValues ...interface{}) logger.Logger { return l }
+
+const testQueue = "edugo.attempt.review_requested"
+
+var testTiers = []time.Duration{10 * time.Second, time.Minute, 10 * time.Minute}
+
+// fakeAcker registra los ack/nack de las entregas.
+type fakeAcker struct {
+	mu       sync.Mutex
+	acked    []uint64
+	requeued []uint64
+	dead     []uint64
+}
+
+func (a *fakeAcker) Ack(tag uint64, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *fakeAcker) Nack(tag uint64, _ bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if requeue {
+		a.requeued = append(a.requeued, tag)
+	} else {
+		a.dead = append(a.dead, tag)
+	}
+	return nil
+}
+
+func (a *fakeAcker) Reject(tag uint64, requeue bool) error { return a.Nack(tag, false, requeue) }
+
+type published struct {
+	exchange, key string
+	msg           amqp.Publishing
+}
+
+type declared struct {
+	name string
+	args amqp.Table
+}
+
+// fakeChannel captura declaraciones y publicaciones; Consume entrega lo que se
+// envíe por deliveries. En modo confirm responde cada publicación con ack, con nack
+// (nackConfirms) o no responde (dropConfirms).
+type fakeChannel struct {
+	mu           sync.Mutex
+	declared     []declared
+	published    []published
+	publishErr   error
+	nackConfirms bool
+	dropConfirms bool
+	confirming   bool
+	seq          uint64
+	notify       chan amqp.Confirmation
+	canceled     bool
+	closed       bool
+	deliveries   chan amqp.Delivery
+}
+
+func newFakeChannel() *fakeChannel {
+	return &fakeChannel{deliveries: make(chan amqp.Delivery, 10)}
+}
+
+func (c *fakeChannel) Qos(int, int, bool) error { return nil }
+func (c *fakeChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return nil
+}
+func (c *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.declared = append(c.declared, declared{name: name, args: args})
+	return amqp.Queue{Name: name}, nil
+}
+func (c *fakeChannel) QueueBind(string, string, string, bool, amqp.Table) error { return nil }
+func (c *fakeChannel) Consume(string, string, bool, bool, bool, bool, amqp.Table) (<-chan amqp.Delivery, error) {
+	return c.deliveries, nil
+}
+func (c *fakeChannel) Cancel(string, bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canceled = true
+	return nil
+}
+func (c *fakeChannel) PublishWithContext(_ context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+	c.published = append(c.published, published{exchange: exchange, key: key, msg: msg})
+	if c.confirming {
+		c.seq++
+		if !c.dropConfirms {
+			c.notify <- amqp.Confirmation{DeliveryTag: c.seq, Ack: !c.nackConfirms}
+		}
+	}
+	return nil
+}
+func (c *fakeChannel) Confirm(bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.confirming = true
+	return nil
+}
+func (c *fakeChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = confirm
+	return confirm
+}
+func (c *fakeChannel) GetNextPublishSeqNo() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq + 1
+}
+func (c *fakeChannel) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed && c.notify != nil {
+		close(c.notify)
+	}
+	c.closed = true
+	return nil
+}
+
+var errPermanent = errors.New("evento malformado")
+
+func newTestConsumer(ch *fakeChannel) *Consumer {
+	c := newConsumer(func() (Channel, error) { return ch, nil }, Config{
+		Name:           "edugo-worker",
+		PrefetchCount:  5,
+		DLXExchange:    "edugo_dlx",
+		DLQName:        testQueue + ".dlq",
+		Tiers:          testTiers,
+		IsPermanent:    func(err error) bool { return errors.Is(err, errPermanent) },
+		ConfirmTimeout: 50 * time.Millisecond,
+	}, &nopLogger{})
+	c.ch = ch
+	confirms, err := newConfirmer(ch, 5, c.cfg.ConfirmTimeout)
+	if err != nil {
+		panic(err)
+	}
+	c.confirms = confirms
+	return c
+}
+
+func delivery(acker *fakeAcker, retries int) amqp.Delivery {
+	d := amqp.Delivery{
+		Acknowledger: acker,
+		DeliveryTag:  1,
+		Exchange:     "edugo.assessments",
+		RoutingKey:   "attempt.review_requested",
+		ContentType:  "application/json",
+		Priority:     5,
+		Headers:      amqp.Table{"x-tenant": "keep"},
+		Body:         []byte(`{"event_type":"attempt.review_requested"}`),
+	}
+	if retries > 0 {
+		d.Exchange = ""
+		d.RoutingKey = testQueue
+		d.Headers[RetryCountHeader] = int32(retries)
+		d.Headers[OriginExchangeHeader] = "edugo.assessments"
+		d.Headers[OriginRoutingKeyHeader] = "attempt.review_requested"
+		d.Headers["x-death"] = []interface{}{amqp.Table{"queue": "q.retry", "reason": "expired"}}
+	}
+	return d
+}
+
+func failWith(err error) func(context.Context, []byte) error {
+	return func(context.Context, []byte) error { return err }
+}
+
+func TestQueueName(t *testing.T) {
+	assert.Equal(t, "q.retry.10s", QueueName("q", 10*time.Second))
+	assert.Equal(t, "q.retry.1m", QueueName("q", time.Minute))
+	assert.Equal(t, "q.retry.2h", QueueName("q", 2*time.Hour))
+	assert.Equal(t, "q.retry.1500ms", QueueName("q", 1500*time.Millisecond))
+}
+
+func TestHandle_ExitoHaceAck(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+
+	c.handle(context.Background(), testQueue, delivery(acker, 0), failWith(nil))
+
+	assert.Equal(t, []uint64{1}, acker.acked)
+	assert.Empty(t, ch.published)
+}
+
+func TestHandle_TransitorioVaAlPrimerEscalon(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+
+	c.handle(context.Background(), testQueue, delivery(acker, 0), failWith(errors.New("ollama caído")))
+
+	require.Len(t, ch.published, 1)
+	pub := ch.published[0]
+	assert.Equal(t, "", pub.exchange, "las colas de retry se publican por el exchange por defecto")
+	assert.Equal(t, testQueue+".retry.10s", pub.key)
+	assert.Equal(t, int32(1), pub.msg.Headers[RetryCountHeader])
+	assert.Equal(t, "edugo.assessments", pub.msg.Headers[OriginExchangeHeader])
+	assert.Equal(t, "attempt.review_requested", pub.msg.Headers[OriginRoutingKeyHeader])
+	assert.Equal(t, "keep", pub.msg.Headers["x-tenant"])
+	assert.Equal(t, uint8(5), pub.msg.Priority)
+	assert.Equal(t, amqp.Persistent, pub.msg.DeliveryMode)
+	assert.Equal(t, []uint64{1}, acker.acked, "el original se ACKea una vez copiado")
+}
+
+func TestHandle_SiguienteEscalonConservaElOrigen(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+
+	c.handle(context.Background(), testQueue, delivery(acker, 2), failWith(errors.New("timeout")))
+
+	require.Len(t, ch.published, 1)
+	pub := ch.published[0]
+	assert.Equal(t, testQueue+".retry.10m", pub.key)
+	assert.Equal(t, int32(3), pub.msg.Headers[RetryCountHeader])
+	assert.Equal(t, "edugo.assessments", pub.msg.Headers[OriginExchangeHeader], "el origen no se pisa con el exchange por defecto")
+	assert.NotContains(t, pub.msg.Headers, "x-death")
+}
+
+func TestHandle_EscalonesAgotadosVaALaDLQ(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+
+	c.handle(context.Background(), testQueue, delivery(acker, len(testTiers)), failWith(errors.New("timeout")))
+
+	assert.Empty(t, ch.published)
+	assert.Equal(t, []uint64{1}, acker.dead, "Nack sin requeue: el dead-letter de la cola lo lleva a la DLQ")
+}
+
+func TestHandle_PermanenteVaDirectoALaDLQ(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+
+	c.handle(context.Background(), testQueue, delivery(acker, 0), failWith(errPermanent))
+
+	assert.Empty(t, ch.published)
+	assert.Equal(t, []uint64{1}, acker.dead)
+}
+
+func TestHandle_PublishFallidoReencola(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	ch.publishErr = errors.New("channel closed")
+	c := newTestConsumer(ch)
+
+	c.handle(context.Background(), testQueue, delivery(acker, 0), failWith(errors.New("timeout")))
+
+	assert.Empty(t, acker.acked)
+	assert.Equal(t, []uint64{1}, acker.requeued)
+}
+
+func TestHandle_CopiaRechazadaOSinConfirmReencola(t *testing.T) {
+	for name, setup := range map[string]func(*fakeChannel){
+		"nack":        func(ch *fakeChannel) { ch.nackConfirms = true },
+		"sin confirm": func(ch *fakeChannel) { ch.dropConfirms = true },
+	} {
+		t.Run(name, func(t *testing.T) {
+			ch, acker := newFakeChannel(), &fakeAcker{}
+			c := newTestConsumer(ch)
+			setup(ch)
+
+			c.handle(context.Background(), testQueue, delivery(acker, 0), failWith(errors.New("timeout")))
+
+			assert.Len(t, ch.published, 1)
+			assert.Empty(t, acker.acked, "sin confirm del broker el original no se ACKea")
+			assert.Equal(t, []uint64{1}, acker.requeued)
+		})
+	}
+}
+
+func TestHandle_ShutdownReencolaSinConsumirEscalon(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c.handle(ctx, testQueue, delivery(acker, 0), failWith(context.Canceled))
+
+	assert.Empty(t, ch.published)
+	assert.Equal(t, []uint64{1}, acker.requeued)
+}
+
+func TestConsumeWithDLQ_DeclaraColasYProcesa(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+	c.ch = nil
+
+	var mu sync.Mutex
+	var bodies []string
+	handler := func(_ context.Context, body []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		return nil
+	}
+	require.NoError(t, c.ConsumeWithDLQ(context.Background(), testQueue, handler))
+	require.Error(t, c.ConsumeWithDLQ(context.Background(), testQueue, handler), "una sola ConsumeWithDLQ por consumer")
+
+	require.Len(t, ch.declared, 1+len(testTiers))
+	assert.Equal(t, testQueue+".dlq", ch.declared[0].name)
+	retry := ch.declared[2]
+	assert.Equal(t, testQueue+".retry.1m", retry.name)
+	assert.Equal(t, int64(60000), retry.args["x-message-ttl"])
+	assert.Equal(t, "", retry.args["x-dead-letter-exchange"])
+	assert.Equal(t, testQueue, retry.args["x-dead-letter-routing-key"], "al expirar vuelve a la cola del carril")
+
+	ch.deliveries <- delivery(acker, 0)
+	assert.Eventually(t, func() bool {
+		acker.mu.Lock()
+		defer acker.mu.Unlock()
+		return len(acker.acked) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	c.Stop()
+	require.NoError(t, c.Wait())
+	assert.True(t, ch.canceled)
+	assert.True(t, ch.closed)
+	assert.Len(t, bodies, 1)
+}
+
+func TestConsumeWithDLQ_AcotaPorConcurrency(t *testing.T) {
+	ch, acker := newFakeChannel(), &fakeAcker{}
+	c := newTestConsumer(ch)
+	c.ch = nil
+	c.cfg.Concurrency = 2
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	release := make(chan struct{})
+	handler := func(context.Context, []byte) error {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}
+	require.NoError(t, c.ConsumeWithDLQ(context.Background(), testQueue, handler))
+	for i := 0; i < 5; i++ {
+		ch.deliveries <- delivery(acker, 0)
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return inFlight == 2
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	assert.Eventually(t, func() bool {
+		acker.mu.Lock()
+		defer acker.mu.Unlock()
+		return len(acker.acked) == 5
+	}, time.Second, 5*time.Millisecond)
+
+	c.Stop()
+	require.NoError(t, c.Wait())
+	assert.Equal(t, 2, maxInFlight, "el prefetch no debe subir la concurrencia del carril")
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -35,63 +37,43 @@ var (
 	)
 )
 
-// Métricas de OpenAI/NLP
+// Métricas de llamadas LLM (decorador de instrumentación, por provider, modelo y operación)
 var (
-	// OpenAIRequestsTotal cuenta las solicitudes a OpenAI por estado
-	OpenAIRequestsTotal = promauto.NewCounterVec(
+	// LLMRequestsTotal cuenta las llamadas LLM por resultado
+	LLMRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "worker_openai_requests_total",
-			Help: "Total number of OpenAI API requests by status",
+			Name: "worker_llm_requests_total",
+			Help: "Total number of LLM calls by provider, model, operation and status",
 		},
-		[]string{"status"}, // success, error, rate_limited, timeout
+		[]string{"provider", "model", "operation", "status"}, // success, quality_error, infra_error
 	)
 
-	// OpenAILatency mide la latencia de las solicitudes a OpenAI
-	OpenAILatency = promauto.NewHistogram(
+	// LLMRequestDuration mide la latencia de las llamadas LLM
+	LLMRequestDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "worker_openai_latency_seconds",
-			Help:    "Latency of OpenAI API requests in seconds",
-			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60}, // Buckets personalizados para APIs externas
-		},
-	)
-
-	// OpenAITokensUsed cuenta el total de tokens consumidos
-	OpenAITokensUsed = promauto.NewCounter(
-		prometheus.CounterOpts{
-			Name: "worker_openai_tokens_used_total",
-			Help: "Total number of tokens used in OpenAI requests",
+			Name:    "worker_llm_request_duration_seconds",
+			Help:    "Latency of LLM calls in seconds",
+			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120, 300}, // los modelos locales tardan minutos en trozos grandes
 		},
+		[]string{"provider", "model", "operation"},
 	)
 
-	// OpenAIErrors cuenta los errores de OpenAI por tipo
-	OpenAIErrors = promauto.NewCounterVec(
+	// LLMTokensTotal cuenta los tokens reportados por los backends
+	LLMTokensTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "worker_openai_errors_total",
-			Help: "Total number of OpenAI errors by type",
+			Name: "worker_llm_tokens_total",
+			Help: "Total number of LLM tokens reported by the backends by kind",
 		},
-		[]string{"error_type"}, // rate_limit, timeout, server_error, invalid_request
+		[]string{"provider", "model", "operation", "kind"}, // prompt, completion
 	)
-)
 
-// Métricas de almacenamiento (S3)
-var (
-	// S3OperationsTotal cuenta las operaciones de S3 por tipo y estado
-	S3OperationsTotal = promauto.NewCounterVec(
+	// LLMPromptRequestsTotal cuenta las llamadas LLM por versión de prompt (rollout)
+	LLMPromptRequestsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "worker_s3_operations_total",
-			Help: "Total number of S3 operations by type and status",
-		},
-		[]string{"operation", "status"}, // operation: download, upload, delete
-	)
-
-	// S3OperationDuration mide la duración de operaciones S3
-	S3OperationDuration = promauto.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "worker_s3_operation_duration_seconds",
-			Help:    "Duration of S3 operations in seconds",
-			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30},
+			Name: "worker_llm_prompt_requests_total",
+			Help: "Total number of LLM calls by operation, prompt, prompt version and status",
 		},
-		[]string{"operation"},
+		[]string{"operation", "prompt", "version", "status"},
 	)
 )
 
@@ -133,7 +115,7 @@ var (
 			Name: "worker_circuit_breaker_state",
 			Help: "Current state of circuit breakers (0=closed, 1=half-open, 2=open)",
 		},
-		[]string{"service"}, // nlp
+		[]string{"service"}, // nlp, llm_local, llm_api, llm_embed
 	)
 
 	// CircuitBreakerTransitions cuenta las transiciones de estado
@@ -186,30 +168,190 @@ var (
 	)
 )
 
+// Métricas de carriles (consumers por riel)
+var (
+	// LanePaused indica si el consumo de un carril está pausado (1) o activo (0)
+	LanePaused = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_lane_paused",
+			Help: "Whether consumption of a lane is paused (1) or running (0)",
+		},
+		[]string{"lane"}, // review, prep, material
+	)
+
+	// LaneInFlight indica los mensajes que se están procesando por carril
+	LaneInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_lane_in_flight",
+			Help: "Number of messages currently being processed by lane",
+		},
+		[]string{"lane"},
+	)
+)
+
+// Métricas del reparto por escuela (FairScheduler)
+var (
+	// FairSchedulerBuffered indica los mensajes entregados que esperan turno por carril
+	FairSchedulerBuffered = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_fair_scheduler_buffered",
+			Help: "Number of delivered messages waiting for their school's turn by lane",
+		},
+		[]string{"lane"},
+	)
+
+	// FairSchedulerWait mide la espera de turno antes de procesar
+	FairSchedulerWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_fair_scheduler_wait_seconds",
+			Help:    "Time a message waited for its school's turn before processing",
+			Buckets: []float64{0.01, 0.1, 1, 5, 30, 60, 300, 900, 1800},
+		},
+		[]string{"lane"},
+	)
+)
+
+// Métricas del scheduler de llamadas LLM (prioridad por carril)
+var (
+	// LLMSchedulerQueued indica las llamadas LLM que esperan turno por clase
+	LLMSchedulerQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_llm_scheduler_queued",
+			Help: "Number of LLM calls waiting for a slot by scheduler and priority class",
+		},
+		[]string{"scheduler", "class"}, // class: review, prep, material, default
+	)
+
+	// LLMSchedulerWait mide la espera de turno de una llamada LLM
+	LLMSchedulerWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_llm_scheduler_wait_seconds",
+			Help:    "Time an LLM call waited for a slot by scheduler and priority class",
+			Buckets: []float64{0.01, 0.1, 1, 5, 15, 30, 60, 120, 300, 900},
+		},
+		[]string{"scheduler", "class"},
+	)
+
+	// LLMPoolHostUp indica si un host Ollama de un pool está en el reparto
+	LLMPoolHostUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_llm_pool_host_up",
+			Help: "Whether an Ollama host is in its pool rotation (1=healthy, 0=ejected)",
+		},
+		[]string{"pool", "host"}, // pool: local, embed
+	)
+)
+
+// Métricas de reintentos diferidos (colas de retry con TTL)
+var (
+	// MessageRetries cuenta los mensajes re-publicados en una cola de retry
+	MessageRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_message_retries_total",
+			Help: "Total number of messages sent to a delayed retry tier",
+		},
+		[]string{"queue", "tier"}, // tier: 1, 2, 3...
+	)
+
+	// MessagesDeadLettered cuenta los mensajes enviados a la DLQ por el consumer con reintentos diferidos
+	MessagesDeadLettered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_messages_dead_lettered_total",
+			Help: "Total number of messages sent to the DLQ after a failure",
+		},
+		[]string{"queue", "reason"}, // permanent, retries_exhausted
+	)
+)
+
+// Métricas de disponibilidad de providers LLM
+var (
+	// LLMProviderUp indica si el monitor considera disponible al provider (1) o caído (0)
+	LLMProviderUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "worker_llm_provider_up",
+			Help: "Whether the LLM availability monitor reports the provider up (1) or down (0)",
+		},
+		[]string{"provider"}, // local
+	)
+
+	// LLMProviderTransitions cuenta los cambios de disponibilidad
+	LLMProviderTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_llm_provider_transitions_total",
+			Help: "Total number of LLM provider availability transitions",
+		},
+		[]string{"provider", "to_state"}, // up, down
+	)
+)
+
+// Métricas del presupuesto de contexto de los prompts LLM
+var (
+	// LLMPromptTokens mide los tokens estimados de cada prompt
+	LLMPromptTokens = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "worker_llm_prompt_tokens",
+			Help:    "Estimated prompt tokens per LLM request",
+			Buckets: []float64{256, 512, 1024, 2048, 4096, 8192, 16384, 32768},
+		},
+		[]string{"operation"},
+	)
+
+	// LLMPromptTruncations cuenta los recortes de listas para que el prompt quepa
+	LLMPromptTruncations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_llm_prompt_truncations_total",
+			Help: "Total number of prompt lists truncated to fit the LLM context window",
+		},
+		[]string{"operation", "field"},
+	)
+
+	// LLMPromptOverflows cuenta los prompts que exceden la ventana aun recortados
+	LLMPromptOverflows = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_llm_prompt_overflows_total",
+			Help: "Total number of LLM prompts that exceed the context window even after truncation",
+		},
+		[]string{"operation"},
+	)
+)
+
+// Métricas del cache de respuestas LLM
+var (
+	// LLMCacheRequests cuenta las consultas al cache por operación y resultado
+	LLMCacheRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "worker_llm_cache_requests_total",
+			Help: "Total number of LLM cache lookups by operation and result",
+		},
+		[]string{"operation", "result"}, // hit, miss, bypass
+	)
+)
+
 // RecordEventProcessing registra una métrica de procesamiento de evento
 func RecordEventProcessing(eventType string, status string, durationSeconds float64) {
 	EventsProcessedTotal.WithLabelValues(eventType, status).Inc()
 	ProcessingDuration.WithLabelValues(eventType).Observe(durationSeconds)
 }
 
-// RecordOpenAIRequest registra una métrica de solicitud a OpenAI
-func RecordOpenAIRequest(status string, durationSeconds float64, tokensUsed int) {
-	OpenAIRequestsTotal.WithLabelValues(status).Inc()
-	OpenAILatency.Observe(durationSeconds)
-	if tokensUsed > 0 {
-		OpenAITokensUsed.Add(float64(tokensUsed))
-	}
+// RecordLLMRequest registra una llamada LLM (status: success|quality_error|infra_error)
+func RecordLLMRequest(provider, model, operation, status string, durationSeconds float64) {
+	LLMRequestsTotal.WithLabelValues(provider, model, operation, status).Inc()
+	LLMRequestDuration.WithLabelValues(provider, model, operation).Observe(durationSeconds)
 }
 
-// RecordOpenAIError registra un error de OpenAI
-func RecordOpenAIError(errorType string) {
-	OpenAIErrors.WithLabelValues(errorType).Inc()
+// RecordLLMTokens registra los tokens de prompt y de salida de una llamada LLM
+func RecordLLMTokens(provider, model, operation string, promptTokens, completionTokens int) {
+	if promptTokens > 0 {
+		LLMTokensTotal.WithLabelValues(provider, model, operation, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		LLMTokensTotal.WithLabelValues(provider, model, operation, "completion").Add(float64(completionTokens))
+	}
 }
 
-// RecordS3Operation registra una operación de S3
-func RecordS3Operation(operation string, status string, durationSeconds float64) {
-	S3OperationsTotal.WithLabelValues(operation, status).Inc()
-	S3OperationDuration.WithLabelValues(operation).Observe(durationSeconds)
+// RecordLLMPromptRequest registra la versión del prompt usada en una llamada LLM
+func RecordLLMPromptRequest(operation, prompt, version, status string) {
+	LLMPromptRequestsTotal.WithLabelValues(operation, prompt, version, status).Inc()
 }
 
 // RecordPDFExtraction registra una extracción de PDF
@@ -225,6 +367,7 @@ func RecordPDFExtraction(status string, durationSeconds float64, pageCount int)
 //
 // Valores esperados para el parámetro service:
 // - "nlp": Cliente de procesamiento de lenguaje natural (OpenAI/Fallback)
+// - "llm_local", "llm_api", "llm_embed": Backends LLM (internal/llm/resilience)
 //
 // IMPORTANTE: No usar sufijos como "-test" en producción. Los sufijos solo
 // deben usarse en tests unitarios para aislar las métricas de prueba.
@@ -236,6 +379,7 @@ func SetCircuitBreakerState(service string, state int) {
 //
 // Valores esperados para el parámetro service:
 // - "nlp": Cliente de procesamiento de lenguaje natural (OpenAI/Fallback)
+// - "llm_local", "llm_api", "llm_embed": Backends LLM (internal/llm/resilience)
 //
 // IMPORTANTE: No usar sufijos como "-test" en producción. Los sufijos solo
 // deben usarse en tests unitarios para aislar las métricas de prueba.
@@ -273,3 +417,90 @@ func RecordRateLimiterAllowed(eventType string) {
 func UpdateRateLimiterTokens(eventType string, tokens float64) {
 	RateLimiterTokens.WithLabelValues(eventType).Set(tokens)
 }
+
+// SetLanePaused actualiza el estado de pausa de un carril
+func SetLanePaused(lane string, paused bool) {
+	v := 0.0
+	if paused {
+		v = 1
+	}
+	LanePaused.WithLabelValues(lane).Set(v)
+}
+
+// UpdateLaneInFlight actualiza los mensajes en proceso de un carril
+func UpdateLaneInFlight(lane string, inFlight int) {
+	LaneInFlight.WithLabelValues(lane).Set(float64(inFlight))
+}
+
+// SetLLMProviderUp actualiza la disponibilidad de un provider LLM
+func SetLLMProviderUp(provider string, up bool) {
+	v := 0.0
+	if up {
+		v = 1
+	}
+	LLMProviderUp.WithLabelValues(provider).Set(v)
+}
+
+// RecordLLMProviderTransition registra un cambio de disponibilidad (toState: up|down)
+func RecordLLMProviderTransition(provider string, toState string) {
+	LLMProviderTransitions.WithLabelValues(provider, toState).Inc()
+}
+
+// RecordLLMPromptTokens registra los tokens estimados de un prompt
+func RecordLLMPromptTokens(operation string, tokens int) {
+	LLMPromptTokens.WithLabelValues(operation).Observe(float64(tokens))
+}
+
+// RecordLLMPromptTruncation registra el recorte de una lista del prompt
+func RecordLLMPromptTruncation(operation string, field string) {
+	LLMPromptTruncations.WithLabelValues(operation, field).Inc()
+}
+
+// RecordLLMPromptOverflow registra un prompt que no cabe en la ventana de contexto
+func RecordLLMPromptOverflow(operation string) {
+	LLMPromptOverflows.WithLabelValues(operation).Inc()
+}
+
+// RecordLLMCacheRequest registra una consulta al cache LLM (result: hit|miss|bypass)
+func RecordLLMCacheRequest(operation string, result string) {
+	LLMCacheRequests.WithLabelValues(operation, result).Inc()
+}
+
+// RecordMessageRetry registra un mensaje enviado al escalón de retry tier (1-based)
+func RecordMessageRetry(queue string, tier int) {
+	MessageRetries.WithLabelValues(queue, strconv.Itoa(tier)).Inc()
+}
+
+// RecordMessageDeadLettered registra un mensaje enviado a la DLQ (reason: permanent|retries_exhausted)
+func RecordMessageDeadLettered(queue string, reason string) {
+	MessagesDeadLettered.WithLabelValues(queue, reason).Inc()
+}
+
+// UpdateFairSchedulerBuffered actualiza los mensajes en espera de turno de un carril
+func UpdateFairSchedulerBuffered(lane string, buffered int) {
+	FairSchedulerBuffered.WithLabelValues(lane).Set(float64(buffered))
+}
+
+// RecordFairSchedulerWait registra la espera de turno de un mensaje
+func RecordFairSchedulerWait(lane string, durationSeconds float64) {
+	FairSchedulerWait.WithLabelValues(lane).Observe(durationSeconds)
+}
+
+// UpdateLLMSchedulerQueued actualiza las llamadas LLM en espera de una clase
+func UpdateLLMSchedulerQueued(scheduler string, class string, queued int) {
+	LLMSchedulerQueued.WithLabelValues(scheduler, class).Set(float64(queued))
+}
+
+// RecordLLMSchedulerWait registra la espera de turno de una llamada LLM
+func RecordLLMSchedulerWait(scheduler string, class string, durationSeconds float64) {
+	LLMSchedulerWait.WithLabelValues(scheduler, class).Observe(durationSeconds)
+}
+
+// SetLLMPoolHostUp actualiza la salud de un host de un pool Ollama
+func SetLLMPoolHostUp(pool string, host string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	LLMPoolHostUp.WithLabelValues(pool, host).Set(value)
+}
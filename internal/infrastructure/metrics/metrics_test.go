@@ -172,47 +172,48 @@ func TestRecordEventProcessing(t *testing.T) {
 	assert.Equal(t, initialHistCount+1, newHistCount, "El histogram de duración debería tener una observación adicional")
 }
 
-func TestRecordOpenAIRequest(t *testing.T) {
-	status := "success"
-	duration := 2.5
-	tokens := 500
+func TestRecordLLMRequest(t *testing.T) {
+	labels := prometheus.Labels{"provider": "ollama", "model": "test-model", "operation": "review_answer"}
+	success := prometheus.Labels{"provider": "ollama", "model": "test-model", "operation": "review_answer", "status": "success"}
+	quality := prometheus.Labels{"provider": "ollama", "model": "test-model", "operation": "review_answer", "status": "quality_error"}
 
-	initialRequests := getCounterValue(t, OpenAIRequestsTotal, prometheus.Labels{"status": status})
+	initialSuccess := getCounterValue(t, LLMRequestsTotal, success)
+	initialQuality := getCounterValue(t, LLMRequestsTotal, quality)
+	initialHistCount := getHistogramCount(t, LLMRequestDuration, labels)
 
-	RecordOpenAIRequest(status, duration, tokens)
+	RecordLLMRequest("ollama", "test-model", "review_answer", "success", 2.5)
+	RecordLLMRequest("ollama", "test-model", "review_answer", "quality_error", 1.0)
 
-	newRequests := getCounterValue(t, OpenAIRequestsTotal, prometheus.Labels{"status": status})
-	assert.Equal(t, initialRequests+1, newRequests, "Las requests de OpenAI deberían incrementar")
+	assert.Equal(t, initialSuccess+1, getCounterValue(t, LLMRequestsTotal, success), "Las llamadas exitosas deberían incrementar")
+	assert.Equal(t, initialQuality+1, getCounterValue(t, LLMRequestsTotal, quality), "Los fallos de calidad deberían incrementar")
+	assert.Equal(t, initialHistCount+2, getHistogramCount(t, LLMRequestDuration, labels), "El histogram de latencia debería tener dos observaciones adicionales")
 }
 
-func TestRecordOpenAIError(t *testing.T) {
-	errorType := "rate_limit"
+func TestRecordLLMTokens(t *testing.T) {
+	prompt := prometheus.Labels{"provider": "anthropic", "model": "test-model", "operation": "digest_chunk", "kind": "prompt"}
+	completion := prometheus.Labels{"provider": "anthropic", "model": "test-model", "operation": "digest_chunk", "kind": "completion"}
 
-	initialErrors := getCounterValue(t, OpenAIErrors, prometheus.Labels{"error_type": errorType})
+	initialPrompt := getCounterValue(t, LLMTokensTotal, prompt)
+	initialCompletion := getCounterValue(t, LLMTokensTotal, completion)
 
-	RecordOpenAIError(errorType)
+	RecordLLMTokens("anthropic", "test-model", "digest_chunk", 1200, 300)
+	RecordLLMTokens("anthropic", "test-model", "digest_chunk", 0, 0)
 
-	newErrors := getCounterValue(t, OpenAIErrors, prometheus.Labels{"error_type": errorType})
-	assert.Equal(t, initialErrors+1, newErrors, "Los errores de OpenAI deberían incrementar")
+	assert.Equal(t, initialPrompt+1200, getCounterValue(t, LLMTokensTotal, prompt), "Los tokens de prompt deberían sumarse")
+	assert.Equal(t, initialCompletion+300, getCounterValue(t, LLMTokensTotal, completion), "Los tokens de salida deberían sumarse")
 }
 
-func TestRecordS3Operation(t *testing.T) {
-	operation := "upload"
-	status := "success"
-	duration := 3.0
+func TestRecordLLMPromptRequest(t *testing.T) {
+	candidate := prometheus.Labels{"operation": "review_answer", "prompt": "review_open_ended", "version": "v2", "status": "success"}
+	builtin := prometheus.Labels{"operation": "review_answer", "prompt": "review_open_ended", "version": "builtin", "status": "success"}
 
-	initialOps := getCounterValue(t, S3OperationsTotal, prometheus.Labels{
-		"operation": operation,
-		"status":    status,
-	})
+	initialCandidate := getCounterValue(t, LLMPromptRequestsTotal, candidate)
+	initialBuiltin := getCounterValue(t, LLMPromptRequestsTotal, builtin)
 
-	RecordS3Operation(operation, status, duration)
+	RecordLLMPromptRequest("review_answer", "review_open_ended", "v2", "success")
 
-	newOps := getCounterValue(t, S3OperationsTotal, prometheus.Labels{
-		"operation": operation,
-		"status":    status,
-	})
-	assert.Equal(t, initialOps+1, newOps, "Las operaciones de S3 deberían incrementar")
+	assert.Equal(t, initialCandidate+1, getCounterValue(t, LLMPromptRequestsTotal, candidate), "La versión candidata debería incrementar")
+	assert.Equal(t, initialBuiltin, getCounterValue(t, LLMPromptRequestsTotal, builtin), "Las demás versiones no deberían cambiar")
 }
 
 func TestRecordPDFExtraction(t *testing.T) {
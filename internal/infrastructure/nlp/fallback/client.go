@@ -297,8 +297,9 @@ func isTitleLine(line string) bool {
 	lower := strings.ToLower(line)
 	keywordPrefixes := []string{
 		"chapter ", "capítulo ", "capitulo ",
-		"sección ", "seccion ",
-		"tema ", "unidad ",
+		"sección ", "seccion ", "seção ", "secção ", "secao ", "section ",
+		"tema ", "unidad ", "unidade ", "unit ",
+		"lección ", "leccion ", "lição ", "licao ", "lesson ",
 	}
 	for _, kw := range keywordPrefixes {
 		if strings.HasPrefix(lower, kw) {
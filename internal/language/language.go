@@ -0,0 +1,107 @@
+// Package language resuelve el idioma de los carriles LLM. El worker nació en español
+// (regla global del ecosistema); con escuelas de Portugal/Brasil y de habla inglesa el
+// idioma pasa a resolverse por escuela (setting llm.language) o por material (detectado
+// del texto extraído). Es puro: sin red, sin config.
+//
+// Los idiomas son códigos ISO 639-1 sin región: "pt-BR" y "pt_PT" se resuelven a "pt".
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Idiomas soportados.
+const (
+	Spanish    = "es"
+	Portuguese = "pt"
+	English    = "en"
+)
+
+// Default es el idioma cuando no hay setting ni detección concluyente.
+const Default = Spanish
+
+// Supported son los idiomas soportados, en orden estable.
+var Supported = []string{Spanish, Portuguese, English}
+
+// Resolve normaliza code (mayúsculas, región) a un idioma soportado. ok=false si code
+// está vacío o no es un idioma soportado.
+func Resolve(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	for _, lang := range Supported {
+		if code == lang {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// OrDefault es Resolve con caída a Default.
+func OrDefault(code string) string {
+	if lang, ok := Resolve(code); ok {
+		return lang
+	}
+	return Default
+}
+
+// stopwords son palabras funcionales FRECUENTES y EXCLUSIVAS de cada idioma: se
+// excluyen las compartidas (es/pt: «de», «que», «para», «como», «este»; pt/en: «do»;
+// es/en: «no») para que cada acierto sea evidencia de un solo idioma.
+var stopwords = map[string]map[string]struct{}{
+	Spanish: set("el", "la", "los", "las", "del", "y", "en", "un", "una", "es", "con", "su", "sus",
+		"al", "lo", "pero", "cuando", "muy", "sin", "también", "hay", "donde", "fue", "son",
+		"puede", "pueden", "según"),
+	Portuguese: set("os", "da", "dos", "das", "na", "nas", "um", "uma", "é", "não", "com", "em",
+		"ao", "aos", "pelo", "pela", "são", "mais", "muito", "sem", "onde", "há", "também",
+		"quando", "isso", "você", "seu", "sua", "foi", "pode", "podem"),
+	English: set("the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "are", "was",
+		"this", "by", "be", "from", "or", "which", "an", "they", "have", "not", "can", "on",
+		"their", "these", "when", "between", "during", "into"),
+}
+
+// minEvidence es el mínimo de aciertos del idioma ganador para confiar en la
+// detección; por debajo (textos muy cortos) se usa el fallback.
+const minEvidence = 3
+
+func set(words ...string) map[string]struct{} {
+	m := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+	return m
+}
+
+// Detect estima el idioma de text contando palabras funcionales de cada idioma.
+// Devuelve fallback (resuelto con OrDefault) si el texto es corto o si el idioma
+// ganador no duplica al segundo: un material bilingüe o una tabla de datos no cambian
+// el idioma de la escuela.
+func Detect(text, fallback string) string {
+	counts := make(map[string]int, len(Supported))
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for lang, words := range stopwords {
+			if _, ok := words[word]; ok {
+				counts[lang]++
+			}
+		}
+	}
+	best, second := Default, 0
+	for _, lang := range Supported {
+		if counts[lang] > counts[best] {
+			best = lang
+		}
+	}
+	for _, lang := range Supported {
+		if lang != best && counts[lang] > second {
+			second = counts[lang]
+		}
+	}
+	if counts[best] < minEvidence || counts[best] < 2*second {
+		return OrDefault(fallback)
+	}
+	return best
+}
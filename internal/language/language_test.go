@@ -0,0 +1,66 @@
+package language
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"es", Spanish, true},
+		{" PT-BR ", Portuguese, true},
+		{"pt_PT", Portuguese, true},
+		{"en-US", English, true},
+		{"fr", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		got, ok := Resolve(c.code)
+		if got != c.want || ok != c.ok {
+			t.Errorf("Resolve(%q) = (%q, %v), esperaba (%q, %v)", c.code, got, ok, c.want, c.ok)
+		}
+	}
+	if got := OrDefault("de"); got != Default {
+		t.Errorf("un idioma no soportado cae al default: %q", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		fallback string
+		want     string
+	}{
+		{
+			"español",
+			"La fotosíntesis es el proceso por el cual las plantas convierten la energía luminosa en energía química. Ocurre en los cloroplastos, que contienen clorofila.",
+			English, Spanish,
+		},
+		{
+			"portugués",
+			"A fotossíntese é o processo pelo qual as plantas convertem a energia luminosa em energia química. Ocorre nos cloroplastos, que contêm clorofila, e não há fotossíntese sem luz.",
+			Spanish, Portuguese,
+		},
+		{
+			"inglés",
+			"Photosynthesis is the process by which plants convert light energy into chemical energy. It takes place in the chloroplasts, which contain chlorophyll.",
+			Spanish, English,
+		},
+		{"texto corto usa el fallback", "Capítulo 3", Portuguese, Portuguese},
+		{"sin fallback válido cae al default", "12 34 56", "", Default},
+		{
+			"bilingüe sin ganador claro",
+			"The cell is the unit of life and it has a membrane. La célula es la unidad de la vida y tiene una membrana con los poros.",
+			Portuguese, Portuguese,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Detect(c.text, c.fallback); got != c.want {
+				t.Errorf("Detect = %q, esperaba %q", got, c.want)
+			}
+		})
+	}
+}
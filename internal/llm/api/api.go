@@ -1,7 +1,8 @@
 // Package api implementa llm.LLMProvider contra un proveedor de LLM por API
-// (variante "api" de D-039.3). Implementa Anthropic (Claude) de forma completa y
-// deja Gemini como stub explícito: la interfaz no cambia cuando se complete
-// (decisión de costo/calidad diferida al encender 040/041, design 039 §6).
+// (variante "api" de D-039.3). Implementa Anthropic (Claude, Messages API) y Gemini
+// (generateContent con salida JSON); ambos comparten los prompts y el parseo de
+// llm, así que la elección es de costo/calidad y no cambia el contrato (design 039
+// §6).
 //
 // Credenciales/URL/modelo entran por Config (inyectado desde bootstrap/config);
 // el provider NUNCA lee env directo (D-039.3).
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
 
@@ -30,6 +32,7 @@ const (
 const (
 	defaultAnthropicBaseURL = "https://api.anthropic.com"
 	anthropicVersion        = "2023-06-01"
+	defaultGeminiBaseURL    = "https://generativelanguage.googleapis.com"
 	defaultMaxTokens        = 4096
 )
 
@@ -45,8 +48,13 @@ type Config struct {
 	BaseURL string
 	// Timeout de la request HTTP. Default 60s.
 	Timeout time.Duration
+	// Transport opcional (nil = http.DefaultTransport). Con un cassette.Recorder el
+	// harness congela respuestas reales del proveedor sin volver a pagarlas.
+	Transport http.RoundTripper
 	// MaxTokens del completion. Default 4096.
 	MaxTokens int
+	// Prompts elige la versión de cada prompt por escuela. nil = builtin.
+	Prompts *prompts.Registry
 }
 
 // Provider es la implementación por API de llm.LLMProvider.
@@ -64,8 +72,9 @@ func New(cfg Config) (*Provider, error) {
 			cfg.BaseURL = defaultAnthropicBaseURL
 		}
 	case ProviderGemini:
-		// Stub: se acepta la construcción para no bloquear el wiring, pero las
-		// llamadas devuelven error claro hasta que se implemente.
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = defaultGeminiBaseURL
+		}
 	default:
 		return nil, fmt.Errorf("proveedor LLM API no soportado: %q (soportados: %q, %q)", cfg.Provider, ProviderAnthropic, ProviderGemini)
 	}
@@ -78,7 +87,7 @@ func New(cfg Config) (*Provider, error) {
 	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
 	return &Provider{
 		cfg:        cfg,
-		httpClient: &http.Client{Timeout: cfg.Timeout},
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
 	}, nil
 }
 
@@ -87,28 +96,33 @@ func (p *Provider) Name() string { return "api:" + p.cfg.Provider + ":" + p.cfg.
 
 // GenerateAssessment pide un JSON del contrato assessment_import v1.
 func (p *Provider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
-	prompt := llm.BuildGenerationPrompt(material, params)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.Generation(ctx, material, params)
+	out, err := p.complete(ctx, prompt, llm.OutputSchema{}, nil)
 	if err != nil {
 		return nil, err
 	}
-	return llm.ExtractJSON(out)
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return rawJSON, nil
 }
 
-// ReviewAnswer pide la corrección de una respuesta.
+// ReviewAnswer pide la corrección de una respuesta. Sin temperatura forzada el
+// juicio es greedy (ver judgeTemperature).
 func (p *Provider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
-	prompt := llm.BuildReviewPrompt(req)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.Review(ctx, req)
+	out, err := p.complete(ctx, prompt, llm.ReviewSchema, judgeTemperature(req.Temperature))
 	if err != nil {
 		return llm.ReviewResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.ReviewResult{}, err
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
 	var result llm.ReviewResult
 	if err := json.Unmarshal(rawJSON, &result); err != nil {
-		return llm.ReviewResult{}, fmt.Errorf("respuesta de corrección no parseable: %w", err)
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de corrección no parseable: %v", llm.ErrLLMQuality, err)
 	}
 	return result, nil
 }
@@ -116,29 +130,33 @@ func (p *Provider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm
 // PrepareQuestion pide el artefacto de preparación (JSON crudo del contrato
 // llm_prep v1). El caller valida el JSON contra el contrato antes de persistirlo.
 func (p *Provider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
-	prompt := llm.BuildPrepPrompt(req)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.Prep(ctx, req)
+	out, err := p.complete(ctx, prompt, llm.PrepSchema, nil)
 	if err != nil {
 		return nil, err
 	}
-	return llm.ExtractJSON(out)
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return rawJSON, nil
 }
 
 // JudgePairEquivalence pide la equivalencia binaria de un par (plan 042 F3c). Mismo
 // camino que ReviewAnswer: el resultado es un ReviewResult (verdict/score/feedback).
 func (p *Provider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
-	prompt := llm.BuildPairEquivalencePrompt(req)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.PairEquivalence(ctx, req)
+	out, err := p.complete(ctx, prompt, llm.ReviewSchema, nil)
 	if err != nil {
 		return llm.ReviewResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.ReviewResult{}, err
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
 	var result llm.ReviewResult
 	if err := json.Unmarshal(rawJSON, &result); err != nil {
-		return llm.ReviewResult{}, fmt.Errorf("respuesta de equivalencia no parseable: %w", err)
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de equivalencia no parseable: %v", llm.ErrLLMQuality, err)
 	}
 	return result, nil
 }
@@ -146,18 +164,18 @@ func (p *Provider) JudgePairEquivalence(ctx context.Context, req llm.PairEquival
 // CheckCriterion pide el cumplimiento binario de un criterio (plan 042 F4b). Mismo
 // camino que ReviewAnswer: el resultado es un ReviewResult (verdict/score/feedback).
 func (p *Provider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
-	prompt := llm.BuildCriterionCheckPrompt(req)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.CriterionCheck(ctx, req)
+	out, err := p.complete(ctx, prompt, llm.ReviewSchema, judgeTemperature(req.Temperature))
 	if err != nil {
 		return llm.ReviewResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.ReviewResult{}, err
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
 	var result llm.ReviewResult
 	if err := json.Unmarshal(rawJSON, &result); err != nil {
-		return llm.ReviewResult{}, fmt.Errorf("respuesta de criterio no parseable: %w", err)
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de criterio no parseable: %v", llm.ErrLLMQuality, err)
 	}
 	return result, nil
 }
@@ -167,16 +185,20 @@ func (p *Provider) CheckCriterion(ctx context.Context, req llm.CriterionCheckReq
 // → completar → ExtractJSON → ParseRelevanceResult (valida forma y rango [0,1]). No está
 // en el puerto llm.LLMProvider: la pasada la consume por una interfaz mínima propia (ISP).
 func (p *Provider) ScoreRelevance(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
-	prompt := llm.BuildRelevancePrompt(req)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.Relevance(ctx, req)
+	out, err := p.complete(ctx, prompt, llm.RelevanceSchema, nil)
 	if err != nil {
 		return llm.RelevanceResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.RelevanceResult{}, err
+		return llm.RelevanceResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
-	return llm.ParseRelevanceResult(rawJSON)
+	result, err := llm.ParseRelevanceResult(rawJSON)
+	if err != nil {
+		return llm.RelevanceResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return result, nil
 }
 
 // ExtractIdeas descompone la respuesta del alumno en ideas atómicas (plan 045 F4).
@@ -184,16 +206,20 @@ func (p *Provider) ScoreRelevance(ctx context.Context, req llm.RelevanceRequest)
 // la forma {"ideas":[…]}. Una extracción que no parsea es fallo transitorio (el caller
 // decide el fallback a la respuesta cruda).
 func (p *Provider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
-	prompt := llm.BuildExtractIdeasPrompt(req)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.ExtractIdeas(ctx, req)
+	out, err := p.complete(ctx, prompt, llm.ExtractIdeasSchema, nil)
 	if err != nil {
 		return nil, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	ideas, err := llm.ParseExtractedIdeas(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
-	return llm.ParseExtractedIdeas(rawJSON)
+	return ideas, nil
 }
 
 // DigestChunk ejecuta la llamada A ("leer") del pipeline material→evaluación (plan 043
@@ -201,8 +227,8 @@ func (p *Provider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest
 // ParseDigestResult. La impl api existe para que la fase 2 del 044 reuse B por API con
 // los MISMOS prompts (D-043.7); la fase 1 del processor solo usa el local.
 func (p *Provider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
-	prompt := llm.BuildDigestChunkPrompt(in)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.DigestChunk(ctx, in)
+	out, err := p.complete(ctx, prompt, llm.DigestSchema, in.Temperature)
 	if err != nil {
 		// Fallo de transporte/HTTP: INFRA, sube SIN el sentinel de calidad.
 		return nil, err
@@ -221,8 +247,8 @@ func (p *Provider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*l
 // ProposeCandidates ejecuta la llamada B ("preguntar") del pipeline (plan 043 F3).
 // Mismo camino: build prompt → completar → ExtractJSON → ParseCandidates.
 func (p *Provider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
-	prompt := llm.BuildProposeCandidatesPrompt(in)
-	out, err := p.complete(ctx, prompt)
+	prompt := p.cfg.Prompts.ProposeCandidates(ctx, in)
+	out, err := p.complete(ctx, prompt, llm.ProposeCandidatesSchema, in.Temperature)
 	if err != nil {
 		// Fallo de transporte/HTTP: INFRA, sube SIN el sentinel de calidad.
 		return nil, err
@@ -238,13 +264,28 @@ func (p *Provider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidat
 	return candidates, nil
 }
 
-// complete enruta al backend concreto.
-func (p *Provider) complete(ctx context.Context, prompt string) (string, error) {
+// judgeTemperature es la temperatura de un juicio de corrección: la forzada por la
+// llamada o, sin override, 0. Las APIs muestrean a ~1 por defecto; el backend local
+// corrige greedy (llm.local.temperature = 0) y la votación cuenta con que su primera
+// muestra, la que no fuerza temperatura, también lo sea aquí.
+func judgeTemperature(forced *float64) *float64 {
+	if forced != nil {
+		return forced
+	}
+	greedy := 0.0
+	return &greedy
+}
+
+// complete enruta al backend concreto. schema es la forma de la salida; sin Schema
+// (GenerateAssessment) solo se pide JSON. temperature, si != nil, fuerza la
+// temperatura de esta llamada (votación, jitter del reintento por calidad); nil deja
+// la del backend.
+func (p *Provider) complete(ctx context.Context, prompt string, schema llm.OutputSchema, temperature *float64) (string, error) {
 	switch p.cfg.Provider {
 	case ProviderAnthropic:
-		return p.completeAnthropic(ctx, prompt)
+		return p.completeAnthropic(ctx, prompt, schema, temperature)
 	case ProviderGemini:
-		return "", fmt.Errorf("provider gemini aún no implementado (039 F4 entrega anthropic; gemini se completa al encender 040/041)")
+		return p.completeGemini(ctx, prompt, schema, temperature)
 	default:
 		return "", fmt.Errorf("proveedor no soportado: %q", p.cfg.Provider)
 	}
@@ -253,9 +294,26 @@ func (p *Provider) complete(ctx context.Context, prompt string) (string, error)
 // ---- Anthropic Messages API ----
 
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	Messages  []anthropicMessage `json:"messages"`
+	Model       string               `json:"model"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature *float64             `json:"temperature,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool declara la salida como una tool cuyo input_schema es el del contrato:
+// con tool_choice forzado, el modelo responde con un bloque tool_use cuyo input es
+// el objeto ya conforme al schema.
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
 
 type anthropicMessage struct {
@@ -265,12 +323,19 @@ type anthropicMessage struct {
 
 type anthropicResponse struct {
 	Content []anthropicContentBlock `json:"content"`
+	Usage   anthropicUsage          `json:"usage"`
 	Error   *anthropicError         `json:"error,omitempty"`
 }
 
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
 type anthropicContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 type anthropicError struct {
@@ -278,11 +343,20 @@ type anthropicError struct {
 	Message string `json:"message"`
 }
 
-func (p *Provider) completeAnthropic(ctx context.Context, prompt string) (string, error) {
+func (p *Provider) completeAnthropic(ctx context.Context, prompt string, schema llm.OutputSchema, temperature *float64) (string, error) {
 	reqBody := anthropicRequest{
-		Model:     p.cfg.Model,
-		MaxTokens: p.cfg.MaxTokens,
-		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+		Model:       p.cfg.Model,
+		MaxTokens:   p.cfg.MaxTokens,
+		Temperature: temperature,
+		Messages:    []anthropicMessage{{Role: "user", Content: prompt}},
+	}
+	if schema.Schema != nil {
+		reqBody.Tools = []anthropicTool{{
+			Name:        schema.Name,
+			Description: "Entrega la respuesta con la forma pedida.",
+			InputSchema: schema.Schema,
+		}}
+		reqBody.ToolChoice = &anthropicToolChoice{Type: "tool", Name: schema.Name}
 	}
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
@@ -319,15 +393,140 @@ func (p *Provider) completeAnthropic(ctx context.Context, prompt string) (string
 		}
 		return "", fmt.Errorf("anthropic returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
 	}
+	llm.ReportUsage(ctx, ar.Usage.InputTokens, ar.Usage.OutputTokens)
 
+	// Con tool forzada la salida es el input del bloque tool_use; el texto queda como
+	// respaldo (sin schema, o un modelo que responde en prosa pese a tool_choice).
 	var sb strings.Builder
 	for _, block := range ar.Content {
-		if block.Type == "text" {
+		switch block.Type {
+		case "tool_use":
+			if len(block.Input) > 0 {
+				return string(block.Input), nil
+			}
+		case "text":
 			sb.WriteString(block.Text)
 		}
 	}
 	if sb.Len() == 0 {
-		return "", fmt.Errorf("anthropic devolvió una respuesta sin bloques de texto")
+		return "", fmt.Errorf("anthropic devolvió una respuesta sin bloques de texto ni tool_use")
+	}
+	return sb.String(), nil
+}
+
+// ---- Gemini generateContent API ----
+
+type geminiRequest struct {
+	Contents         []geminiContent        `json:"contents"`
+	GenerationConfig geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiGenerationConfig pide salida JSON (responseMimeType): todas las llamadas
+// del provider esperan un objeto JSON y así el modelo no lo envuelve en prosa. Con
+// responseJsonSchema además restringe su forma al contrato de la operación.
+// Temperature solo viaja cuando la llamada la fuerza.
+type geminiGenerationConfig struct {
+	ResponseMIMEType   string          `json:"responseMimeType"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+	MaxOutputTokens    int             `json:"maxOutputTokens"`
+	Temperature        *float64        `json:"temperature,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate     `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  geminiUsageMetadata   `json:"usageMetadata"`
+	Error          *geminiError          `json:"error,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (p *Provider) completeGemini(ctx context.Context, prompt string, schema llm.OutputSchema, temperature *float64) (string, error) {
+	reqBody := geminiRequest{
+		Contents: []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+		GenerationConfig: geminiGenerationConfig{
+			ResponseMIMEType:   "application/json",
+			ResponseJSONSchema: schema.Schema,
+			MaxOutputTokens:    p.cfg.MaxTokens,
+			Temperature:        temperature,
+		},
+	}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("marshaling gemini request: %w", err)
+	}
+
+	url := p.cfg.BaseURL + "/v1beta/models/" + p.cfg.Model + ":generateContent"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("creating gemini request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.cfg.APIKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("gemini request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading gemini response: %w", err)
+	}
+
+	var gr geminiResponse
+	if err := json.Unmarshal(body, &gr); err != nil {
+		return "", fmt.Errorf("parsing gemini response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if gr.Error != nil {
+			return "", fmt.Errorf("gemini error (status %d): %s: %s", resp.StatusCode, gr.Error.Status, gr.Error.Message)
+		}
+		return "", fmt.Errorf("gemini returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	llm.ReportUsage(ctx, gr.UsageMetadata.PromptTokenCount, gr.UsageMetadata.CandidatesTokenCount)
+	if gr.PromptFeedback != nil && gr.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("gemini bloqueó el prompt: %s", gr.PromptFeedback.BlockReason)
+	}
+
+	var sb strings.Builder
+	finishReason := ""
+	if len(gr.Candidates) > 0 {
+		finishReason = gr.Candidates[0].FinishReason
+		for _, part := range gr.Candidates[0].Content.Parts {
+			sb.WriteString(part.Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("gemini devolvió una respuesta sin texto (finishReason %q)", finishReason)
 	}
 	return sb.String(), nil
 }
@@ -3,12 +3,14 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
 
 func fakeAnthropic(t *testing.T, text string) *httptest.Server {
@@ -73,14 +75,288 @@ func TestAnthropic_APIError(t *testing.T) {
 	}
 }
 
-func TestGemini_Stub(t *testing.T) {
-	p, err := New(Config{Provider: ProviderGemini, APIKey: "k", Model: "gemini"})
+// Con schema, la salida llega como input de un bloque tool_use forzado.
+func TestAnthropic_ReviewAnswer_ToolUse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("request no parseable: %v", err)
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Name != llm.ReviewSchema.Name || !strings.Contains(string(req.Tools[0].InputSchema), `"verdict"`) {
+			t.Errorf("tools inesperadas: %+v", req.Tools)
+		}
+		if req.ToolChoice == nil || req.ToolChoice.Type != "tool" || req.ToolChoice.Name != llm.ReviewSchema.Name {
+			t.Errorf("tool_choice inesperado: %+v", req.ToolChoice)
+		}
+		_ = json.NewEncoder(w).Encode(anthropicResponse{Content: []anthropicContentBlock{{
+			Type:  "tool_use",
+			Input: json.RawMessage(`{"verdict":"partial","score":0.5,"feedback":"casi"}`),
+		}}, Usage: anthropicUsage{InputTokens: 210, OutputTokens: 45}})
+	}))
+	defer srv.Close()
+
+	p, _ := New(Config{Provider: ProviderAnthropic, APIKey: "secret-key", Model: "m", BaseURL: srv.URL})
+	ctx, usage := llm.WithUsage(context.Background())
+	res, err := p.ReviewAnswer(ctx, llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
 	if err != nil {
-		t.Fatalf("New (gemini) no debe fallar en construcción: %v", err)
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Verdict != llm.VerdictPartial || res.Score != 0.5 {
+		t.Fatalf("resultado inesperado: %+v", res)
+	}
+	if promptTokens, completionTokens := usage.Tokens(); promptTokens != 210 || completionTokens != 45 {
+		t.Errorf("tokens reportados inesperados: prompt=%d completion=%d", promptTokens, completionTokens)
+	}
+}
+
+// fakeGemini responde generateContent con text como único part y verifica el
+// contrato de la request (modelo en el path, API key, modo JSON).
+func fakeGemini(t *testing.T, text string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-x:generateContent" {
+			t.Errorf("path inesperado: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "secret-key" {
+			t.Errorf("x-goog-api-key ausente/incorrecta: %q", r.Header.Get("x-goog-api-key"))
+		}
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("request no parseable: %v", err)
+		}
+		if req.GenerationConfig.ResponseMIMEType != "application/json" {
+			t.Errorf("esperaba modo JSON, llegó %q", req.GenerationConfig.ResponseMIMEType)
+		}
+		if len(req.Contents) != 1 || len(req.Contents[0].Parts) != 1 || req.Contents[0].Parts[0].Text == "" {
+			t.Errorf("esperaba el prompt como único part: %+v", req.Contents)
+			return
+		}
+		// Todas las operaciones salvo GenerateAssessment declaran su schema.
+		if !strings.Contains(req.Contents[0].Parts[0].Text, "assessment_import") && len(req.GenerationConfig.ResponseJSONSchema) == 0 {
+			t.Error("esperaba responseJsonSchema")
+		}
+		resp := geminiResponse{Candidates: []geminiCandidate{{
+			Content:      geminiContent{Role: "model", Parts: []geminiPart{{Text: text}}},
+			FinishReason: "STOP",
+		}}}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func newGemini(t *testing.T, srv *httptest.Server) *Provider {
+	t.Helper()
+	p, err := New(Config{Provider: ProviderGemini, APIKey: "secret-key", Model: "gemini-x", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New (gemini) falló: %v", err)
+	}
+	return p
+}
+
+func TestGemini_Operaciones(t *testing.T) {
+	review := `{"verdict":"correct","score":1.0,"feedback":"bien"}`
+	tests := []struct {
+		name string
+		text string
+		call func(p *Provider) (any, error)
+		want func(t *testing.T, got any)
+	}{
+		{"GenerateAssessment", `{"format":"edugo.assessment_import","version":1,"assessment":{"title":"x"},"questions":[]}`,
+			func(p *Provider) (any, error) {
+				return p.GenerateAssessment(context.Background(), llm.MaterialInput{Content: "c"}, llm.GenerationParams{NumQuestions: 1})
+			},
+			func(t *testing.T, got any) {
+				if !strings.Contains(string(got.(json.RawMessage)), "edugo.assessment_import") {
+					t.Errorf("JSON inesperado: %s", got)
+				}
+			}},
+		{"ReviewAnswer", review,
+			func(p *Provider) (any, error) {
+				return p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
+			},
+			func(t *testing.T, got any) {
+				if res := got.(llm.ReviewResult); res.Verdict != llm.VerdictCorrect || res.Score != 1.0 {
+					t.Errorf("resultado inesperado: %+v", res)
+				}
+			}},
+		{"PrepareQuestion", `{"version":1,"intent":"x"}`,
+			func(p *Provider) (any, error) {
+				return p.PrepareQuestion(context.Background(), llm.PrepRequest{QuestionText: "q"})
+			},
+			func(t *testing.T, got any) {
+				if !strings.Contains(string(got.(json.RawMessage)), `"intent"`) {
+					t.Errorf("JSON inesperado: %s", got)
+				}
+			}},
+		{"JudgePairEquivalence", review,
+			func(p *Provider) (any, error) {
+				return p.JudgePairEquivalence(context.Background(), llm.PairEquivalenceRequest{})
+			},
+			func(t *testing.T, got any) {
+				if got.(llm.ReviewResult).Verdict != llm.VerdictCorrect {
+					t.Errorf("resultado inesperado: %+v", got)
+				}
+			}},
+		{"CheckCriterion", review,
+			func(p *Provider) (any, error) {
+				return p.CheckCriterion(context.Background(), llm.CriterionCheckRequest{})
+			},
+			func(t *testing.T, got any) {
+				if got.(llm.ReviewResult).Verdict != llm.VerdictCorrect {
+					t.Errorf("resultado inesperado: %+v", got)
+				}
+			}},
+		{"ScoreRelevance", `{"category":"central","rationale":"cubre la idea"}`,
+			func(p *Provider) (any, error) {
+				return p.ScoreRelevance(context.Background(), llm.RelevanceRequest{})
+			},
+			func(t *testing.T, got any) {
+				if res := got.(llm.RelevanceResult); res.Score != 1.0 || !strings.HasPrefix(res.Rationale, "central") {
+					t.Errorf("resultado inesperado: %+v", res)
+				}
+			}},
+		{"ExtractIdeas", `{"ideas":["la fotosíntesis produce oxígeno"," "]}`,
+			func(p *Provider) (any, error) {
+				return p.ExtractIdeas(context.Background(), llm.ExtractIdeasRequest{})
+			},
+			func(t *testing.T, got any) {
+				if ideas := got.([]string); len(ideas) != 1 {
+					t.Errorf("ideas inesperadas: %q", ideas)
+				}
+			}},
+		{"DigestChunk", `{"version":1,"main_ideas":["idea"],"chunk_topic":"tema","summary":" resumen "}`,
+			func(p *Provider) (any, error) {
+				return p.DigestChunk(context.Background(), llm.DigestChunkInput{})
+			},
+			func(t *testing.T, got any) {
+				res := got.(*llm.DigestChunkResult)
+				if res.Summary != "resumen" || res.Artifacts.ChunkTopic != "tema" || len(res.Artifacts.MainIdeas) != 1 {
+					t.Errorf("resultado inesperado: %+v", res)
+				}
+			}},
+		{"ProposeCandidates", `{"candidates":[{"version":1,"question_type":"multiple_choice","question_text":"¿?"}]}`,
+			func(p *Provider) (any, error) {
+				return p.ProposeCandidates(context.Background(), llm.ProposeCandidatesInput{})
+			},
+			func(t *testing.T, got any) {
+				if candidates := got.([]materialpipeline.CandidatePayloadV1); len(candidates) != 1 || candidates[0].QuestionText != "¿?" {
+					t.Errorf("candidatas inesperadas: %+v", candidates)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeGemini(t, tt.text)
+			defer srv.Close()
+
+			got, err := tt.call(newGemini(t, srv))
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			tt.want(t, got)
+		})
+	}
+}
+
+func TestGemini_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(geminiResponse{Error: &geminiError{Code: 403, Status: "PERMISSION_DENIED", Message: "API key not valid"}})
+	}))
+	defer srv.Close()
+
+	_, err := newGemini(t, srv).GenerateAssessment(context.Background(), llm.MaterialInput{}, llm.GenerationParams{})
+	if err == nil || !strings.Contains(err.Error(), "PERMISSION_DENIED") {
+		t.Fatalf("esperaba error de auth: %v", err)
+	}
+}
+
+func TestGemini_PromptBloqueado(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(geminiResponse{PromptFeedback: &geminiPromptFeedback{BlockReason: "SAFETY"}})
+	}))
+	defer srv.Close()
+
+	_, err := newGemini(t, srv).ReviewAnswer(context.Background(), llm.ReviewRequest{})
+	if err == nil || !strings.Contains(err.Error(), "SAFETY") {
+		t.Fatalf("esperaba error de prompt bloqueado: %v", err)
+	}
+}
+
+// La salida que no parsea en las llamadas del pipeline es calidad, no infra: igual
+// que en Anthropic, sube con llm.ErrLLMQuality.
+func TestGemini_DigestNoParseableEsCalidad(t *testing.T) {
+	srv := fakeGemini(t, "sin json")
+	defer srv.Close()
+
+	_, err := newGemini(t, srv).DigestChunk(context.Background(), llm.DigestChunkInput{})
+	if !errors.Is(err, llm.ErrLLMQuality) {
+		t.Fatalf("esperaba ErrLLMQuality: %v", err)
+	}
+}
+
+func TestGemini_ReviewNoParseableEsCalidad(t *testing.T) {
+	srv := fakeGemini(t, `{"verdict": 3}`)
+	defer srv.Close()
+
+	_, err := newGemini(t, srv).ReviewAnswer(context.Background(), llm.ReviewRequest{})
+	if !errors.Is(err, llm.ErrLLMQuality) {
+		t.Fatalf("esperaba ErrLLMQuality: %v", err)
+	}
+}
+
+// La temperatura por llamada (muestras de la votación) viaja al backend; sin ella el
+// juicio va greedy (0), no al default ~1 de la API: la primera muestra de la votación
+// es la greedy.
+func TestAnthropic_TemperaturaPorLlamada(t *testing.T) {
+	var got []*float64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("request no parseable: %v", err)
+		}
+		got = append(got, req.Temperature)
+		_ = json.NewEncoder(w).Encode(anthropicResponse{Content: []anthropicContentBlock{{Type: "text", Text: `{"verdict":"correct","score":1,"feedback":"ok"}`}}})
+	}))
+	defer srv.Close()
+
+	p, _ := New(Config{Provider: ProviderAnthropic, APIKey: "secret-key", Model: "m", BaseURL: srv.URL})
+	hot := 0.7
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{Temperature: &hot}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(got) != 2 || got[0] == nil || *got[0] != 0.7 || got[1] == nil || *got[1] != 0 {
+		t.Fatalf("temperaturas enviadas = %v, quiero [0.7 0]", got)
+	}
+}
+
+func TestGemini_TemperaturaPorLlamada(t *testing.T) {
+	var got []*float64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("request no parseable: %v", err)
+		}
+		got = append(got, req.GenerationConfig.Temperature)
+		_ = json.NewEncoder(w).Encode(geminiResponse{Candidates: []geminiCandidate{{
+			Content:      geminiContent{Role: "model", Parts: []geminiPart{{Text: `{"verdict":"correct","score":1,"feedback":"ok"}`}}},
+			FinishReason: "STOP",
+		}}})
+	}))
+	defer srv.Close()
+
+	p := newGemini(t, srv)
+	hot := 0.7
+	if _, err := p.CheckCriterion(context.Background(), llm.CriterionCheckRequest{Temperature: &hot}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := p.CheckCriterion(context.Background(), llm.CriterionCheckRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
 	}
-	_, err = p.GenerateAssessment(context.Background(), llm.MaterialInput{}, llm.GenerationParams{})
-	if err == nil || !strings.Contains(err.Error(), "gemini") {
-		t.Fatalf("esperaba error de stub gemini: %v", err)
+	if len(got) != 2 || got[0] == nil || *got[0] != 0.7 || got[1] == nil || *got[1] != 0 {
+		t.Fatalf("temperaturas enviadas = %v, quiero [0.7 0]", got)
 	}
 }
 
@@ -0,0 +1,195 @@
+// Package availability vigila la disponibilidad de un provider LLM (hoy el local,
+// Ollama) combinando una sonda periódica con los errores observados en las
+// llamadas reales. Mientras el provider está caído, los carriles que dependen de él
+// se pausan en vez de quemar reintentos hasta el DLQ (classifyError trata todo fallo
+// de transporte del LLM como transitorio); al volver, se reanudan solos.
+package availability
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/health"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// Prober es la sonda de disponibilidad del provider. *ollama.Provider la satisface
+// (GET /api/tags + modelo instalado).
+type Prober interface {
+	Ping(ctx context.Context) error
+}
+
+// Config parametriza el Monitor.
+type Config struct {
+	// Provider es el nombre del provider vigilado (label de métricas, check de health).
+	Provider string
+	// Interval entre sondas. Default 15s.
+	Interval time.Duration
+	// ProbeTimeout acota cada sonda. Default 5s.
+	ProbeTimeout time.Duration
+	// FailureThreshold son los fallos de transporte consecutivos (sondas o llamadas
+	// observadas) que marcan el provider caído. Default 3.
+	FailureThreshold int
+}
+
+// Monitor mantiene el estado up/down del provider. Arranca optimista (up). Cualquier
+// éxito —sonda o llamada— lo marca up; FailureThreshold fallos de transporte
+// consecutivos lo marcan down. Los errores de CALIDAD (llm.ErrLLMQuality) cuentan
+// como éxito: el servidor respondió. Las cancelaciones de contexto no cuentan.
+type Monitor struct {
+	cfg    Config
+	prober Prober
+	logger logger.Logger
+
+	mu          sync.Mutex
+	up          bool
+	failures    int
+	lastErr     string
+	changedAt   time.Time
+	subscribers []func(up bool)
+	transition  sync.Mutex // serializa la notificación de transiciones
+}
+
+// NewMonitor construye el monitor con defaults para los campos vacíos de cfg.
+func NewMonitor(cfg Config, prober Prober, log logger.Logger) *Monitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = 5 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	metrics.SetLLMProviderUp(cfg.Provider, true)
+	return &Monitor{
+		cfg:       cfg,
+		prober:    prober,
+		logger:    log,
+		up:        true,
+		changedAt: time.Now(),
+	}
+}
+
+// OnChange suscribe fn a las transiciones up/down. Se llama fuera del lock del
+// monitor y en orden (una transición a la vez).
+func (m *Monitor) OnChange(fn func(up bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Up indica si el provider se considera disponible.
+func (m *Monitor) Up() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.up
+}
+
+// Start lanza el loop de sondas (una inmediata y luego cada Interval) hasta que
+// ctx se cancele.
+func (m *Monitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			m.probe(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *Monitor) probe(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, m.cfg.ProbeTimeout)
+	defer cancel()
+	err := m.prober.Ping(probeCtx)
+	if err != nil && ctx.Err() != nil {
+		return // shutdown: no es un fallo del provider
+	}
+	m.record(err, "probe")
+}
+
+// Observe registra el resultado de una llamada real al provider.
+func (m *Monitor) Observe(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, llm.ErrLLMQuality) {
+		err = nil
+	}
+	m.record(err, "call")
+}
+
+func (m *Monitor) record(err error, source string) {
+	m.transition.Lock()
+	defer m.transition.Unlock()
+
+	m.mu.Lock()
+	wasUp := m.up
+	if err == nil {
+		m.failures = 0
+		m.lastErr = ""
+		m.up = true
+	} else {
+		m.failures++
+		m.lastErr = err.Error()
+		if m.failures >= m.cfg.FailureThreshold {
+			m.up = false
+		}
+	}
+	changed := wasUp != m.up
+	if changed {
+		m.changedAt = time.Now()
+	}
+	up, failures, lastErr := m.up, m.failures, m.lastErr
+	subscribers := append([]func(bool){}, m.subscribers...)
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	state := "up"
+	if !up {
+		state = "down"
+		m.logger.Error("provider LLM no disponible: se pausan los carriles dependientes",
+			"provider", m.cfg.Provider, "source", source, "consecutive_failures", failures, "error", lastErr)
+	} else {
+		m.logger.Info("provider LLM disponible de nuevo: se reanudan los carriles dependientes",
+			"provider", m.cfg.Provider, "source", source)
+	}
+	metrics.SetLLMProviderUp(m.cfg.Provider, up)
+	metrics.RecordLLMProviderTransition(m.cfg.Provider, state)
+	for _, fn := range subscribers {
+		fn(up)
+	}
+}
+
+// Name satisface health.HealthCheck.
+func (m *Monitor) Name() string { return "llm_" + m.cfg.Provider }
+
+// Check satisface health.HealthCheck. Con el provider caído reporta degraded (no
+// unhealthy): el pod sigue ready —los carriles que no dependen del provider siguen
+// consumiendo— y el readiness lo muestra como ready_degraded.
+func (m *Monitor) Check(context.Context) health.CheckResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := health.CheckResult{
+		Status:    health.StatusHealthy,
+		Component: m.Name(),
+		Timestamp: time.Now(),
+	}
+	if !m.up {
+		res.Status = health.StatusDegraded
+		res.Message = "provider down since " + m.changedAt.UTC().Format(time.RFC3339) +
+			"; dependent lanes paused: " + m.lastErr
+	}
+	return res
+}
@@ -0,0 +1,196 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/health"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+type nopLogger struct{}
+
+func (l *nopLogger) Debug(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Info(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Warn(msg string, keysAndValues ...interface{})   {}
+func (l *nopLogger) Error(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Fatal(msg string, keysAndValues ...interface{})  {}
+func (l *nopLogger) Sync() error                                     { return nil }
+func (l *nopLogger) With(keysAndValues ...interface{}) logger.Logger { return l }
+
+// fakeProber devuelve el error configurado en cada sonda.
+type fakeProber struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *fakeProber) Ping(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *fakeProber) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// transitionRecorder guarda las transiciones notificadas por OnChange.
+type transitionRecorder struct {
+	mu  sync.Mutex
+	ups []bool
+}
+
+func (r *transitionRecorder) record(up bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ups = append(r.ups, up)
+}
+
+func (r *transitionRecorder) get() []bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]bool(nil), r.ups...)
+}
+
+func newTestMonitor(threshold int) (*Monitor, *transitionRecorder) {
+	m := NewMonitor(Config{Provider: "local-test", FailureThreshold: threshold}, &fakeProber{}, &nopLogger{})
+	rec := &transitionRecorder{}
+	m.OnChange(rec.record)
+	return m, rec
+}
+
+func TestMonitor_CaeTrasUmbralYVuelveConUnExito(t *testing.T) {
+	m, rec := newTestMonitor(3)
+	connRefused := errors.New("ollama request failed: connection refused")
+
+	m.Observe(connRefused)
+	m.Observe(connRefused)
+	if !m.Up() {
+		t.Fatal("no debe caer antes del umbral")
+	}
+	m.Observe(connRefused)
+	if m.Up() {
+		t.Fatal("3 fallos consecutivos deben marcar el provider caído")
+	}
+
+	m.Observe(nil)
+	if !m.Up() {
+		t.Fatal("un éxito debe marcar el provider disponible")
+	}
+	if got := rec.get(); fmt.Sprint(got) != "[false true]" {
+		t.Errorf("transiciones = %v, want [false true]", got)
+	}
+}
+
+func TestMonitor_ExitoReiniciaElConteo(t *testing.T) {
+	m, rec := newTestMonitor(2)
+	boom := errors.New("ollama returned status 500")
+
+	m.Observe(boom)
+	m.Observe(nil)
+	m.Observe(boom)
+
+	if !m.Up() || len(rec.get()) != 0 {
+		t.Error("fallos no consecutivos no deben tumbar el provider")
+	}
+}
+
+func TestMonitor_CalidadYCancelacionNoCuentan(t *testing.T) {
+	m, _ := newTestMonitor(1)
+
+	m.Observe(fmt.Errorf("respuesta sin JSON: %w", llm.ErrLLMQuality))
+	m.Observe(context.Canceled)
+
+	if !m.Up() {
+		t.Error("un error de calidad o una cancelación no indican provider caído")
+	}
+}
+
+func TestMonitor_SondaPausaYReanuda(t *testing.T) {
+	prober := &fakeProber{err: errors.New("dial tcp: connection refused")}
+	m := NewMonitor(Config{Provider: "local-test", FailureThreshold: 1, Interval: 5 * time.Millisecond}, prober, &nopLogger{})
+	rec := &transitionRecorder{}
+	m.OnChange(rec.record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	waitFor(t, func() bool { return !m.Up() }, "la sonda fallida debe marcar el provider caído")
+	if res := m.Check(ctx); res.Status != health.StatusDegraded {
+		t.Errorf("check con provider caído = %s, want degraded", res.Status)
+	}
+
+	prober.set(nil)
+	waitFor(t, m.Up, "la sonda exitosa debe marcar el provider disponible")
+	if res := m.Check(ctx); res.Status != health.StatusHealthy {
+		t.Errorf("check con provider disponible = %s, want healthy", res.Status)
+	}
+	if got := rec.get(); len(got) < 2 || got[0] || !got[1] {
+		t.Errorf("transiciones = %v, want [false true]", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatal(msg)
+}
+
+// failingProvider falla todas las llamadas con un error de transporte.
+type failingProvider struct{ llm.LLMProvider }
+
+func (failingProvider) Name() string { return "failing" }
+func (failingProvider) ReviewAnswer(context.Context, llm.ReviewRequest) (llm.ReviewResult, error) {
+	return llm.ReviewResult{}, errors.New("ollama request failed: connection refused")
+}
+
+func TestObservedProvider_AlimentaAlMonitor(t *testing.T) {
+	m, _ := newTestMonitor(2)
+	p := Observe(failingProvider{}, m)
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err == nil {
+			t.Fatal("el decorador no debe tragarse el error")
+		}
+	}
+
+	if m.Up() {
+		t.Error("las llamadas fallidas observadas deben tumbar el provider")
+	}
+	if p.Name() != "failing" {
+		t.Errorf("Name = %q, want el del provider decorado", p.Name())
+	}
+	if _, ok := p.(llm.RelevanceScorer); ok {
+		t.Error("no debe exponer ScoreRelevance si el provider decorado no la implementa")
+	}
+}
+
+func TestObservedProvider_DeadlineDelMensajeNoCuenta(t *testing.T) {
+	m, _ := newTestMonitor(1)
+	p := Observe(failingProvider{}, m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := p.ReviewAnswer(ctx, llm.ReviewRequest{}); err == nil {
+		t.Fatal("el decorador no debe tragarse el error")
+	}
+
+	if !m.Up() {
+		t.Error("una llamada cuyo mensaje venció no indica provider caído")
+	}
+}
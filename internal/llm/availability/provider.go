@@ -0,0 +1,101 @@
+package availability
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+// ObservedProvider decora un llm.LLMProvider reportando el resultado de cada
+// llamada al Monitor. No altera entradas, salidas ni errores.
+type ObservedProvider struct {
+	inner   llm.LLMProvider
+	monitor *Monitor
+}
+
+// Observe envuelve p para que sus llamadas alimenten a m. Expone ScoreRelevance
+// solo si p la implementa (ver llm.WithRelevance).
+func Observe(p llm.LLMProvider, m *Monitor) llm.LLMProvider {
+	observed := &ObservedProvider{inner: p, monitor: m}
+	return llm.WithRelevance(observed, p, observed.scoreRelevance)
+}
+
+// observe reporta err al monitor salvo que el contexto de la llamada ya haya vencido
+// o se haya cancelado: el deadline del mensaje no dice nada del backend (el timeout
+// propio del cliente HTTP sí llega como error de transporte).
+func (p *ObservedProvider) observe(ctx context.Context, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	p.monitor.Observe(err)
+}
+
+// Name satisface llm.LLMProvider (el del provider decorado).
+func (p *ObservedProvider) Name() string { return p.inner.Name() }
+
+// GenerateAssessment satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
+	out, err := p.inner.GenerateAssessment(ctx, material, params)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// ReviewAnswer satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	out, err := p.inner.ReviewAnswer(ctx, req)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// PrepareQuestion satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
+	out, err := p.inner.PrepareQuestion(ctx, req)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// JudgePairEquivalence satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
+	out, err := p.inner.JudgePairEquivalence(ctx, req)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// CheckCriterion satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	out, err := p.inner.CheckCriterion(ctx, req)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// ExtractIdeas satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
+	out, err := p.inner.ExtractIdeas(ctx, req)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// DigestChunk satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	out, err := p.inner.DigestChunk(ctx, in)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// ProposeCandidates satisface llm.LLMProvider observando el resultado.
+func (p *ObservedProvider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
+	out, err := p.inner.ProposeCandidates(ctx, in)
+	p.observe(ctx, err)
+	return out, err
+}
+
+// scoreRelevance observa la relevancia del provider decorado.
+func (p *ObservedProvider) scoreRelevance(scorer llm.RelevanceScorer) llm.RelevanceFunc {
+	return func(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
+		out, err := scorer.ScoreRelevance(ctx, req)
+		p.observe(ctx, err)
+		return out, err
+	}
+}
@@ -0,0 +1,224 @@
+package llm
+
+// budget.go — presupuesto de tokens del prompt contra la ventana de contexto del modelo.
+//
+// La pasada de relevancia ya lo sufrió en producción: >400 ideas agregadas desbordaron el
+// num_ctx de 4096 de gemma, Ollama descartó el principio del prompt (las reglas de salida),
+// todos los parseos fallaron y la selección cayó a 0. RelevanceMaxIdeas lo acotó para ese
+// carril; TokenBudget lo generaliza: estima los tokens de cada Build*Prompt, elige el
+// num_ctx de la request y, si ni el máximo alcanza, recorta de forma DETERMINISTA las
+// listas recortables (ideas, pistas del prep, resumen anterior) hasta que el prompt quepa.
+// Los recortes se devuelven como []Truncation para que el provider los reporte: un prompt
+// recortado es visible en logs y métricas, nunca un fallo de parseo silencioso.
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken es la estimación de caracteres por token. Los tokenizers de los modelos
+// locales rondan 4 caracteres por token en español; 3 deja margen para la puntuación,
+// los acentos y el JSON de las reglas de salida (sobrestimar solo cuesta contexto, quedarse
+// corto desborda).
+const charsPerToken = 3
+
+// EstimateTokens estima los tokens de text (conservador, ver charsPerToken).
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Defaults de TokenBudget.
+const (
+	defaultOutputReserve = 1024
+	defaultMinContext    = 4096
+)
+
+// TokenBudget es el presupuesto de contexto de un modelo. El valor cero (ContextWindow 0)
+// es "sin presupuesto": NumCtx devuelve 0 (el provider no fija num_ctx) y los Fit* no
+// recortan.
+type TokenBudget struct {
+	// ContextWindow es el num_ctx máximo que se pide al modelo (lo que el host soporta
+	// en memoria, no el máximo teórico del modelo).
+	ContextWindow int
+	// OutputReserve son los tokens reservados para la salida. Default 1024.
+	OutputReserve int
+	// MinContext es el piso del num_ctx por request. Default 4096, el contexto con el
+	// que ya corren los prompts chicos: solo los grandes piden más (y recargan).
+	MinContext int
+}
+
+// Truncation describe un recorte del presupuesto: Field es la lista recortada y
+// Kept/Dropped cuentan elementos (o palabras, en el resumen anterior).
+type Truncation struct {
+	Field   string
+	Kept    int
+	Dropped int
+}
+
+// Enabled indica si hay presupuesto.
+func (b TokenBudget) Enabled() bool { return b.ContextWindow > 0 }
+
+func (b TokenBudget) withDefaults() TokenBudget {
+	if b.OutputReserve <= 0 {
+		b.OutputReserve = defaultOutputReserve
+	}
+	if b.MinContext <= 0 {
+		b.MinContext = defaultMinContext
+	}
+	return b
+}
+
+// PromptLimit son los tokens disponibles para el prompt: la ventana menos la reserva de
+// salida. 0 sin presupuesto.
+func (b TokenBudget) PromptLimit() int {
+	if !b.Enabled() {
+		return 0
+	}
+	b = b.withDefaults()
+	return max(b.ContextWindow-b.OutputReserve, 0)
+}
+
+// Fits indica si prompt cabe en el presupuesto (siempre, sin presupuesto).
+func (b TokenBudget) Fits(prompt string) bool {
+	return !b.Enabled() || EstimateTokens(prompt) <= b.PromptLimit()
+}
+
+// NumCtx es el num_ctx para prompt: sus tokens más la reserva de salida, redondeado a
+// la siguiente potencia de dos entre MinContext y ContextWindow. Se redondea porque
+// Ollama recarga el modelo cada vez que cambia num_ctx: pocos valores distintos, pocas
+// recargas. 0 sin presupuesto.
+func (b TokenBudget) NumCtx(prompt string) int {
+	if !b.Enabled() {
+		return 0
+	}
+	b = b.withDefaults()
+	need := EstimateTokens(prompt) + b.OutputReserve
+	numCtx := b.MinContext
+	for numCtx < need && numCtx < b.ContextWindow {
+		numCtx *= 2
+	}
+	return min(numCtx, b.ContextWindow)
+}
+
+// trimmable es una lista recortable de un request: n elementos, keep(k) deja los k
+// primeros en el request.
+type trimmable struct {
+	field string
+	n     int
+	keep  func(k int)
+}
+
+// fit recorta fields EN ORDEN hasta que build() quepa: cada lista conserva su prefijo
+// más largo que cabe (búsqueda binaria; el prompt crece con k) y, si ni vaciándola
+// alcanza, se vacía y se pasa a la siguiente. Recortar por el final es determinista
+// (mismo request ⇒ mismo prompt) y respeta el orden de los callers, que ponen primero
+// lo importante (ideas fuente de la candidata, ideas principales). Si ni vaciando todas
+// las listas el prompt cabe, no recorta nada (el problema es otro: el trozo, la
+// pregunta) y devuelve nil.
+func (b TokenBudget) fit(build func() string, fields ...trimmable) []Truncation {
+	if b.Fits(build()) {
+		return nil
+	}
+	var cuts []Truncation
+	for _, f := range fields {
+		f.keep(0)
+		if !b.Fits(build()) {
+			if f.n > 0 {
+				cuts = append(cuts, Truncation{Field: f.field, Kept: 0, Dropped: f.n})
+			}
+			continue
+		}
+		lo, hi := 0, f.n // invariante: cabe con lo, no cabe con hi
+		for hi-lo > 1 {
+			mid := (lo + hi) / 2
+			f.keep(mid)
+			if b.Fits(build()) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		f.keep(lo)
+		return append(cuts, Truncation{Field: f.field, Kept: lo, Dropped: f.n - lo})
+	}
+	for _, f := range fields {
+		f.keep(f.n)
+	}
+	return nil
+}
+
+// listField es el trimmable de una lista de strings del request.
+func listField(field string, list *[]string) trimmable {
+	full := *list
+	return trimmable{field: field, n: len(full), keep: func(k int) { *list = full[:k] }}
+}
+
+// textField es el trimmable de un texto del request, por palabras (conserva el
+// principio). Vaciarlo deja el puntero en nil: el prompt omite la sección.
+func textField(field string, text **string) trimmable {
+	if *text == nil {
+		return trimmable{field: field, keep: func(int) {}}
+	}
+	words := strings.Fields(**text)
+	original := *text
+	return trimmable{field: field, n: len(words), keep: func(k int) {
+		switch {
+		case k == len(words):
+			*text = original
+		case k == 0:
+			*text = nil
+		default:
+			s := strings.Join(words[:k], " ") + " …"
+			*text = &s
+		}
+	}}
+}
+
+// FitRelevance recorta las ideas del job (MainIdeas) para que el prompt de relevancia
+// quepa.
+func (b TokenBudget) FitRelevance(req RelevanceRequest) (RelevanceRequest, []Truncation) {
+	cuts := b.fit(func() string { return BuildRelevancePrompt(req) },
+		listField("main_ideas", &req.MainIdeas))
+	return req, cuts
+}
+
+// FitCriterionCheck recorta las ideas extraídas del alumno (son una AYUDA: la
+// respuesta cruda sigue en el prompt).
+func (b TokenBudget) FitCriterionCheck(req CriterionCheckRequest) (CriterionCheckRequest, []Truncation) {
+	cuts := b.fit(func() string { return BuildCriterionCheckPrompt(req) },
+		listField("extracted_ideas", &req.ExtractedIdeas))
+	return req, cuts
+}
+
+// FitReview recorta las pistas del prep del prompt global de open_ended, de la menos a
+// la más necesaria: ideas secundarias, variantes válidas, ideas principales.
+func (b TokenBudget) FitReview(req ReviewRequest) (ReviewRequest, []Truncation) {
+	if req.Prep == nil {
+		return req, nil
+	}
+	prep := *req.Prep
+	req.Prep = &prep
+	cuts := b.fit(func() string { return BuildReviewPrompt(req) },
+		listField("prep.secondary_ideas", &prep.SecondaryIdeas),
+		listField("prep.valid_variants", &prep.ValidVariants),
+		listField("prep.main_ideas", &prep.MainIdeas))
+	return req, cuts
+}
+
+// FitDigestChunk recorta el resumen anterior para que el prompt de build (una de las
+// llamadas A: BuildDigestChunkPrompt, BuildDigestSummaryPrompt, BuildDigestIdeasPrompt)
+// quepa. El trozo nunca se recorta: su tamaño lo decide internal/chunking.
+func (b TokenBudget) FitDigestChunk(in DigestChunkInput, build func(DigestChunkInput) string) (DigestChunkInput, []Truncation) {
+	cuts := b.fit(func() string { return build(in) },
+		textField("prev_summary", &in.PrevSummary))
+	return in, cuts
+}
+
+// FitProposeCandidates recorta las ideas del trozo: primero las secundarias, después
+// las principales.
+func (b TokenBudget) FitProposeCandidates(in ProposeCandidatesInput) (ProposeCandidatesInput, []Truncation) {
+	cuts := b.fit(func() string { return BuildProposeCandidatesPrompt(in) },
+		listField("secondary_ideas", &in.Artifacts.SecondaryIdeas),
+		listField("main_ideas", &in.Artifacts.MainIdeas))
+	return in, cuts
+}
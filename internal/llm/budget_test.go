@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+func manyIdeas(n int) []string {
+	ideas := make([]string, n)
+	for i := range ideas {
+		ideas[i] = fmt.Sprintf("idea número %d del material, con algo de texto para ocupar contexto", i)
+	}
+	return ideas
+}
+
+func TestTokenBudget_NumCtx(t *testing.T) {
+	b := TokenBudget{ContextWindow: 16384}
+	if got := b.NumCtx("corto"); got != 4096 {
+		t.Errorf("un prompt chico debe quedar en el piso: %d", got)
+	}
+	if got := b.NumCtx(strings.Repeat("a", 3*5000)); got != 8192 {
+		t.Errorf("5000 tokens + reserva deben redondear a 8192: %d", got)
+	}
+	if got := b.NumCtx(strings.Repeat("a", 3*50000)); got != 16384 {
+		t.Errorf("num_ctx no debe pasar de ContextWindow: %d", got)
+	}
+	if got := (TokenBudget{}).NumCtx(strings.Repeat("a", 100000)); got != 0 {
+		t.Errorf("sin presupuesto num_ctx debe ser 0: %d", got)
+	}
+}
+
+// El caso de producción: >400 ideas agregadas en el prompt de relevancia con num_ctx 4096.
+func TestFitRelevance_RecortaIdeasDeterministicamente(t *testing.T) {
+	b := TokenBudget{ContextWindow: 4096}
+	req := RelevanceRequest{QuestionText: "¿Qué es la fotosíntesis?", MainIdeas: manyIdeas(450)}
+
+	fitted, cuts := b.FitRelevance(req)
+	if !b.Fits(BuildRelevancePrompt(fitted)) {
+		t.Fatal("el prompt recortado debe caber")
+	}
+	if len(cuts) != 1 || cuts[0].Field != "main_ideas" || cuts[0].Kept+cuts[0].Dropped != 450 || cuts[0].Kept == 0 {
+		t.Fatalf("recorte inesperado: %+v", cuts)
+	}
+	if len(fitted.MainIdeas) != cuts[0].Kept || fitted.MainIdeas[0] != req.MainIdeas[0] {
+		t.Errorf("debe conservar el prefijo de la lista: %d ideas", len(fitted.MainIdeas))
+	}
+	// Una idea más ya no cabe: el prefijo es el más largo posible.
+	fitted.MainIdeas = req.MainIdeas[:cuts[0].Kept+1]
+	if b.Fits(BuildRelevancePrompt(fitted)) {
+		t.Error("el recorte no es el mínimo")
+	}
+	if again, _ := b.FitRelevance(req); len(again.MainIdeas) != cuts[0].Kept {
+		t.Error("el recorte debe ser determinista")
+	}
+	if len(req.MainIdeas) != 450 {
+		t.Error("no debe mutar el request del caller")
+	}
+}
+
+func TestFit_SinRecorteSiCabeOSiNoAlcanza(t *testing.T) {
+	b := TokenBudget{ContextWindow: 4096}
+	if _, cuts := b.FitRelevance(RelevanceRequest{MainIdeas: manyIdeas(3)}); cuts != nil {
+		t.Errorf("un prompt que cabe no se recorta: %+v", cuts)
+	}
+	// El trozo no es recortable: si ni sin resumen anterior cabe, no se toca nada.
+	summary := "resumen anterior"
+	in := DigestChunkInput{ChunkText: strings.Repeat("texto ", 5000), PrevSummary: &summary}
+	fitted, cuts := b.FitDigestChunk(in, BuildDigestSummaryPrompt)
+	if cuts != nil || fitted.PrevSummary == nil || *fitted.PrevSummary != summary {
+		t.Errorf("no debe recortar si no alcanza: %+v", cuts)
+	}
+}
+
+func TestFitDigestChunk_RecortaResumenAnterior(t *testing.T) {
+	b := TokenBudget{ContextWindow: 4096}
+	summary := strings.Repeat("palabra ", 4000)
+	fitted, cuts := b.FitDigestChunk(DigestChunkInput{ChunkText: "trozo", PrevSummary: &summary}, BuildDigestIdeasPrompt)
+	if len(cuts) != 1 || cuts[0].Field != "prev_summary" || cuts[0].Kept == 0 {
+		t.Fatalf("recorte inesperado: %+v", cuts)
+	}
+	if !strings.HasSuffix(*fitted.PrevSummary, "…") || !b.Fits(BuildDigestIdeasPrompt(fitted)) {
+		t.Errorf("resumen recortado inesperado")
+	}
+}
+
+func TestFitProposeCandidates_SecundariasPrimero(t *testing.T) {
+	b := TokenBudget{ContextWindow: 4096}
+	in := ProposeCandidatesInput{Artifacts: materialpipeline.ChunkArtifactsV1{
+		MainIdeas:      manyIdeas(20),
+		SecondaryIdeas: manyIdeas(300),
+	}}
+	fitted, cuts := b.FitProposeCandidates(in)
+	if len(cuts) != 1 || cuts[0].Field != "secondary_ideas" {
+		t.Fatalf("deben recortarse solo las secundarias: %+v", cuts)
+	}
+	if len(fitted.Artifacts.MainIdeas) != 20 {
+		t.Errorf("las principales no deben tocarse: %d", len(fitted.Artifacts.MainIdeas))
+	}
+}
@@ -0,0 +1,252 @@
+// Package cache decora llm.LLMProvider y llm.Embedder con un cache de respuestas.
+// Las redeliveries vuelven a leer el mismo trozo, a juzgar los mismos pares del
+// dedupe y a puntuar la misma relevancia, y el harness repite prompts idénticos entre
+// corridas: con temperatura 0 (greedy) la respuesta es la misma, así que se sirve del
+// cache en vez de volver a pagar el modelo.
+//
+// La clave es (nombre del provider —que incluye el modelo—, operación, hash del
+// prompt, temperatura): un cambio de modelo o de prompt invalida solo. Las llamadas con
+// temperatura > 0 (el jitter del reintento por calidad) NUNCA pasan por el cache: buscan
+// justamente otra salida. Los errores no se cachean. Los juicios guardan también su
+// confianza: un hit no puede saltarse el umbral llm.review.min_confidence.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+// Config parametriza el decorador.
+type Config struct {
+	// Temperature es la temperatura por instancia del provider decorado: la de las
+	// llamadas sin override. Si es > 0 ninguna llamada se cachea.
+	Temperature float64
+	// Logger reporta los fallos de escritura del store. Opcional.
+	Logger logger.Logger
+	// Prompts es el registry del provider decorado: la clave se arma con el prompt que
+	// ese provider va a enviar, así cada versión del rollout tiene su propia entrada.
+	// nil = builtin.
+	Prompts *prompts.Registry
+}
+
+// CachedProvider decora un llm.LLMProvider sirviendo del Store las llamadas ya hechas.
+type CachedProvider struct {
+	inner llm.LLMProvider
+	store Store
+	cfg   Config
+}
+
+// NewProvider envuelve p con el cache de store. Expone ScoreRelevance solo si p la
+// implementa (ver llm.WithRelevance).
+func NewProvider(p llm.LLMProvider, store Store, cfg Config) llm.LLMProvider {
+	cachedProvider := &CachedProvider{inner: p, store: store, cfg: cfg}
+	return llm.WithRelevance(cachedProvider, p, cachedProvider.scoreRelevance)
+}
+
+// Name satisface llm.LLMProvider (el del provider decorado).
+func (p *CachedProvider) Name() string { return p.inner.Name() }
+
+func (p *CachedProvider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
+	return cached(p, "generate_assessment", p.cfg.Temperature, p.cfg.Prompts.Generation(ctx, material, params), func() (json.RawMessage, error) {
+		return p.inner.GenerateAssessment(ctx, material, params)
+	})
+}
+
+func (p *CachedProvider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	return cachedReview(p, "review_answer", p.temperature(req.Temperature), p.cfg.Prompts.Review(ctx, req), func() (llm.ReviewResult, error) {
+		return p.inner.ReviewAnswer(ctx, req)
+	})
+}
+
+func (p *CachedProvider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
+	return cached(p, "prepare_question", p.cfg.Temperature, p.cfg.Prompts.Prep(ctx, req), func() (json.RawMessage, error) {
+		return p.inner.PrepareQuestion(ctx, req)
+	})
+}
+
+func (p *CachedProvider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
+	return cachedReview(p, "judge_pair_equivalence", p.cfg.Temperature, p.cfg.Prompts.PairEquivalence(ctx, req), func() (llm.ReviewResult, error) {
+		return p.inner.JudgePairEquivalence(ctx, req)
+	})
+}
+
+func (p *CachedProvider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	return cachedReview(p, "check_criterion", p.temperature(req.Temperature), p.cfg.Prompts.CriterionCheck(ctx, req), func() (llm.ReviewResult, error) {
+		return p.inner.CheckCriterion(ctx, req)
+	})
+}
+
+func (p *CachedProvider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
+	return cached(p, "extract_ideas", p.cfg.Temperature, p.cfg.Prompts.ExtractIdeas(ctx, req), func() ([]string, error) {
+		return p.inner.ExtractIdeas(ctx, req)
+	})
+}
+
+// DigestChunk cachea la llamada A. El prompt de la clave son las dos mitades de la
+// forma partida (A1+A2), que es lo que mandan los providers locales.
+func (p *CachedProvider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	prompt := p.cfg.Prompts.DigestSummary(ctx, in) + p.cfg.Prompts.DigestIdeas(ctx, in)
+	return cached(p, "digest_chunk", p.temperature(in.Temperature), prompt, func() (*llm.DigestChunkResult, error) {
+		return p.inner.DigestChunk(ctx, in)
+	})
+}
+
+func (p *CachedProvider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
+	return cached(p, "propose_candidates", p.temperature(in.Temperature), p.cfg.Prompts.ProposeCandidates(ctx, in), func() ([]materialpipeline.CandidatePayloadV1, error) {
+		return p.inner.ProposeCandidates(ctx, in)
+	})
+}
+
+// scoreRelevance cachea la relevancia del provider decorado.
+func (p *CachedProvider) scoreRelevance(scorer llm.RelevanceScorer) llm.RelevanceFunc {
+	return func(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
+		return cached(p, "score_relevance", p.cfg.Temperature, p.cfg.Prompts.Relevance(ctx, req), func() (llm.RelevanceResult, error) {
+			return scorer.ScoreRelevance(ctx, req)
+		})
+	}
+}
+
+// temperature es la temperatura efectiva de una llamada con override opcional.
+func (p *CachedProvider) temperature(override *float64) float64 {
+	if override != nil {
+		return *override
+	}
+	return p.cfg.Temperature
+}
+
+// cached sirve op del store o, si no está, llama a call y guarda su resultado. Con
+// temperatura > 0 llama siempre (bypass). Una entrada que no deserializa cuenta como
+// miss y se sobrescribe.
+func cached[T any](p *CachedProvider, op string, temperature float64, prompt string, call func() (T, error)) (T, error) {
+	if temperature > 0 {
+		metrics.RecordLLMCacheRequest(op, "bypass")
+		return call()
+	}
+	key := cacheKey(p.inner.Name(), op, temperature, prompt)
+	if raw, ok := p.store.Get(key); ok {
+		var out T
+		if err := json.Unmarshal(raw, &out); err == nil {
+			metrics.RecordLLMCacheRequest(op, "hit")
+			return out, nil
+		}
+	}
+	metrics.RecordLLMCacheRequest(op, "miss")
+	out, err := call()
+	if err != nil {
+		return out, err
+	}
+	store(p.store, p.cfg.Logger, key, op, out)
+	return out, nil
+}
+
+// reviewEntry es la forma guardada de un juicio. ReviewResult no serializa Confidence
+// (no es salida del modelo), así que viaja aparte.
+type reviewEntry struct {
+	Result     llm.ReviewResult `json:"result"`
+	Confidence *float64         `json:"confidence,omitempty"`
+}
+
+// cachedReview es cached para los juicios (ReviewResult), conservando la confianza.
+func cachedReview(p *CachedProvider, op string, temperature float64, prompt string, call func() (llm.ReviewResult, error)) (llm.ReviewResult, error) {
+	entry, err := cached(p, op, temperature, prompt, func() (reviewEntry, error) {
+		res, err := call()
+		return reviewEntry{Result: res, Confidence: res.Confidence}, err
+	})
+	entry.Result.Confidence = entry.Confidence
+	return entry.Result, err
+}
+
+// store guarda value en key. Un fallo del store no falla la llamada: solo se pierde
+// el cacheo.
+func store(s Store, log logger.Logger, key, op string, value any) {
+	raw, err := json.Marshal(value)
+	if err == nil {
+		err = s.Set(key, raw)
+	}
+	if err != nil && log != nil {
+		log.Warn("no se pudo guardar la respuesta LLM en cache", "operation", op, "error", err.Error())
+	}
+}
+
+// keyVersion versiona la forma de las entradas: subirlo invalida las guardadas con
+// una forma anterior (p.ej. los juicios sin confianza) en vez de servirlas a medias.
+const keyVersion = "2"
+
+// cacheKey es el sha256 de (versión, provider, operación, temperatura, sha256 del prompt).
+func cacheKey(provider, op string, temperature float64, prompt string) string {
+	promptHash := sha256.Sum256([]byte(prompt))
+	h := sha256.New()
+	for _, part := range []string{keyVersion, provider, op, strconv.FormatFloat(temperature, 'g', -1, 64), hex.EncodeToString(promptHash[:])} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CachedEmbedder decora un llm.Embedder cacheando el vector de cada texto: un lote
+// con aciertos parciales solo manda al backend los textos que faltan.
+type CachedEmbedder struct {
+	inner llm.Embedder
+	store Store
+	name  string
+	log   logger.Logger
+}
+
+// NewEmbedder envuelve e con el cache de store. La clave usa el Name() del embedder
+// (incluye el modelo) si lo expone.
+func NewEmbedder(e llm.Embedder, store Store, log logger.Logger) *CachedEmbedder {
+	return &CachedEmbedder{inner: e, store: store, name: llm.EmbedderName(e), log: log}
+}
+
+// Name identifica al embedder decorado.
+func (e *CachedEmbedder) Name() string { return e.name }
+
+// Embed devuelve los vectores de texts en orden, del cache o del backend.
+func (e *CachedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	var missing []int
+	for i, text := range texts {
+		keys[i] = cacheKey(e.name, "embed", 0, text)
+		if raw, ok := e.store.Get(keys[i]); ok {
+			var vec []float32
+			if err := json.Unmarshal(raw, &vec); err == nil {
+				metrics.RecordLLMCacheRequest("embed", "hit")
+				vectors[i] = vec
+				continue
+			}
+		}
+		metrics.RecordLLMCacheRequest("embed", "miss")
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	batch := make([]string, len(missing))
+	for j, i := range missing {
+		batch[j] = texts[i]
+	}
+	fresh, err := e.inner.Embed(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if len(fresh) != len(batch) {
+		return nil, fmt.Errorf("embedder %s devolvió %d vectores para %d textos", e.name, len(fresh), len(batch))
+	}
+	for j, i := range missing {
+		vectors[i] = fresh[j]
+		store(e.store, e.log, keys[i], "embed", fresh[j])
+	}
+	return vectors, nil
+}
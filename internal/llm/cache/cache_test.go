@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// countingProvider cuenta las llamadas que llegan al provider decorado. Las
+// operaciones no sobrescritas entran en pánico (interfaz embebida nil).
+type countingProvider struct {
+	llm.LLMProvider
+	calls      int
+	err        error
+	confidence *float64
+}
+
+func (p *countingProvider) Name() string { return "fake:m" }
+
+func (p *countingProvider) ReviewAnswer(_ context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	p.calls++
+	if p.err != nil {
+		return llm.ReviewResult{}, p.err
+	}
+	return llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 1, Feedback: req.StudentAnswer, Confidence: p.confidence}, nil
+}
+
+func (p *countingProvider) DigestChunk(_ context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	p.calls++
+	return &llm.DigestChunkResult{Summary: in.ChunkText}, nil
+}
+
+func (p *countingProvider) ScoreRelevance(_ context.Context, _ llm.RelevanceRequest) (llm.RelevanceResult, error) {
+	p.calls++
+	return llm.RelevanceResult{Score: 0.5, Rationale: "peripheral"}, nil
+}
+
+func TestCachedProvider_SirveDelCacheElMismoPrompt(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewProvider(inner, NewMemoryStore(10, 0), Config{})
+	req := llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}
+
+	first, err := p.ReviewAnswer(context.Background(), req)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	second, err := p.ReviewAnswer(context.Background(), req)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if inner.calls != 1 || second != first {
+		t.Fatalf("la segunda llamada debía salir del cache: calls=%d %+v", inner.calls, second)
+	}
+
+	req.StudentAnswer = "otra"
+	if _, err := p.ReviewAnswer(context.Background(), req); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if inner.calls != 2 {
+		t.Fatalf("otro prompt no debe acertar: calls=%d", inner.calls)
+	}
+}
+
+// La confianza (logprobs) no es parte del JSON del veredicto pero sí de la entrada: un
+// hit la devuelve y el umbral min_confidence sigue aplicando.
+func TestCachedProvider_HitConservaLaConfianza(t *testing.T) {
+	confidence := 0.42
+	inner := &countingProvider{confidence: &confidence}
+	p := NewProvider(inner, NewMemoryStore(10, 0), Config{})
+	req := llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}
+
+	for range 2 {
+		res, err := p.ReviewAnswer(context.Background(), req)
+		if err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+		if res.Confidence == nil || *res.Confidence != confidence {
+			t.Fatalf("confianza %v, esperaba %v", res.Confidence, confidence)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("la segunda llamada debía salir del cache: calls=%d", inner.calls)
+	}
+}
+
+func TestCachedProvider_TemperaturaMayorACeroNoCachea(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewProvider(inner, NewMemoryStore(10, 0), Config{})
+	jitter := 0.7
+	in := llm.DigestChunkInput{ChunkText: "trozo", Temperature: &jitter}
+	for range 2 {
+		if _, err := p.DigestChunk(context.Background(), in); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("el reintento con jitter no debe pasar por el cache: calls=%d", inner.calls)
+	}
+
+	// Con la temperatura por instancia > 0 tampoco se cachea nada.
+	inner.calls = 0
+	p = NewProvider(inner, NewMemoryStore(10, 0), Config{Temperature: 0.3})
+	for range 2 {
+		if _, err := p.DigestChunk(context.Background(), llm.DigestChunkInput{ChunkText: "trozo"}); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("calls=%d, esperaba 2", inner.calls)
+	}
+}
+
+func TestCachedProvider_ErroresNoSeCachean(t *testing.T) {
+	inner := &countingProvider{err: errors.New("ollama caído")}
+	p := NewProvider(inner, NewMemoryStore(10, 0), Config{})
+	req := llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}
+	for range 2 {
+		if _, err := p.ReviewAnswer(context.Background(), req); err == nil {
+			t.Fatal("esperaba el error del provider")
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("un error no debe cachearse: calls=%d", inner.calls)
+	}
+}
+
+func TestCachedProvider_ReexponeScoreRelevance(t *testing.T) {
+	inner := &countingProvider{}
+	p, ok := NewProvider(inner, NewMemoryStore(10, 0), Config{}).(llm.RelevanceScorer)
+	if !ok {
+		t.Fatal("el provider decorado implementa ScoreRelevance: el cache debe exponerla")
+	}
+	req := llm.RelevanceRequest{QuestionText: "q", MainIdeas: []string{"idea"}}
+	for range 2 {
+		res, err := p.ScoreRelevance(context.Background(), req)
+		if err != nil || res.Score != 0.5 {
+			t.Fatalf("resultado inesperado: %+v %v", res, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("calls=%d, esperaba 1", inner.calls)
+	}
+}
+
+type countingEmbedder struct {
+	batches [][]string
+}
+
+func (e *countingEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.batches = append(e.batches, texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(len(text))}
+	}
+	return out, nil
+}
+
+func TestCachedEmbedder_SoloPideLosQueFaltan(t *testing.T) {
+	inner := &countingEmbedder{}
+	e := NewEmbedder(inner, NewMemoryStore(10, 0), nil)
+
+	if _, err := e.Embed(context.Background(), []string{"a", "bb"}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	vecs, err := e.Embed(context.Background(), []string{"bb", "ccc", "a"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(vecs) != 3 || vecs[0][0] != 2 || vecs[1][0] != 3 || vecs[2][0] != 1 {
+		t.Fatalf("vectores fuera de orden: %v", vecs)
+	}
+	if len(inner.batches) != 2 || len(inner.batches[1]) != 1 || inner.batches[1][0] != "ccc" {
+		t.Fatalf("solo el texto nuevo debía ir al backend: %v", inner.batches)
+	}
+}
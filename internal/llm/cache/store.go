@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store guarda respuestas serializadas por clave. Las entradas vencen a los ttl de
+// escritas; Get no devuelve entradas vencidas.
+type Store interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+}
+
+// MemoryStore es un Store LRU en memoria: acotado a maxEntries, desaloja la entrada
+// menos usada recientemente. Se pierde al reiniciar el proceso.
+type MemoryStore struct {
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // frente = más reciente
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore construye el store en memoria. maxEntries <= 0 usa 10000; ttl <= 0
+// no vence.
+func NewMemoryStore(maxEntries int, ttl time.Duration) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get devuelve la entrada de key si existe y no venció.
+func (s *MemoryStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && s.now().After(entry.expiresAt) {
+		s.order.Remove(el)
+		delete(s.entries, key)
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set guarda value en key, desalojando la menos reciente si el store está lleno.
+func (s *MemoryStore) Set(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expiresAt time.Time
+	if s.ttl > 0 {
+		expiresAt = s.now().Add(s.ttl)
+	}
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		s.order.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len devuelve las entradas guardadas (vencidas incluidas hasta que se lean).
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// DiskStore es un Store en un directorio: un archivo por clave, repartidos en
+// subdirectorios por los dos primeros caracteres. Sobrevive a reinicios y se
+// comparte entre corridas del harness. El vencimiento se mide por la fecha de
+// modificación del archivo.
+type DiskStore struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewDiskStore construye el store sobre dir (lo crea si no existe). ttl <= 0 no vence.
+func NewDiskStore(dir string, ttl time.Duration) (*DiskStore, error) {
+	if dir == "" {
+		return nil, errors.New("cache en disco sin directorio")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creando directorio de cache %s: %w", dir, err)
+	}
+	return &DiskStore{dir: dir, ttl: ttl, now: time.Now}, nil
+}
+
+func (s *DiskStore) path(key string) string {
+	shard := key
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return filepath.Join(s.dir, shard, key)
+}
+
+// Get devuelve la entrada de key si existe y no venció. Una entrada vencida se borra.
+func (s *DiskStore) Get(key string) ([]byte, bool) {
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if s.ttl > 0 && s.now().Sub(info.ModTime()) > s.ttl {
+		_ = os.Remove(path)
+		return nil, false
+	}
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set escribe value en key de forma atómica (archivo temporal + rename): un lector
+// concurrente ve la entrada entera o ninguna.
+func (s *DiskStore) Set(key string, value []byte) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creando shard de cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creando entrada de cache: %w", err)
+	}
+	if _, err := tmp.Write(value); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("escribiendo entrada de cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("cerrando entrada de cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("publicando entrada de cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_DesalojaLaMenosReciente(t *testing.T) {
+	s := NewMemoryStore(2, 0)
+	_ = s.Set("a", []byte("1"))
+	_ = s.Set("b", []byte("2"))
+	s.Get("a") // a pasa a ser la más reciente
+	_ = s.Set("c", []byte("3"))
+
+	if _, ok := s.Get("b"); ok {
+		t.Error("b era la menos reciente y debía desalojarse")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := s.Get(key); !ok {
+			t.Errorf("%s debía seguir en el store", key)
+		}
+	}
+	if s.Len() != 2 {
+		t.Errorf("Len=%d, esperaba 2", s.Len())
+	}
+}
+
+func TestMemoryStore_TTL(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore(10, time.Minute)
+	s.now = func() time.Time { return now }
+	_ = s.Set("a", []byte("1"))
+
+	s.now = func() time.Time { return now.Add(30 * time.Second) }
+	if _, ok := s.Get("a"); !ok {
+		t.Fatal("la entrada no debía vencer aún")
+	}
+	s.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("la entrada debía vencer")
+	}
+}
+
+func TestDiskStore_PersisteYVence(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("NewDiskStore falló: %v", err)
+	}
+	if err := s.Set("abcdef", []byte(`{"x":1}`)); err != nil {
+		t.Fatalf("Set falló: %v", err)
+	}
+
+	// Otra instancia sobre el mismo directorio (otra corrida) ve la entrada.
+	reopened, _ := NewDiskStore(dir, time.Hour)
+	value, ok := reopened.Get("abcdef")
+	if !ok || string(value) != `{"x":1}` {
+		t.Fatalf("entrada inesperada: %q %v", value, ok)
+	}
+
+	reopened.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, ok := reopened.Get("abcdef"); ok {
+		t.Fatal("la entrada debía vencer")
+	}
+	if _, err := os.Stat(reopened.path("abcdef")); !os.IsNotExist(err) {
+		t.Error("la entrada vencida debía borrarse")
+	}
+	if _, err := NewDiskStore("", time.Hour); err == nil {
+		t.Error("esperaba error sin directorio")
+	}
+}
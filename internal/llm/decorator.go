@@ -0,0 +1,30 @@
+package llm
+
+import "context"
+
+// RelevanceFunc adapta una función a RelevanceScorer.
+type RelevanceFunc func(ctx context.Context, req RelevanceRequest) (RelevanceResult, error)
+
+// ScoreRelevance satisface RelevanceScorer.
+func (f RelevanceFunc) ScoreRelevance(ctx context.Context, req RelevanceRequest) (RelevanceResult, error) {
+	return f(ctx, req)
+}
+
+// WithRelevance completa un decorador de inner. Si inner puntúa relevancia, devuelve
+// decorated exponiendo además ScoreRelevance a través de wrap (el decorador la mide,
+// la protege o la encola como al resto); si no, devuelve decorated tal cual, sin el
+// método. Así el assert de RelevanceScorer sobre un provider decorado dice la verdad:
+// bootstrap falla al arrancar en vez de en la primera candidata.
+func WithRelevance(decorated, inner LLMProvider, wrap func(RelevanceScorer) RelevanceFunc) LLMProvider {
+	scorer, ok := inner.(RelevanceScorer)
+	if !ok {
+		return decorated
+	}
+	return scoringProvider{LLMProvider: decorated, RelevanceScorer: wrap(scorer)}
+}
+
+// scoringProvider suma ScoreRelevance a un provider decorado.
+type scoringProvider struct {
+	LLMProvider
+	RelevanceScorer
+}
@@ -18,3 +18,18 @@ type Embedder interface {
 	// len(result) == len(texts); si el backend devuelve otra cantidad, es error.
 	Embed(ctx context.Context, texts []string) ([][]float32, error)
 }
+
+// Namer es el Name() opcional de un Embedder: identifica backend y modelo en la
+// clave del cache, los logs y las métricas.
+type Namer interface {
+	Name() string
+}
+
+// EmbedderName es el Name() de e si lo expone, o "embedder". Los decoradores lo
+// reexponen con él.
+func EmbedderName(e Embedder) string {
+	if n, ok := e.(Namer); ok {
+		return n.Name()
+	}
+	return "embedder"
+}
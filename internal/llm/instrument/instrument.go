@@ -0,0 +1,169 @@
+// Package instrument decora llm.LLMProvider y llm.Embedder midiendo cada llamada:
+// latencia, tokens de prompt y de salida (los que reporta el backend vía
+// llm.ReportUsage) y el resultado, distinguiendo los fallos de calidad del modelo
+// (llm.ErrLLMQuality: respondió pero mal) de los de infraestructura (red, timeout,
+// status HTTP). Las series llevan provider, modelo y operación. Además cuenta cada
+// llamada por prompt y versión (las que el provider reporta con llm.ReportPrompt), para
+// comparar el resultado de una versión en rollout contra la por defecto.
+//
+// Va pegado al provider real (por dentro del cache y del monitor): mide llamadas al
+// backend, no aciertos de cache. No altera entradas, salidas ni errores.
+package instrument
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+// Resultados de una llamada (label status).
+const (
+	StatusSuccess      = "success"
+	StatusQualityError = "quality_error"
+	StatusInfraError   = "infra_error"
+)
+
+// Labels identifica las series del provider decorado.
+type Labels struct {
+	// Provider es el backend (ollama, openai, anthropic, gemini).
+	Provider string
+	// Model es el modelo tal como se configuró.
+	Model string
+}
+
+// InstrumentedProvider decora un llm.LLMProvider registrando métricas de cada llamada.
+type InstrumentedProvider struct {
+	inner  llm.LLMProvider
+	labels Labels
+}
+
+// NewProvider envuelve p para medir sus llamadas con labels. Expone ScoreRelevance
+// solo si p la implementa (ver llm.WithRelevance).
+func NewProvider(p llm.LLMProvider, labels Labels) llm.LLMProvider {
+	measured := &InstrumentedProvider{inner: p, labels: labels}
+	return llm.WithRelevance(measured, p, measured.scoreRelevance)
+}
+
+// Name satisface llm.LLMProvider (el del provider decorado).
+func (p *InstrumentedProvider) Name() string { return p.inner.Name() }
+
+func (p *InstrumentedProvider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
+	return measure(ctx, p.labels, "generate_assessment", func(ctx context.Context) (json.RawMessage, error) {
+		return p.inner.GenerateAssessment(ctx, material, params)
+	})
+}
+
+func (p *InstrumentedProvider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	return measure(ctx, p.labels, "review_answer", func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.ReviewAnswer(ctx, req)
+	})
+}
+
+func (p *InstrumentedProvider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
+	return measure(ctx, p.labels, "prepare_question", func(ctx context.Context) (json.RawMessage, error) {
+		return p.inner.PrepareQuestion(ctx, req)
+	})
+}
+
+func (p *InstrumentedProvider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
+	return measure(ctx, p.labels, "judge_pair_equivalence", func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.JudgePairEquivalence(ctx, req)
+	})
+}
+
+func (p *InstrumentedProvider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	return measure(ctx, p.labels, "check_criterion", func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.CheckCriterion(ctx, req)
+	})
+}
+
+func (p *InstrumentedProvider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
+	return measure(ctx, p.labels, "extract_ideas", func(ctx context.Context) ([]string, error) {
+		return p.inner.ExtractIdeas(ctx, req)
+	})
+}
+
+// DigestChunk mide la llamada A entera: en los providers locales son dos requests
+// (A1+A2) y sus tokens se suman.
+func (p *InstrumentedProvider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	return measure(ctx, p.labels, "digest_chunk", func(ctx context.Context) (*llm.DigestChunkResult, error) {
+		return p.inner.DigestChunk(ctx, in)
+	})
+}
+
+func (p *InstrumentedProvider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
+	return measure(ctx, p.labels, "propose_candidates", func(ctx context.Context) ([]materialpipeline.CandidatePayloadV1, error) {
+		return p.inner.ProposeCandidates(ctx, in)
+	})
+}
+
+// scoreRelevance mide la relevancia del provider decorado.
+func (p *InstrumentedProvider) scoreRelevance(scorer llm.RelevanceScorer) llm.RelevanceFunc {
+	return func(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
+		return measure(ctx, p.labels, "score_relevance", func(ctx context.Context) (llm.RelevanceResult, error) {
+			return scorer.ScoreRelevance(ctx, req)
+		})
+	}
+}
+
+// InstrumentedEmbedder decora un llm.Embedder registrando métricas de cada lote
+// (operación "embed").
+type InstrumentedEmbedder struct {
+	inner  llm.Embedder
+	labels Labels
+}
+
+// NewEmbedder envuelve e para medir sus llamadas con labels.
+func NewEmbedder(e llm.Embedder, labels Labels) *InstrumentedEmbedder {
+	return &InstrumentedEmbedder{inner: e, labels: labels}
+}
+
+// Name es el del embedder decorado, si lo expone.
+func (e *InstrumentedEmbedder) Name() string {
+	return llm.EmbedderName(e.inner)
+}
+
+// Embed satisface llm.Embedder. Un lote vacío no llega al backend y no se mide.
+func (e *InstrumentedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return e.inner.Embed(ctx, texts)
+	}
+	return measure(ctx, e.labels, "embed", func(ctx context.Context) ([][]float32, error) {
+		return e.inner.Embed(ctx, texts)
+	})
+}
+
+// measure ejecuta call con un acumulador de tokens en el contexto y registra su
+// latencia, resultado, tokens y versiones de prompt.
+func measure[T any](ctx context.Context, labels Labels, op string, call func(ctx context.Context) (T, error)) (T, error) {
+	ctx, usage := llm.WithUsage(ctx)
+	start := time.Now()
+	out, err := call(ctx)
+	status := Status(err)
+	metrics.RecordLLMRequest(labels.Provider, labels.Model, op, status, time.Since(start).Seconds())
+	for prompt, version := range usage.Prompts() {
+		metrics.RecordLLMPromptRequest(op, prompt, version, status)
+	}
+	promptTokens, completionTokens := usage.Tokens()
+	metrics.RecordLLMTokens(labels.Provider, labels.Model, op, promptTokens, completionTokens)
+	return out, err
+}
+
+// Status clasifica el resultado de una llamada: los fallos de calidad (el modelo
+// respondió algo inutilizable) se separan de los de infraestructura, que son los que
+// indican un backend caído o saturado.
+func Status(err error) string {
+	switch {
+	case err == nil:
+		return StatusSuccess
+	case errors.Is(err, llm.ErrLLMQuality):
+		return StatusQualityError
+	default:
+		return StatusInfraError
+	}
+}
@@ -0,0 +1,170 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeProvider reporta tokens como lo hacen los backends (dos requests en el digest).
+// Las operaciones no sobrescritas entran en pánico (interfaz embebida nil).
+type fakeProvider struct {
+	llm.LLMProvider
+	err error
+}
+
+func (p *fakeProvider) Name() string { return "fake:m" }
+
+func (p *fakeProvider) ReviewAnswer(ctx context.Context, _ llm.ReviewRequest) (llm.ReviewResult, error) {
+	llm.ReportUsage(ctx, 100, 20)
+	if p.err != nil {
+		return llm.ReviewResult{}, p.err
+	}
+	return llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 1}, nil
+}
+
+func (p *fakeProvider) DigestChunk(ctx context.Context, _ llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	llm.ReportPrompt(ctx, "digest_summary", "v2")
+	llm.ReportUsage(ctx, 300, 50)
+	llm.ReportPrompt(ctx, "digest_ideas", "builtin")
+	llm.ReportUsage(ctx, 280, 40)
+	return &llm.DigestChunkResult{Summary: "s"}, nil
+}
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Name() string { return "fake-embed:e" }
+
+func (fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	llm.ReportUsage(ctx, 7*len(texts), 0)
+	return make([][]float32, len(texts)), nil
+}
+
+func counter(t *testing.T, vec *prometheus.CounterVec, labels ...string) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := vec.WithLabelValues(labels...).Write(m); err != nil {
+		t.Fatalf("leyendo counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func histogramCount(t *testing.T, vec *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := vec.WithLabelValues(labels...).(prometheus.Histogram).Write(m); err != nil {
+		t.Fatalf("leyendo histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentedProvider_RegistraLatenciaTokensYResultado(t *testing.T) {
+	labels := Labels{Provider: "test-ok", Model: "m"}
+	p := NewProvider(&fakeProvider{}, labels)
+
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := p.DigestChunk(context.Background(), llm.DigestChunkInput{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	if got := counter(t, metrics.LLMRequestsTotal, "test-ok", "m", "review_answer", StatusSuccess); got != 1 {
+		t.Errorf("llamadas review_answer=%v, esperaba 1", got)
+	}
+	if got := histogramCount(t, metrics.LLMRequestDuration, "test-ok", "m", "review_answer"); got != 1 {
+		t.Errorf("observaciones de latencia=%d, esperaba 1", got)
+	}
+	if got := counter(t, metrics.LLMTokensTotal, "test-ok", "m", "review_answer", "prompt"); got != 100 {
+		t.Errorf("tokens de prompt=%v, esperaba 100", got)
+	}
+	// El digest suma las dos requests de la operación.
+	if got := counter(t, metrics.LLMTokensTotal, "test-ok", "m", "digest_chunk", "prompt"); got != 580 {
+		t.Errorf("tokens de prompt del digest=%v, esperaba 580", got)
+	}
+	if got := counter(t, metrics.LLMTokensTotal, "test-ok", "m", "digest_chunk", "completion"); got != 90 {
+		t.Errorf("tokens de salida del digest=%v, esperaba 90", got)
+	}
+	// Cada prompt de la operación cuenta con la versión que recibió.
+	if got := counter(t, metrics.LLMPromptRequestsTotal, "digest_chunk", "digest_summary", "v2", StatusSuccess); got < 1 {
+		t.Errorf("llamadas digest_summary v2=%v, esperaba al menos 1", got)
+	}
+	if got := counter(t, metrics.LLMPromptRequestsTotal, "digest_chunk", "digest_ideas", "builtin", StatusSuccess); got < 1 {
+		t.Errorf("llamadas digest_ideas builtin=%v, esperaba al menos 1", got)
+	}
+	if p.Name() != "fake:m" {
+		t.Errorf("Name debe ser el del provider decorado: %s", p.Name())
+	}
+}
+
+func TestInstrumentedProvider_ClasificaErrores(t *testing.T) {
+	inner := &fakeProvider{}
+	p := NewProvider(inner, Labels{Provider: "test-err", Model: "m"})
+
+	inner.err = fmt.Errorf("%w: verdict inválido", llm.ErrLLMQuality)
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); !errors.Is(err, llm.ErrLLMQuality) {
+		t.Fatalf("el error debe pasar sin cambios: %v", err)
+	}
+	inner.err = errors.New("connection refused")
+	_, _ = p.ReviewAnswer(context.Background(), llm.ReviewRequest{})
+
+	if got := counter(t, metrics.LLMRequestsTotal, "test-err", "m", "review_answer", StatusQualityError); got != 1 {
+		t.Errorf("fallos de calidad=%v, esperaba 1", got)
+	}
+	if got := counter(t, metrics.LLMRequestsTotal, "test-err", "m", "review_answer", StatusInfraError); got != 1 {
+		t.Errorf("fallos de infraestructura=%v, esperaba 1", got)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"éxito", nil, StatusSuccess},
+		{"sin JSON", fmt.Errorf("%w: no se encontró objeto JSON en la respuesta del modelo", llm.ErrLLMQuality), StatusQualityError},
+		{"corrección no parseable", fmt.Errorf("%w: respuesta de corrección no parseable: json: cannot unmarshal", llm.ErrLLMQuality), StatusQualityError},
+		{"relevancia fuera de contrato", fmt.Errorf("%w: respuesta de relevancia no parseable", llm.ErrLLMQuality), StatusQualityError},
+		{"transporte", errors.New("ollama request failed: connection refused"), StatusInfraError},
+		{"timeout", context.DeadlineExceeded, StatusInfraError},
+	}
+	for _, tc := range cases {
+		if got := Status(tc.err); got != tc.want {
+			t.Errorf("%s: Status=%s, esperaba %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestInstrumentedProvider_ScoreRelevanceNoSoportado(t *testing.T) {
+	p := NewProvider(&fakeProvider{}, Labels{Provider: "test-rel", Model: "m"})
+	if _, ok := p.(llm.RelevanceScorer); ok {
+		t.Fatal("el provider decorado no implementa ScoreRelevance: no debe exponerla")
+	}
+}
+
+func TestInstrumentedEmbedder(t *testing.T) {
+	e := NewEmbedder(fakeEmbedder{}, Labels{Provider: "test-embed", Model: "e"})
+	if _, err := e.Embed(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := e.Embed(context.Background(), nil); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	if got := counter(t, metrics.LLMRequestsTotal, "test-embed", "e", "embed", StatusSuccess); got != 1 {
+		t.Errorf("lotes medidos=%v, esperaba 1 (el vacío no se mide)", got)
+	}
+	if got := counter(t, metrics.LLMTokensTotal, "test-embed", "e", "embed", "prompt"); got != 14 {
+		t.Errorf("tokens de prompt=%v, esperaba 14", got)
+	}
+	if e.Name() != "fake-embed:e" {
+		t.Errorf("Name debe ser el del embedder decorado: %s", e.Name())
+	}
+}
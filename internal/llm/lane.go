@@ -0,0 +1,46 @@
+package llm
+
+import "context"
+
+type laneKey struct{}
+
+// WithLane marca ctx con el carril que origina las llamadas LLM (review, prep,
+// material). El scheduler LLM lo usa para elegir la clase de prioridad de la llamada.
+func WithLane(ctx context.Context, lane string) context.Context {
+	return context.WithValue(ctx, laneKey{}, lane)
+}
+
+// LaneFrom devuelve el carril de ctx ("" si no está marcado).
+func LaneFrom(ctx context.Context) string {
+	lane, _ := ctx.Value(laneKey{}).(string)
+	return lane
+}
+
+type affinityKey struct{}
+
+// WithAffinity marca ctx con una clave de afinidad (el id del job de materiales): el
+// pool de hosts Ollama manda todas sus llamadas al mismo host mientras esté sano, que
+// ya tiene el modelo cargado.
+func WithAffinity(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+// AffinityFrom devuelve la clave de afinidad de ctx ("" si no hay).
+func AffinityFrom(ctx context.Context) string {
+	key, _ := ctx.Value(affinityKey{}).(string)
+	return key
+}
+
+type schoolKey struct{}
+
+// WithSchool marca ctx con la escuela que origina las llamadas LLM. El registry de
+// prompts la usa para elegir la versión de cada prompt según su rollout.
+func WithSchool(ctx context.Context, schoolID string) context.Context {
+	return context.WithValue(ctx, schoolKey{}, schoolID)
+}
+
+// SchoolFrom devuelve la escuela de ctx ("" si no está marcada).
+func SchoolFrom(ctx context.Context) string {
+	schoolID, _ := ctx.Value(schoolKey{}).(string)
+	return schoolID
+}
@@ -0,0 +1,75 @@
+package llm
+
+// logprobs.go — confianza del veredicto a partir de los logprobs de la salida.
+//
+// Los backends locales (Ollama, vLLM) pueden devolver el logprob de cada token generado.
+// En una salida de ReviewSchema el juicio se decide en los tokens del valor de
+// "verdict": la probabilidad conjunta de esos tokens es cuán seguro estuvo el modelo del
+// veredicto que emitió. El resto del objeto (score, feedback) no entra: un feedback
+// largo y poco probable no hace dudoso un veredicto firme.
+
+import (
+	"math"
+	"strings"
+)
+
+// TokenLogprob es un token generado con su logprob (log natural).
+type TokenLogprob struct {
+	Token   string
+	Logprob float64
+}
+
+// VerdictConfidence devuelve exp(Σ logprob) de los tokens que cubren el valor de
+// "verdict" en la salida reconstruida a partir de tokens. nil si la salida no trae
+// tokens o no contiene un verdict reconocible (el caller sigue sin señal).
+func VerdictConfidence(tokens []TokenLogprob) *float64 {
+	if len(tokens) == 0 {
+		return nil
+	}
+	var text strings.Builder
+	starts := make([]int, len(tokens))
+	for i, t := range tokens {
+		starts[i] = text.Len()
+		text.WriteString(t.Token)
+	}
+	start, end, ok := verdictSpan(text.String())
+	if !ok {
+		return nil
+	}
+	var sum float64
+	covered := false
+	for i, t := range tokens {
+		tokStart, tokEnd := starts[i], starts[i]+len(t.Token)
+		if tokEnd > start && tokStart < end {
+			sum += t.Logprob
+			covered = true
+		}
+	}
+	if !covered {
+		return nil
+	}
+	confidence := math.Min(math.Exp(sum), 1)
+	return &confidence
+}
+
+// verdictSpan ubica el valor (sin comillas) de la clave "verdict" en out.
+func verdictSpan(out string) (start, end int, ok bool) {
+	key := strings.Index(out, `"verdict"`)
+	if key < 0 {
+		return 0, 0, false
+	}
+	rest := strings.TrimLeft(out[key+len(`"verdict"`):], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return 0, 0, false
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return 0, 0, false
+	}
+	start = len(out) - len(rest) + 1
+	n := strings.IndexByte(out[start:], '"')
+	if n <= 0 {
+		return 0, 0, false
+	}
+	return start, start + n, true
+}
@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVerdictConfidence(t *testing.T) {
+	tokens := []TokenLogprob{
+		{`{"`, 0}, {`verdict`, 0}, {`":`, 0}, {` "`, 0},
+		{`part`, math.Log(0.6)}, {`ial`, math.Log(0.9)},
+		{`", "score": 0.5, "feedback": "`, 0},
+		{`poco probable`, math.Log(0.01)}, {`"}`, 0},
+	}
+	got := VerdictConfidence(tokens)
+	if got == nil {
+		t.Fatal("esperaba confianza")
+	}
+	// Solo cuentan los tokens del valor de verdict: 0.6 × 0.9.
+	if math.Abs(*got-0.54) > 1e-9 {
+		t.Errorf("confianza %v, esperaba 0.54", *got)
+	}
+
+	for name, toks := range map[string][]TokenLogprob{
+		"sin tokens":  nil,
+		"sin verdict": {{`{"score": 1}`, 0}},
+		"incompleto":  {{`{"verdict": "corr`, -0.1}},
+	} {
+		if got := VerdictConfidence(toks); got != nil {
+			t.Errorf("%s: esperaba nil, obtuve %v", name, *got)
+		}
+	}
+}
+
+func TestReviewSchema_SinConfidence(t *testing.T) {
+	// Confidence la calcula el worker, no la emite el modelo.
+	props := decodeSchema(t, ReviewSchema)["properties"].(map[string]any)
+	if _, ok := props["confidence"]; ok {
+		t.Errorf("confidence no debe estar en el schema: %v", props)
+	}
+	if _, ok := props["Confidence"]; ok {
+		t.Errorf("Confidence no debe estar en el schema: %v", props)
+	}
+}
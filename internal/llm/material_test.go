@@ -65,6 +65,17 @@ func TestBuildProposeCandidatesPrompt_ContainsIdeasAndRules(t *testing.T) {
 	}
 }
 
+func TestBuildProposeCandidatesPrompt_DeicticosEnElIdioma(t *testing.T) {
+	in := ProposeCandidatesInput{
+		Artifacts: materialpipeline.ChunkArtifactsV1{Version: 1, MainIdeas: []string{"Plants need light"}},
+		Language:  "en",
+	}
+	p := BuildProposeCandidatesPrompt(in)
+	if !strings.Contains(p, "according to the text") || strings.Contains(p, "según el texto") {
+		t.Error("los ejemplos prohibidos deben ir en el idioma del contenido")
+	}
+}
+
 func TestParseDigestResult_SeparaSummaryYValida(t *testing.T) {
 	raw := []byte(`{
 		"version": 1,
@@ -1,33 +1,36 @@
 package ollama
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
 )
 
 // EmbedConfig configura el cliente de embeddings Ollama. Se inyecta desde
 // bootstrap/config; el cliente NUNCA lee env directo (D-039.3). No hay temperatura:
 // embeder es determinista por definición (no muestrea).
 type EmbedConfig struct {
-	// BaseURL del servidor Ollama (ej. http://localhost:11434).
+	// BaseURL del servidor Ollama (ej. http://localhost:11434). Se ignora si hay Pool.
 	BaseURL string
+	// Pool reparte los lotes entre varios hosts Ollama. nil = solo BaseURL.
+	Pool *Pool
 	// Model de embeddings a usar (ej. "nomic-embed-text"). El harness (plan 044 F1b)
 	// decide el modelo final midiendo pares duplicados/no-duplicados reales.
 	Model string
 	// Timeout de la request HTTP. Embeder un lote chico es rápido: default 60s.
 	Timeout time.Duration
+	// Transport de las requests (ver Config.Transport).
+	Transport http.RoundTripper
 }
 
 // EmbedProvider es la implementación Ollama de llm.Embedder. Pega a POST
-// {baseURL}/api/embed con model + input batch (contrato Ollama 0.31.x).
+// {host}/api/embed con model + input batch (contrato Ollama 0.31.x).
 type EmbedProvider struct {
-	baseURL    string
+	pool       *Pool
 	model      string
 	httpClient *http.Client
 }
@@ -38,10 +41,14 @@ func NewEmbedder(cfg EmbedConfig) *EmbedProvider {
 	if timeout <= 0 {
 		timeout = 60 * time.Second
 	}
+	pool := cfg.Pool
+	if pool == nil {
+		pool = singleHostPool(cfg.BaseURL)
+	}
 	return &EmbedProvider{
-		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		pool:       pool,
 		model:      cfg.Model,
-		httpClient: &http.Client{Timeout: timeout},
+		httpClient: &http.Client{Timeout: timeout, Transport: cfg.Transport},
 	}
 }
 
@@ -56,7 +63,8 @@ type embedRequest struct {
 
 // embedResponse es la respuesta: un vector por texto de entrada, mismo orden.
 type embedResponse struct {
-	Embeddings [][]float32 `json:"embeddings"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
 }
 
 // Embed vectoriza un lote de textos contra POST /api/embed. Devuelve un vector por
@@ -73,31 +81,21 @@ func (p *EmbedProvider) Embed(ctx context.Context, texts []string) ([][]float32,
 		return nil, fmt.Errorf("marshaling ollama embed request: %w", err)
 	}
 
-	url := p.baseURL + "/api/embed"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("creating ollama embed request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("ollama embed request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading ollama embed response: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("ollama embed returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
-	}
-
 	var er embedResponse
-	if err := json.Unmarshal(body, &er); err != nil {
-		return nil, fmt.Errorf("parsing ollama embed response: %w", err)
+	err = p.pool.do(ctx, func(baseURL string) error {
+		body, err := postJSON(ctx, p.httpClient, baseURL+"/api/embed", bodyBytes)
+		if err != nil {
+			return fmt.Errorf("ollama embed: %w", err)
+		}
+		if err := json.Unmarshal(body, &er); err != nil {
+			return fmt.Errorf("parsing ollama embed response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	llm.ReportUsage(ctx, er.PromptEvalCount, 0)
 	if len(er.Embeddings) != len(texts) {
 		return nil, fmt.Errorf("ollama embed returned %d vectors for %d texts", len(er.Embeddings), len(texts))
 	}
@@ -13,19 +13,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
 
 // Config configura el provider Ollama. Se inyecta desde bootstrap/config; el
 // provider NUNCA lee env directo (D-039.3).
 type Config struct {
-	// BaseURL del servidor Ollama (ej. http://localhost:11434).
+	// BaseURL del servidor Ollama (ej. http://localhost:11434). Se ignora si hay Pool.
 	BaseURL string
+	// Pool reparte las requests entre varios hosts Ollama. nil = solo BaseURL.
+	Pool *Pool
 	// Model a usar (ej. "llama3.1", "qwen2.5:7b").
 	Model string
 	// Timeout de la request HTTP. Generar puede ser lento en CPU: default 120s.
 	Timeout time.Duration
+	// Transport de las requests HTTP (nil = http.DefaultTransport). Los tests y el
+	// harness inyectan un cassette.Recorder para grabar/reproducir el tráfico.
+	Transport http.RoundTripper
 	// Temperature del muestreo. TODAS las llamadas del worker piden JSON
 	// estructurado (extracción/clasificación/juicio binario), no prosa creativa:
 	// el default 0 = greedy determinista hace la corrección REPRODUCIBLE (mismo
@@ -33,14 +41,32 @@ type Config struct {
 	// parpadea entre corridas (medido en 045: criterios open_ended que oscilaban
 	// correct/incorrect sin cambiar el input).
 	Temperature float64
+	// Budget es el presupuesto de contexto: fija num_ctx por request y recorta las
+	// listas de los prompts que no caben (llm.TokenBudget). Cero = sin num_ctx (el
+	// default del servidor) y sin recortes.
+	Budget llm.TokenBudget
+	// Logger reporta los recortes del presupuesto. Opcional.
+	Logger logger.Logger
+	// Prompts resuelve la versión de cada prompt (rollout por escuela). nil = los
+	// builders de internal/llm.
+	Prompts *prompts.Registry
+	// Logprobs pide los logprobs de la salida en los juicios (ReviewAnswer,
+	// CheckCriterion, JudgePairEquivalence) para calcular la confianza del veredicto.
+	// Requiere un Ollama que los soporte; uno viejo ignora el campo y el juicio queda
+	// sin confianza.
+	Logprobs bool
 }
 
 // Provider es la implementación Ollama de llm.LLMProvider.
 type Provider struct {
-	baseURL     string
+	pool        *Pool
 	model       string
 	temperature float64
+	budget      llm.TokenBudget
+	prompts     *prompts.Registry
+	logger      logger.Logger
 	httpClient  *http.Client
+	logprobs    bool
 }
 
 // New construye el provider Ollama a partir de su config.
@@ -49,24 +75,34 @@ func New(cfg Config) *Provider {
 	if timeout <= 0 {
 		timeout = 120 * time.Second
 	}
+	pool := cfg.Pool
+	if pool == nil {
+		pool = singleHostPool(cfg.BaseURL)
+	}
 	return &Provider{
-		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		pool:        pool,
 		model:       cfg.Model,
 		temperature: cfg.Temperature,
-		httpClient:  &http.Client{Timeout: timeout},
+		budget:      cfg.Budget,
+		prompts:     cfg.Prompts,
+		logger:      cfg.Logger,
+		httpClient:  &http.Client{Timeout: timeout, Transport: cfg.Transport},
+		logprobs:    cfg.Logprobs,
 	}
 }
 
 // Name identifica al provider.
 func (p *Provider) Name() string { return "ollama:" + p.model }
 
-// generateRequest es el body de POST /api/generate. format:"json" fuerza a
-// Ollama a emitir JSON válido (reduce alucinaciones de formato).
+// generateRequest es el body de POST /api/generate. format fuerza a Ollama a emitir
+// JSON válido: "json" a secas o, si la operación tiene contrato, su JSON Schema
+// (decodificación restringida: el objeto sale con las claves y los enums del
+// contrato, no `{}` ni claves sueltas).
 type generateRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
-	Format string `json:"format,omitempty"`
+	Model  string          `json:"model"`
+	Prompt string          `json:"prompt"`
+	Stream bool            `json:"stream"`
+	Format json.RawMessage `json:"format,omitempty"`
 	// Think desactiva el "thinking" de los modelos que lo soportan (qwen3, etc.).
 	// Con format:"json" el razonamiento se corta y deja el objeto vacío `{}`
 	// (verdict/score/feedback en cero): una review IA engañosa. Forzar think:false
@@ -77,96 +113,135 @@ type generateRequest struct {
 	// Options son las opciones de muestreo de Ollama (temperature, etc.). Se omite
 	// si es nil para no alterar el comportamiento cuando no se configura nada.
 	Options *generateOptions `json:"options,omitempty"`
+	// Logprobs pide el logprob de cada token generado (solo en los juicios).
+	Logprobs bool `json:"logprobs,omitempty"`
 }
 
-// generateOptions son las opciones de muestreo de Ollama que el worker fija.
-// Hoy solo temperature (determinismo); ampliable (top_p, seed) sin tocar callers.
+// generateOptions son las opciones de Ollama que el worker fija: temperature
+// (determinismo) y num_ctx (ventana de contexto elegida por el presupuesto; se omite
+// sin presupuesto). Ampliable (top_p, seed) sin tocar callers.
 type generateOptions struct {
 	Temperature float64 `json:"temperature"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
 }
 
 // generateResponse es la respuesta (con stream:false, un solo objeto).
+// PromptEvalCount y EvalCount son los tokens de prompt y de salida.
 type generateResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+	Response        string         `json:"response"`
+	Done            bool           `json:"done"`
+	PromptEvalCount int            `json:"prompt_eval_count"`
+	EvalCount       int            `json:"eval_count"`
+	Logprobs        []tokenLogprob `json:"logprobs,omitempty"`
+}
+
+// tokenLogprob es un token de la salida con su logprob (request con logprobs:true).
+type tokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
 }
 
 // GenerateAssessment pide al modelo un JSON del contrato assessment_import v1.
 func (p *Provider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
-	prompt := llm.BuildGenerationPrompt(material, params)
-	out, err := p.generate(ctx, prompt)
+	prompt := p.prompts.Generation(ctx, material, params)
+	out, err := p.generate(ctx, "generate_assessment", prompt, jsonFormat)
 	if err != nil {
 		return nil, err
 	}
-	return llm.ExtractJSON(out)
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return rawJSON, nil
 }
 
 // ReviewAnswer pide al modelo la corrección de una respuesta.
 func (p *Provider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
-	prompt := llm.BuildReviewPrompt(req)
-	out, err := p.generate(ctx, prompt)
+	req, cuts := p.budget.FitReview(req)
+	p.reportTruncation("review_answer", cuts)
+	prompt := p.prompts.Review(ctx, req)
+	// Override opcional de temperatura (muestras de la revisión por votación).
+	temperature := p.temperature
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
+	out, tokens, err := p.generateScored(ctx, "review_answer", prompt, llm.ReviewSchema, temperature, p.logprobs)
 	if err != nil {
 		return llm.ReviewResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.ReviewResult{}, err
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
 	var result llm.ReviewResult
 	if err := json.Unmarshal(rawJSON, &result); err != nil {
-		return llm.ReviewResult{}, fmt.Errorf("respuesta de corrección no parseable: %w", err)
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de corrección no parseable: %v", llm.ErrLLMQuality, err)
 	}
+	result.Confidence = llm.VerdictConfidence(tokens)
 	return result, nil
 }
 
 // PrepareQuestion pide al modelo el artefacto de preparación (JSON crudo del
-// contrato llm_prep v1). Hereda el mismo camino que review: format:"json" +
+// contrato llm_prep v1). Hereda el mismo camino que review: format con el schema +
 // think:false (fix e7c70fe) para que qwen3 emita el objeto directo, sin el `{}` del
 // thinking. El caller valida el JSON contra el contrato antes de persistirlo.
 func (p *Provider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
-	prompt := llm.BuildPrepPrompt(req)
-	out, err := p.generate(ctx, prompt)
+	prompt := p.prompts.Prep(ctx, req)
+	out, err := p.generate(ctx, "prepare_question", prompt, llm.PrepSchema)
 	if err != nil {
 		return nil, err
 	}
-	return llm.ExtractJSON(out)
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return rawJSON, nil
 }
 
 // JudgePairEquivalence pide la equivalencia binaria de un par (plan 042 F3c). Mismo
 // camino que ReviewAnswer: el resultado es un ReviewResult (verdict/score/feedback).
 func (p *Provider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
-	prompt := llm.BuildPairEquivalencePrompt(req)
-	out, err := p.generate(ctx, prompt)
+	prompt := p.prompts.PairEquivalence(ctx, req)
+	out, tokens, err := p.generateScored(ctx, "judge_pair_equivalence", prompt, llm.ReviewSchema, p.temperature, p.logprobs)
 	if err != nil {
 		return llm.ReviewResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.ReviewResult{}, err
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
 	var result llm.ReviewResult
 	if err := json.Unmarshal(rawJSON, &result); err != nil {
-		return llm.ReviewResult{}, fmt.Errorf("respuesta de equivalencia no parseable: %w", err)
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de equivalencia no parseable: %v", llm.ErrLLMQuality, err)
 	}
+	result.Confidence = llm.VerdictConfidence(tokens)
 	return result, nil
 }
 
 // CheckCriterion pide el cumplimiento binario de un criterio (plan 042 F4b). Mismo
 // camino que ReviewAnswer: el resultado es un ReviewResult (verdict/score/feedback).
 func (p *Provider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
-	prompt := llm.BuildCriterionCheckPrompt(req)
-	out, err := p.generate(ctx, prompt)
+	req, cuts := p.budget.FitCriterionCheck(req)
+	p.reportTruncation("check_criterion", cuts)
+	prompt := p.prompts.CriterionCheck(ctx, req)
+	// Override opcional de temperatura (muestras de la revisión por votación).
+	temperature := p.temperature
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
+	out, tokens, err := p.generateScored(ctx, "check_criterion", prompt, llm.ReviewSchema, temperature, p.logprobs)
 	if err != nil {
 		return llm.ReviewResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.ReviewResult{}, err
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
 	var result llm.ReviewResult
 	if err := json.Unmarshal(rawJSON, &result); err != nil {
-		return llm.ReviewResult{}, fmt.Errorf("respuesta de criterio no parseable: %w", err)
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de criterio no parseable: %v", llm.ErrLLMQuality, err)
 	}
+	result.Confidence = llm.VerdictConfidence(tokens)
 	return result, nil
 }
 
@@ -177,16 +252,22 @@ func (p *Provider) CheckCriterion(ctx context.Context, req llm.CriterionCheckReq
 // y, si persiste, deja el score nil sin descartar la candidata (conservador). No está en
 // el puerto llm.LLMProvider: la pasada la consume por una interfaz mínima propia (ISP).
 func (p *Provider) ScoreRelevance(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
-	prompt := llm.BuildRelevancePrompt(req)
-	out, err := p.generate(ctx, prompt)
+	req, cuts := p.budget.FitRelevance(req)
+	p.reportTruncation("score_relevance", cuts)
+	prompt := p.prompts.Relevance(ctx, req)
+	out, err := p.generate(ctx, "score_relevance", prompt, llm.RelevanceSchema)
 	if err != nil {
 		return llm.RelevanceResult{}, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return llm.RelevanceResult{}, err
+		return llm.RelevanceResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	result, err := llm.ParseRelevanceResult(rawJSON)
+	if err != nil {
+		return llm.RelevanceResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
-	return llm.ParseRelevanceResult(rawJSON)
+	return result, nil
 }
 
 // ExtractIdeas descompone la respuesta del alumno en ideas atómicas (plan 045 F4).
@@ -194,16 +275,20 @@ func (p *Provider) ScoreRelevance(ctx context.Context, req llm.RelevanceRequest)
 // la forma {"ideas":[…]}. Una extracción que no parsea es fallo transitorio (el caller
 // decide el fallback a la respuesta cruda).
 func (p *Provider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
-	prompt := llm.BuildExtractIdeasPrompt(req)
-	out, err := p.generate(ctx, prompt)
+	prompt := p.prompts.ExtractIdeas(ctx, req)
+	out, err := p.generate(ctx, "extract_ideas", prompt, llm.ExtractIdeasSchema)
 	if err != nil {
 		return nil, err
 	}
 	rawJSON, err := llm.ExtractJSON(out)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	ideas, err := llm.ParseExtractedIdeas(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
 	}
-	return llm.ParseExtractedIdeas(rawJSON)
+	return ideas, nil
 }
 
 // DigestChunk ejecuta la llamada A ("leer") del pipeline material→evaluación (plan 043
@@ -221,7 +306,9 @@ func (p *Provider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*l
 	}
 
 	// A1: summary encadenable + tema (la mitad que sostiene el pipeline, va primero).
-	outS, err := p.generateWithTemperature(ctx, llm.BuildDigestSummaryPrompt(in), temperature)
+	inS, cuts := p.budget.FitDigestChunk(in, llm.BuildDigestSummaryPrompt)
+	p.reportTruncation("digest_summary", cuts)
+	outS, err := p.generateWithTemperature(ctx, "digest_summary", p.prompts.DigestSummary(ctx, inS), llm.DigestSummarySchema, temperature)
 	if err != nil {
 		// Fallo de transporte/HTTP: es INFRA, sube SIN el sentinel de calidad.
 		return nil, err
@@ -237,7 +324,9 @@ func (p *Provider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*l
 	}
 
 	// A2: solo las ideas del trozo.
-	outI, err := p.generateWithTemperature(ctx, llm.BuildDigestIdeasPrompt(in), temperature)
+	inI, cuts := p.budget.FitDigestChunk(in, llm.BuildDigestIdeasPrompt)
+	p.reportTruncation("digest_ideas", cuts)
+	outI, err := p.generateWithTemperature(ctx, "digest_ideas", p.prompts.DigestIdeas(ctx, inI), llm.DigestIdeasSchema, temperature)
 	if err != nil {
 		return nil, err
 	}
@@ -257,13 +346,15 @@ func (p *Provider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*l
 // Mismo camino: build prompt → generar → ExtractJSON → ParseCandidates. El caller valida
 // cada candidata contra CandidatePayloadV1.
 func (p *Provider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
-	prompt := llm.BuildProposeCandidatesPrompt(in)
+	in, cuts := p.budget.FitProposeCandidates(in)
+	p.reportTruncation("propose_candidates", cuts)
+	prompt := p.prompts.ProposeCandidates(ctx, in)
 	// Override opcional de temperatura (jitter del reintento por calidad de la fase B).
 	temperature := p.temperature
 	if in.Temperature != nil {
 		temperature = *in.Temperature
 	}
-	out, err := p.generateWithTemperature(ctx, prompt, temperature)
+	out, err := p.generateWithTemperature(ctx, "propose_candidates", prompt, llm.ProposeCandidatesSchema, temperature)
 	if err != nil {
 		// Fallo de transporte/HTTP: INFRA, sube SIN el sentinel de calidad.
 		return nil, err
@@ -279,52 +370,177 @@ func (p *Provider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidat
 	return candidates, nil
 }
 
-// generate ejecuta POST /api/generate con la temperatura por instancia del provider.
-func (p *Provider) generate(ctx context.Context, prompt string) (string, error) {
-	return p.generateWithTemperature(ctx, prompt, p.temperature)
+// tagsResponse es la respuesta de GET /api/tags (modelos instalados).
+type tagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
 }
 
-// generateWithTemperature ejecuta POST /api/generate con una temperatura explícita y
-// devuelve el texto crudo del modelo. La usa DigestChunk para aplicar el jitter del
-// reintento por calidad sin cambiar el default determinista del resto de llamadas.
-func (p *Provider) generateWithTemperature(ctx context.Context, prompt string, temperature float64) (string, error) {
-	reqBody := generateRequest{
-		Model:   p.model,
-		Prompt:  prompt,
-		Stream:  false,
-		Format:  "json",
-		Think:   false,
-		Options: &generateOptions{Temperature: temperature},
+// Ping verifica que el servidor Ollama responde GET /api/tags y que el modelo
+// configurado está instalado (un modelo sin pull falla todas las llamadas igual que
+// un servidor caído). Es la sonda del monitor de disponibilidad. Con varios hosts
+// sondea todos (y actualiza el pool): basta uno sano.
+func (p *Provider) Ping(ctx context.Context) error {
+	if len(p.pool.hosts) > 1 {
+		return p.pool.probe(ctx)
+	}
+	return pingHost(ctx, p.httpClient, p.pool.hosts[0].baseURL, p.model)
+}
+
+// pingHost sondea GET {baseURL}/api/tags y, si model no es "", que esté instalado.
+func pingHost(ctx context.Context, client *http.Client, baseURL, model string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("creating ollama tags request: %w", err)
 	}
-	bodyBytes, err := json.Marshal(reqBody)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("marshaling ollama request: %w", err)
+		return fmt.Errorf("ollama tags request failed: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading ollama tags response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ollama tags returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if model == "" {
+		return nil
+	}
+
+	var tags tagsResponse
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return fmt.Errorf("parsing ollama tags response: %w", err)
+	}
+	for _, m := range tags.Models {
+		for _, name := range []string{m.Name, m.Model} {
+			// Ollama lista "llama3.1:latest" para un modelo pedido como "llama3.1".
+			if name == model || name == model+":latest" {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("ollama model %q not installed", model)
+}
+
+// jsonFormat es la salida de las operaciones sin contrato propio (GenerateAssessment):
+// cualquier objeto JSON, el format:"json" histórico.
+var jsonFormat = llm.OutputSchema{Name: "json"}
+
+// reportTruncation deja en logs y métricas los recortes del presupuesto en op.
+func (p *Provider) reportTruncation(op string, cuts []llm.Truncation) {
+	for _, cut := range cuts {
+		metrics.RecordLLMPromptTruncation(op, cut.Field)
+		if p.logger != nil {
+			p.logger.Warn("prompt recortado para caber en la ventana de contexto",
+				"model", p.model, "operation", op, "field", cut.Field,
+				"kept", cut.Kept, "dropped", cut.Dropped, "context_window", p.budget.ContextWindow)
+		}
+	}
+}
 
-	url := p.baseURL + "/api/generate"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+// generate ejecuta POST /api/generate con la temperatura por instancia del provider.
+func (p *Provider) generate(ctx context.Context, op, prompt string, schema llm.OutputSchema) (string, error) {
+	return p.generateWithTemperature(ctx, op, prompt, schema, p.temperature)
+}
+
+// postJSON hace POST de body a url y devuelve el body de la respuesta. Los fallos de
+// transporte y los 5xx son del host (hostError: el pool reintenta en otro); un 4xx es
+// de la request y no se reintenta.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("creating ollama request: %w", err)
+		return nil, fmt.Errorf("creating ollama request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %w", err)
+		return nil, &hostError{fmt.Errorf("ollama request failed: %w", err)}
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("reading ollama response: %w", err)
+		return nil, &hostError{fmt.Errorf("reading ollama response: %w", err)}
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		err := fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		if resp.StatusCode >= 500 {
+			return nil, &hostError{err}
+		}
+		return nil, err
+	}
+	return respBody, nil
+}
+
+// generateWithTemperature ejecuta POST /api/generate con una temperatura explícita y
+// devuelve el texto crudo del modelo. La usa DigestChunk para aplicar el jitter del
+// reintento por calidad sin cambiar el default determinista del resto de llamadas.
+// op nombra la operación (label de métricas); schema es el format de la request (sin
+// Schema = format:"json"). El num_ctx sale del presupuesto; un prompt que no cabe ni
+// recortado se envía igual (el servidor lo truncará) pero queda reportado.
+func (p *Provider) generateWithTemperature(ctx context.Context, op, prompt string, schema llm.OutputSchema, temperature float64) (string, error) {
+	out, _, err := p.generateScored(ctx, op, prompt, schema, temperature, false)
+	return out, err
+}
+
+// generateScored es generateWithTemperature que, con logprobs, pide además el logprob
+// de cada token generado y lo devuelve junto al texto (nil si no se pidieron o el
+// servidor no los envía).
+func (p *Provider) generateScored(ctx context.Context, op, prompt string, schema llm.OutputSchema, temperature float64, logprobs bool) (string, []llm.TokenLogprob, error) {
+	format := schema.Schema
+	if format == nil {
+		format = json.RawMessage(`"json"`)
+	}
+	tokens := llm.EstimateTokens(prompt)
+	metrics.RecordLLMPromptTokens(op, tokens)
+	if !p.budget.Fits(prompt) {
+		metrics.RecordLLMPromptOverflow(op)
+		if p.logger != nil {
+			p.logger.Warn("prompt excede la ventana de contexto aun recortado",
+				"model", p.model, "operation", op, "estimated_tokens", tokens, "prompt_limit", p.budget.PromptLimit())
+		}
+	}
+	reqBody := generateRequest{
+		Model:    p.model,
+		Prompt:   prompt,
+		Stream:   false,
+		Format:   format,
+		Think:    false,
+		Options:  &generateOptions{Temperature: temperature, NumCtx: p.budget.NumCtx(prompt)},
+		Logprobs: logprobs,
+	}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling ollama request: %w", err)
 	}
 
 	var gr generateResponse
-	if err := json.Unmarshal(body, &gr); err != nil {
-		return "", fmt.Errorf("parsing ollama response: %w", err)
+	err = p.pool.do(ctx, func(baseURL string) error {
+		body, err := postJSON(ctx, p.httpClient, baseURL+"/api/generate", bodyBytes)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, &gr); err != nil {
+			return fmt.Errorf("parsing ollama response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	llm.ReportUsage(ctx, gr.PromptEvalCount, gr.EvalCount)
+	if !logprobs {
+		return gr.Response, nil, nil
+	}
+	var scored []llm.TokenLogprob
+	for _, t := range gr.Logprobs {
+		scored = append(scored, llm.TokenLogprob{Token: t.Token, Logprob: t.Logprob})
 	}
-	return gr.Response, nil
+	return gr.Response, scored, nil
 }
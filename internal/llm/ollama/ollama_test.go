@@ -3,12 +3,16 @@ package ollama
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/cassette"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 )
 
@@ -48,20 +52,26 @@ func TestGenerateAssessment_OK(t *testing.T) {
 func TestReviewAnswer_OK(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		_ = json.NewEncoder(w).Encode(generateResponse{
-			Response: `{"verdict":"partial","score":0.5,"feedback":"casi"}`,
-			Done:     true,
+			Response:        `{"verdict":"partial","score":0.5,"feedback":"casi"}`,
+			Done:            true,
+			PromptEvalCount: 120,
+			EvalCount:       30,
 		})
 	}))
 	defer srv.Close()
 
 	p := New(Config{BaseURL: srv.URL, Model: "m"})
-	res, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
+	ctx, usage := llm.WithUsage(context.Background())
+	res, err := p.ReviewAnswer(ctx, llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
 	if err != nil {
 		t.Fatalf("error inesperado: %v", err)
 	}
 	if res.Verdict != llm.VerdictPartial || res.Score != 0.5 {
 		t.Fatalf("resultado inesperado: %+v", res)
 	}
+	if promptTokens, completionTokens := usage.Tokens(); promptTokens != 120 || completionTokens != 30 {
+		t.Errorf("tokens reportados inesperados: prompt=%d completion=%d", promptTokens, completionTokens)
+	}
 }
 
 func TestGenerateAssessment_HTTPError(t *testing.T) {
@@ -143,8 +153,249 @@ func TestGenerate_RequestIncluyeTemperature(t *testing.T) {
 	}
 }
 
+func TestReviewAnswer_TemperatureOverride(t *testing.T) {
+	// Las muestras de la revisión por votación piden su propia temperatura; la
+	// configurada sigue siendo la de las llamadas sin override.
+	var temps []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		temps = append(temps, body["options"].(map[string]any)["temperature"])
+		_ = json.NewEncoder(w).Encode(generateResponse{
+			Response: `{"verdict":"correct","score":1,"feedback":"ok"}`,
+			Done:     true,
+		})
+	}))
+	defer srv.Close()
+
+	p := New(Config{BaseURL: srv.URL, Model: "gemma3:4b"})
+	jitter := 0.5
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a", Temperature: &jitter}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := p.CheckCriterion(context.Background(), llm.CriterionCheckRequest{Criterion: "c", StudentAnswer: "a"}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(temps) != 2 || temps[0] != 0.5 || temps[1] != float64(0) {
+		t.Fatalf("temperaturas enviadas %v, esperaba [0.5 0]", temps)
+	}
+}
+
+func TestReviewAnswer_ConfianzaPorLogprobs(t *testing.T) {
+	// Con Logprobs, los juicios piden logprobs y la confianza sale de los tokens del
+	// verdict; el resto de llamadas no los pide.
+	var asked []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		asked = append(asked, body["logprobs"])
+		_ = json.NewEncoder(w).Encode(generateResponse{
+			Response: `{"verdict":"partial","score":0.5,"feedback":"ok"}`,
+			Done:     true,
+			Logprobs: []tokenLogprob{
+				{Token: `{"verdict":"`}, {Token: "partial", Logprob: math.Log(0.8)},
+				{Token: `","score":0.5,"feedback":"ok"}`, Logprob: -3},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	p := New(Config{BaseURL: srv.URL, Model: "gemma3:4b", Logprobs: true})
+	res, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Confidence == nil || math.Abs(*res.Confidence-0.8) > 1e-9 {
+		t.Fatalf("confianza %v, esperaba 0.8", res.Confidence)
+	}
+	if _, err := p.PrepareQuestion(context.Background(), llm.PrepRequest{QuestionText: "q"}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(asked) != 2 || asked[0] != true || asked[1] != nil {
+		t.Fatalf("logprobs pedidos %v, esperaba [true <nil>]", asked)
+	}
+
+	// Sin Logprobs no hay señal.
+	res, err = New(Config{BaseURL: srv.URL, Model: "gemma3:4b"}).ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Confidence != nil {
+		t.Errorf("sin logprobs pedidos la confianza debe ser nil: %v", *res.Confidence)
+	}
+}
+
+func TestGenerate_FormatEsElSchemaDelContrato(t *testing.T) {
+	// ReviewAnswer manda como format el schema de ReviewResult (decodificación
+	// restringida); GenerateAssessment, sin contrato en llm, sigue con "json".
+	var formats []any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var captured map[string]any
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &captured); err != nil {
+			t.Errorf("body no parseable: %v", err)
+		}
+		formats = append(formats, captured["format"])
+		_ = json.NewEncoder(w).Encode(generateResponse{
+			Response: `{"verdict":"correct","score":1,"feedback":"ok"}`,
+			Done:     true,
+		})
+	}))
+	defer srv.Close()
+
+	p := New(Config{BaseURL: srv.URL, Model: "m"})
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	_, _ = p.GenerateAssessment(context.Background(), llm.MaterialInput{}, llm.GenerationParams{})
+
+	schema, ok := formats[0].(map[string]any)
+	if !ok || schema["type"] != "object" {
+		t.Fatalf("format de ReviewAnswer esperado un schema objeto, hubo %v", formats[0])
+	}
+	if _, ok := schema["properties"].(map[string]any)["verdict"]; !ok {
+		t.Errorf("el schema no declara verdict: %v", schema)
+	}
+	if formats[1] != "json" {
+		t.Errorf("format de GenerateAssessment esperado \"json\", hubo %v", formats[1])
+	}
+}
+
+func TestGenerate_PresupuestoFijaNumCtxYRecorta(t *testing.T) {
+	// Con presupuesto, options.num_ctx sale de la estimación del prompt y las ideas
+	// que no caben se recortan antes de enviarlo; sin presupuesto num_ctx no viaja.
+	var captured []generateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req generateRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("body no parseable: %v", err)
+		}
+		captured = append(captured, req)
+		_ = json.NewEncoder(w).Encode(generateResponse{
+			Response: `{"category":"central","rationale":"ok"}`,
+			Done:     true,
+		})
+	}))
+	defer srv.Close()
+
+	ideas := make([]string, 450)
+	for i := range ideas {
+		ideas[i] = fmt.Sprintf("idea agregada número %d del material de estudio", i)
+	}
+	req := llm.RelevanceRequest{QuestionText: "q", MainIdeas: ideas}
+
+	budgeted := New(Config{BaseURL: srv.URL, Model: "m", Budget: llm.TokenBudget{ContextWindow: 4096}})
+	if _, err := budgeted.ScoreRelevance(context.Background(), req); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := New(Config{BaseURL: srv.URL, Model: "m"}).ScoreRelevance(context.Background(), req); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	if got := captured[0].Options.NumCtx; got != 4096 {
+		t.Errorf("num_ctx esperado=4096, hubo %d", got)
+	}
+	if llm.EstimateTokens(captured[0].Prompt) > 4096-1024 || strings.Contains(captured[0].Prompt, ideas[449]) {
+		t.Error("el prompt con presupuesto debe llegar recortado")
+	}
+	if captured[1].Options.NumCtx != 0 || !strings.Contains(captured[1].Prompt, ideas[449]) {
+		t.Error("sin presupuesto no se fija num_ctx ni se recorta")
+	}
+}
+
 func TestName(t *testing.T) {
 	if got := New(Config{Model: "abc"}).Name(); got != "ollama:abc" {
 		t.Fatalf("Name inesperado: %s", got)
 	}
 }
+
+func TestPing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/tags" {
+			t.Errorf("request inesperada: %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3.1:latest","model":"llama3.1:latest"},{"name":"qwen3:1.7b","model":"qwen3:1.7b"}]}`))
+	}))
+	defer srv.Close()
+
+	for _, model := range []string{"llama3.1", "qwen3:1.7b", ""} {
+		if err := New(Config{BaseURL: srv.URL, Model: model}).Ping(context.Background()); err != nil {
+			t.Errorf("modelo %q: error inesperado: %v", model, err)
+		}
+	}
+	if err := New(Config{BaseURL: srv.URL, Model: "gemma4:e4b"}).Ping(context.Background()); err == nil {
+		t.Error("un modelo no instalado debe fallar la sonda")
+	}
+}
+
+func TestPing_ServidorCaido(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	srv.Close()
+
+	if err := New(Config{BaseURL: srv.URL, Model: "m"}).Ping(context.Background()); err == nil {
+		t.Error("un servidor caído debe fallar la sonda")
+	}
+}
+
+func TestReviewAnswer_ReproduceCassette(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(generateResponse{Response: `{"verdict":"correct","score":1,"feedback":"bien"}`, Done: true})
+	}))
+	path := t.TempDir() + "/review.json"
+	req := llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}
+
+	rec, err := cassette.New(path, cassette.ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := New(Config{BaseURL: srv.URL, Model: "m", Transport: rec}).ReviewAnswer(context.Background(), req); err != nil {
+		t.Fatalf("error inesperado grabando: %v", err)
+	}
+	srv.Close()
+
+	play, err := cassette.New(path, cassette.ModeReplay, nil)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	res, err := New(Config{BaseURL: srv.URL, Model: "m", Transport: play}).ReviewAnswer(context.Background(), req)
+	if err != nil {
+		t.Fatalf("el replay no debe salir a la red: %v", err)
+	}
+	if res.Verdict != llm.VerdictCorrect || res.Feedback != "bien" {
+		t.Fatalf("resultado reproducido inesperado: %+v", res)
+	}
+}
+
+func TestSalidaNoParseableEsCalidad(t *testing.T) {
+	// Sin objeto JSON o con un objeto que no cumple el contrato: el backend respondió,
+	// es calidad (no cuenta como caída en métricas, breaker ni monitor).
+	for _, out := range []string{"sin json", `{"verdict": 3, "category": 3, "ideas": "x"}`} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode(generateResponse{Response: out, Done: true})
+		}))
+		p := New(Config{BaseURL: srv.URL, Model: "m"})
+		ctx := context.Background()
+		calls := map[string]func() error{
+			"review_answer": func() error { _, err := p.ReviewAnswer(ctx, llm.ReviewRequest{}); return err },
+			"check_criterion": func() error {
+				_, err := p.CheckCriterion(ctx, llm.CriterionCheckRequest{})
+				return err
+			},
+			"judge_pair_equivalence": func() error {
+				_, err := p.JudgePairEquivalence(ctx, llm.PairEquivalenceRequest{})
+				return err
+			},
+			"extract_ideas":   func() error { _, err := p.ExtractIdeas(ctx, llm.ExtractIdeasRequest{}); return err },
+			"score_relevance": func() error { _, err := p.ScoreRelevance(ctx, llm.RelevanceRequest{}); return err },
+		}
+		for op, call := range calls {
+			if err := call(); !errors.Is(err, llm.ErrLLMQuality) {
+				t.Errorf("%s con %q: esperaba ErrLLMQuality, obtuve %v", op, out, err)
+			}
+		}
+		srv.Close()
+	}
+}
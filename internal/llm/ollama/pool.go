@@ -0,0 +1,225 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// PoolConfig configura un Pool.
+type PoolConfig struct {
+	// Name identifica al pool en métricas y logs (local, embed).
+	Name string
+	// BaseURLs son los hosts Ollama (ej. http://10.0.0.5:11434).
+	BaseURLs []string
+	// Model que la sonda exige instalado en cada host ("" = solo que responda).
+	Model string
+	// ProbeTimeout acota cada sonda GET /api/tags. Default 5s.
+	ProbeTimeout time.Duration
+	// Logger reporta los hosts que salen y vuelven. Opcional.
+	Logger logger.Logger
+}
+
+// Pool reparte las requests de un cliente Ollama entre varios hosts de inferencia.
+// Elige el host sano con menos requests en curso (least-outstanding) salvo que el
+// contexto traiga una afinidad (llm.WithAffinity, el job de materiales): esa va
+// siempre al mismo host sano (rendezvous hashing), que ya tiene el modelo cargado y
+// el prefijo del prompt en cache. Un host sale del reparto cuando su sonda
+// GET /api/tags falla y vuelve cuando pasa. Una request que falla por el host
+// (transporte, 5xx) se reintenta UNA vez en otro.
+//
+// Con un solo host es transparente: sin sondas propias (las hace el monitor de
+// disponibilidad) ni reintento.
+type Pool struct {
+	cfg    PoolConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	hosts []*poolHost
+	next  int
+}
+
+type poolHost struct {
+	baseURL     string
+	outstanding int
+	healthy     bool
+}
+
+// NewPool construye el pool con todos los hosts sanos.
+func NewPool(cfg PoolConfig) *Pool {
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = 5 * time.Second
+	}
+	p := &Pool{cfg: cfg, client: &http.Client{}}
+	for _, u := range cfg.BaseURLs {
+		h := &poolHost{baseURL: strings.TrimRight(u, "/"), healthy: true}
+		p.hosts = append(p.hosts, h)
+		metrics.SetLLMPoolHostUp(cfg.Name, h.baseURL, true)
+	}
+	return p
+}
+
+// singleHostPool es el pool de un cliente configurado con un solo BaseURL.
+func singleHostPool(baseURL string) *Pool {
+	return &Pool{client: &http.Client{}, hosts: []*poolHost{{baseURL: strings.TrimRight(baseURL, "/"), healthy: true}}}
+}
+
+// Start sondea los hosts cada interval hasta que ctx termine. Con un solo host no
+// hace nada.
+func (p *Pool) Start(ctx context.Context, interval time.Duration) {
+	if len(p.hosts) < 2 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			_ = p.probe(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// probe sondea todos los hosts, actualiza su salud y devuelve nil si alguno está
+// sano (o el error de cada uno).
+func (p *Pool) probe(ctx context.Context) error {
+	var errs []error
+	for _, h := range p.hosts {
+		probeCtx, cancel := context.WithTimeout(ctx, p.cfg.ProbeTimeout)
+		err := pingHost(probeCtx, p.client, h.baseURL, p.cfg.Model)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err() // shutdown: no es un fallo del host
+		}
+		p.setHealthy(h, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.baseURL, err))
+		}
+	}
+	if len(errs) == len(p.hosts) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (p *Pool) setHealthy(h *poolHost, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy := err == nil
+	if h.healthy == healthy {
+		return
+	}
+	h.healthy = healthy
+	metrics.SetLLMPoolHostUp(p.cfg.Name, h.baseURL, healthy)
+	if p.cfg.Logger == nil {
+		return
+	}
+	if healthy {
+		p.cfg.Logger.Info("host Ollama vuelve al pool", "pool", p.cfg.Name, "host", h.baseURL)
+	} else {
+		p.cfg.Logger.Warn("host Ollama fuera del pool (sonda fallida)", "pool", p.cfg.Name, "host", h.baseURL, "error", err.Error())
+	}
+}
+
+// do ejecuta call contra un host del pool y, si falla por el host, una vez más
+// contra otro.
+func (p *Pool) do(ctx context.Context, call func(baseURL string) error) error {
+	h := p.acquire(ctx, nil)
+	err := call(h.baseURL)
+	p.release(h)
+	if !isHostFailure(err) || ctx.Err() != nil {
+		return err
+	}
+	retry := p.acquire(ctx, h)
+	if retry == nil {
+		return err
+	}
+	defer p.release(retry)
+	return call(retry.baseURL)
+}
+
+// acquire elige un host distinto de exclude y cuenta la request en curso. Si no hay
+// ninguno sano reparte entre todos (la caída del backend la detecta el monitor). nil
+// si no queda candidato.
+func (p *Pool) acquire(ctx context.Context, exclude *poolHost) *poolHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	candidates := make([]*poolHost, 0, len(p.hosts))
+	for _, h := range p.hosts {
+		if h != exclude && h.healthy {
+			candidates = append(candidates, h)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, h := range p.hosts {
+			if h != exclude {
+				candidates = append(candidates, h)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var chosen *poolHost
+	if key := llm.AffinityFrom(ctx); key != "" {
+		var best uint64
+		for _, h := range candidates {
+			if score := rendezvous(key, h.baseURL); chosen == nil || score > best {
+				chosen, best = h, score
+			}
+		}
+	} else {
+		// Least-outstanding; los empates rotan para no cargar siempre el primero.
+		p.next++
+		for i := range candidates {
+			h := candidates[(p.next+i)%len(candidates)]
+			if chosen == nil || h.outstanding < chosen.outstanding {
+				chosen = h
+			}
+		}
+	}
+	chosen.outstanding++
+	return chosen
+}
+
+func (p *Pool) release(h *poolHost) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h.outstanding--
+}
+
+// rendezvous es el peso de host para key (highest random weight): cada key prefiere
+// un host estable y, si ese sale del pool, solo se mueven las keys que lo preferían.
+func rendezvous(key, host string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(host))
+	return h.Sum64()
+}
+
+// hostError marca un fallo atribuible al host (transporte o status 5xx): el pool lo
+// reintenta en otro. Conserva el mensaje del error envuelto.
+type hostError struct{ err error }
+
+func (e *hostError) Error() string { return e.err.Error() }
+func (e *hostError) Unwrap() error { return e.err }
+
+func isHostFailure(err error) bool {
+	var he *hostError
+	return errors.As(err, &he)
+}
@@ -0,0 +1,174 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// poolHostServer es un host Ollama de prueba: cuenta las generaciones y responde
+// status (200 = un veredicto válido). /api/tags responde tagsStatus.
+type poolHostServer struct {
+	*httptest.Server
+	calls      atomic.Int32
+	status     atomic.Int32
+	tagsStatus atomic.Int32
+}
+
+func newPoolHostServer(t *testing.T) *poolHostServer {
+	t.Helper()
+	h := &poolHostServer{}
+	h.status.Store(http.StatusOK)
+	h.tagsStatus.Store(http.StatusOK)
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			w.WriteHeader(int(h.tagsStatus.Load()))
+			_, _ = w.Write([]byte(`{"models":[{"name":"m:latest"}]}`))
+			return
+		}
+		h.calls.Add(1)
+		if status := int(h.status.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(generateResponse{Response: `{"verdict":"correct","score":1,"feedback":"ok"}`, Done: true})
+	}))
+	t.Cleanup(h.Close)
+	return h
+}
+
+func newTestPool(hosts ...*poolHostServer) *Pool {
+	urls := make([]string, len(hosts))
+	for i, h := range hosts {
+		urls[i] = h.URL
+	}
+	return NewPool(PoolConfig{Name: "test", BaseURLs: urls, Model: "m"})
+}
+
+func TestPool_LeastOutstanding(t *testing.T) {
+	p := NewPool(PoolConfig{Name: "test", BaseURLs: []string{"http://a", "http://b", "http://c"}})
+
+	busy := p.acquire(context.Background(), nil)
+	other := p.acquire(context.Background(), nil)
+	if busy == other {
+		t.Fatal("con hosts libres no debe repetir el host ocupado")
+	}
+	third := p.acquire(context.Background(), nil)
+	if third == busy || third == other {
+		t.Fatal("el tercer host libre debía recibir la tercera request")
+	}
+	p.release(other)
+	if next := p.acquire(context.Background(), nil); next != other {
+		t.Fatalf("esperaba el host con menos requests en curso (%s), fue %s", other.baseURL, next.baseURL)
+	}
+}
+
+func TestPool_ReintentaEnOtroHost(t *testing.T) {
+	down, up := newPoolHostServer(t), newPoolHostServer(t)
+	down.status.Store(http.StatusBadGateway)
+	p := New(Config{Pool: newTestPool(down, up), Model: "m"})
+
+	for range 4 {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}); err != nil {
+			t.Fatalf("el reintento en el host sano debía resolver la request: %v", err)
+		}
+	}
+	if up.calls.Load() != 4 {
+		t.Errorf("el host sano debía atender todas las requests: calls=%d", up.calls.Load())
+	}
+}
+
+func TestPool_NoReintentaErroresDeLaRequest(t *testing.T) {
+	a, b := newPoolHostServer(t), newPoolHostServer(t)
+	a.status.Store(http.StatusBadRequest)
+	b.status.Store(http.StatusBadRequest)
+	p := New(Config{Pool: newTestPool(a, b), Model: "m"})
+
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err == nil {
+		t.Fatal("esperaba el error 400")
+	}
+	if total := a.calls.Load() + b.calls.Load(); total != 1 {
+		t.Errorf("un 4xx no es del host y no se reintenta: calls=%d", total)
+	}
+}
+
+func TestPool_AfinidadPorJob(t *testing.T) {
+	hosts := []*poolHostServer{newPoolHostServer(t), newPoolHostServer(t), newPoolHostServer(t)}
+	pool := newTestPool(hosts...)
+	p := New(Config{Pool: pool, Model: "m"})
+
+	ctx := llm.WithAffinity(context.Background(), "job-42")
+	for range 5 {
+		if _, err := p.ReviewAnswer(ctx, llm.ReviewRequest{}); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	var preferred *poolHostServer
+	for _, h := range hosts {
+		switch h.calls.Load() {
+		case 5:
+			preferred = h
+		case 0:
+		default:
+			t.Fatalf("las llamadas de un job deben ir a un solo host: calls=%d", h.calls.Load())
+		}
+	}
+	if preferred == nil {
+		t.Fatal("ningún host atendió el job completo")
+	}
+
+	// Si su host sale del pool, el job sigue en otro.
+	preferred.tagsStatus.Store(http.StatusServiceUnavailable)
+	if err := pool.probe(context.Background()); err != nil {
+		t.Fatalf("quedan hosts sanos: %v", err)
+	}
+	if _, err := p.ReviewAnswer(ctx, llm.ReviewRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if preferred.calls.Load() != 5 {
+		t.Error("el host expulsado no debe recibir más llamadas del job")
+	}
+}
+
+func TestPool_SondaExpulsaYReadmite(t *testing.T) {
+	a, b := newPoolHostServer(t), newPoolHostServer(t)
+	pool := newTestPool(a, b)
+	p := New(Config{Pool: pool, Model: "m"})
+
+	a.tagsStatus.Store(http.StatusInternalServerError)
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("con un host sano el pool está disponible: %v", err)
+	}
+	for range 3 {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	if a.calls.Load() != 0 {
+		t.Errorf("el host expulsado no debe recibir requests: calls=%d", a.calls.Load())
+	}
+
+	b.tagsStatus.Store(http.StatusInternalServerError)
+	if err := p.Ping(context.Background()); err == nil {
+		t.Fatal("sin hosts sanos Ping debe fallar")
+	}
+
+	a.tagsStatus.Store(http.StatusOK)
+	b.tagsStatus.Store(http.StatusOK)
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	for range 4 {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err != nil {
+			t.Fatalf("error inesperado: %v", err)
+		}
+	}
+	if a.calls.Load() == 0 {
+		t.Error("el host readmitido debía volver al reparto")
+	}
+}
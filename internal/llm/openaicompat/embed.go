@@ -0,0 +1,129 @@
+package openaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// EmbedConfig configura el cliente de embeddings OpenAI-compatible. Se inyecta desde
+// bootstrap/config; el cliente NUNCA lee env directo (D-039.3).
+type EmbedConfig struct {
+	// BaseURL del servidor (ej. http://localhost:8000). Sin el sufijo /v1.
+	BaseURL string
+	// Model de embeddings tal como lo sirve el servidor.
+	Model string
+	// APIKey opcional (vLLM --api-key). Vacía = sin header Authorization.
+	APIKey string
+	// Timeout de la request HTTP. Default 60s.
+	Timeout time.Duration
+	// Transport de las requests (ver Config.Transport).
+	Transport http.RoundTripper
+}
+
+// EmbedProvider es la implementación OpenAI-compatible de llm.Embedder. Pega a
+// POST {baseURL}/v1/embeddings con model + input batch.
+type EmbedProvider struct {
+	baseURL    string
+	model      string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewEmbedder construye el cliente de embeddings a partir de su config.
+func NewEmbedder(cfg EmbedConfig) *EmbedProvider {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &EmbedProvider{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		model:      cfg.Model,
+		apiKey:     cfg.APIKey,
+		httpClient: &http.Client{Timeout: timeout, Transport: cfg.Transport},
+	}
+}
+
+// Name identifica al cliente (para logs).
+func (p *EmbedProvider) Name() string { return "openai-compat-embed:" + p.model }
+
+// embedRequest es el body de POST /v1/embeddings. input es un lote de textos.
+type embedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embedResponse es la respuesta: un vector por texto con su índice en la entrada
+// (la API no garantiza el orden de data).
+type embedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage *chatUsage `json:"usage,omitempty"`
+}
+
+// Embed vectoriza un lote de textos contra POST /v1/embeddings. Devuelve un vector
+// por texto en el mismo orden; un lote vacío no llama al backend. Valida que los
+// vectores devueltos cubran exactamente los índices de la entrada.
+func (p *EmbedProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	reqBody := embedRequest{Model: p.model, Input: texts}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling openai-compat embed request: %w", err)
+	}
+
+	url := p.baseURL + "/v1/embeddings"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("creating openai-compat embed request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openai-compat embed request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading openai-compat embed response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("openai-compat embed returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var er embedResponse
+	if err := json.Unmarshal(body, &er); err != nil {
+		return nil, fmt.Errorf("parsing openai-compat embed response: %w", err)
+	}
+	if er.Usage != nil {
+		llm.ReportUsage(ctx, er.Usage.PromptTokens, 0)
+	}
+	if len(er.Data) != len(texts) {
+		return nil, fmt.Errorf("openai-compat embed returned %d vectors for %d texts", len(er.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range er.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("openai-compat embed returned an invalid index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
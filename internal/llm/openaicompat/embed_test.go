@@ -0,0 +1,70 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbed_OK_ReordenaPorIndex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path inesperado: %s", r.URL.Path)
+		}
+		var req embedRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("body no parseable: %v", err)
+		}
+		if req.Model != "test-embed" || len(req.Input) != 2 {
+			t.Errorf("request inesperada: %+v", req)
+		}
+		// data llega fuera de orden: el cliente ordena por index.
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"index":1,"embedding":[0.4,0.5]},{"index":0,"embedding":[0.1,0.2]}]}`))
+	}))
+	defer srv.Close()
+
+	vecs, err := NewEmbedder(EmbedConfig{BaseURL: srv.URL, Model: "test-embed"}).Embed(context.Background(), []string{"hola", "mundo"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(vecs) != 2 || vecs[0][0] != 0.1 || vecs[1][0] != 0.4 {
+		t.Fatalf("vectores inesperados: %+v", vecs)
+	}
+}
+
+func TestEmbed_EmptyInput_NoCall(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	vecs, err := NewEmbedder(EmbedConfig{BaseURL: srv.URL, Model: "m"}).Embed(context.Background(), nil)
+	if err != nil || len(vecs) != 0 {
+		t.Fatalf("resultado inesperado: %v %v", vecs, err)
+	}
+	if called {
+		t.Fatal("un lote vacío no debe llamar al backend")
+	}
+}
+
+func TestEmbed_IndicesInvalidos(t *testing.T) {
+	for _, body := range []string{
+		`{"data":[{"index":0,"embedding":[0.1]}]}`,
+		`{"data":[{"index":0,"embedding":[0.1]},{"index":0,"embedding":[0.2]}]}`,
+		`{"data":[{"index":0,"embedding":[0.1]},{"index":5,"embedding":[0.2]}]}`,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+		_, err := NewEmbedder(EmbedConfig{BaseURL: srv.URL, Model: "m"}).Embed(context.Background(), []string{"a", "b"})
+		srv.Close()
+		if err == nil {
+			t.Errorf("esperaba error para %s", body)
+		}
+	}
+}
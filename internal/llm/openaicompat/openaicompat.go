@@ -0,0 +1,515 @@
+// Package openaicompat implementa llm.LLMProvider contra un servidor con la API
+// compatible con OpenAI (POST /v1/chat/completions): vLLM, llama.cpp server, LM
+// Studio. Es una alternativa a Ollama para la variante "local" de D-039.3 (se
+// elige con llm.local.backend): mismos prompts, mismo parseo y misma semántica de
+// errores que el provider Ollama.
+package openaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/llm/prompts"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+// Modos de Config.ResponseFormat.
+const (
+	// ResponseFormatJSONObject pide response_format {"type":"json_object"}: JSON
+	// válido, sin forma impuesta. Es el equivalente al format:"json" de Ollama.
+	ResponseFormatJSONObject = "json_object"
+	// ResponseFormatJSONSchema pide response_format {"type":"json_schema"} con el
+	// schema del contrato de la operación (llm.OutputSchema; decodificación
+	// restringida en vLLM/llama.cpp).
+	ResponseFormatJSONSchema = "json_schema"
+	// ResponseFormatNone no envía response_format (servidores que no lo soportan).
+	ResponseFormatNone = "none"
+)
+
+// Config configura el provider. Se inyecta desde bootstrap/config; el provider
+// NUNCA lee env directo (D-039.3).
+type Config struct {
+	// BaseURL del servidor (ej. http://localhost:8000). Sin el sufijo /v1.
+	BaseURL string
+	// Model a usar, tal como lo sirve el servidor (ej. "google/gemma-3-4b-it").
+	Model string
+	// APIKey opcional (vLLM --api-key). Vacía = sin header Authorization.
+	APIKey string
+	// Timeout de la request HTTP. Generar puede ser lento: default 120s.
+	Timeout time.Duration
+	// Transport opcional de las requests (nil = http.DefaultTransport), p.ej. un
+	// cassette.Recorder.
+	Transport http.RoundTripper
+	// Temperature del muestreo. Default 0 = greedy determinista, por la misma razón
+	// que en Ollama: la corrección pide JSON estructurado y debe ser reproducible.
+	Temperature float64
+	// ResponseFormat: json_object (default) | json_schema | none.
+	ResponseFormat string
+	// Prompts es el registry de versiones de prompts. Opcional (nil = builtin).
+	Prompts *prompts.Registry
+	// Logprobs pide logprobs en los juicios (ReviewAnswer, CheckCriterion,
+	// JudgePairEquivalence) para la confianza del veredicto. vLLM y llama.cpp server
+	// los soportan.
+	Logprobs bool
+}
+
+// Provider es la implementación OpenAI-compatible de llm.LLMProvider.
+type Provider struct {
+	baseURL        string
+	model          string
+	apiKey         string
+	temperature    float64
+	responseFormat string
+	prompts        *prompts.Registry
+	httpClient     *http.Client
+	logprobs       bool
+}
+
+// New construye el provider. Devuelve error si ResponseFormat no es un modo
+// soportado, para fallar temprano en bootstrap en vez de en la primera llamada.
+func New(cfg Config) (*Provider, error) {
+	switch cfg.ResponseFormat {
+	case "":
+		cfg.ResponseFormat = ResponseFormatJSONObject
+	case ResponseFormatJSONObject, ResponseFormatJSONSchema, ResponseFormatNone:
+	default:
+		return nil, fmt.Errorf("response_format no soportado: %q (soportados: %q, %q, %q)",
+			cfg.ResponseFormat, ResponseFormatJSONObject, ResponseFormatJSONSchema, ResponseFormatNone)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	return &Provider{
+		baseURL:        strings.TrimRight(cfg.BaseURL, "/"),
+		model:          cfg.Model,
+		apiKey:         cfg.APIKey,
+		temperature:    cfg.Temperature,
+		responseFormat: cfg.ResponseFormat,
+		prompts:        cfg.Prompts,
+		httpClient:     &http.Client{Timeout: timeout, Transport: cfg.Transport},
+		logprobs:       cfg.Logprobs,
+	}, nil
+}
+
+// Name identifica al provider.
+func (p *Provider) Name() string { return "openai-compat:" + p.model }
+
+// chatRequest es el body de POST /v1/chat/completions (sin streaming).
+type chatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []chatMessage   `json:"messages"`
+	Temperature float64         `json:"temperature"`
+	Stream      bool            `json:"stream"`
+	Format      *responseFormat `json:"response_format,omitempty"`
+	Logprobs    bool            `json:"logprobs,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// responseFormat es el response_format de la request.
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict"`
+}
+
+// assessmentSchema es la salida de GenerateAssessment, sin contrato propio en llm:
+// cualquier objeto.
+var assessmentSchema = llm.OutputSchema{Name: "assessment_import", Schema: json.RawMessage(`{"type":"object"}`)}
+
+type chatResponse struct {
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+	Error   *chatError   `json:"error,omitempty"`
+}
+
+// chatUsage son los tokens consumidos. Algunos servidores no lo envían.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type chatChoice struct {
+	Message      chatMessage   `json:"message"`
+	FinishReason string        `json:"finish_reason"`
+	Logprobs     *chatLogprobs `json:"logprobs,omitempty"`
+}
+
+// chatLogprobs son los logprobs de la salida (request con logprobs:true).
+type chatLogprobs struct {
+	Content []struct {
+		Token   string  `json:"token"`
+		Logprob float64 `json:"logprob"`
+	} `json:"content"`
+}
+
+type chatError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// call describe una llamada: el schema de su salida, la temperatura y si pide
+// logprobs.
+type call struct {
+	schema      llm.OutputSchema
+	temperature float64
+	logprobs    bool
+}
+
+// newCall arma una llamada con la temperatura por instancia.
+func (p *Provider) newCall(schema llm.OutputSchema) call {
+	return call{schema: schema, temperature: p.temperature}
+}
+
+// GenerateAssessment pide al modelo un JSON del contrato assessment_import v1.
+func (p *Provider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
+	prompt := p.prompts.Generation(ctx, material, params)
+	out, err := p.complete(ctx, prompt, p.newCall(assessmentSchema))
+	if err != nil {
+		return nil, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return rawJSON, nil
+}
+
+// ReviewAnswer pide al modelo la corrección de una respuesta.
+func (p *Provider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	prompt := p.prompts.Review(ctx, req)
+	c := p.newCall(llm.ReviewSchema)
+	if req.Temperature != nil {
+		c.temperature = *req.Temperature
+	}
+	c.logprobs = p.logprobs
+	out, tokens, err := p.completeScored(ctx, prompt, c)
+	if err != nil {
+		return llm.ReviewResult{}, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	var result llm.ReviewResult
+	if err := json.Unmarshal(rawJSON, &result); err != nil {
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de corrección no parseable: %v", llm.ErrLLMQuality, err)
+	}
+	result.Confidence = llm.VerdictConfidence(tokens)
+	return result, nil
+}
+
+// PrepareQuestion pide al modelo el artefacto de preparación (JSON crudo del
+// contrato llm_prep v1). El caller valida el JSON contra el contrato antes de
+// persistirlo.
+func (p *Provider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
+	prompt := p.prompts.Prep(ctx, req)
+	out, err := p.complete(ctx, prompt, p.newCall(llm.PrepSchema))
+	if err != nil {
+		return nil, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return rawJSON, nil
+}
+
+// JudgePairEquivalence pide la equivalencia binaria de un par. Mismo camino que
+// ReviewAnswer: el resultado es un ReviewResult (verdict/score/feedback).
+func (p *Provider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
+	prompt := p.prompts.PairEquivalence(ctx, req)
+	c := p.newCall(llm.ReviewSchema)
+	c.logprobs = p.logprobs
+	out, tokens, err := p.completeScored(ctx, prompt, c)
+	if err != nil {
+		return llm.ReviewResult{}, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	var result llm.ReviewResult
+	if err := json.Unmarshal(rawJSON, &result); err != nil {
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de equivalencia no parseable: %v", llm.ErrLLMQuality, err)
+	}
+	result.Confidence = llm.VerdictConfidence(tokens)
+	return result, nil
+}
+
+// CheckCriterion pide el cumplimiento binario de un criterio. Mismo camino que
+// ReviewAnswer: el resultado es un ReviewResult (verdict/score/feedback).
+func (p *Provider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	prompt := p.prompts.CriterionCheck(ctx, req)
+	c := p.newCall(llm.ReviewSchema)
+	if req.Temperature != nil {
+		c.temperature = *req.Temperature
+	}
+	c.logprobs = p.logprobs
+	out, tokens, err := p.completeScored(ctx, prompt, c)
+	if err != nil {
+		return llm.ReviewResult{}, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return llm.ReviewResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	var result llm.ReviewResult
+	if err := json.Unmarshal(rawJSON, &result); err != nil {
+		return llm.ReviewResult{}, fmt.Errorf("%w: respuesta de criterio no parseable: %v", llm.ErrLLMQuality, err)
+	}
+	result.Confidence = llm.VerdictConfidence(tokens)
+	return result, nil
+}
+
+// ScoreRelevance puntúa la relevancia 0..1 de una candidata contra las ideas del job
+// (pasada 2 del reduce). Como en Ollama, no está en el puerto llm.LLMProvider: la
+// pasada la consume por una interfaz mínima propia.
+func (p *Provider) ScoreRelevance(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
+	prompt := p.prompts.Relevance(ctx, req)
+	out, err := p.complete(ctx, prompt, p.newCall(llm.RelevanceSchema))
+	if err != nil {
+		return llm.RelevanceResult{}, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return llm.RelevanceResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	result, err := llm.ParseRelevanceResult(rawJSON)
+	if err != nil {
+		return llm.RelevanceResult{}, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return result, nil
+}
+
+// ExtractIdeas descompone la respuesta del alumno en ideas atómicas. Una extracción
+// que no parsea es fallo transitorio (el caller decide el fallback).
+func (p *Provider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
+	prompt := p.prompts.ExtractIdeas(ctx, req)
+	out, err := p.complete(ctx, prompt, p.newCall(llm.ExtractIdeasSchema))
+	if err != nil {
+		return nil, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	ideas, err := llm.ParseExtractedIdeas(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return ideas, nil
+}
+
+// DigestChunk ejecuta la llamada A ("leer") del pipeline material→evaluación en su
+// forma "tarea partida" (digest_split.go), igual que Ollama: el backend sirve los
+// mismos modelos chicos para los que se midió la partición.
+func (p *Provider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	// Override opcional de temperatura (jitter del reintento por calidad); nil =
+	// temperatura por instancia. Aplica a AMBAS mitades.
+	summaryCall := p.newCall(llm.DigestSummarySchema)
+	ideasCall := p.newCall(llm.DigestIdeasSchema)
+	if in.Temperature != nil {
+		summaryCall.temperature = *in.Temperature
+		ideasCall.temperature = *in.Temperature
+	}
+
+	// A1: summary encadenable + tema.
+	outS, err := p.complete(ctx, p.prompts.DigestSummary(ctx, in), summaryCall)
+	if err != nil {
+		// Fallo de transporte/HTTP: es INFRA, sube SIN el sentinel de calidad.
+		return nil, err
+	}
+	rawS, err := llm.ExtractJSON(outS)
+	if err != nil {
+		return nil, fmt.Errorf("%w: digest A1: %v", llm.ErrLLMQuality, err)
+	}
+	summaryPart, err := llm.ParseDigestSummaryPart(rawS)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+
+	// A2: solo las ideas del trozo.
+	outI, err := p.complete(ctx, p.prompts.DigestIdeas(ctx, in), ideasCall)
+	if err != nil {
+		return nil, err
+	}
+	rawI, err := llm.ExtractJSON(outI)
+	if err != nil {
+		return nil, fmt.Errorf("%w: digest A2: %v", llm.ErrLLMQuality, err)
+	}
+	ideasPart, err := llm.ParseDigestIdeasPart(rawI)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+
+	return llm.CombineDigestParts(summaryPart, ideasPart), nil
+}
+
+// ProposeCandidates ejecuta la llamada B ("preguntar") del pipeline. El caller valida
+// cada candidata contra CandidatePayloadV1.
+func (p *Provider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
+	prompt := p.prompts.ProposeCandidates(ctx, in)
+	c := p.newCall(llm.ProposeCandidatesSchema)
+	// Override opcional de temperatura (jitter del reintento por calidad de la fase B).
+	if in.Temperature != nil {
+		c.temperature = *in.Temperature
+	}
+	out, err := p.complete(ctx, prompt, c)
+	if err != nil {
+		// Fallo de transporte/HTTP: INFRA, sube SIN el sentinel de calidad.
+		return nil, err
+	}
+	rawJSON, err := llm.ExtractJSON(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	candidates, err := llm.ParseCandidates(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", llm.ErrLLMQuality, err)
+	}
+	return candidates, nil
+}
+
+// modelsResponse es la respuesta de GET /v1/models.
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// Ping verifica que el servidor responde GET /v1/models y que sirve el modelo
+// configurado. Es la sonda del monitor de disponibilidad.
+func (p *Provider) Ping(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("creating openai-compat models request: %w", err)
+	}
+	p.authorize(httpReq)
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("openai-compat models request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading openai-compat models response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("openai-compat models returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if p.model == "" {
+		return nil
+	}
+
+	var models modelsResponse
+	if err := json.Unmarshal(body, &models); err != nil {
+		return fmt.Errorf("parsing openai-compat models response: %w", err)
+	}
+	for _, m := range models.Data {
+		if m.ID == p.model {
+			return nil
+		}
+	}
+	return fmt.Errorf("openai-compat model %q not served", p.model)
+}
+
+// complete ejecuta POST /v1/chat/completions con el prompt como único mensaje de
+// usuario y devuelve el texto crudo del modelo.
+func (p *Provider) complete(ctx context.Context, prompt string, c call) (string, error) {
+	out, _, err := p.completeScored(ctx, prompt, c)
+	return out, err
+}
+
+// completeScored es complete devolviendo además los logprobs de la salida cuando la
+// llamada los pide (nil si el servidor no los envía).
+func (p *Provider) completeScored(ctx context.Context, prompt string, c call) (string, []llm.TokenLogprob, error) {
+	reqBody := chatRequest{
+		Model:       p.model,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: c.temperature,
+		Stream:      false,
+		Logprobs:    c.logprobs,
+	}
+	switch p.responseFormat {
+	case ResponseFormatJSONObject:
+		reqBody.Format = &responseFormat{Type: ResponseFormatJSONObject}
+	case ResponseFormatJSONSchema:
+		reqBody.Format = &responseFormat{
+			Type:       ResponseFormatJSONSchema,
+			JSONSchema: &jsonSchema{Name: c.schema.Name, Schema: c.schema.Schema, Strict: true},
+		}
+	}
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling openai-compat request: %w", err)
+	}
+
+	url := p.baseURL + "/v1/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", nil, fmt.Errorf("creating openai-compat request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	p.authorize(httpReq)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return "", nil, fmt.Errorf("openai-compat request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading openai-compat response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var cr chatResponse
+		if json.Unmarshal(body, &cr) == nil && cr.Error != nil {
+			return "", nil, fmt.Errorf("openai-compat error (status %d): %s: %s", resp.StatusCode, cr.Error.Type, cr.Error.Message)
+		}
+		return "", nil, fmt.Errorf("openai-compat returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var cr chatResponse
+	if err := json.Unmarshal(body, &cr); err != nil {
+		return "", nil, fmt.Errorf("parsing openai-compat response: %w", err)
+	}
+	if cr.Usage != nil {
+		llm.ReportUsage(ctx, cr.Usage.PromptTokens, cr.Usage.CompletionTokens)
+	}
+	if len(cr.Choices) == 0 {
+		return "", nil, fmt.Errorf("openai-compat devolvió una respuesta sin choices")
+	}
+	var tokens []llm.TokenLogprob
+	if lp := cr.Choices[0].Logprobs; c.logprobs && lp != nil {
+		for _, t := range lp.Content {
+			tokens = append(tokens, llm.TokenLogprob{Token: t.Token, Logprob: t.Logprob})
+		}
+	}
+	return cr.Choices[0].Message.Content, tokens, nil
+}
+
+// authorize agrega el bearer token si se configuró una API key.
+func (p *Provider) authorize(req *http.Request) {
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+}
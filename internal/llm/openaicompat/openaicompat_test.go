@@ -0,0 +1,231 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// fakeServer responde /v1/chat/completions con content y guarda cada body recibido.
+func fakeServer(t *testing.T, content string) (*httptest.Server, func() []map[string]any) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path inesperado: %s", r.URL.Path)
+		}
+		var captured map[string]any
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &captured); err != nil {
+			t.Errorf("body no parseable: %v", err)
+		}
+		mu.Lock()
+		bodies = append(bodies, captured)
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(chatResponse{Choices: []chatChoice{{
+			Message:      chatMessage{Role: "assistant", Content: content},
+			FinishReason: "stop",
+		}}})
+	}))
+	return srv, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+func newProvider(t *testing.T, cfg Config) *Provider {
+	t.Helper()
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New falló: %v", err)
+	}
+	return p
+}
+
+func TestReviewAnswer_OK(t *testing.T) {
+	srv, bodies := fakeServer(t, `{"verdict":"partial","score":0.5,"feedback":"casi"}`)
+	defer srv.Close()
+
+	p := newProvider(t, Config{BaseURL: srv.URL, Model: "gemma-3-4b-it", APIKey: "k"})
+	res, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Verdict != llm.VerdictPartial || res.Score != 0.5 {
+		t.Fatalf("resultado inesperado: %+v", res)
+	}
+
+	body := bodies()[0]
+	if body["model"] != "gemma-3-4b-it" || body["stream"] != false {
+		t.Errorf("request inesperada: %v", body)
+	}
+	if body["temperature"] != float64(0) {
+		t.Errorf("temperature esperada=0, hubo %v", body["temperature"])
+	}
+	format, _ := body["response_format"].(map[string]any)
+	if format["type"] != ResponseFormatJSONObject {
+		t.Errorf("response_format esperado json_object, hubo %v", body["response_format"])
+	}
+	messages, _ := body["messages"].([]any)
+	if len(messages) != 1 || !strings.Contains(messages[0].(map[string]any)["content"].(string), "a") {
+		t.Errorf("mensajes inesperados: %v", messages)
+	}
+}
+
+func TestResponseFormat_JSONSchemaYNone(t *testing.T) {
+	srv, bodies := fakeServer(t, `{"ideas":["una"]}`)
+	defer srv.Close()
+
+	p := newProvider(t, Config{BaseURL: srv.URL, Model: "m", ResponseFormat: ResponseFormatJSONSchema})
+	if _, err := p.ExtractIdeas(context.Background(), llm.ExtractIdeasRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	format, _ := bodies()[0]["response_format"].(map[string]any)
+	schema, _ := format["json_schema"].(map[string]any)
+	if format["type"] != ResponseFormatJSONSchema || schema["name"] != llm.ExtractIdeasSchema.Name || schema["schema"] == nil {
+		t.Errorf("response_format json_schema inesperado: %v", format)
+	}
+
+	p = newProvider(t, Config{BaseURL: srv.URL, Model: "m", ResponseFormat: ResponseFormatNone})
+	if _, err := p.ExtractIdeas(context.Background(), llm.ExtractIdeasRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, ok := bodies()[1]["response_format"]; ok {
+		t.Error("con none no debe enviarse response_format")
+	}
+
+	if _, err := New(Config{ResponseFormat: "grammar"}); err == nil {
+		t.Error("esperaba error por response_format no soportado")
+	}
+}
+
+func TestDigestChunk_JitterDeTemperatura(t *testing.T) {
+	srv, bodies := fakeServer(t, `{"version":1,"summary":"s","chunk_topic":"t","main_ideas":["i"]}`)
+	defer srv.Close()
+
+	p := newProvider(t, Config{BaseURL: srv.URL, Model: "m", Temperature: 0.1})
+	jitter := 0.7
+	res, err := p.DigestChunk(context.Background(), llm.DigestChunkInput{Temperature: &jitter})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Summary != "s" || res.Artifacts.ChunkTopic != "t" || len(res.Artifacts.MainIdeas) != 1 {
+		t.Fatalf("resultado inesperado: %+v", res)
+	}
+	got := bodies()
+	if len(got) != 2 {
+		t.Fatalf("esperaba las dos mitades del digest, hubo %d llamadas", len(got))
+	}
+	for _, body := range got {
+		if body["temperature"] != jitter {
+			t.Errorf("temperature esperada=%v (jitter), hubo %v", jitter, body["temperature"])
+		}
+	}
+}
+
+func TestDigestChunk_SalidaNoParseableEsCalidad(t *testing.T) {
+	srv, _ := fakeServer(t, "sin json")
+	defer srv.Close()
+
+	_, err := newProvider(t, Config{BaseURL: srv.URL, Model: "m"}).DigestChunk(context.Background(), llm.DigestChunkInput{})
+	if !errors.Is(err, llm.ErrLLMQuality) {
+		t.Fatalf("esperaba ErrLLMQuality: %v", err)
+	}
+}
+
+func TestJuicios_SalidaNoParseableEsCalidad(t *testing.T) {
+	for _, out := range []string{"sin json", `{"verdict": 3, "category": 3, "ideas": "x"}`} {
+		srv, _ := fakeServer(t, out)
+		p := newProvider(t, Config{BaseURL: srv.URL, Model: "m"})
+		ctx := context.Background()
+		calls := map[string]func() error{
+			"review_answer": func() error { _, err := p.ReviewAnswer(ctx, llm.ReviewRequest{}); return err },
+			"check_criterion": func() error {
+				_, err := p.CheckCriterion(ctx, llm.CriterionCheckRequest{})
+				return err
+			},
+			"judge_pair_equivalence": func() error {
+				_, err := p.JudgePairEquivalence(ctx, llm.PairEquivalenceRequest{})
+				return err
+			},
+			"extract_ideas":   func() error { _, err := p.ExtractIdeas(ctx, llm.ExtractIdeasRequest{}); return err },
+			"score_relevance": func() error { _, err := p.ScoreRelevance(ctx, llm.RelevanceRequest{}); return err },
+		}
+		for op, call := range calls {
+			if err := call(); !errors.Is(err, llm.ErrLLMQuality) {
+				t.Errorf("%s con %q: esperaba ErrLLMQuality, obtuve %v", op, out, err)
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestProposeCandidates_OK(t *testing.T) {
+	srv, bodies := fakeServer(t, `{"candidates":[{"version":1,"question_type":"multiple_choice","question_text":"¿?"}]}`)
+	defer srv.Close()
+
+	jitter := 0.4
+	candidates, err := newProvider(t, Config{BaseURL: srv.URL, Model: "m"}).
+		ProposeCandidates(context.Background(), llm.ProposeCandidatesInput{Temperature: &jitter})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].QuestionText != "¿?" {
+		t.Fatalf("candidatas inesperadas: %+v", candidates)
+	}
+	if bodies()[0]["temperature"] != jitter {
+		t.Errorf("temperature esperada=%v, hubo %v", jitter, bodies()[0]["temperature"])
+	}
+}
+
+func TestComplete_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer bad" {
+			t.Errorf("Authorization inesperado: %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(chatResponse{Error: &chatError{Type: "invalid_request_error", Message: "invalid api key"}})
+	}))
+	defer srv.Close()
+
+	p := newProvider(t, Config{BaseURL: srv.URL, Model: "m", APIKey: "bad"})
+	_, err := p.GenerateAssessment(context.Background(), llm.MaterialInput{}, llm.GenerationParams{})
+	if err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Fatalf("esperaba error de auth: %v", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := newProvider(t, Config{Model: "abc"}).Name(); got != "openai-compat:abc" {
+		t.Fatalf("Name inesperado: %s", got)
+	}
+}
+
+func TestPing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" {
+			t.Errorf("request inesperada: %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"google/gemma-3-4b-it","object":"model"}]}`))
+	}))
+	defer srv.Close()
+
+	for _, model := range []string{"google/gemma-3-4b-it", ""} {
+		if err := newProvider(t, Config{BaseURL: srv.URL, Model: model}).Ping(context.Background()); err != nil {
+			t.Errorf("modelo %q: error inesperado: %v", model, err)
+		}
+	}
+	if err := newProvider(t, Config{BaseURL: srv.URL, Model: "qwen3"}).Ping(context.Background()); err == nil {
+		t.Error("un modelo no servido debe fallar la sonda")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
 
@@ -406,10 +407,7 @@ var relevanceCategoryScore = map[string]float64{
 // trae una categoría desconocida es error (el caller la trata como malformada: reintenta una
 // vez y, si persiste, NO descarta la candidata).
 func ParseRelevanceResult(raw json.RawMessage) (RelevanceResult, error) {
-	var parsed struct {
-		Category  string `json:"category"`
-		Rationale string `json:"rationale"`
-	}
+	var parsed relevanceOutput
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return RelevanceResult{}, fmt.Errorf("respuesta de relevancia no parseable: %w", err)
 	}
@@ -466,9 +464,7 @@ func BuildExtractIdeasPrompt(req ExtractIdeasRequest) string {
 // lista legítimamente vacía (respuesta sin ideas evaluables) devuelve slice vacío sin
 // error: el caller distingue vacío de fallo.
 func ParseExtractedIdeas(raw json.RawMessage) ([]string, error) {
-	var parsed struct {
-		Ideas []string `json:"ideas"`
-	}
+	var parsed extractedIdeasOutput
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return nil, fmt.Errorf("respuesta de extracción de ideas no parseable: %w", err)
 	}
@@ -655,6 +651,19 @@ func BuildDigestChunkPrompt(in DigestChunkInput) string {
 	return b.String()
 }
 
+// deicticExamplesByLanguage son los ejemplos de referencias al contexto del prompt que
+// la llamada B prohíbe, en el idioma del contenido (la lista completa, por idioma,
+// vive en materialpipeline.DetectDeicticReference).
+var deicticExamplesByLanguage = map[string]string{
+	language.Spanish:    "«según el texto», «según las ideas», «el material», «lo visto»",
+	language.Portuguese: "«segundo o texto», «segundo as ideias», «o material», «o que foi visto»",
+	language.English:    "«according to the text», «according to the ideas», «the material», «as seen above»",
+}
+
+func deicticExamples(lang string) string {
+	return deicticExamplesByLanguage[language.OrDefault(lang)]
+}
+
 // BuildProposeCandidatesPrompt arma el prompt de la llamada B. Recibe SOLO el tema y
 // las ideas del trozo (jamás el texto crudo) y pide {candidates:[…]} con 2–4 preguntas
 // candidatas conformes a CandidatePayloadV1 (misma forma de correct_answer que la
@@ -690,7 +699,9 @@ func BuildProposeCandidatesPrompt(in ProposeCandidatesInput) string {
 	b.WriteString("- Genera preguntas SOLO a partir de las ideas dadas: no introduzcas hechos que no estén en ellas.\n")
 	// Autocontenido (deuda 043): el alumno no comparte el contexto de este prompt; un
 	// enunciado que dice «según las ideas» referencia algo que él jamás verá.
-	b.WriteString("- Cada enunciado debe ser AUTOCONTENIDO: el alumno NO ve estas ideas ni ningún texto. PROHIBIDO escribir «según el texto», «según las ideas», «el material», «lo visto» o similares; si la pregunta necesita un dato, el dato va DENTRO del enunciado.\n\n")
+	// Los ejemplos van en el idioma del contenido: en un material en inglés el modelo
+	// escribiría «according to the text», no «según el texto».
+	fmt.Fprintf(&b, "- Cada enunciado debe ser AUTOCONTENIDO: el alumno NO ve estas ideas ni ningún texto. PROHIBIDO escribir %s o similares; si la pregunta necesita un dato, el dato va DENTRO del enunciado.\n\n", deicticExamples(lang))
 
 	b.WriteString(prepAntiInjection)
 	b.WriteString("\n")
@@ -720,13 +731,9 @@ func BuildProposeCandidatesPrompt(in ProposeCandidatesInput) string {
 // que devolvió el modelo (no fuerza la versión ni limpia ideas): así el validador del
 // caller mide de verdad la salida del modelo. Un JSON que no parsea es fallo transitorio.
 func ParseDigestResult(raw json.RawMessage) (materialpipeline.ChunkArtifactsV1, string, error) {
-	// El summary comparte el objeto de nivel superior con los campos de ChunkArtifactsV1;
-	// se embebe el contrato para que sus json tags (main_ideas, chunk_topic…) promuevan
-	// al nivel de arriba y "summary" quede como campo propio.
-	var combined struct {
-		materialpipeline.ChunkArtifactsV1
-		Summary string `json:"summary"`
-	}
+	// El summary comparte el objeto de nivel superior con los campos de ChunkArtifactsV1
+	// (digestOutput embebe el contrato).
+	var combined digestOutput
 	if err := json.Unmarshal(raw, &combined); err != nil {
 		return materialpipeline.ChunkArtifactsV1{}, "", fmt.Errorf("respuesta de digest de trozo no parseable: %w", err)
 	}
@@ -739,9 +746,7 @@ func ParseDigestResult(raw json.RawMessage) (materialpipeline.ChunkArtifactsV1,
 // no se fuerza) para que ValidateCandidatePayload mida de verdad la salida. Un JSON que
 // no cumple la forma es fallo transitorio.
 func ParseCandidates(raw json.RawMessage) ([]materialpipeline.CandidatePayloadV1, error) {
-	var parsed struct {
-		Candidates []materialpipeline.CandidatePayloadV1 `json:"candidates"`
-	}
+	var parsed candidatesOutput
 	if err := json.Unmarshal(raw, &parsed); err != nil {
 		return nil, fmt.Errorf("respuesta de candidatas no parseable: %w", err)
 	}
@@ -0,0 +1,83 @@
+package prompts
+
+import (
+	"context"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// Un método por builder de internal/llm: mismo request, misma ramificación por tipo de
+// pregunta. Los providers y el cache los llaman en vez del builder para que la versión
+// elegida por el rollout sea la que viaja (y la que se cachea).
+
+// GenerationData es el dato de las plantillas de Generation.
+type GenerationData struct {
+	Material llm.MaterialInput
+	Params   llm.GenerationParams
+}
+
+// Generation es el prompt de GenerateAssessment.
+func (r *Registry) Generation(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) string {
+	return r.render(ctx, Generation, params.Language, GenerationData{Material: material, Params: params}, func() string {
+		return llm.BuildGenerationPrompt(material, params)
+	})
+}
+
+// Review es el prompt de ReviewAnswer (ReviewShortAnswer u ReviewOpenEnded según el
+// tipo de pregunta, como llm.BuildReviewPrompt).
+func (r *Registry) Review(ctx context.Context, req llm.ReviewRequest) string {
+	id := ReviewOpenEnded
+	if req.QuestionType == llm.QuestionTypeShortAnswer {
+		id = ReviewShortAnswer
+	}
+	return r.render(ctx, id, req.Language, req, func() string { return llm.BuildReviewPrompt(req) })
+}
+
+// Prep es el prompt de PrepareQuestion (PrepShortAnswer o PrepOpenEnded).
+func (r *Registry) Prep(ctx context.Context, req llm.PrepRequest) string {
+	id := PrepOpenEnded
+	if req.QuestionType == llm.QuestionTypeShortAnswer {
+		id = PrepShortAnswer
+	}
+	return r.render(ctx, id, req.Language, req, func() string { return llm.BuildPrepPrompt(req) })
+}
+
+// PairEquivalence es el prompt de JudgePairEquivalence.
+func (r *Registry) PairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) string {
+	return r.render(ctx, PairEquivalence, req.Language, req, func() string { return llm.BuildPairEquivalencePrompt(req) })
+}
+
+// CriterionCheck es el prompt de CheckCriterion.
+func (r *Registry) CriterionCheck(ctx context.Context, req llm.CriterionCheckRequest) string {
+	return r.render(ctx, CriterionCheck, req.Language, req, func() string { return llm.BuildCriterionCheckPrompt(req) })
+}
+
+// Relevance es el prompt de ScoreRelevance.
+func (r *Registry) Relevance(ctx context.Context, req llm.RelevanceRequest) string {
+	return r.render(ctx, Relevance, req.Language, req, func() string { return llm.BuildRelevancePrompt(req) })
+}
+
+// ExtractIdeas es el prompt de ExtractIdeas.
+func (r *Registry) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) string {
+	return r.render(ctx, ExtractIdeas, req.Language, req, func() string { return llm.BuildExtractIdeasPrompt(req) })
+}
+
+// DigestChunk es el prompt de la llamada A en una sola llamada (provider api).
+func (r *Registry) DigestChunk(ctx context.Context, in llm.DigestChunkInput) string {
+	return r.render(ctx, DigestChunk, in.Language, in, func() string { return llm.BuildDigestChunkPrompt(in) })
+}
+
+// DigestSummary es el prompt de la llamada A1 (tarea partida).
+func (r *Registry) DigestSummary(ctx context.Context, in llm.DigestChunkInput) string {
+	return r.render(ctx, DigestSummary, in.Language, in, func() string { return llm.BuildDigestSummaryPrompt(in) })
+}
+
+// DigestIdeas es el prompt de la llamada A2 (tarea partida).
+func (r *Registry) DigestIdeas(ctx context.Context, in llm.DigestChunkInput) string {
+	return r.render(ctx, DigestIdeas, in.Language, in, func() string { return llm.BuildDigestIdeasPrompt(in) })
+}
+
+// ProposeCandidates es el prompt de la llamada B.
+func (r *Registry) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) string {
+	return r.render(ctx, ProposeCandidates, in.Language, in, func() string { return llm.BuildProposeCandidatesPrompt(in) })
+}
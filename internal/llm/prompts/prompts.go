@@ -0,0 +1,294 @@
+// Package prompts resuelve el texto de cada prompt del worker por versión. La versión
+// "builtin" de cada prompt es el builder en Go de internal/llm (prompt.go,
+// digest_split.go): siempre existe y es el default. Las demás versiones son plantillas
+// text/template —embebidas en templates/<id>/<version>.tmpl o cargadas de un
+// directorio de config con la misma forma— que reciben como dato el request de la
+// operación (llm.ReviewRequest, llm.DigestChunkInput…).
+//
+// Una versión puede traer variantes por idioma (<version>.<idioma>.tmpl, ej.
+// v1.en.tmpl) con las instrucciones traducidas: la llamada usa la variante del idioma
+// del request y, si no la hay, la plantilla base. Los builders en Go escriben las
+// instrucciones en español y piden la salida en el idioma del request (directiva
+// IDIOMA); localizar un prompt es darle una versión con variantes.
+//
+// Qué versión recibe una llamada lo decide el rollout del prompt según la escuela del
+// contexto (llm.WithSchool): las escuelas de la lista y un porcentaje estable del resto
+// reciben la versión candidata; las demás, la versión por defecto. Así un prompt nuevo
+// de revisión se prueba en unas pocas escuelas antes de hacerse global. La versión
+// usada queda en el log (Debug) y en la métrica de la llamada (llm.ReportPrompt →
+// decorador de instrumentación).
+//
+// Un Registry nil sirve siempre los builtin: los providers construidos sin registry
+// (tests, harness) no cambian.
+package prompts
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// ID identifica un prompt.
+type ID string
+
+// Prompts del worker. Review y prep ramifican por tipo de pregunta: cada rama es un
+// prompt propio.
+const (
+	Generation        ID = "generation"
+	ReviewShortAnswer ID = "review_short_answer"
+	ReviewOpenEnded   ID = "review_open_ended"
+	PairEquivalence   ID = "pair_equivalence"
+	CriterionCheck    ID = "criterion_check"
+	Relevance         ID = "relevance"
+	ExtractIdeas      ID = "extract_ideas"
+	PrepShortAnswer   ID = "prep_short_answer"
+	PrepOpenEnded     ID = "prep_open_ended"
+	DigestChunk       ID = "digest_chunk"
+	DigestSummary     ID = "digest_summary"
+	DigestIdeas       ID = "digest_ideas"
+	ProposeCandidates ID = "propose_candidates"
+)
+
+// Builtin es la versión implementada por los builders en Go.
+const Builtin = "builtin"
+
+var knownIDs = []ID{
+	Generation, ReviewShortAnswer, ReviewOpenEnded, PairEquivalence, CriterionCheck,
+	Relevance, ExtractIdeas, PrepShortAnswer, PrepOpenEnded, DigestChunk, DigestSummary,
+	DigestIdeas, ProposeCandidates,
+}
+
+//go:embed templates
+var embedded embed.FS
+
+// Rollout reparte una versión candidata de un prompt entre escuelas.
+type Rollout struct {
+	// Version es la versión candidata.
+	Version string
+	// Schools reciben siempre la candidata.
+	Schools []string
+	// Percent (0-100) es la fracción del resto de escuelas que la recibe. El reparto es
+	// estable (hash de prompt+escuela): una escuela no cambia de versión entre llamadas.
+	Percent int
+}
+
+// Config configura un Registry.
+type Config struct {
+	// Dir es un directorio con plantillas <id>/<version>.tmpl (y sus variantes
+	// <id>/<version>.<idioma>.tmpl) que se suman a las embebidas (una plantilla con el
+	// mismo nombre reemplaza a la embebida). Opcional.
+	Dir string
+	// Defaults es la versión por defecto de cada prompt (ausente = Builtin).
+	Defaults map[ID]string
+	// Rollouts son las versiones candidatas por prompt.
+	Rollouts map[ID]Rollout
+	// Logger registra la versión de cada prompt servido (Debug) y los fallos de
+	// plantilla. Opcional.
+	Logger logger.Logger
+}
+
+// Registry sirve los prompts por versión. Seguro para uso concurrente (inmutable tras
+// New).
+type Registry struct {
+	cfg       Config
+	templates map[ID]map[string]*localized
+}
+
+// localized es una versión de un prompt: la plantilla base y sus variantes por idioma.
+type localized struct {
+	base   *template.Template
+	byLang map[string]*template.Template
+}
+
+// For es la plantilla para lang: su variante o, si no la hay, la base.
+func (l *localized) For(lang string) *template.Template {
+	if tmpl, ok := l.byLang[language.OrDefault(lang)]; ok {
+		return tmpl
+	}
+	return l.base
+}
+
+// New carga las plantillas y valida que cada versión referenciada exista.
+func New(cfg Config) (*Registry, error) {
+	r := &Registry{cfg: cfg, templates: make(map[ID]map[string]*localized)}
+	if err := r.load(embedded, "templates"); err != nil {
+		return nil, err
+	}
+	if cfg.Dir != "" {
+		if err := r.load(os.DirFS(cfg.Dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	for id, versions := range r.templates {
+		for version, l := range versions {
+			if l.base == nil {
+				return nil, fmt.Errorf("prompt %s: la versión %q tiene variantes por idioma pero no plantilla base", id, version)
+			}
+		}
+	}
+	for id, version := range cfg.Defaults {
+		if err := r.check(id, version); err != nil {
+			return nil, fmt.Errorf("versión por defecto: %w", err)
+		}
+	}
+	for id, rollout := range cfg.Rollouts {
+		if err := r.check(id, rollout.Version); err != nil {
+			return nil, fmt.Errorf("rollout: %w", err)
+		}
+		if rollout.Percent < 0 || rollout.Percent > 100 {
+			return nil, fmt.Errorf("rollout de %s: percent %d fuera de 0-100", id, rollout.Percent)
+		}
+	}
+	return r, nil
+}
+
+// load agrega las plantillas <id>/<version>.tmpl y <id>/<version>.<idioma>.tmpl bajo
+// root.
+func (r *Registry) load(fsys fs.FS, root string) error {
+	files, err := fs.Glob(fsys, path.Join(root, "*", "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("listando plantillas de prompts: %w", err)
+	}
+	for _, file := range files {
+		id := ID(path.Base(path.Dir(file)))
+		version, lang, _ := strings.Cut(strings.TrimSuffix(path.Base(file), ".tmpl"), ".")
+		if !slices.Contains(knownIDs, id) {
+			return fmt.Errorf("plantilla %s: prompt desconocido %q", file, id)
+		}
+		if version == Builtin {
+			return fmt.Errorf("plantilla %s: la versión %q está reservada al builder en Go", file, Builtin)
+		}
+		if lang != "" && !slices.Contains(language.Supported, lang) {
+			return fmt.Errorf("plantilla %s: idioma %q no soportado (hay: %s)", file, lang, strings.Join(language.Supported, ", "))
+		}
+		raw, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("leyendo plantilla %s: %w", file, err)
+		}
+		tmpl, err := template.New(string(id) + "/" + strings.TrimSuffix(path.Base(file), ".tmpl")).Funcs(funcs).Option("missingkey=error").Parse(string(raw))
+		if err != nil {
+			return fmt.Errorf("parseando plantilla %s: %w", file, err)
+		}
+		if r.templates[id] == nil {
+			r.templates[id] = make(map[string]*localized)
+		}
+		l := r.templates[id][version]
+		if l == nil {
+			l = &localized{byLang: make(map[string]*template.Template)}
+			r.templates[id][version] = l
+		}
+		if lang == "" {
+			l.base = tmpl
+		} else {
+			l.byLang[lang] = tmpl
+		}
+	}
+	return nil
+}
+
+func (r *Registry) check(id ID, version string) error {
+	if !slices.Contains(knownIDs, id) {
+		return fmt.Errorf("prompt desconocido %q", id)
+	}
+	if version == Builtin {
+		return nil
+	}
+	if _, ok := r.templates[id][version]; !ok {
+		return fmt.Errorf("prompt %s: versión %q inexistente (hay: %s)", id, version, strings.Join(r.Versions(id), ", "))
+	}
+	return nil
+}
+
+// Versions devuelve las versiones disponibles de id (Builtin primero).
+func (r *Registry) Versions(id ID) []string {
+	versions := []string{Builtin}
+	if r == nil {
+		return versions
+	}
+	var loaded []string
+	for version := range r.templates[id] {
+		loaded = append(loaded, version)
+	}
+	sort.Strings(loaded)
+	return append(versions, loaded...)
+}
+
+// Version es la versión de id que recibe school.
+func (r *Registry) Version(id ID, school string) string {
+	if r == nil {
+		return Builtin
+	}
+	if rollout, ok := r.cfg.Rollouts[id]; ok && school != "" {
+		if slices.Contains(rollout.Schools, school) || bucket(id, school) < rollout.Percent {
+			return rollout.Version
+		}
+	}
+	if version, ok := r.cfg.Defaults[id]; ok {
+		return version
+	}
+	return Builtin
+}
+
+// bucket ubica a school en 0-99 para el rollout de id.
+func bucket(id ID, school string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(school))
+	return int(h.Sum32() % 100)
+}
+
+// render devuelve el prompt id para ctx: la plantilla de la versión elegida (en la
+// variante de lang si la hay) con data o, para Builtin, el builder. Una plantilla que falla al ejecutarse cae al builtin
+// (la llamada no se pierde por un error de plantilla) y queda en el log.
+func (r *Registry) render(ctx context.Context, id ID, lang string, data any, builtin func() string) string {
+	school := llm.SchoolFrom(ctx)
+	version := r.Version(id, school)
+	text := ""
+	if version != Builtin {
+		var b strings.Builder
+		if err := r.templates[id][version].For(lang).Execute(&b, data); err != nil {
+			if r.cfg.Logger != nil {
+				r.cfg.Logger.Error("plantilla de prompt falló, se usa el builtin",
+					"prompt", string(id), "version", version, "error", err.Error())
+			}
+			version = Builtin
+		} else {
+			text = b.String()
+		}
+	}
+	if version == Builtin {
+		text = builtin()
+	}
+	llm.ReportPrompt(ctx, string(id), version)
+	if r != nil && r.cfg.Logger != nil {
+		r.cfg.Logger.Debug("prompt LLM", "prompt", string(id), "version", version, "language", language.OrDefault(lang), "school_id", school)
+	}
+	return text
+}
+
+// funcs son las funciones disponibles en las plantillas.
+var funcs = template.FuncMap{
+	// lang devuelve el idioma o "es" si viene vacío (el default de los builders).
+	"lang": func(lang string) string {
+		if lang == "" {
+			return "es"
+		}
+		return lang
+	},
+	"trim": strings.TrimSpace,
+	// quote cita como %q (el formato del idioma en los builders).
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+}
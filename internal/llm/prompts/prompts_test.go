@@ -0,0 +1,210 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// writeTemplate deja la plantilla id/version en dir.
+func writeTemplate(t *testing.T, dir string, id ID, version, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, string(id)), 0o755); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, string(id), version+".tmpl"), []byte(text), 0o644); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+}
+
+func TestPlantillasEmbebidas_IgualesAlBuilder(t *testing.T) {
+	// Las v1 embebidas son el port a plantilla de los builders: el mismo texto exacto,
+	// punto de partida de las versiones candidatas. En español: en/pt usan su variante.
+	r, err := New(Config{Defaults: map[ID]string{ReviewOpenEnded: "v1", ReviewShortAnswer: "v1"}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	for name, req := range map[string]llm.ReviewRequest{
+		"open_ended mínimo": {QuestionText: "¿Qué es la fotosíntesis?", StudentAnswer: "algo"},
+		"open_ended completo": {
+			QuestionText: "q", ExpectedAnswer: "e", Rubric: "r", StudentAnswer: "a", Language: "es",
+			Prep: &llm.ReviewPrep{
+				QuestionIntent: " intención ",
+				MainIdeas:      []string{"idea 1", " ", "idea 2"},
+				SecondaryIdeas: []string{"detalle"},
+				ValidVariants:  []string{"variante"},
+			},
+		},
+		"open_ended prep sin ideas": {QuestionText: "q", StudentAnswer: "a", Prep: &llm.ReviewPrep{ValidVariants: []string{"v"}}},
+		"short_answer": {
+			QuestionType: llm.QuestionTypeShortAnswer, QuestionText: "¿Capital de Chile?",
+			ExpectedAnswer: "Santiago", StudentAnswer: "santiago de chile",
+		},
+	} {
+		if got, want := r.Review(context.Background(), req), llm.BuildReviewPrompt(req); got != want {
+			t.Errorf("%s: la plantilla difiere del builder\n--- plantilla ---\n%s\n--- builder ---\n%s", name, got, want)
+		}
+	}
+}
+
+func TestPlantillasEmbebidas_VarianteDelIdioma(t *testing.T) {
+	r, err := New(Config{Defaults: map[ID]string{ReviewOpenEnded: "v1", ReviewShortAnswer: "v1"}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	for _, tc := range []struct {
+		name, questionType, lang, want string
+	}{
+		{"open_ended en", "", "en", "You are a strict and fair educational grader."},
+		{"open_ended pt-BR", "", "pt-BR", "Você é um avaliador educativo rigoroso e justo."},
+		{"short_answer en", llm.QuestionTypeShortAnswer, "EN", "SECOND OPINION"},
+		{"short_answer pt", llm.QuestionTypeShortAnswer, "pt", "SEGUNDA OPINIÃO"},
+		{"idioma sin variante", "", "fr", "Eres un evaluador educativo estricto y justo."},
+	} {
+		req := llm.ReviewRequest{
+			QuestionType: tc.questionType, QuestionText: "q", ExpectedAnswer: "e", StudentAnswer: "a", Language: tc.lang,
+		}
+		got := r.Review(context.Background(), req)
+		if !strings.Contains(got, tc.want) {
+			t.Errorf("%s: esperaba %q en el prompt:\n%s", tc.name, tc.want, got)
+		}
+		if !strings.Contains(got, fmt.Sprintf("%q", tc.lang)) {
+			t.Errorf("%s: el feedback debe pedirse en %q", tc.name, tc.lang)
+		}
+	}
+}
+
+func TestRegistry_VarianteDeIdiomaDesdeDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, ExtractIdeas, "v2", "base {{.StudentAnswer}}")
+	writeTemplate(t, dir, ExtractIdeas, "v2.pt", "pt {{.StudentAnswer}}")
+	r, err := New(Config{Dir: dir, Defaults: map[ID]string{ExtractIdeas: "v2"}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if got := r.ExtractIdeas(context.Background(), llm.ExtractIdeasRequest{StudentAnswer: "a", Language: "pt_PT"}); got != "pt a" {
+		t.Errorf("pt debía usar su variante: %q", got)
+	}
+	if got := r.ExtractIdeas(context.Background(), llm.ExtractIdeasRequest{StudentAnswer: "a", Language: "en"}); got != "base a" {
+		t.Errorf("sin variante se usa la base: %q", got)
+	}
+	if got := r.Versions(ExtractIdeas); len(got) != 2 {
+		t.Errorf("las variantes no son versiones propias: %v", got)
+	}
+}
+
+func TestRegistry_RolloutPorEscuela(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, ReviewOpenEnded, "v2", "candidata {{.QuestionText}}")
+	r, err := New(Config{Dir: dir, Rollouts: map[ID]Rollout{
+		ReviewOpenEnded: {Version: "v2", Schools: []string{"school-piloto"}},
+	}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	req := llm.ReviewRequest{QuestionText: "q", StudentAnswer: "a"}
+
+	ctx, usage := llm.WithUsage(llm.WithSchool(context.Background(), "school-piloto"))
+	if got := r.Review(ctx, req); got != "candidata q" {
+		t.Fatalf("la escuela piloto debía recibir v2: %q", got)
+	}
+	if got := usage.Prompts()[string(ReviewOpenEnded)]; got != "v2" {
+		t.Errorf("versión reportada %q, esperaba v2", got)
+	}
+
+	ctx, usage = llm.WithUsage(llm.WithSchool(context.Background(), "otra"))
+	if got := r.Review(ctx, req); got != llm.BuildReviewPrompt(req) {
+		t.Fatalf("el resto de escuelas sigue en el builtin: %q", got)
+	}
+	if got := usage.Prompts()[string(ReviewOpenEnded)]; got != Builtin {
+		t.Errorf("versión reportada %q, esperaba %s", got, Builtin)
+	}
+	// Otro prompt no participa del rollout.
+	if got := r.Version(ReviewShortAnswer, "school-piloto"); got != Builtin {
+		t.Errorf("short_answer no tiene rollout: %q", got)
+	}
+}
+
+func TestRegistry_RolloutPorcentaje(t *testing.T) {
+	r, err := New(Config{Rollouts: map[ID]Rollout{ReviewOpenEnded: {Version: "v1", Percent: 30}}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	inRollout := 0
+	for i := range 1000 {
+		school := fmt.Sprintf("school-%d", i)
+		version := r.Version(ReviewOpenEnded, school)
+		if version != r.Version(ReviewOpenEnded, school) {
+			t.Fatal("el reparto debe ser estable por escuela")
+		}
+		if version == "v1" {
+			inRollout++
+		}
+	}
+	if inRollout < 200 || inRollout > 400 {
+		t.Errorf("con percent 30 esperaba ~300 de 1000 escuelas, hubo %d", inRollout)
+	}
+	if got := r.Version(ReviewOpenEnded, ""); got != Builtin {
+		t.Errorf("sin escuela no hay rollout: %q", got)
+	}
+}
+
+func TestNew_ValidaVersiones(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"versión inexistente": {Rollouts: map[ID]Rollout{ReviewOpenEnded: {Version: "v9"}}},
+		"prompt desconocido":  {Defaults: map[ID]string{"review": "v1"}},
+		"percent inválido":    {Rollouts: map[ID]Rollout{ReviewOpenEnded: {Version: "v1", Percent: 101}}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%s: esperaba error", name)
+		}
+	}
+
+	dir := t.TempDir()
+	writeTemplate(t, dir, "no_existe", "v1", "x")
+	if _, err := New(Config{Dir: dir}); err == nil {
+		t.Error("una plantilla de un prompt desconocido debe fallar al cargar")
+	}
+
+	dir = t.TempDir()
+	writeTemplate(t, dir, ExtractIdeas, "v2.fr", "x")
+	if _, err := New(Config{Dir: dir}); err == nil {
+		t.Error("una variante de un idioma no soportado debe fallar al cargar")
+	}
+
+	dir = t.TempDir()
+	writeTemplate(t, dir, ExtractIdeas, "v2.en", "x")
+	if _, err := New(Config{Dir: dir}); err == nil {
+		t.Error("una variante sin plantilla base debe fallar al cargar")
+	}
+}
+
+func TestRegistry_PlantillaQueFallaUsaElBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, ExtractIdeas, "v2", "{{.NoExiste}}")
+	r, err := New(Config{Dir: dir, Defaults: map[ID]string{ExtractIdeas: "v2"}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	req := llm.ExtractIdeasRequest{StudentAnswer: "a"}
+	ctx, usage := llm.WithUsage(context.Background())
+	if got := r.ExtractIdeas(ctx, req); got != llm.BuildExtractIdeasPrompt(req) {
+		t.Fatalf("esperaba el builtin: %q", got)
+	}
+	if got := usage.Prompts()[string(ExtractIdeas)]; got != Builtin {
+		t.Errorf("la versión reportada debe ser la servida: %q", got)
+	}
+}
+
+func TestRegistryNil_SirveBuiltin(t *testing.T) {
+	var r *Registry
+	in := llm.DigestChunkInput{ChunkText: "trozo"}
+	if got := r.DigestSummary(context.Background(), in); got != llm.BuildDigestSummaryPrompt(in) {
+		t.Fatalf("un registry nil debe servir el builder: %q", got)
+	}
+}
@@ -73,6 +73,11 @@ type ReviewRequest struct {
 	// variantes válidas. Sin él, el prompt es el actual (fallback). No aplica al
 	// carril de criterios (F4b lo reemplaza por completo).
 	Prep *ReviewPrep
+
+	// Temperature, si != nil, fuerza la temperatura del muestreo SOLO en esta llamada
+	// (las muestras de la revisión por votación, internal/llm/vote). nil = el provider
+	// usa su temperatura configurada.
+	Temperature *float64
 }
 
 // ReviewPrep son las pistas del artefacto llm_prep (open_ended) que enriquecen el
@@ -102,9 +107,14 @@ const (
 // ReviewResult es el resultado de corregir una respuesta. Score es 0..1
 // (fracción del puntaje) para que el consumidor (040) lo escale al puntaje real.
 type ReviewResult struct {
-	Verdict  Verdict `json:"verdict"`
-	Score    float64 `json:"score"`
+	Verdict  Verdict `json:"verdict" jsonschema:"enum=correct|partial|incorrect"`
+	Score    float64 `json:"score" jsonschema:"minimum=0,maximum=1"`
 	Feedback string  `json:"feedback"`
+	// Confidence (0..1) es cuán firme es el veredicto: la probabilidad del token del
+	// veredicto (logprobs), el agreement de la votación o el mínimo de los juicios de
+	// un carril compuesto. nil = sin señal. No es parte de la salida del modelo (fuera
+	// del schema); el cache la guarda aparte.
+	Confidence *float64 `json:"-"`
 }
 
 // PrepRequest es la petición de PREPARACIÓN de una pregunta para el LLM (plan 042
@@ -166,6 +176,9 @@ type CriterionCheckRequest struct {
 	ExtractedIdeas []string
 	// Language del feedback (default "es").
 	Language string
+	// Temperature, si != nil, fuerza la temperatura del muestreo SOLO en esta llamada
+	// (como ReviewRequest.Temperature).
+	Temperature *float64
 }
 
 // ExtractIdeasRequest es la petición de EXTRACCIÓN DE IDEAS de la respuesta del alumno
@@ -183,6 +196,15 @@ type ExtractIdeasRequest struct {
 	Language string
 }
 
+// RelevanceScorer puntúa la relevancia de una candidata (pasada 2 del reduce,
+// D-044.3). No está en LLMProvider (es propio del carril de materiales): los providers
+// concretos lo implementan y los decoradores lo reexponen con WithRelevance, solo si
+// el provider decorado lo tiene, para que el assert de bootstrap sobre el provider ya
+// decorado diga la verdad.
+type RelevanceScorer interface {
+	ScoreRelevance(ctx context.Context, req RelevanceRequest) (RelevanceResult, error)
+}
+
 // RelevanceRequest es la petición de RELEVANCIA de UNA candidata contra las ideas del
 // job (plan 044 F2a, pasada 2 del reduce, D-044.3): el modelo puntúa qué tan central es
 // la pregunta respecto a las ideas principales agregadas del material. UNA llamada por
@@ -0,0 +1,204 @@
+// Package resilience protege a los backends LLM con un circuit breaker por backend y
+// un bulkhead (tope de llamadas en vuelo). Los tres carriles comparten un mismo Ollama:
+// sin tope, un job de materiales con muchos trozos lo satura y las revisiones que un
+// alumno está esperando quedan detrás; sin breaker, un backend caído recibe cada
+// llamada hasta su timeout.
+//
+// El breaker cuenta solo fallos de infraestructura: un llm.ErrLLMQuality es una
+// respuesta del modelo (el backend está vivo) y una cancelación es del caller. Con el
+// breaker abierto las llamadas fallan al instante con circuitbreaker.ErrCircuitOpen.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/circuitbreaker"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// bulkOps son las operaciones del carril de materiales: largas y en lote (un job
+// dispara decenas). Compiten por el cupo de lote del Bulkhead; el resto son llamadas
+// que un usuario espera (revisión, preparación). JudgePairEquivalence la usan los dos
+// lados (short answer y dedupe) y cuenta como interactiva.
+var bulkOps = map[string]bool{
+	"generate_assessment": true,
+	"digest_chunk":        true,
+	"propose_candidates":  true,
+	"score_relevance":     true,
+	"embed":               true,
+}
+
+// Bulkhead acota las llamadas en vuelo contra un backend. Las operaciones de lote
+// tienen además su propio tope, menor: siempre quedan cupos para las interactivas.
+// Se comparte entre los decoradores que pegan al mismo host.
+type Bulkhead struct {
+	all  chan struct{}
+	bulk chan struct{}
+}
+
+// NewBulkhead construye el bulkhead. maxInFlight <= 0 no acota; maxBulkInFlight <= 0
+// (o >= maxInFlight) deja al lote solo con el tope general.
+func NewBulkhead(maxInFlight, maxBulkInFlight int) *Bulkhead {
+	b := &Bulkhead{}
+	if maxInFlight > 0 {
+		b.all = make(chan struct{}, maxInFlight)
+	}
+	if maxBulkInFlight > 0 && (maxInFlight <= 0 || maxBulkInFlight < maxInFlight) {
+		b.bulk = make(chan struct{}, maxBulkInFlight)
+	}
+	return b
+}
+
+// acquire espera un cupo para op (primero el de lote, si aplica) o a que ctx termine.
+// release devuelve los cupos tomados.
+func (b *Bulkhead) acquire(ctx context.Context, op string) (release func(), err error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	var taken []chan struct{}
+	release = func() {
+		for _, sem := range taken {
+			<-sem
+		}
+	}
+	for _, sem := range []chan struct{}{b.bulkFor(op), b.all} {
+		if sem == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+			taken = append(taken, sem)
+		case <-ctx.Done():
+			release()
+			return nil, fmt.Errorf("esperando cupo LLM para %s: %w", op, ctx.Err())
+		}
+	}
+	return release, nil
+}
+
+func (b *Bulkhead) bulkFor(op string) chan struct{} {
+	if bulkOps[op] {
+		return b.bulk
+	}
+	return nil
+}
+
+// Config parametriza el breaker de un Guard.
+type Config struct {
+	// Name identifica al breaker (label service de las métricas: llm_local, llm_api,
+	// llm_embed).
+	Name string
+	// MaxFailures son los fallos de infraestructura consecutivos que lo abren.
+	MaxFailures int
+	// OpenTimeout es lo que queda abierto antes de dejar pasar una llamada de prueba.
+	OpenTimeout time.Duration
+}
+
+// Guard es el breaker más el bulkhead de un backend.
+type Guard struct {
+	name     string
+	breaker  *circuitbreaker.CircuitBreaker
+	bulkhead *Bulkhead
+
+	mu    sync.Mutex
+	state circuitbreaker.State
+}
+
+// NewGuard construye el guard. bulkhead puede ser nil (sin tope) o compartido.
+func NewGuard(cfg Config, bulkhead *Bulkhead) *Guard {
+	cbCfg := circuitbreaker.DefaultConfig(cfg.Name)
+	if cfg.MaxFailures > 0 {
+		cbCfg.MaxFailures = uint32(cfg.MaxFailures)
+	}
+	if cfg.OpenTimeout > 0 {
+		cbCfg.Timeout = cfg.OpenTimeout
+	}
+	g := &Guard{
+		name:     cfg.Name,
+		breaker:  circuitbreaker.New(cbCfg),
+		bulkhead: bulkhead,
+		state:    circuitbreaker.StateClosed,
+	}
+	metrics.SetCircuitBreakerState(g.name, stateValue(g.state))
+	return g
+}
+
+// State devuelve el estado actual del breaker.
+func (g *Guard) State() circuitbreaker.State { return g.breaker.State() }
+
+// do ejecuta call con un cupo del bulkhead y a través del breaker. Devuelve el error
+// de call sin tocar; si el breaker la rechaza, ErrCircuitOpen (o ErrTooManyRequests en
+// half-open) envuelto con el nombre del guard. El cupo se toma FUERA del breaker: una
+// espera que vence no llegó al backend y no debe contar como éxito (reiniciaría el
+// conteo de fallos o cerraría un half-open sin probar nada).
+func (g *Guard) do(ctx context.Context, op string, call func(ctx context.Context) error) error {
+	release, err := g.bulkhead.acquire(ctx, op)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var callErr error
+	err = g.breaker.Execute(ctx, func(ctx context.Context) error {
+		callErr = call(ctx)
+		if isInfraFailure(callErr) {
+			return callErr
+		}
+		return nil
+	})
+	g.report()
+	if callErr != nil {
+		return callErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", g.name, err)
+	}
+	return nil
+}
+
+// report publica el estado del breaker y sus transiciones.
+func (g *Guard) report() {
+	state := g.breaker.State()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if state == g.state {
+		return
+	}
+	metrics.RecordCircuitBreakerTransition(g.name, stateName(g.state), stateName(state))
+	metrics.SetCircuitBreakerState(g.name, stateValue(state))
+	g.state = state
+}
+
+// isInfraFailure indica si err cuenta para abrir el breaker.
+func isInfraFailure(err error) bool {
+	return err != nil && !errors.Is(err, llm.ErrLLMQuality) && !errors.Is(err, context.Canceled)
+}
+
+// stateValue es el valor del gauge worker_circuit_breaker_state (0=closed,
+// 1=half-open, 2=open).
+func stateValue(s circuitbreaker.State) int {
+	switch s {
+	case circuitbreaker.StateHalfOpen:
+		return 1
+	case circuitbreaker.StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
+
+func stateName(s circuitbreaker.State) string {
+	switch s {
+	case circuitbreaker.StateHalfOpen:
+		return "half_open"
+	case circuitbreaker.StateOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
@@ -0,0 +1,157 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/circuitbreaker"
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeProvider devuelve err en ReviewAnswer y bloquea DigestChunk hasta que se cierre
+// release (si no es nil). Las operaciones no sobrescritas entran en pánico.
+type fakeProvider struct {
+	llm.LLMProvider
+	calls   int
+	err     error
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *fakeProvider) Name() string { return "fake:m" }
+
+func (p *fakeProvider) ReviewAnswer(_ context.Context, _ llm.ReviewRequest) (llm.ReviewResult, error) {
+	p.calls++
+	return llm.ReviewResult{Verdict: llm.VerdictCorrect}, p.err
+}
+
+func (p *fakeProvider) DigestChunk(_ context.Context, _ llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	if p.started != nil {
+		p.started <- struct{}{}
+	}
+	if p.release != nil {
+		<-p.release
+	}
+	return &llm.DigestChunkResult{Summary: "s"}, nil
+}
+
+func gaugeValue(t *testing.T, service string) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := metrics.CircuitBreakerState.WithLabelValues(service).Write(m); err != nil {
+		t.Fatalf("leyendo gauge: %v", err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestGuardedProvider_FallosDeInfraAbrenElBreaker(t *testing.T) {
+	inner := &fakeProvider{err: errors.New("connection refused")}
+	p := NewProvider(inner, NewGuard(Config{Name: "llm_test_open", MaxFailures: 2, OpenTimeout: time.Hour}, nil))
+
+	for range 2 {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err == nil || errors.Is(err, circuitbreaker.ErrCircuitOpen) {
+			t.Fatalf("esperaba el error del backend: %v", err)
+		}
+	}
+	_, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{})
+	if !errors.Is(err, circuitbreaker.ErrCircuitOpen) {
+		t.Fatalf("con el breaker abierto esperaba ErrCircuitOpen: %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("con el breaker abierto la llamada no debe llegar al backend: calls=%d", inner.calls)
+	}
+	if got := gaugeValue(t, "llm_test_open"); got != 2 {
+		t.Errorf("gauge del breaker=%v, esperaba 2 (open)", got)
+	}
+}
+
+func TestGuardedProvider_FallosDeCalidadNoAbren(t *testing.T) {
+	inner := &fakeProvider{err: fmt.Errorf("%w: verdict inválido", llm.ErrLLMQuality)}
+	g := NewGuard(Config{Name: "llm_test_quality", MaxFailures: 1, OpenTimeout: time.Hour}, nil)
+	p := NewProvider(inner, g)
+
+	for range 3 {
+		if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); !errors.Is(err, llm.ErrLLMQuality) {
+			t.Fatalf("el error de calidad debe pasar sin cambios: %v", err)
+		}
+	}
+	if g.State() != circuitbreaker.StateClosed || inner.calls != 3 {
+		t.Fatalf("un fallo de calidad no debe abrir el breaker: state=%v calls=%d", g.State(), inner.calls)
+	}
+}
+
+func TestBulkhead_ElLoteNoTomaTodosLosCupos(t *testing.T) {
+	inner := &fakeProvider{started: make(chan struct{}, 1), release: make(chan struct{})}
+	p := NewProvider(inner, NewGuard(Config{Name: "llm_test_bulkhead"}, NewBulkhead(2, 1)))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.DigestChunk(context.Background(), llm.DigestChunkInput{})
+		done <- err
+	}()
+	<-inner.started
+
+	// Un segundo trozo espera su cupo de lote hasta que vence el contexto.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.DigestChunk(ctx, llm.DigestChunkInput{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("el segundo trozo debía esperar cupo: %v", err)
+	}
+
+	// Una revisión entra por el cupo general que el lote no puede tomar.
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err != nil {
+		t.Fatalf("la revisión no debía esperar al lote: %v", err)
+	}
+
+	close(inner.release)
+	if err := <-done; err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if _, err := p.DigestChunk(context.Background(), llm.DigestChunkInput{}); err != nil {
+		t.Fatalf("con el cupo liberado el trozo debía pasar: %v", err)
+	}
+}
+
+func TestBulkhead_EsperaVencidaNoCuentaComoExito(t *testing.T) {
+	inner := &fakeProvider{err: errors.New("connection refused"), started: make(chan struct{}, 1), release: make(chan struct{})}
+	g := NewGuard(Config{Name: "llm_test_wait", MaxFailures: 2, OpenTimeout: time.Hour}, NewBulkhead(2, 1))
+	p := NewProvider(inner, g)
+
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err == nil {
+		t.Fatal("esperaba el error del backend")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = p.DigestChunk(context.Background(), llm.DigestChunkInput{})
+	}()
+	<-inner.started
+
+	// Un trozo que no consigue cupo de lote no llegó al backend: no reinicia el conteo.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.DigestChunk(ctx, llm.DigestChunkInput{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("el trozo debía esperar cupo: %v", err)
+	}
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err == nil {
+		t.Fatal("esperaba el error del backend")
+	}
+	if g.State() != circuitbreaker.StateOpen {
+		t.Errorf("dos fallos consecutivos del backend debían abrir el breaker: state=%v", g.State())
+	}
+
+	close(inner.release)
+	<-done
+}
+
+func TestGuardedProvider_ScoreRelevanceNoSoportado(t *testing.T) {
+	p := NewProvider(&fakeProvider{}, NewGuard(Config{Name: "llm_test_rel"}, nil))
+	if _, ok := p.(llm.RelevanceScorer); ok {
+		t.Fatal("el provider decorado no implementa ScoreRelevance: no debe exponerla")
+	}
+}
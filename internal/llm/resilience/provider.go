@@ -0,0 +1,120 @@
+package resilience
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+// GuardedProvider decora un llm.LLMProvider pasando cada llamada por un Guard.
+type GuardedProvider struct {
+	inner llm.LLMProvider
+	guard *Guard
+}
+
+// NewProvider envuelve p con g. Expone ScoreRelevance solo si p la implementa (ver
+// llm.WithRelevance).
+func NewProvider(p llm.LLMProvider, g *Guard) llm.LLMProvider {
+	guarded := &GuardedProvider{inner: p, guard: g}
+	return llm.WithRelevance(guarded, p, guarded.scoreRelevance)
+}
+
+// Name satisface llm.LLMProvider (el del provider decorado).
+func (p *GuardedProvider) Name() string { return p.inner.Name() }
+
+func (p *GuardedProvider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
+	return guarded(ctx, p.guard, "generate_assessment", func(ctx context.Context) (json.RawMessage, error) {
+		return p.inner.GenerateAssessment(ctx, material, params)
+	})
+}
+
+func (p *GuardedProvider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	return guarded(ctx, p.guard, "review_answer", func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.ReviewAnswer(ctx, req)
+	})
+}
+
+func (p *GuardedProvider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
+	return guarded(ctx, p.guard, "prepare_question", func(ctx context.Context) (json.RawMessage, error) {
+		return p.inner.PrepareQuestion(ctx, req)
+	})
+}
+
+func (p *GuardedProvider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
+	return guarded(ctx, p.guard, "judge_pair_equivalence", func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.JudgePairEquivalence(ctx, req)
+	})
+}
+
+func (p *GuardedProvider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	return guarded(ctx, p.guard, "check_criterion", func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.CheckCriterion(ctx, req)
+	})
+}
+
+func (p *GuardedProvider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
+	return guarded(ctx, p.guard, "extract_ideas", func(ctx context.Context) ([]string, error) {
+		return p.inner.ExtractIdeas(ctx, req)
+	})
+}
+
+func (p *GuardedProvider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	return guarded(ctx, p.guard, "digest_chunk", func(ctx context.Context) (*llm.DigestChunkResult, error) {
+		return p.inner.DigestChunk(ctx, in)
+	})
+}
+
+func (p *GuardedProvider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
+	return guarded(ctx, p.guard, "propose_candidates", func(ctx context.Context) ([]materialpipeline.CandidatePayloadV1, error) {
+		return p.inner.ProposeCandidates(ctx, in)
+	})
+}
+
+// scoreRelevance pasa por el guard la relevancia del provider decorado.
+func (p *GuardedProvider) scoreRelevance(scorer llm.RelevanceScorer) llm.RelevanceFunc {
+	return func(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
+		return guarded(ctx, p.guard, "score_relevance", func(ctx context.Context) (llm.RelevanceResult, error) {
+			return scorer.ScoreRelevance(ctx, req)
+		})
+	}
+}
+
+// GuardedEmbedder decora un llm.Embedder pasando cada lote por un Guard (operación
+// "embed", de lote).
+type GuardedEmbedder struct {
+	inner llm.Embedder
+	guard *Guard
+}
+
+// NewEmbedder envuelve e con g.
+func NewEmbedder(e llm.Embedder, g *Guard) *GuardedEmbedder {
+	return &GuardedEmbedder{inner: e, guard: g}
+}
+
+// Name es el del embedder decorado, si lo expone.
+func (e *GuardedEmbedder) Name() string {
+	return llm.EmbedderName(e.inner)
+}
+
+// Embed satisface llm.Embedder. Un lote vacío no llega al backend y no pasa por el
+// guard.
+func (e *GuardedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return e.inner.Embed(ctx, texts)
+	}
+	return guarded(ctx, e.guard, "embed", func(ctx context.Context) ([][]float32, error) {
+		return e.inner.Embed(ctx, texts)
+	})
+}
+
+func guarded[T any](ctx context.Context, g *Guard, op string, call func(ctx context.Context) (T, error)) (T, error) {
+	var out T
+	err := g.do(ctx, op, func(ctx context.Context) error {
+		var err error
+		out, err = call(ctx)
+		return err
+	})
+	return out, err
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+)
+
+// ScheduledProvider decora un llm.LLMProvider haciendo que cada llamada espere su
+// turno en un Scheduler.
+type ScheduledProvider struct {
+	inner     llm.LLMProvider
+	scheduler *Scheduler
+}
+
+// NewProvider envuelve p con s. Expone ScoreRelevance solo si p la implementa (ver
+// llm.WithRelevance).
+func NewProvider(p llm.LLMProvider, s *Scheduler) llm.LLMProvider {
+	scheduled := &ScheduledProvider{inner: p, scheduler: s}
+	return llm.WithRelevance(scheduled, p, scheduled.scoreRelevance)
+}
+
+// Name satisface llm.LLMProvider (el del provider decorado).
+func (p *ScheduledProvider) Name() string { return p.inner.Name() }
+
+// GenerateAssessment satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) GenerateAssessment(ctx context.Context, material llm.MaterialInput, params llm.GenerationParams) (json.RawMessage, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) (json.RawMessage, error) {
+		return p.inner.GenerateAssessment(ctx, material, params)
+	})
+}
+
+// ReviewAnswer satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.ReviewAnswer(ctx, req)
+	})
+}
+
+// PrepareQuestion satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) PrepareQuestion(ctx context.Context, req llm.PrepRequest) (json.RawMessage, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) (json.RawMessage, error) {
+		return p.inner.PrepareQuestion(ctx, req)
+	})
+}
+
+// JudgePairEquivalence satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) JudgePairEquivalence(ctx context.Context, req llm.PairEquivalenceRequest) (llm.ReviewResult, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.JudgePairEquivalence(ctx, req)
+	})
+}
+
+// CheckCriterion satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) (llm.ReviewResult, error) {
+		return p.inner.CheckCriterion(ctx, req)
+	})
+}
+
+// ExtractIdeas satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) ExtractIdeas(ctx context.Context, req llm.ExtractIdeasRequest) ([]string, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) ([]string, error) {
+		return p.inner.ExtractIdeas(ctx, req)
+	})
+}
+
+// DigestChunk satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) DigestChunk(ctx context.Context, in llm.DigestChunkInput) (*llm.DigestChunkResult, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) (*llm.DigestChunkResult, error) {
+		return p.inner.DigestChunk(ctx, in)
+	})
+}
+
+// ProposeCandidates satisface llm.LLMProvider esperando turno en el scheduler.
+func (p *ScheduledProvider) ProposeCandidates(ctx context.Context, in llm.ProposeCandidatesInput) ([]materialpipeline.CandidatePayloadV1, error) {
+	return scheduled(ctx, p.scheduler, func(ctx context.Context) ([]materialpipeline.CandidatePayloadV1, error) {
+		return p.inner.ProposeCandidates(ctx, in)
+	})
+}
+
+// scoreRelevance hace esperar turno a la relevancia del provider decorado.
+func (p *ScheduledProvider) scoreRelevance(scorer llm.RelevanceScorer) llm.RelevanceFunc {
+	return func(ctx context.Context, req llm.RelevanceRequest) (llm.RelevanceResult, error) {
+		return scheduled(ctx, p.scheduler, func(ctx context.Context) (llm.RelevanceResult, error) {
+			return scorer.ScoreRelevance(ctx, req)
+		})
+	}
+}
+
+// ScheduledEmbedder decora un llm.Embedder haciendo que cada lote espere su turno en
+// un Scheduler (el mismo del provider local si comparten host).
+type ScheduledEmbedder struct {
+	inner     llm.Embedder
+	scheduler *Scheduler
+}
+
+// NewEmbedder envuelve e con s.
+func NewEmbedder(e llm.Embedder, s *Scheduler) *ScheduledEmbedder {
+	return &ScheduledEmbedder{inner: e, scheduler: s}
+}
+
+// Name es el del embedder decorado, si lo expone.
+func (e *ScheduledEmbedder) Name() string {
+	return llm.EmbedderName(e.inner)
+}
+
+// Embed satisface llm.Embedder. Un lote vacío no llega al backend y no espera turno.
+func (e *ScheduledEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return e.inner.Embed(ctx, texts)
+	}
+	return scheduled(ctx, e.scheduler, func(ctx context.Context) ([][]float32, error) {
+		return e.inner.Embed(ctx, texts)
+	})
+}
+
+// scheduled ejecuta call con un cupo del scheduler.
+func scheduled[T any](ctx context.Context, s *Scheduler, call func(ctx context.Context) (T, error)) (T, error) {
+	release, err := s.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	return call(ctx)
+}
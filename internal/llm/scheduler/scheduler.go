@@ -0,0 +1,181 @@
+// Package scheduler ordena las llamadas LLM de todos los carriles contra un mismo
+// backend. Revisión (la espera un alumno), preparación (la espera un docente) y el
+// pipeline de materiales (lotes de horas) comparten el modelo local: sin orden, un
+// job de materiales encadena trozo tras trozo y una revisión entra cuando le toca.
+//
+// El Scheduler es un semáforo con prioridad: Concurrency llamadas a la vez y, cuando
+// una termina, el cupo pasa a la llamada en espera de la clase más prioritaria (FIFO
+// dentro de la clase). La preempción es ENTRE llamadas —la que está en curso termina—:
+// el siguiente digest de un trozo espera mientras haya una revisión pendiente. La
+// clase de una llamada es el carril de su contexto (llm.WithLane); las llamadas sin
+// carril, o de un carril sin prioridad configurada, van a DefaultClass.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/infrastructure/metrics"
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// DefaultClass es la clase de las llamadas sin carril o de carriles sin prioridad.
+const DefaultClass = "default"
+
+// Config parametriza un Scheduler.
+type Config struct {
+	// Name identifica al backend planificado (label scheduler de las métricas: local,
+	// api).
+	Name string
+	// Concurrency son las llamadas en curso a la vez. <= 0 usa 1.
+	Concurrency int
+	// Priorities es la prioridad de cada clase (carril): menor número, antes. Una
+	// clase ausente, y DefaultClass si no está, toman la prioridad más baja de las
+	// configuradas.
+	Priorities map[string]int
+}
+
+// Scheduler reparte los cupos de llamada por prioridad de clase.
+type Scheduler struct {
+	cfg             Config
+	defaultPriority int
+
+	mu      sync.Mutex
+	running int
+	seq     uint64
+	waiting waitQueue
+	queued  map[string]int
+}
+
+// New construye el scheduler.
+func New(cfg Config) *Scheduler {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	defaultPriority, ok := cfg.Priorities[DefaultClass]
+	if !ok {
+		for _, p := range cfg.Priorities {
+			defaultPriority = max(defaultPriority, p)
+		}
+	}
+	return &Scheduler{cfg: cfg, defaultPriority: defaultPriority, queued: make(map[string]int)}
+}
+
+// waiter es una llamada en espera de cupo. granted lo fija quien le cede el cupo
+// (bajo mu), antes de cerrar ready.
+type waiter struct {
+	class    string
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	granted  bool
+	index    int
+}
+
+// classOf resuelve la clase y su prioridad para ctx.
+func (s *Scheduler) classOf(ctx context.Context) (string, int) {
+	class := llm.LaneFrom(ctx)
+	if p, ok := s.cfg.Priorities[class]; ok && class != "" {
+		return class, p
+	}
+	return DefaultClass, s.defaultPriority
+}
+
+// Acquire espera un cupo para la clase de ctx o a que ctx termine. release devuelve
+// el cupo (se lo cede a la llamada en espera más prioritaria).
+func (s *Scheduler) Acquire(ctx context.Context) (release func(), err error) {
+	class, priority := s.classOf(ctx)
+	start := time.Now()
+
+	s.mu.Lock()
+	if s.running < s.cfg.Concurrency && s.waiting.Len() == 0 {
+		s.running++
+		s.mu.Unlock()
+		metrics.RecordLLMSchedulerWait(s.cfg.Name, class, 0)
+		return s.release, nil
+	}
+	s.seq++
+	w := &waiter{class: class, priority: priority, seq: s.seq, ready: make(chan struct{})}
+	heap.Push(&s.waiting, w)
+	s.updateQueued(class, 1)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		metrics.RecordLLMSchedulerWait(s.cfg.Name, class, time.Since(start).Seconds())
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.granted {
+			// El cupo llegó a la vez que la cancelación: se devuelve.
+			s.mu.Unlock()
+			s.release()
+		} else {
+			heap.Remove(&s.waiting, w.index)
+			s.updateQueued(class, -1)
+			s.mu.Unlock()
+		}
+		return nil, fmt.Errorf("esperando turno LLM (%s/%s): %w", s.cfg.Name, class, ctx.Err())
+	}
+}
+
+// release cede el cupo a la llamada en espera más prioritaria o lo libera.
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.waiting.Len() == 0 {
+		s.running--
+		return
+	}
+	next := heap.Pop(&s.waiting).(*waiter)
+	s.updateQueued(next.class, -1)
+	next.granted = true
+	close(next.ready)
+}
+
+// Queued devuelve las llamadas en espera de class.
+func (s *Scheduler) Queued(class string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued[class]
+}
+
+func (s *Scheduler) updateQueued(class string, delta int) {
+	s.queued[class] += delta
+	metrics.UpdateLLMSchedulerQueued(s.cfg.Name, class, s.queued[class])
+}
+
+// waitQueue es un heap de waiters por (prioridad, orden de llegada).
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return w
+}
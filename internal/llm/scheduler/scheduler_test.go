@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+var lanePriorities = map[string]int{"review": 0, "prep": 1, "material": 2}
+
+// waitQueued espera a que class tenga n llamadas en espera.
+func waitQueued(t *testing.T, s *Scheduler, class string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Queued(class) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("clase %s: esperaba %d en cola, hay %d", class, n, s.Queued(class))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler_LaRevisionPasaDelanteDelLote(t *testing.T) {
+	s := New(Config{Name: "test-priority", Concurrency: 1, Priorities: lanePriorities})
+
+	// Un digest en curso ocupa el único cupo.
+	release, err := s.Acquire(llm.WithLane(context.Background(), "material"))
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+
+	order := make(chan string, 3)
+	enqueue := func(lane string) {
+		go func() {
+			rel, err := s.Acquire(llm.WithLane(context.Background(), lane))
+			if err != nil {
+				t.Errorf("%s: error inesperado: %v", lane, err)
+				return
+			}
+			order <- lane
+			rel()
+		}()
+	}
+	enqueue("material")
+	waitQueued(t, s, "material", 1)
+	enqueue("prep")
+	waitQueued(t, s, "prep", 1)
+	enqueue("review")
+	waitQueued(t, s, "review", 1)
+
+	release()
+	for _, want := range []string{"review", "prep", "material"} {
+		if got := <-order; got != want {
+			t.Fatalf("orden de despacho: esperaba %s, hubo %s", want, got)
+		}
+	}
+}
+
+func TestScheduler_FIFODentroDeLaClase(t *testing.T) {
+	s := New(Config{Name: "test-fifo", Concurrency: 1, Priorities: lanePriorities})
+	release, _ := s.Acquire(context.Background())
+
+	order := make(chan int, 3)
+	for i := range 3 {
+		go func() {
+			rel, err := s.Acquire(llm.WithLane(context.Background(), "review"))
+			if err != nil {
+				t.Errorf("error inesperado: %v", err)
+				return
+			}
+			order <- i
+			rel()
+		}()
+		waitQueued(t, s, "review", i+1)
+	}
+	release()
+	for want := range 3 {
+		if got := <-order; got != want {
+			t.Fatalf("dentro de la clase el orden es de llegada: esperaba %d, hubo %d", want, got)
+		}
+	}
+}
+
+func TestScheduler_CancelarLiberaLaEspera(t *testing.T) {
+	s := New(Config{Name: "test-cancel", Concurrency: 1, Priorities: lanePriorities})
+	release, _ := s.Acquire(context.Background())
+
+	ctx, cancel := context.WithCancel(llm.WithLane(context.Background(), "material"))
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx)
+		done <- err
+	}()
+	waitQueued(t, s, "material", 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("esperaba context.Canceled: %v", err)
+	}
+	if s.Queued("material") != 0 {
+		t.Fatalf("la llamada cancelada debe salir de la cola")
+	}
+
+	// El cupo sigue siendo uno solo: al liberarlo lo toma la siguiente.
+	release()
+	rel, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	rel()
+}
+
+func TestScheduler_ClasePorDefecto(t *testing.T) {
+	s := New(Config{Name: "test-default", Priorities: lanePriorities})
+	for _, ctx := range []context.Context{context.Background(), llm.WithLane(context.Background(), "otro")} {
+		if class, priority := s.classOf(ctx); class != DefaultClass || priority != 2 {
+			t.Errorf("sin carril configurado esperaba (%s, 2), hubo (%s, %d)", DefaultClass, class, priority)
+		}
+	}
+	if class, priority := s.classOf(llm.WithLane(context.Background(), "review")); class != "review" || priority != 0 {
+		t.Errorf("clase de review inesperada: (%s, %d)", class, priority)
+	}
+}
@@ -0,0 +1,164 @@
+package llm
+
+// schema.go — JSON Schema de la salida de cada operación, derivado de su contrato Go.
+//
+// Pedir solo "JSON" (format:"json" en Ollama, response_format json_object) garantiza
+// un objeto parseable, no su forma: el modelo puede devolver `{}` o saltarse claves
+// obligatorias, que es el origen de ErrInvalidVerdict, ErrInvalidChunkArtifacts y del
+// reintento por calidad. Con el schema, los backends que decodifican restringido
+// (Ollama format=<schema>, vLLM/llama.cpp json_schema, tool use de Anthropic,
+// responseJsonSchema de Gemini) solo pueden emitir objetos con esa forma. Los prompts
+// siguen describiendo la forma: el schema la impone, el prompt la explica.
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
+	"github.com/EduGoGroup/edugo-worker/internal/questionprep"
+)
+
+// OutputSchema es el JSON Schema de la salida de una operación y el nombre con que
+// se declara ante el backend (name del json_schema, nombre de la tool).
+type OutputSchema struct {
+	Name   string
+	Schema json.RawMessage
+}
+
+// relevanceOutput es la forma que devuelve el modelo en ScoreRelevance (la categoría;
+// el score se deriva en ParseRelevanceResult).
+type relevanceOutput struct {
+	Category  string `json:"category" jsonschema:"enum=central|peripheral|unanswerable"`
+	Rationale string `json:"rationale"`
+}
+
+// extractedIdeasOutput es la forma que devuelve el modelo en ExtractIdeas.
+type extractedIdeasOutput struct {
+	Ideas []string `json:"ideas"`
+}
+
+// digestOutput es la forma de la llamada A única (v1): los artefactos del trozo más
+// su summary en el mismo objeto. Se embebe el contrato para que sus json tags
+// (main_ideas, chunk_topic…) promuevan al nivel de arriba.
+type digestOutput struct {
+	materialpipeline.ChunkArtifactsV1
+	Summary string `json:"summary"`
+}
+
+// candidatesOutput es la forma que devuelve el modelo en ProposeCandidates.
+type candidatesOutput struct {
+	Candidates []materialpipeline.CandidatePayloadV1 `json:"candidates"`
+}
+
+// Schemas de salida por operación. JudgePairEquivalence y CheckCriterion comparten el
+// de ReviewAnswer (las tres devuelven un ReviewResult).
+var (
+	ReviewSchema            = newOutputSchema("review_result", ReviewResult{})
+	PrepSchema              = newOutputSchema("question_prep", questionprep.Prep{})
+	RelevanceSchema         = newOutputSchema("relevance", relevanceOutput{})
+	ExtractIdeasSchema      = newOutputSchema("extracted_ideas", extractedIdeasOutput{})
+	DigestSchema            = newOutputSchema("chunk_digest", digestOutput{})
+	DigestSummarySchema     = newOutputSchema("chunk_digest_summary", DigestSummaryPart{})
+	DigestIdeasSchema       = newOutputSchema("chunk_digest_ideas", DigestIdeasPart{})
+	ProposeCandidatesSchema = newOutputSchema("candidates", candidatesOutput{})
+)
+
+func newOutputSchema(name string, v any) OutputSchema {
+	return OutputSchema{Name: name, Schema: SchemaFor(v)}
+}
+
+// SchemaFor deriva el JSON Schema de un valor Go a partir de sus json tags: los
+// structs son objetos cerrados (additionalProperties false) cuyas claves sin
+// omitempty son obligatorias, los slices son arrays y json.RawMessage acepta
+// cualquier valor (campos polimórficos como correct_answer). Los structs embebidos
+// sin tag promueven sus campos, como en encoding/json. El tag jsonschema agrega
+// restricciones: enum=a|b|c, minimum=N, maximum=N.
+func SchemaFor(v any) json.RawMessage {
+	raw, err := json.Marshal(schemaOf(reflect.TypeOf(v)))
+	if err != nil {
+		// Solo hay mapas, slices, strings y números: no puede fallar.
+		panic(fmt.Sprintf("llm: schema de %T: %v", v, err))
+	}
+	return raw
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+func schemaOf(t reflect.Type) map[string]any {
+	if t == rawMessageType {
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		addStructFields(t, properties, &required)
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
+
+func addStructFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := schemaOf(field.Type)
+		applyConstraints(prop, field.Tag.Get("jsonschema"))
+		properties[name] = prop
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// applyConstraints aplica el tag jsonschema (enum=a|b, minimum=N, maximum=N).
+func applyConstraints(prop map[string]any, tag string) {
+	if tag == "" {
+		return
+	}
+	for _, constraint := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(constraint, "=")
+		switch key {
+		case "enum":
+			prop["enum"] = strings.Split(value, "|")
+		case "minimum", "maximum":
+			prop[key] = json.Number(value)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func decodeSchema(t *testing.T, s OutputSchema) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(s.Schema, &m); err != nil {
+		t.Fatalf("schema %s no parseable: %v", s.Name, err)
+	}
+	return m
+}
+
+func TestReviewSchema_RequeridosYRestricciones(t *testing.T) {
+	schema := decodeSchema(t, ReviewSchema)
+	if schema["type"] != "object" || schema["additionalProperties"] != false {
+		t.Fatalf("esperaba objeto cerrado: %v", schema)
+	}
+	required, _ := schema["required"].([]any)
+	for _, want := range []string{"verdict", "score", "feedback"} {
+		if !slices.Contains(required, any(want)) {
+			t.Errorf("%q debería ser obligatorio: %v", want, required)
+		}
+	}
+	props := schema["properties"].(map[string]any)
+	verdict := props["verdict"].(map[string]any)
+	if enum, _ := verdict["enum"].([]any); len(enum) != 3 {
+		t.Errorf("verdict debería enumerar los 3 veredictos: %v", verdict)
+	}
+	score := props["score"].(map[string]any)
+	if score["type"] != "number" || score["minimum"] != float64(0) || score["maximum"] != float64(1) {
+		t.Errorf("score debería ser un número en [0,1]: %v", score)
+	}
+}
+
+func TestDigestSchema_PromueveElContratoEmbebido(t *testing.T) {
+	schema := decodeSchema(t, DigestSchema)
+	props := schema["properties"].(map[string]any)
+	for _, want := range []string{"summary", "version", "chunk_topic", "main_ideas"} {
+		if _, ok := props[want]; !ok {
+			t.Errorf("falta la clave %q en el schema del digest: %v", want, props)
+		}
+	}
+	if _, ok := props["ChunkArtifactsV1"]; ok {
+		t.Error("el contrato embebido no debe aparecer como clave propia")
+	}
+}
+
+func TestSchemaFor_OmitemptyYRawMessage(t *testing.T) {
+	type sample struct {
+		Name    string          `json:"name"`
+		Note    string          `json:"note,omitempty"`
+		Answer  json.RawMessage `json:"answer"`
+		Skipped string          `json:"-"`
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(SchemaFor(sample{}), &schema); err != nil {
+		t.Fatalf("schema no parseable: %v", err)
+	}
+	required, _ := schema["required"].([]any)
+	if !slices.Equal(required, []any{"name", "answer"}) {
+		t.Errorf("required inesperado: %v", required)
+	}
+	props := schema["properties"].(map[string]any)
+	if answer := props["answer"].(map[string]any); len(answer) != 0 {
+		t.Errorf("json.RawMessage debería aceptar cualquier valor: %v", answer)
+	}
+	if _, ok := props["Skipped"]; ok {
+		t.Error("los campos json:\"-\" no deben aparecer")
+	}
+}
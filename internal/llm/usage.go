@@ -0,0 +1,82 @@
+package llm
+
+// usage.go — consumo de tokens reportado por los backends.
+//
+// Los tokens reales solo los conoce el provider que parsea la respuesta (eval_count de
+// Ollama, usage de Anthropic/OpenAI, usageMetadata de Gemini), pero quien los mide es el
+// decorador de instrumentación, que solo ve la llamada del puerto. El puente es el
+// contexto: el decorador cuelga un *Usage con WithUsage y el provider suma cada respuesta
+// HTTP con ReportUsage. Una operación con varias llamadas (las dos mitades del digest)
+// acumula todas. Sin acumulador en el contexto ReportUsage no hace nada.
+//
+// Por el mismo puente viaja la versión de cada prompt usado (ReportPrompt): la elige el
+// registry de prompts al armar la llamada y la registra el decorador con el resultado.
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// Usage acumula los tokens consumidos por una operación LLM y los prompts que usó.
+type Usage struct {
+	mu         sync.Mutex
+	prompt     int
+	completion int
+	prompts    map[string]string
+}
+
+// Add suma los tokens de una respuesta.
+func (u *Usage) Add(promptTokens, completionTokens int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.prompt += promptTokens
+	u.completion += completionTokens
+}
+
+// Tokens devuelve los tokens acumulados (prompt, completion).
+func (u *Usage) Tokens() (promptTokens, completionTokens int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.prompt, u.completion
+}
+
+// AddPrompt registra que la operación usó la versión version del prompt id.
+func (u *Usage) AddPrompt(id, version string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.prompts == nil {
+		u.prompts = make(map[string]string)
+	}
+	u.prompts[id] = version
+}
+
+// Prompts devuelve la versión de cada prompt usado (id → versión).
+func (u *Usage) Prompts() map[string]string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return maps.Clone(u.prompts)
+}
+
+type usageKey struct{}
+
+// WithUsage devuelve un contexto derivado de ctx con un acumulador de tokens nuevo.
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// ReportUsage suma tokens al acumulador de ctx, si lo hay. Los providers lo llaman con
+// los contadores de cada respuesta del backend.
+func ReportUsage(ctx context.Context, promptTokens, completionTokens int) {
+	if u, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		u.Add(promptTokens, completionTokens)
+	}
+}
+
+// ReportPrompt registra en el acumulador de ctx, si lo hay, la versión del prompt id.
+func ReportPrompt(ctx context.Context, id, version string) {
+	if u, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		u.AddPrompt(id, version)
+	}
+}
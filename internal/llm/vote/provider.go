@@ -0,0 +1,83 @@
+package vote
+
+import (
+	"context"
+	"sync"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// Provider decora un llm.LLMProvider votando los juicios de corrección: ReviewAnswer y
+// CheckCriterion (una votación por criterio dentro de openended.Grade). El resto de
+// operaciones pasa directo por el provider embebido. Se construye uno por respuesta
+// corregida: Agreement resume las votaciones de esa respuesta.
+type Provider struct {
+	llm.LLMProvider
+	cfg Config
+
+	mu         sync.Mutex
+	agreements []float64
+}
+
+// NewProvider envuelve p con la votación de cfg.
+func NewProvider(p llm.LLMProvider, cfg Config) *Provider {
+	return &Provider{LLMProvider: p, cfg: cfg}
+}
+
+// Agreement es el agreement medio de las votaciones hechas (una por ReviewAnswer o por
+// criterio). ok=false si no hubo ninguna (p.ej. el carril triturado, que no vota).
+func (p *Provider) Agreement() (agreement float64, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.agreements) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, a := range p.agreements {
+		sum += a
+	}
+	return sum / float64(len(p.agreements)), true
+}
+
+// ReviewAnswer vota el juicio global.
+func (p *Provider) ReviewAnswer(ctx context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	return p.vote(func(temperature *float64) (llm.ReviewResult, error) {
+		req.Temperature = temperature
+		return p.LLMProvider.ReviewAnswer(ctx, req)
+	})
+}
+
+// CheckCriterion vota el cumplimiento de un criterio.
+func (p *Provider) CheckCriterion(ctx context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	return p.vote(func(temperature *float64) (llm.ReviewResult, error) {
+		req.Temperature = temperature
+		return p.LLMProvider.CheckCriterion(ctx, req)
+	})
+}
+
+// vote muestrea call cfg.Samples veces (la primera sin override de temperatura) y
+// devuelve el veredicto de la mayoría. Un error en cualquier muestra se propaga: el
+// caller ya reintenta la corrección completa (idempotente).
+func (p *Provider) vote(call func(temperature *float64) (llm.ReviewResult, error)) (llm.ReviewResult, error) {
+	if p.cfg.Samples <= 1 {
+		return call(nil)
+	}
+	samples := make([]llm.ReviewResult, 0, p.cfg.Samples)
+	for i := range p.cfg.Samples {
+		var temperature *float64
+		if i > 0 {
+			t := p.cfg.Temperature
+			temperature = &t
+		}
+		res, err := call(temperature)
+		if err != nil {
+			return llm.ReviewResult{}, err
+		}
+		samples = append(samples, res)
+	}
+	tally := Decide(samples)
+	p.mu.Lock()
+	p.agreements = append(p.agreements, tally.Agreement)
+	p.mu.Unlock()
+	return tally.Result, nil
+}
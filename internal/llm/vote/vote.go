@@ -0,0 +1,95 @@
+// Package vote implementa la revisión por votación (self-consistency): en vez de una
+// sola llamada greedy, cada juicio se muestrea varias veces y se queda el veredicto de
+// la mayoría. Con modelos locales chicos una respuesta parcial limítrofe cambia de
+// veredicto entre corridas; la votación lo estabiliza y la fracción de muestras que
+// coinciden (agreement) dice cuán limítrofe fue.
+//
+// La primera muestra usa la temperatura del provider (la misma llamada que sin
+// votación, cacheable); las demás, Config.Temperature como jitter. En un empate gana el
+// veredicto que apareció primero, así que la muestra greedy desempata.
+package vote
+
+import (
+	"slices"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+// Config parametriza la votación.
+type Config struct {
+	// Samples es el número de muestras por juicio. ≤1 = sin votación (una llamada).
+	Samples int
+	// Temperature es la temperatura de las muestras 2..Samples.
+	Temperature float64
+}
+
+// Tally es el resultado de una votación.
+type Tally struct {
+	// Result es el veredicto de la mayoría con la mediana de los scores de esas
+	// muestras y el feedback de la muestra más cercana a esa mediana. Su Confidence es
+	// el Agreement: la votación reemplaza la confianza de cada muestra.
+	Result llm.ReviewResult
+	// Agreement es la fracción de muestras con el veredicto ganador (0..1].
+	Agreement float64
+	// Samples es el número de muestras votadas.
+	Samples int
+}
+
+// Decide vota samples (en orden de muestreo). La mediana se toma solo entre las
+// muestras del veredicto ganador: un score de otra banda (un correct 0.9 entre dos
+// partial 0.5) no debe arrastrar el score fuera de la escala del veredicto.
+func Decide(samples []llm.ReviewResult) Tally {
+	if len(samples) == 0 {
+		return Tally{}
+	}
+	counts := make(map[llm.Verdict]int, 3)
+	var order []llm.Verdict
+	for _, s := range samples {
+		if counts[s.Verdict] == 0 {
+			order = append(order, s.Verdict)
+		}
+		counts[s.Verdict]++
+	}
+	winner := order[0]
+	for _, v := range order[1:] {
+		if counts[v] > counts[winner] {
+			winner = v
+		}
+	}
+
+	var scores []float64
+	for _, s := range samples {
+		if s.Verdict == winner {
+			scores = append(scores, s.Score)
+		}
+	}
+	slices.Sort(scores)
+	median := scores[len(scores)/2]
+	if len(scores)%2 == 0 {
+		median = (scores[len(scores)/2-1] + scores[len(scores)/2]) / 2
+	}
+
+	feedback, best := "", -1.0
+	for _, s := range samples {
+		if s.Verdict != winner {
+			continue
+		}
+		if d := abs(s.Score - median); best < 0 || d < best {
+			feedback, best = s.Feedback, d
+		}
+	}
+
+	agreement := float64(counts[winner]) / float64(len(samples))
+	return Tally{
+		Result:    llm.ReviewResult{Verdict: winner, Score: median, Feedback: feedback, Confidence: &agreement},
+		Agreement: agreement,
+		Samples:   len(samples),
+	}
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,137 @@
+package vote
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/EduGoGroup/edugo-worker/internal/llm"
+)
+
+func TestDecide(t *testing.T) {
+	cases := []struct {
+		name      string
+		samples   []llm.ReviewResult
+		want      llm.ReviewResult
+		agreement float64
+	}{
+		{
+			name: "mayoría y mediana entre las muestras ganadoras",
+			samples: []llm.ReviewResult{
+				{Verdict: llm.VerdictPartial, Score: 0.4, Feedback: "a"},
+				{Verdict: llm.VerdictCorrect, Score: 0.9, Feedback: "b"},
+				{Verdict: llm.VerdictPartial, Score: 0.6, Feedback: "c"},
+				{Verdict: llm.VerdictPartial, Score: 0.5, Feedback: "d"},
+			},
+			want:      llm.ReviewResult{Verdict: llm.VerdictPartial, Score: 0.5, Feedback: "d"},
+			agreement: 0.75,
+		},
+		{
+			name: "empate: gana la primera muestra (greedy)",
+			samples: []llm.ReviewResult{
+				{Verdict: llm.VerdictIncorrect, Score: 0.1, Feedback: "a"},
+				{Verdict: llm.VerdictPartial, Score: 0.5, Feedback: "b"},
+			},
+			want:      llm.ReviewResult{Verdict: llm.VerdictIncorrect, Score: 0.1, Feedback: "a"},
+			agreement: 0.5,
+		},
+		{
+			name: "mediana par",
+			samples: []llm.ReviewResult{
+				{Verdict: llm.VerdictCorrect, Score: 0.8, Feedback: "a"},
+				{Verdict: llm.VerdictCorrect, Score: 1.0, Feedback: "b"},
+			},
+			want:      llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 0.9, Feedback: "a"},
+			agreement: 1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Decide(tc.samples)
+			confidence := got.Result.Confidence
+			got.Result.Confidence = nil
+			if got.Result != tc.want || got.Agreement != tc.agreement || got.Samples != len(tc.samples) {
+				t.Errorf("Decide = %+v, esperaba %+v con agreement %v", got, tc.want, tc.agreement)
+			}
+			if confidence == nil || *confidence != tc.agreement {
+				t.Errorf("la confianza del veredicto votado es el agreement: %v", confidence)
+			}
+		})
+	}
+	if got := Decide(nil); got != (Tally{}) {
+		t.Errorf("sin muestras no hay veredicto: %+v", got)
+	}
+}
+
+// fakeProvider responde los veredictos de verdicts en orden y registra la
+// temperatura de cada llamada.
+type fakeProvider struct {
+	llm.LLMProvider
+	verdicts []llm.Verdict
+	temps    []*float64
+	err      error
+}
+
+func (f *fakeProvider) next(temperature *float64) (llm.ReviewResult, error) {
+	f.temps = append(f.temps, temperature)
+	if f.err != nil {
+		return llm.ReviewResult{}, f.err
+	}
+	v := f.verdicts[(len(f.temps)-1)%len(f.verdicts)]
+	return llm.ReviewResult{Verdict: v, Score: map[llm.Verdict]float64{llm.VerdictCorrect: 1}[v]}, nil
+}
+
+func (f *fakeProvider) ReviewAnswer(_ context.Context, req llm.ReviewRequest) (llm.ReviewResult, error) {
+	return f.next(req.Temperature)
+}
+
+func (f *fakeProvider) CheckCriterion(_ context.Context, req llm.CriterionCheckRequest) (llm.ReviewResult, error) {
+	return f.next(req.Temperature)
+}
+
+func TestProvider_VotaConJitter(t *testing.T) {
+	inner := &fakeProvider{verdicts: []llm.Verdict{llm.VerdictIncorrect, llm.VerdictCorrect, llm.VerdictCorrect}}
+	p := NewProvider(inner, Config{Samples: 3, Temperature: 0.7})
+
+	res, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{StudentAnswer: "a"})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Verdict != llm.VerdictCorrect {
+		t.Errorf("veredicto %q, esperaba el de la mayoría", res.Verdict)
+	}
+	if len(inner.temps) != 3 || inner.temps[0] != nil || *inner.temps[1] != 0.7 || *inner.temps[2] != 0.7 {
+		t.Errorf("la primera muestra va sin override y las demás con jitter: %v", inner.temps)
+	}
+
+	// Un criterio unánime: el agreement es la media de las dos votaciones.
+	inner.verdicts = []llm.Verdict{llm.VerdictCorrect}
+	if _, err := p.CheckCriterion(context.Background(), llm.CriterionCheckRequest{Criterion: "c"}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	agreement, ok := p.Agreement()
+	if want := (2.0/3 + 1) / 2; !ok || math.Abs(agreement-want) > 1e-9 {
+		t.Errorf("agreement = %v (%v), esperaba %v", agreement, ok, want)
+	}
+}
+
+func TestProvider_SinVotacionYErrores(t *testing.T) {
+	inner := &fakeProvider{verdicts: []llm.Verdict{llm.VerdictPartial}}
+	p := NewProvider(inner, Config{Samples: 1})
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(inner.temps) != 1 || inner.temps[0] != nil {
+		t.Errorf("con Samples=1 es una sola llamada sin override: %v", inner.temps)
+	}
+	if _, ok := p.Agreement(); ok {
+		t.Error("sin votación no hay agreement")
+	}
+
+	boom := errors.New("ollama caído")
+	p = NewProvider(&fakeProvider{err: boom}, Config{Samples: 3})
+	if _, err := p.ReviewAnswer(context.Background(), llm.ReviewRequest{}); !errors.Is(err, boom) {
+		t.Fatalf("el error de una muestra se propaga: %v", err)
+	}
+}
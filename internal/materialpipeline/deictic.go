@@ -1,6 +1,14 @@
 package materialpipeline
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/EduGoGroup/edugo-shared/textmatch"
+
+	"github.com/EduGoGroup/edugo-worker/internal/language"
+)
 
 // deictic.go — detector determinista de referencias deícticas al contexto del prompt
 // (deuda 043-candidatas-enunciados-no-autocontenidos). Un enunciado que dice «según las
@@ -8,72 +16,162 @@ import "strings"
 // (main_ideas/chunk) que el alumno jamás ve: no es autocontenido. Ciego al modelo y
 // gratis; lo consumen QualityPass (guard post-generación) y el harness (medición).
 
-// deicticPhrases son las frases prohibidas, ya normalizadas (minúsculas, sin acentos,
-// espacios simples). Lista CONSERVADORA: cada frase ancla al sustantivo del contexto del
-// prompt (ideas/texto/material/fragmento/párrafo/visto/mencionado), nunca a «según» solo
+// deicticPhrases son las frases prohibidas por idioma, ya normalizadas
+// (textmatch.Normalize: minúsculas, sin acentos, espacios simples). Lista
+// CONSERVADORA: cada frase ancla al sustantivo del contexto del prompt
+// (ideas/texto/material/fragmento/párrafo/visto/mencionado), nunca a «según» solo
 // — «según la ley» o «según el reglamento» son legítimos en un manual de conducir.
-var deicticPhrases = []string{
-	// «las ideas» = las main_ideas del prompt B; el alumno no ve ideas de ningún tipo.
-	"segun las ideas",
-	"las ideas proporcionadas",
-	"las ideas dadas",
-	"las ideas mencionadas",
-	"las ideas presentadas",
-	"las ideas anteriores",
-	// «el texto» del chunk (no se incluye «en el texto» pelado: un enunciado legítimo
-	// podría hablar del texto de una señal).
-	"segun el texto",
-	"de acuerdo con el texto",
-	"de acuerdo al texto",
-	"conforme al texto",
-	"el texto anterior",
-	"el texto proporcionado",
-	// «el material» que viaja por el pipeline.
-	"segun el material",
-	"en el material",
-	"el material proporcionado",
-	// unidades internas del porcionado.
-	"segun el fragmento",
-	"en el fragmento",
-	"segun el parrafo",
-	"en el parrafo anterior",
-	// deixis temporal a un discurso previo que el alumno no compartió.
-	"segun lo visto",
-	"visto anteriormente",
-	"vista anteriormente",
-	"mencionado anteriormente",
-	"mencionada anteriormente",
-	"mencionados anteriormente",
-	"mencionadas anteriormente",
-	"se menciono anteriormente",
-	"segun lo anterior",
-}
-
-// deicticNormalizer aplana el texto para el match: minúsculas se aplican antes; aquí
-// solo se quitan los acentos del español (la ñ se conserva: distingue palabras).
-var deicticNormalizer = strings.NewReplacer(
-	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u",
-)
-
-// normalizeDeictic normaliza un texto para compararlo contra deicticPhrases:
-// minúsculas, sin acentos y con todo espacio en blanco colapsado a uno.
-func normalizeDeictic(text string) string {
-	s := deicticNormalizer.Replace(strings.ToLower(text))
-	return strings.Join(strings.Fields(s), " ")
+var deicticPhrases = map[string][]string{
+	language.Spanish: {
+		// «las ideas» = las main_ideas del prompt B; el alumno no ve ideas de ningún tipo.
+		"segun las ideas",
+		"las ideas proporcionadas",
+		"las ideas dadas",
+		"las ideas mencionadas",
+		"las ideas presentadas",
+		"las ideas anteriores",
+		// «el texto» del chunk (no se incluye «en el texto» pelado: un enunciado legítimo
+		// podría hablar del texto de una señal).
+		"segun el texto",
+		"de acuerdo con el texto",
+		"de acuerdo al texto",
+		"conforme al texto",
+		"el texto anterior",
+		"el texto proporcionado",
+		// «el material» que viaja por el pipeline.
+		"segun el material",
+		"en el material",
+		"el material proporcionado",
+		// unidades internas del porcionado.
+		"segun el fragmento",
+		"en el fragmento",
+		"segun el parrafo",
+		"en el parrafo anterior",
+		// deixis temporal a un discurso previo que el alumno no compartió.
+		"segun lo visto",
+		"visto anteriormente",
+		"vista anteriormente",
+		"mencionado anteriormente",
+		"mencionada anteriormente",
+		"mencionados anteriormente",
+		"mencionadas anteriormente",
+		"se menciono anteriormente",
+		"segun lo anterior",
+	},
+	// Mismas anclas en portugués («segundo» sin sustantivo tampoco dispara: «segundo a
+	// lei» es legítimo; «no texto» pelado tampoco, como «en el texto»). Sin «no
+	// material»/«no fragmento»: «no» es también la negación española y «bienes no
+	// materiales» dispararía; «ideias» sin artículo para cubrir la contracción «nas».
+	language.Portuguese: {
+		"segundo as ideias",
+		"ideias fornecidas",
+		"ideias apresentadas",
+		"ideias mencionadas",
+		"ideias anteriores",
+		"segundo o texto",
+		"de acordo com o texto",
+		"conforme o texto",
+		"o texto anterior",
+		"o texto acima",
+		"o texto fornecido",
+		"segundo o material",
+		"o material fornecido",
+		"segundo o fragmento",
+		"segundo o paragrafo",
+		"o paragrafo anterior",
+		"segundo o que foi visto",
+		"visto anteriormente",
+		"vista anteriormente",
+		"mencionado anteriormente",
+		"mencionada anteriormente",
+		"mencionados anteriormente",
+		"mencionadas anteriormente",
+	},
+	// En inglés «according to» solo tampoco dispara («according to the law»).
+	language.English: {
+		"according to the ideas",
+		"the ideas provided",
+		"the given ideas",
+		"the ideas mentioned",
+		"the ideas presented",
+		"the ideas above",
+		"according to the text",
+		"based on the text",
+		"the text above",
+		"the previous text",
+		"the provided text",
+		"the given text",
+		"according to the material",
+		"in the material",
+		"the material provided",
+		"the provided material",
+		"according to the passage",
+		"in the passage",
+		"according to the excerpt",
+		"in the excerpt",
+		"the previous paragraph",
+		"as seen above",
+		"seen previously",
+		"mentioned above",
+		"mentioned earlier",
+		"mentioned previously",
+		"previously mentioned",
+		"discussed earlier",
+		"according to the above",
+	},
 }
 
 // DetectDeicticReference busca en el texto de un enunciado referencias deícticas al
-// contexto del prompt. Devuelve la primera frase prohibida encontrada (normalizada,
-// para logs/motivos) o "" si el enunciado es autocontenido en este aspecto.
+// contexto del prompt, en cualquiera de los idiomas soportados (el reduce no conoce
+// el idioma del material). Las frases solo cuentan como palabras completas («en el
+// material» no dispara dentro de «en el materialismo»), y las listas excluyen las
+// frases que en otro idioma son texto legítimo. Devuelve la primera frase prohibida
+// encontrada (normalizada, para logs/motivos) o "" si el enunciado es autocontenido
+// en este aspecto.
 func DetectDeicticReference(text string) string {
-	norm := normalizeDeictic(text)
+	norm := textmatch.Normalize(text)
 	if norm == "" {
 		return ""
 	}
-	for _, phrase := range deicticPhrases {
-		if strings.Contains(norm, phrase) {
-			return phrase
+	for _, lang := range language.Supported {
+		for _, phrase := range deicticPhrases[lang] {
+			if containsWords(norm, phrase) {
+				return phrase
+			}
 		}
 	}
 	return ""
 }
+
+// containsWords informa si phrase aparece en text delimitada por bordes de palabra
+// (inicio/fin del texto o un carácter que no es letra ni dígito a cada lado).
+func containsWords(text, phrase string) bool {
+	for from := 0; from <= len(text)-len(phrase); {
+		i := strings.Index(text[from:], phrase)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(phrase)
+		if isWordBoundary(text, start, end) {
+			return true
+		}
+		from = start + 1
+	}
+	return false
+}
+
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		if r, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
@@ -22,6 +22,12 @@ func TestDetectDeicticReference_Positivos(t *testing.T) {
 		{"mencionadas anteriormente", "De las causas mencionadas anteriormente, ¿cuál es la principal?"},
 		{"fragmento", "Según el fragmento, ¿qué obligación tiene el peatón?"},
 		{"espacios multiples", "según   las\n\tideas dadas, ¿qué es la calzada?"},
+		{"portugues segundo o texto", "Segundo o texto, qual é a distância mínima de segurança?"},
+		{"portugues ideias fornecidas", "Com base nas ideias fornecidas, o que é a faixa de rodagem?"},
+		{"portugues paragrafo anterior", "O que o parágrafo anterior diz sobre a faixa de pedestres?"},
+		{"portugues paragrafo com acento", "O que diz o parágrafo? Responda conforme o texto."},
+		{"ingles according to the text", "According to the text, when should high beams be used?"},
+		{"ingles mentioned above", "Which of the causes mentioned above is the most common?"},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -48,6 +54,12 @@ func TestDetectDeicticReference_FalsosPositivos(t *testing.T) {
 		{"anterior sin deixis de discurso", "Si el semáforo anterior estaba en rojo, ¿qué debe esperar del siguiente?"},
 		{"parrafo de la ley", "El artículo 110, en el párrafo segundo, ¿qué establece sobre la velocidad?"},
 		{"enunciado autocontenido normal", "¿Cuál es la distancia mínima de seguimiento en carretera con lluvia?"},
+		{"negacion espanola no material", "¿Qué son los bienes no materiales?"},
+		{"negacion espanola no fragmento", "¿Por qué el parabrisas laminado no fragmentó en el choque?"},
+		{"palabra que contiene la frase", "¿Qué corriente filosófica se centra en el materialismo?"},
+		{"portugues segundo a lei", "Segundo a lei de trânsito, qual é o limite de álcool permitido?"},
+		{"ingles according to the law", "According to the traffic law, who has right of way at an unmarked crossing?"},
+		{"ingles text of a sign", "What does the text on a STOP sign mean?"},
 		{"vacio", ""},
 		{"solo espacios", "   \n\t "},
 	}
@@ -22,6 +22,7 @@ import (
 	"github.com/EduGoGroup/edugo-shared/logger"
 	"github.com/EduGoGroup/edugo-shared/textmatch"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
@@ -299,7 +300,7 @@ func (d *DedupePass) arePairDuplicate(ctx context.Context, cascade *textmatch.Ca
 		QuestionText: a.payload.QuestionText,
 		Expected:     a.payload.QuestionText,
 		Candidate:    b.payload.QuestionText,
-		Language:     "es",
+		Language:     language.Detect(a.payload.QuestionText+" "+b.payload.QuestionText, language.Default),
 	})
 	if err != nil {
 		return false, tierLLM, fmt.Errorf("juez de equivalencia en dedupe: %w", err)
@@ -20,7 +20,10 @@ const defaultVerbatimMaxWords = 25
 // (thresholdWords+1) palabras del texto de la candidata que aparece —en el mismo orden y
 // contigua— dentro del texto del chunk. La comparación es por tokens normalizados
 // (textmatch.Normalize: minúsculas, sin tildes, preserva la «ñ»; frontera = todo carácter
-// no alfanumérico). Se computa AL VUELO (no se persiste: es recomputable gratis).
+// no alfanumérico). Se computa AL VUELO (no se persiste: es recomputable gratis). La
+// normalización no depende del idioma: quitar diacríticos también aplana la «ç» y la
+// «ã» del portugués, y el apóstrofo del inglés es frontera en ambos lados, así que el
+// candado vale igual para materiales en es, pt y en.
 //
 // A diferencia de textmatch.SplitTokens, aquí NO se descartan las conectoras «y»/«e»:
 // quitarlas alteraría la contigüidad y volvería «verbatim» a un texto que no lo es. El
@@ -74,6 +74,26 @@ func TestIsLocalOnly_NormalizacionCasoYTildes(t *testing.T) {
 	}
 }
 
+// La normalización no depende del idioma: una cita en portugués o inglés se detecta
+// aunque la candidata pierda cedillas, tildes o el apóstrofo tipográfico.
+func TestIsLocalOnly_PortuguesEIngles(t *testing.T) {
+	const threshold = 10
+	for name, tc := range map[string]struct{ chunk, cand string }{
+		"portugués": {
+			chunk: "A fotossíntese é o processo pelo qual as plantas não só produzem açúcares, mas também liberam oxigênio na atmosfera.",
+			cand:  "a fotossintese e o processo pelo qual as plantas nao so produzem acucares",
+		},
+		"inglés": {
+			chunk: "The chloroplast’s membrane doesn’t let the sugars leave until the Calvin cycle has finished fixing the carbon dioxide.",
+			cand:  "the chloroplast's membrane doesn't let the sugars leave until the Calvin cycle",
+		},
+	} {
+		if !IsLocalOnly(tc.cand, tc.chunk, threshold) {
+			t.Errorf("%s: la cita literal debe detectarse pese a la ortografía superficial", name)
+		}
+	}
+}
+
 // candidateVerbatimText concatena question_text + options + explanation: una cita repartida
 // entre pregunta y explicación se detecta igual.
 func TestCandidateVerbatimText_ConcatenaCampos(t *testing.T) {
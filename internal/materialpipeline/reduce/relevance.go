@@ -3,10 +3,12 @@ package reduce
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/EduGoGroup/edugo-shared/logger"
 	"github.com/EduGoGroup/edugo-shared/textmatch"
 	"github.com/EduGoGroup/edugo-worker/internal/client/m2m"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 	"github.com/EduGoGroup/edugo-worker/internal/materialpipeline"
 )
@@ -265,7 +267,8 @@ func (r *RelevancePass) scoreWithRetry(ctx context.Context, judge relevanceJudge
 	req := llm.RelevanceRequest{
 		QuestionText: payload.QuestionText,
 		MainIdeas:    mainIdeas,
-		Language:     "es",
+		// El rationale no se muestra a nadie, pero sale en el idioma del material.
+		Language: language.Detect(payload.QuestionText+" "+strings.Join(mainIdeas, " "), language.Default),
 	}
 	result, err := judge.ScoreRelevance(ctx, req)
 	if err == nil {
@@ -5,18 +5,44 @@
 // DETERMINISTA en Go, anclada a las mismas escalas del prompt open_ended actual.
 //
 // La agregación (aggregate) es pura y unit-testeada aparte; solo las comprobaciones
-// de criterio consultan al provider.
+// de criterio consultan al provider. La confianza del resultado es la del criterio
+// menos firme.
 package openended
 
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/EduGoGroup/edugo-shared/logger"
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 )
 
+// feedbackMessages son los feedback que arma la agregación (los ve el alumno), por
+// idioma. Formatos: none (total), correct (total), partial (met, total, faltantes).
+var feedbackMessages = map[string]struct{ empty, none, correct, partial string }{
+	language.Spanish: {
+		empty:   "No hay criterios evaluables para esta pregunta.",
+		none:    "No se cumplió ninguno de los %d criterios esperados.",
+		correct: "Respuesta correcta: cumple los %d criterios esperados.",
+		partial: "Respuesta parcial: cumple %d de %d criterios. Faltó: %s.",
+	},
+	language.Portuguese: {
+		empty:   "Não há critérios avaliáveis para esta pergunta.",
+		none:    "Nenhum dos %d critérios esperados foi cumprido.",
+		correct: "Resposta correta: cumpre os %d critérios esperados.",
+		partial: "Resposta parcial: cumpre %d de %d critérios. Faltou: %s.",
+	},
+	language.English: {
+		empty:   "There are no gradable criteria for this question.",
+		none:    "None of the %d expected criteria were met.",
+		correct: "Correct answer: meets the %d expected criteria.",
+		partial: "Partial answer: meets %d of %d criteria. Missing: %s.",
+	},
+}
+
 // GradeInput es la entrada del carril por criterios: la pregunta y respuesta del
 // alumno más los criterios verificables del prep (≥1).
 type GradeInput struct {
@@ -26,7 +52,7 @@ type GradeInput struct {
 	// Criteria son los criterios verificables del prep (open_ended). Una llamada
 	// binaria por criterio.
 	Criteria []string
-	// Language del feedback (default "es").
+	// Language del feedback (language.Supported; default "es").
 	Language string
 	// Logger opcional para avisar el fallback de extracción de ideas (D-045.9). nil =
 	// sin log; la extracción es AYUDA, su falla no cambia el veredicto.
@@ -41,10 +67,7 @@ type GradeInput struct {
 // EXACTAMENTE al comportamiento anterior (juicio contra la respuesta cruda) y su error
 // NO se propaga.
 func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.ReviewResult, error) {
-	lang := in.Language
-	if lang == "" {
-		lang = "es"
-	}
+	lang := language.OrDefault(in.Language)
 
 	// F4 (D-045.9): descomponer la prosa del alumno en ideas atómicas ANTES de comparar,
 	// para bajarle la dificultad al juicio compuesto que reprobaba el Caso 2 (Go
@@ -66,6 +89,7 @@ func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.Re
 
 	var met, total int
 	var unmet []string
+	var confidences []float64
 	for _, c := range in.Criteria {
 		crit := strings.TrimSpace(c)
 		if crit == "" {
@@ -88,9 +112,14 @@ func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.Re
 		} else {
 			unmet = append(unmet, crit)
 		}
+		if res.Confidence != nil {
+			confidences = append(confidences, *res.Confidence)
+		}
 	}
 
-	return aggregate(met, total, unmet), nil
+	result := aggregate(met, total, unmet, lang)
+	result.Confidence = leastConfident(confidences)
+	return result, nil
 }
 
 // aggregate recompone el veredicto+score global a partir de cuántos criterios se
@@ -102,13 +131,14 @@ func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.Re
 //   - parcial (0<p<1)        → partial,   score 0.3 + 0.4·p (queda dentro de 0.3–0.7)
 //
 // Sin criterios (total==0) el carril no aplica; se devuelve incorrect/0 defensivo (el
-// caller solo entra aquí con ≥1 criterio, pero no asumimos).
-func aggregate(met, total int, unmet []string) llm.ReviewResult {
+// caller solo entra aquí con ≥1 criterio, pero no asumimos). El feedback sale en lang.
+func aggregate(met, total int, unmet []string, lang string) llm.ReviewResult {
+	msg := feedbackMessages[language.OrDefault(lang)]
 	if total == 0 {
 		return llm.ReviewResult{
 			Verdict:  llm.VerdictIncorrect,
 			Score:    0.0,
-			Feedback: "No hay criterios evaluables para esta pregunta.",
+			Feedback: msg.empty,
 		}
 	}
 
@@ -117,13 +147,13 @@ func aggregate(met, total int, unmet []string) llm.ReviewResult {
 		return llm.ReviewResult{
 			Verdict:  llm.VerdictIncorrect,
 			Score:    0.0,
-			Feedback: fmt.Sprintf("No se cumplió ninguno de los %d criterios esperados.", total),
+			Feedback: fmt.Sprintf(msg.none, total),
 		}
 	case total:
 		return llm.ReviewResult{
 			Verdict:  llm.VerdictCorrect,
 			Score:    1.0,
-			Feedback: fmt.Sprintf("Respuesta correcta: cumple los %d criterios esperados.", total),
+			Feedback: fmt.Sprintf(msg.correct, total),
 		}
 	default:
 		p := float64(met) / float64(total)
@@ -131,11 +161,22 @@ func aggregate(met, total int, unmet []string) llm.ReviewResult {
 		return llm.ReviewResult{
 			Verdict:  llm.VerdictPartial,
 			Score:    score,
-			Feedback: fmt.Sprintf("Respuesta parcial: cumple %d de %d criterios. Faltó: %s.", met, total, strings.Join(unmet, "; ")),
+			Feedback: fmt.Sprintf(msg.partial, met, total, strings.Join(unmet, "; ")),
 		}
 	}
 }
 
+// leastConfident es la confianza del veredicto agregado: la del criterio menos firme,
+// porque basta que ese criterio cambie para que cambien el veredicto y el score. nil si
+// ningún criterio trajo confianza (provider sin logprobs ni votación).
+func leastConfident(confidences []float64) *float64 {
+	if len(confidences) == 0 {
+		return nil
+	}
+	least := slices.Min(confidences)
+	return &least
+}
+
 // logExtractFallback avisa (si hay logger) que la extracción de ideas no aportó y la
 // corrección sigue con la respuesta cruda (D-045.9). nil-safe: sin logger no hace nada.
 // El error de extracción NUNCA se propaga como fallo del intento (es AYUDA, no ruta
@@ -25,6 +25,8 @@ type mockProvider struct {
 	extractCalls int
 	// gotIdeas guarda, por cada CheckCriterion, las ExtractedIdeas que llegaron.
 	gotIdeas [][]string
+	// confidence es la confianza por criterio; ausente ⇒ sin señal (nil).
+	confidence map[string]float64
 }
 
 func (m *mockProvider) GenerateAssessment(_ context.Context, _ llm.MaterialInput, _ llm.GenerationParams) (json.RawMessage, error) {
@@ -53,7 +55,11 @@ func (m *mockProvider) CheckCriterion(_ context.Context, req llm.CriterionCheckR
 	if v == llm.VerdictCorrect {
 		score = 1.0
 	}
-	return llm.ReviewResult{Verdict: v, Score: score}, nil
+	res := llm.ReviewResult{Verdict: v, Score: score}
+	if c, ok := m.confidence[req.Criterion]; ok {
+		res.Confidence = &c
+	}
+	return res, nil
 }
 func (m *mockProvider) ExtractIdeas(_ context.Context, _ llm.ExtractIdeasRequest) ([]string, error) {
 	m.extractCalls++
@@ -88,7 +94,7 @@ func TestAggregate(t *testing.T) {
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			res := aggregate(tc.met, tc.total, nil)
+			res := aggregate(tc.met, tc.total, nil, "es")
 			if res.Verdict != tc.wantVerdict {
 				t.Fatalf("verdict = %s, quería %s", res.Verdict, tc.wantVerdict)
 			}
@@ -103,6 +109,19 @@ func TestAggregate(t *testing.T) {
 	}
 }
 
+func TestAggregate_FeedbackEnElIdioma(t *testing.T) {
+	for lang, want := range map[string]string{
+		"es": "Respuesta parcial: cumple 1 de 2 criterios. Faltó: c2.",
+		"pt": "Resposta parcial: cumpre 1 de 2 critérios. Faltou: c2.",
+		"en": "Partial answer: meets 1 of 2 criteria. Missing: c2.",
+		"fr": "Respuesta parcial: cumple 1 de 2 criterios. Faltó: c2.",
+	} {
+		if got := aggregate(1, 2, []string{"c2"}, lang).Feedback; got != want {
+			t.Errorf("%s: feedback = %q, quería %q", lang, got, want)
+		}
+	}
+}
+
 func fotosintesis(criteria []string) GradeInput {
 	return GradeInput{
 		QuestionText:  "Explica el proceso de la fotosíntesis.",
@@ -280,3 +299,25 @@ func TestGrade_ExtraccionError_NoRompeYCaeARaw(t *testing.T) {
 		t.Fatalf("esperaba correct (2/2), hubo %s", res.Verdict)
 	}
 }
+
+func TestGrade_ConfianzaDelCriterioMenosFirme(t *testing.T) {
+	m := &mockProvider{
+		met:        map[string]bool{"c1": true},
+		confidence: map[string]float64{"c1": 0.9, "c2": 0.55},
+	}
+	res, err := Grade(context.Background(), m, GradeInput{StudentAnswer: "a", Criteria: []string{"c1", "c2", "c3"}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Confidence == nil || *res.Confidence != 0.55 {
+		t.Fatalf("confianza %v, esperaba la del criterio menos firme (0.55)", res.Confidence)
+	}
+
+	res, err = Grade(context.Background(), &mockProvider{}, GradeInput{StudentAnswer: "a", Criteria: []string{"c1"}})
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if res.Confidence != nil {
+		t.Errorf("sin confianza de los criterios no hay señal: %v", *res.Confidence)
+	}
+}
@@ -16,13 +16,32 @@ package shortanswer
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/EduGoGroup/edugo-shared/textmatch"
 
+	"github.com/EduGoGroup/edugo-worker/internal/language"
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
 )
 
+// feedbackMessages son los feedback del veredicto recompuesto, por idioma. incomplete
+// recibe la lista de ítems que faltaron.
+var feedbackMessages = map[string]struct{ correct, incomplete string }{
+	language.Spanish: {
+		correct:    "Respuesta correcta: se identificaron todos los elementos esperados.",
+		incomplete: "Respuesta incompleta: faltó mencionar %s.",
+	},
+	language.Portuguese: {
+		correct:    "Resposta correta: todos os elementos esperados foram identificados.",
+		incomplete: "Resposta incompleta: faltou mencionar %s.",
+	},
+	language.English: {
+		correct:    "Correct answer: all the expected items were identified.",
+		incomplete: "Incomplete answer: missing %s.",
+	},
+}
+
 // GradeInput es la entrada del carril triturado: la pregunta y respuesta del alumno
 // más los ítems del prep (normalizados + verbatim). Items ya viene normalizado por el
 // contrato; textmatch re-normaliza al comparar (no confía en que el LLM que produjo el
@@ -36,7 +55,7 @@ type GradeInput struct {
 	// usan para el prompt del par (legible) y el feedback (qué faltó). Puede venir
 	// desalineado/corto: verbatimAt cae al ítem normalizado si falta.
 	ItemsVerbatim []string
-	// Language del feedback (default "es").
+	// Language del feedback (language.Supported; default "es").
 	Language string
 }
 
@@ -58,6 +77,13 @@ func (llmPairStrategy) Name() string { return "llm-pair" }
 // textmatch.Result: VerdictCorrect ⇒ Match/1.0, cualquier otro ⇒ NoMatch/0.0. El
 // error del provider (transitorio) se propaga para que el caller reintente el intento.
 func (s llmPairStrategy) Compare(ctx context.Context, expected, candidate string) (textmatch.Result, error) {
+	r, _, err := s.judge(ctx, expected, candidate)
+	return r, err
+}
+
+// judge es Compare devolviendo además la confianza del juicio del modelo (nil si el
+// provider no la reporta).
+func (s llmPairStrategy) judge(ctx context.Context, expected, candidate string) (textmatch.Result, *float64, error) {
 	res, err := s.provider.JudgePairEquivalence(ctx, llm.PairEquivalenceRequest{
 		QuestionText: s.questionText,
 		Expected:     expected,
@@ -65,12 +91,31 @@ func (s llmPairStrategy) Compare(ctx context.Context, expected, candidate string
 		Language:     s.language,
 	})
 	if err != nil {
-		return textmatch.Result{}, err
+		return textmatch.Result{}, nil, err
 	}
 	if res.Verdict == llm.VerdictCorrect {
-		return textmatch.Result{Outcome: textmatch.OutcomeMatch, Confidence: 1.0, Evidence: res.Feedback, Strategy: "llm-pair"}, nil
+		return textmatch.Result{Outcome: textmatch.OutcomeMatch, Confidence: 1.0, Evidence: res.Feedback, Strategy: "llm-pair"}, res.Confidence, nil
+	}
+	return textmatch.Result{Outcome: textmatch.OutcomeNoMatch, Confidence: 0.0, Evidence: res.Feedback, Strategy: "llm-pair"}, res.Confidence, nil
+}
+
+// coverageRecorder envuelve el comparador de la fase determinista y guarda, por ítem
+// esperado, el Result del match que lo cubrió (el SetMatcher solo devuelve Covered).
+// Su Confidence dice con qué tier quedó cubierto: 1.0 el exacto, la similitud el fuzzy.
+type coverageRecorder struct {
+	cmp     textmatch.Comparator
+	matched map[string]textmatch.Result
+}
+
+// Compare satisface textmatch.Comparator.
+func (r *coverageRecorder) Compare(ctx context.Context, expected, candidate string) (textmatch.Result, error) {
+	res, err := r.cmp.Compare(ctx, expected, candidate)
+	if err == nil && res.Outcome == textmatch.OutcomeMatch {
+		if _, ok := r.matched[expected]; !ok {
+			r.matched[expected] = res
+		}
 	}
-	return textmatch.Result{Outcome: textmatch.OutcomeNoMatch, Confidence: 0.0, Evidence: res.Feedback, Strategy: "llm-pair"}, nil
+	return res, err
 }
 
 // Grade ejecuta el carril triturado y devuelve un ReviewResult BINARIO. Flujo en dos
@@ -84,16 +129,20 @@ func (s llmPairStrategy) Compare(ctx context.Context, expected, candidate string
 //  3. Recomposición: todos los ítems presentes ⇒ correct/1.0; falta alguno ⇒
 //     incorrect/0.0 con feedback de qué faltó.
 //
+// La confianza del resultado es la del ítem menos firme: la del tier que lo cubrió en
+// la fase 1 o la del juicio del par (si el provider la reporta). Un ítem que falta sin
+// candidato no aporta: no hubo nada que juzgar.
+//
 // Un error del provider en un par se propaga (transitorio, el caller reintenta).
 func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.ReviewResult, error) {
-	lang := in.Language
-	if lang == "" {
-		lang = "es"
-	}
+	lang := language.OrDefault(in.Language)
 
 	// Fase 1 — determinista (exacto + fuzzy), sin LLM. Policy Lenient: los sobrantes
 	// del alumno ("el famoso") no penalizan; solo importa cubrir los ítems esperados.
-	det := textmatch.NewCascade(textmatch.Exact{}, textmatch.NewFuzzy(0))
+	det := &coverageRecorder{
+		cmp:     textmatch.NewCascade(textmatch.Exact{}, textmatch.NewFuzzy(0)),
+		matched: make(map[string]textmatch.Result),
+	}
 	rep, err := textmatch.NewSetMatcher(det, textmatch.PolicyLenient).MatchAnswer(ctx, in.Items, in.StudentAnswer)
 	if err != nil {
 		// Las estrategias deterministas nunca devuelven error; se propaga por defensa.
@@ -119,8 +168,12 @@ func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.Re
 	pair := llmPairStrategy{provider: provider, questionText: in.QuestionText, language: lang}
 
 	var missing []string // verbatim de los ítems ausentes (para el feedback)
+	var confidences []float64
 	for i := range in.Items {
 		if rep.Covered[i] {
+			if m, ok := det.matched[in.Items[i]]; ok {
+				confidences = append(confidences, m.Confidence)
+			}
 			continue
 		}
 		best, ok := bestCandidate(textmatch.Normalize(in.Items[i]), cands, usedCand)
@@ -129,10 +182,13 @@ func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.Re
 			missing = append(missing, verbatimAt(in, i))
 			continue
 		}
-		r, err := pair.Compare(ctx, verbatimAt(in, i), best.Text)
+		r, confidence, err := pair.judge(ctx, verbatimAt(in, i), best.Text)
 		if err != nil {
 			return llm.ReviewResult{}, fmt.Errorf("par equivalencia (ítem %q vs %q): %w", in.Items[i], best.Text, err)
 		}
+		if confidence != nil {
+			confidences = append(confidences, *confidence)
+		}
 		if r.Outcome == textmatch.OutcomeMatch {
 			// Marca los tokens del candidato usados para no reofrecerlos a otro ítem.
 			for k := best.Start; k < best.End; k++ {
@@ -143,17 +199,24 @@ func Grade(ctx context.Context, provider llm.LLMProvider, in GradeInput) (llm.Re
 		}
 	}
 
+	var confidence *float64
+	if len(confidences) > 0 {
+		least := slices.Min(confidences)
+		confidence = &least
+	}
 	if len(missing) == 0 {
 		return llm.ReviewResult{
-			Verdict:  llm.VerdictCorrect,
-			Score:    1.0,
-			Feedback: "Respuesta correcta: se identificaron todos los elementos esperados.",
+			Verdict:    llm.VerdictCorrect,
+			Score:      1.0,
+			Feedback:   feedbackMessages[lang].correct,
+			Confidence: confidence,
 		}, nil
 	}
 	return llm.ReviewResult{
-		Verdict:  llm.VerdictIncorrect,
-		Score:    0.0,
-		Feedback: fmt.Sprintf("Respuesta incompleta: faltó mencionar %s.", strings.Join(missing, ", ")),
+		Verdict:    llm.VerdictIncorrect,
+		Score:      0.0,
+		Feedback:   fmt.Sprintf(feedbackMessages[lang].incomplete, strings.Join(missing, ", ")),
+		Confidence: confidence,
 	}, nil
 }
 
@@ -3,6 +3,7 @@ package shortanswer
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"testing"
 
 	"github.com/EduGoGroup/edugo-worker/internal/llm"
@@ -12,11 +13,13 @@ import (
 // mockProvider implementa llm.LLMProvider; solo JudgePairEquivalence hace algo. Cuenta
 // las llamadas de par (para verificar el modelo de costo del carril) y responde según
 // la config: pairErr fuerza un error; alwaysCorrect devuelve siempre correct/1.0. Sin
-// config, el par devuelve incorrect por defecto.
+// config, el par devuelve incorrect por defecto. pairConfidence es la confianza que
+// reporta cada juicio de par (nil = sin señal).
 type mockProvider struct {
-	alwaysCorrect bool
-	pairCalls     int
-	pairErr       error
+	alwaysCorrect  bool
+	pairCalls      int
+	pairErr        error
+	pairConfidence *float64
 }
 
 func (m *mockProvider) GenerateAssessment(_ context.Context, _ llm.MaterialInput, _ llm.GenerationParams) (json.RawMessage, error) {
@@ -34,9 +37,9 @@ func (m *mockProvider) JudgePairEquivalence(_ context.Context, _ llm.PairEquival
 		return llm.ReviewResult{}, m.pairErr
 	}
 	if m.alwaysCorrect {
-		return llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 1.0}, nil
+		return llm.ReviewResult{Verdict: llm.VerdictCorrect, Score: 1.0, Confidence: m.pairConfidence}, nil
 	}
-	return llm.ReviewResult{Verdict: llm.VerdictIncorrect, Score: 0.0}, nil
+	return llm.ReviewResult{Verdict: llm.VerdictIncorrect, Score: 0.0, Confidence: m.pairConfidence}, nil
 }
 func (m *mockProvider) CheckCriterion(_ context.Context, _ llm.CriterionCheckRequest) (llm.ReviewResult, error) {
 	return llm.ReviewResult{}, nil
@@ -119,6 +122,25 @@ func TestGrade_FaltanteReal_SinCandidato_Incorrect(t *testing.T) {
 	}
 }
 
+// El feedback del veredicto recompuesto sale en el idioma de la escuela.
+func TestGrade_FeedbackEnElIdioma(t *testing.T) {
+	prov := &mockProvider{}
+	in := GradeInput{
+		QuestionText:  "Name two South American countries",
+		StudentAnswer: "brazil",
+		Items:         []string{"brazil", "argentina"},
+		ItemsVerbatim: []string{"Brazil", "Argentina"},
+		Language:      "en",
+	}
+	res, err := Grade(context.Background(), prov, in)
+	if err != nil {
+		t.Fatalf("Grade error: %v", err)
+	}
+	if want := "Incomplete answer: missing Argentina."; res.Feedback != want {
+		t.Fatalf("feedback = %q, quería %q", res.Feedback, want)
+	}
+}
+
 // TestGrade_Escalado_UnaLlamada_Cubre: un ítem que el fuzzy NO cubre pero hay un
 // candidato sobrante ⇒ exactamente UNA llamada al provider para ese ítem; si el fake
 // dice correct, el ítem queda cubierto y el veredicto es correct.
@@ -182,3 +204,31 @@ func TestGrade_TodoDeterminista_SinLlamadas(t *testing.T) {
 		t.Fatalf("esperaba 0 llamadas de par (todo determinista), hubo %d", prov.pairCalls)
 	}
 }
+
+// TestGrade_ConfianzaDelItemMenosFirme: la confianza es la del ítem cubierto con menos
+// firmeza. En el Caso 1 es el typo "whastapp" (fuzzy, sim 0.875); con un par escalado,
+// la del juicio del modelo.
+func TestGrade_ConfianzaDelItemMenosFirme(t *testing.T) {
+	res, err := Grade(context.Background(), &mockProvider{}, GradeInput{
+		StudentAnswer: "whastapp instalgram y el famoso facebook",
+		Items:         []string{"facebook", "instagram", "whatsapp"},
+	})
+	if err != nil {
+		t.Fatalf("Grade error: %v", err)
+	}
+	if res.Confidence == nil || math.Abs(*res.Confidence-0.875) > 1e-9 {
+		t.Fatalf("confianza %v, esperaba la del fuzzy de whastapp (0.875)", res.Confidence)
+	}
+
+	pair := 0.6
+	res, err = Grade(context.Background(), &mockProvider{alwaysCorrect: true, pairConfidence: &pair}, GradeInput{
+		StudentAnswer: "colombia y pais azteca",
+		Items:         []string{"colombia", "mexico"},
+	})
+	if err != nil {
+		t.Fatalf("Grade error: %v", err)
+	}
+	if res.Confidence == nil || *res.Confidence != 0.6 {
+		t.Fatalf("confianza %v, esperaba la del par (0.6)", res.Confidence)
+	}
+}